@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/auth"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/infrastructure/storage"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/privacy"
+	"github.com/mundipagg/boleto-api/usermanagement"
+)
+
+//Admin configura as rotas de administração das credenciais dos usuários e de eliminação de dados pessoais
+func Admin(router *gin.Engine, repository db.BoletoRepository) {
+	eraser := privacy.NewEraser(repository, db.NewMongoErasureRepository(), db.CreateRedis(), fallbackStorage)
+
+	admin := router.Group("admin")
+	admin.Use(returnHeaders())
+	admin.Use(adminAuthentication)
+	admin.GET("/credentials", listCredentials)
+	admin.POST("/credentials", createCredentials)
+	admin.POST("/credentials/:key/rotate", rotateCredentials)
+	admin.POST("/credentials/:key/disable", disableCredentials)
+	admin.PUT("/credentials/:key/banks/:bank", setBankCredentials)
+	admin.POST("/credentials/:key/apikeys", createAPIKey)
+	admin.DELETE("/credentials/:key/apikeys/:id", revokeAPIKey)
+	admin.PUT("/credentials/:key/retention", setRetentionPolicy)
+	admin.DELETE("/credentials/:key/retention", removeRetentionPolicy)
+	admin.POST("/erasures", eraseBuyerData(eraser))
+	admin.GET("/erasures", listErasures(eraser))
+}
+
+func fallbackStorage() (privacy.FallbackStorage, error) {
+	return storage.GetClient()
+}
+
+//adminAuthentication Middleware de autenticação das rotas de administração
+//As rotas ficam indisponíveis enquanto ADMIN_USERNAME e ADMIN_PASSWORD_HASH não forem configurados
+func adminAuthentication(c *gin.Context) {
+	username, password, hasAuth := c.Request.BasicAuth()
+
+	admin := models.Credentials{Username: config.Get().AdminUsername, Password: config.Get().AdminPasswordHash}
+	if !hasAuth || admin.Username == "" || admin.Password == "" ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(admin.Username)) != 1 ||
+		!admin.CheckPassword(password) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, models.GetBoletoResponseError("MP401", "Unauthorized"))
+		return
+	}
+}
+
+func listCredentials(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	credentials, err := usermanagement.ListCredentials(ctx)
+	if err != nil {
+		adminError(c, "ListCredentials", err)
+		return
+	}
+
+	views := make([]models.CredentialsView, 0, len(credentials))
+	for _, cred := range credentials {
+		views = append(views, cred.ToView())
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func createCredentials(c *gin.Context) {
+	request := models.CredentialsRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+		return
+	}
+	if request.Username == "" || request.DailyRegistrationQuota < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "username is required and dailyRegistrationQuota cannot be negative"))
+		return
+	}
+	if request.RegistrationsPerMinute < 0 || request.RegistrationBurst < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "registrationsPerMinute and registrationBurst cannot be negative"))
+		return
+	}
+	for bank := range request.Banks {
+		if _, err := strconv.Atoi(bank); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "banks must be keyed by the bank number"))
+			return
+		}
+	}
+	if request.Retention != nil {
+		if err := request.Retention.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, password, err := usermanagement.CreateCredentials(ctx, request)
+	if err != nil {
+		adminError(c, "CreateCredentials", err)
+		return
+	}
+
+	view := cred.ToView()
+	view.Password = password
+	c.JSON(http.StatusCreated, view)
+}
+
+func rotateCredentials(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, password, err := usermanagement.RotatePassword(ctx, c.Param("key"))
+	if err != nil {
+		adminError(c, "RotateCredentials", err)
+		return
+	}
+
+	view := cred.ToView()
+	view.Password = password
+	c.JSON(http.StatusOK, view)
+}
+
+func disableCredentials(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, err := usermanagement.DisableCredentials(ctx, c.Param("key"))
+	if err != nil {
+		adminError(c, "DisableCredentials", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred.ToView())
+}
+
+func setBankCredentials(c *gin.Context) {
+	request := models.BankCredentials{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+		return
+	}
+	if _, err := strconv.Atoi(c.Param("bank")); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "bank must be the bank number"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, err := usermanagement.SetBankCredentials(ctx, c.Param("key"), c.Param("bank"), request)
+	if err != nil {
+		adminError(c, "SetBankCredentials", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred.ToView())
+}
+
+//createAPIKey Cria uma chave de API para o usuário. A chave só é retornada nesta resposta
+func createAPIKey(c *gin.Context) {
+	request := models.APIKeyRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+		return
+	}
+	if len(request.Scopes) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "scopes is required"))
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !auth.IsPermission(scope) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "unknown scope "+scope))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	apiKey, key, err := usermanagement.CreateAPIKey(ctx, c.Param("key"), request.Scopes)
+	if err != nil {
+		adminError(c, "CreateAPIKey", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIKeyView{APIKey: apiKey, Key: key})
+}
+
+func revokeAPIKey(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, err := usermanagement.RevokeAPIKey(ctx, c.Param("key"), c.Param("id"))
+	if err != nil {
+		adminError(c, "RevokeAPIKey", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred.ToView())
+}
+
+//setRetentionPolicy Define a política de retenção própria do usuário. Zero dias mantém os boletos dele para sempre
+func setRetentionPolicy(c *gin.Context) {
+	policy := models.RetentionPolicy{}
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, err := usermanagement.SetRetentionPolicy(ctx, c.Param("key"), &policy)
+	if err != nil {
+		adminError(c, "SetRetentionPolicy", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred.ToView())
+}
+
+//removeRetentionPolicy Remove a política de retenção própria do usuário, que volta a seguir a política padrão
+func removeRetentionPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	cred, err := usermanagement.SetRetentionPolicy(ctx, c.Param("key"), nil)
+	if err != nil {
+		adminError(c, "RemoveRetentionPolicy", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred.ToView())
+}
+
+//eraseBuyerData Elimina os dados pessoais de um comprador, a pedido do titular pela LGPD
+//Retorna o registro da eliminação, que lista as falhas quando ela não foi completa
+func eraseBuyerData(eraser *privacy.Eraser) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := models.ErasureRequest{}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+			return
+		}
+
+		requestedBy, _, _ := c.Request.BasicAuth()
+		record, err := eraser.Erase(c.Request.Context(), request, requestedBy)
+		if err == privacy.ErrBuyerDocumentRequired {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+			return
+		} else if err != nil {
+			adminError(c, "EraseBuyerData", err)
+			return
+		}
+
+		if !record.Completed {
+			l := log.CreateLog()
+			l.Operation = "EraseBuyerData"
+			l.Warn(record.Errors, "Buyer data erasure incomplete")
+		}
+		c.JSON(http.StatusCreated, record)
+	}
+}
+
+//listErasures Lista os registros de eliminação do documento do comprador
+func listErasures(eraser *privacy.Eraser) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		records, err := eraser.History(ctx, c.Query("buyerDocument"))
+		if err == privacy.ErrBuyerDocumentRequired {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+			return
+		} else if err != nil {
+			adminError(c, "ListErasures", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, records)
+	}
+}
+
+func adminError(c *gin.Context, operation string, err error) {
+	if err == db.ErrCredentialsNotFound || err == usermanagement.ErrAPIKeyNotFound {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.GetBoletoResponseError("MP404", err.Error()))
+		return
+	}
+	if err == db.ErrUsernameTaken {
+		c.AbortWithStatusJSON(http.StatusConflict, models.GetBoletoResponseError("MP409", err.Error()))
+		return
+	}
+
+	l := log.CreateLog()
+	l.Operation = operation
+	l.IPAddress = c.ClientIP()
+	l.Error(err.Error(), "Error managing credentials")
+	c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponseToClient())
+}
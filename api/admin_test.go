@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/auth"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/usermanagement"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_Admin_WhenNotConfigured_ReturnUnauthorized(t *testing.T) {
+	router := arrangeAdminRoute("", "")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/credentials", nil)
+	req.SetBasicAuth("", "")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+}
+
+func Test_Admin_WhenInvalidPassword_ReturnUnauthorized(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/credentials", nil)
+	req.SetBasicAuth("admin", "wrong")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+}
+
+func Test_Admin_CreateRotateAndDisableCredentials(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"gandalf","dailyRegistrationQuota":5}`)
+	var created models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, 201, w.Code)
+	assert.NotEmpty(t, created.Password)
+	assert.Equal(t, 5, created.DailyRegistrationQuota)
+	assert.True(t, authenticates(created.UserKey, created.Password))
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials/"+created.UserKey+"/rotate", "")
+	var rotated models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	assert.Equal(t, 200, w.Code)
+	assert.False(t, authenticates(created.UserKey, created.Password))
+	assert.True(t, authenticates(created.UserKey, rotated.Password))
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials/"+created.UserKey+"/disable", "")
+	assert.Equal(t, 200, w.Code)
+	assert.False(t, authenticates(created.UserKey, rotated.Password))
+
+	w = adminRequest(router, http.MethodGet, "/admin/credentials", "")
+	var list []models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, len(list))
+	assert.True(t, list[0].Disabled)
+	assert.Empty(t, list[0].Password)
+}
+
+func Test_Admin_WhenUnknownKey_ReturnNotFound(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+
+	w := adminRequest(router, http.MethodPost, "/admin/credentials/60c293944808daa6fdf2f3b1/rotate", "")
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func Test_Admin_WhenEmptyUsername_ReturnBadRequest(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":""}`)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func Test_Admin_WhenUsernameIsTaken_ReturnConflict(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"frodo"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"frodo"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	_, ok := usermanagement.GetUserByUsername("frodo")
+	assert.True(t, ok)
+}
+
+func authenticates(userKey, password string) bool {
+	req, _ := http.NewRequest(http.MethodPost, "/v1/boleto/register", nil)
+	req.SetBasicAuth(userKey, password)
+	_, err := auth.Authenticate(req)
+	return err == nil
+}
+
+func arrangeAdminRoute(username, password string) *gin.Engine {
+	os.Clearenv()
+	hash := ""
+	if password != "" {
+		h, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		hash = string(h)
+	}
+	os.Setenv("ADMIN_USERNAME", username)
+	os.Setenv("ADMIN_PASSWORD_HASH", hash)
+	config.Install(true, false, true)
+	usermanagement.Install(db.NewMemoryCredentialRepository())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	Admin(router, db.NewMemoryRepository())
+	return router
+}
+
+func adminRequest(router *gin.Engine, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.SetBasicAuth("admin", "secret")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func Test_Admin_SetBankCredentials_HidesClientSecret(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"merchant","banks":{"745":{"certificate":"merchant-citi"}}}`)
+	var created models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "merchant-citi", created.Banks["745"].Certificate)
+
+	w = adminRequest(router, http.MethodPut, "/admin/credentials/"+created.UserKey+"/banks/33", `{"certificate":"merchant-santander","clientId":"id","clientSecret":"top-secret"}`)
+	var updated models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &updated)
+
+	assert.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), "top-secret")
+	assert.Equal(t, models.BankCredentials{Certificate: "merchant-santander", ClientID: "id"}, updated.Banks["33"])
+	assert.Equal(t, "merchant-citi", updated.Banks["745"].Certificate)
+	user, _ := usermanagement.Authenticate(created.UserKey, created.Password)
+	assert.Equal(t, "top-secret", user.ForBank(models.Santander).ClientSecret)
+
+	w = adminRequest(router, http.MethodPut, "/admin/credentials/"+created.UserKey+"/banks/santander", `{}`)
+	assert.Equal(t, 400, w.Code)
+}
+
+func Test_Admin_CreateAndRevokeAPIKey(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"merchant"}`)
+	var created models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials/"+created.UserKey+"/apikeys", `{"scopes":["boleto:admin"]}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials/"+created.UserKey+"/apikeys", `{"scopes":["boleto:read"]}`)
+	var apiKey models.APIKeyView
+	json.Unmarshal(w.Body.Bytes(), &apiKey)
+	assert.Equal(t, 201, w.Code)
+	assert.NotEmpty(t, apiKey.Key)
+	assert.NotContains(t, w.Body.String(), "hash")
+	_, _, ok := usermanagement.AuthenticateAPIKey(apiKey.Key)
+	assert.True(t, ok)
+
+	w = adminRequest(router, http.MethodDelete, "/admin/credentials/"+created.UserKey+"/apikeys/"+apiKey.ID, "")
+	assert.Equal(t, 200, w.Code)
+	_, _, ok = usermanagement.AuthenticateAPIKey(apiKey.Key)
+	assert.False(t, ok)
+
+	w = adminRequest(router, http.MethodDelete, "/admin/credentials/"+created.UserKey+"/apikeys/"+apiKey.ID, "")
+	assert.Equal(t, 404, w.Code)
+}
+
+func Test_Admin_SetAndRemoveRetentionPolicy(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+	w := adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"merchant","retention":{"days":-1}}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = adminRequest(router, http.MethodPost, "/admin/credentials", `{"username":"merchant"}`)
+	var created models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = adminRequest(router, http.MethodPut, "/admin/credentials/"+created.UserKey+"/retention", `{"days":90,"action":"delete"}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = adminRequest(router, http.MethodPut, "/admin/credentials/"+created.UserKey+"/retention", `{"days":90,"action":"purge"}`)
+	var updated models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, &models.RetentionPolicy{Days: 90, Action: models.RetentionPurge}, updated.Retention)
+
+	w = adminRequest(router, http.MethodDelete, "/admin/credentials/"+created.UserKey+"/retention", "")
+	var removed models.CredentialsView
+	json.Unmarshal(w.Body.Bytes(), &removed)
+	assert.Equal(t, 200, w.Code)
+	assert.Nil(t, removed.Retention)
+}
+
+func Test_Admin_EraseBuyerData_WhenDocumentIsMissing_ReturnBadRequest(t *testing.T) {
+	router := arrangeAdminRoute("admin", "secret")
+
+	w := adminRequest(router, http.MethodPost, "/admin/erasures", `{"reason":"protocolo 123"}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = adminRequest(router, http.MethodGet, "/admin/erasures", "")
+	assert.Equal(t, 400, w.Code)
+}
@@ -1,112 +1,150 @@
 package api
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/boleto"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/encryption"
 	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
 	"github.com/mundipagg/boleto-api/queue"
+	"github.com/mundipagg/boleto-api/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var fallback = new(Fallback)
 
 //registerBoleto Realiza o registro online do Boleto
-func registerBoleto(c *gin.Context) {
-
-	if _, hasErr := c.Get("error"); hasErr {
-		return
-	}
-
-	lg := loadBankLog(c)
-	bol := getBoletoFromContext(c)
-	bank := getBankFromContext(c)
-
-	resp, err := bank.ProcessBoleto(&bol)
+func registerBoleto(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, hasErr := c.Get("error"); hasErr {
+			return
+		}
 
-	if qualifiedForNewErrorHandling(c, resp) {
-		c.Set(responseKey, resp)
-		return
-	}
+		lg := loadBankLog(c)
+		bol := getBoletoFromContext(c)
+		bank := getBankFromContext(c)
 
-	if checkError(c, err, lg) {
-		return
-	}
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(tracing.AttrBankName.String(bank.GetBankNameIntegration()))
+		resp, err := bank.ProcessBoleto(c.Request.Context(), &bol)
 
-	st := getResponseStatusCode(resp)
+		if qualifiedForNewErrorHandling(c, resp) {
+			c.Set(responseKey, resp)
+			return
+		}
 
-	if st == http.StatusOK {
+		if checkError(c, err, lg) {
+			return
+		}
 
-		boView := models.NewBoletoView(bol, resp, bank.GetBankNameIntegration())
-		resp.ID = boView.ID.Hex()
-		resp.Links = boView.Links
+		st := getResponseStatusCode(resp)
 
-		errMongo := db.SaveBoleto(boView)
+		if st == http.StatusOK {
 
-		if errMongo != nil {
-			lg.Warn(errMongo.Error(), "Error saving to mongo")
+			boView := models.NewBoletoView(bol, resp, bank.GetBankNameIntegration(), getUserFromContext(c))
+			resp.ID = boView.ID.Hex()
+			resp.Links = boView.Links
 
-			b := boView.ToMinifyJSON()
-			p := queue.NewPublisher(b)
+			ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+			errMongo := repository.SaveBoleto(ctx, boView)
+			cancel()
 
-			if !queue.WriteMessage(p) {
-				fallback.Save(c, boView.ID.Hex(), b)
+			if errMongo != nil {
+				lg.Warn(errMongo.Error(), "Error saving to mongo")
+				metrics.PushFallback(metrics.FallbackMongoFailure)
+				saveToFallback(c, lg, boView, bol.RequestKey)
 			}
 		}
+
+		c.JSON(st, resp)
+		c.Set("boletoResponse", resp)
 	}
+}
 
-	c.JSON(st, resp)
-	c.Set("boletoResponse", resp)
+//saveToFallback Envia o boleto que não foi gravado no banco para a fila ou, se ela falhar, para o blob storage
+//O boleto segue com os dados pessoais já cifrados, como ficaria no banco. Sem a cifragem ele não é gravado em lugar nenhum
+func saveToFallback(c *gin.Context, lg *log.Log, boView models.BoletoView, requestKey string) {
+	payload := boView
+	if err := encryption.EncryptBoleto(c.Request.Context(), &payload); err != nil {
+		lg.ErrorWithBasic("Error encrypting boleto to fallback", "Error", err)
+		metrics.PushFallback(metrics.FallbackEncryptionFailure)
+		recordEvent(c, models.BoletoEvent{BoletoID: boView.ID.Hex(), Type: models.EventFallbackSaved, Outcome: models.OutcomeFailure, RequestKey: requestKey, Detail: "encryption"})
+		return
+	}
+
+	b := payload.ToMinifyJSON()
+	p := queue.NewPublisher(b)
+
+	if queue.WriteMessage(c.Request.Context(), p) {
+		metrics.PushFallback(metrics.FallbackQueue)
+		recordEvent(c, models.BoletoEvent{BoletoID: boView.ID.Hex(), Type: models.EventFallbackSaved, Outcome: models.OutcomeSuccess, RequestKey: requestKey, Detail: "queue"})
+	} else {
+		fallback.Save(c, boView.ID.Hex(), b)
+	}
 }
 
 //getBoleto Recupera um boleto devidamente registrado
-func getBoleto(c *gin.Context) {
-	var boletoHtml string
+func getBoleto(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var boletoHtml string
 
-	var result = models.NewGetBoletoResult(c)
+		var result = models.NewGetBoletoResult(c)
 
-	if !result.HasValidParameters() {
-		setupGetBoletoResultFailResponse(c, result, "Warning", "Not Found")
-		return
-	}
+		if !result.HasValidParameters() {
+			setupGetBoletoResultFailResponse(c, result, "Warning", "Not Found")
+			return
+		}
 
-	var err error
-	var boView models.BoletoView
+		if err := result.VerifySignature(time.Now()); err != nil {
+			setupGetBoletoResultFailResponse(c, result, "Warning", "Not Found")
+			return
+		}
 
-	boView, result.DatabaseElapsedTimeInMilliseconds, err = db.GetBoletoByID(result.Id, result.PublicKey)
+		var err error
+		var boView models.BoletoView
 
-	if err != nil && (err.Error() == db.NotFoundDoc || err.Error() == db.InvalidPK) {
-		setupGetBoletoResultFailResponse(c, result, "Warning", "Not Found")
-		return
-	} else if err != nil {
-		setupGetBoletoResultFailResponse(c, result, "Error", err.Error())
-		return
-	}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
 
-	result.BoletoSource = "mongo"
-	boletoHtml = boleto.MinifyHTML(boView)
+		boView, result.DatabaseElapsedTimeInMilliseconds, err = repository.GetBoletoByID(ctx, result.Id, result.PublicKey)
 
-	if result.Format == "html" {
-		c.Header("Content-Type", "text/html; charset=utf-8")
-		c.Writer.WriteString(boletoHtml)
-	} else {
-		c.Header("Content-Type", "application/pdf")
-		if boletoPdf, err := toPdf(boletoHtml); err == nil {
-			c.Writer.Write(boletoPdf)
-		} else {
-			c.Header("Content-Type", "application/json")
+		if err != nil && (err.Error() == db.NotFoundDoc || err.Error() == db.InvalidPK) {
+			setupGetBoletoResultFailResponse(c, result, "Warning", "Not Found")
+			return
+		} else if err != nil {
 			setupGetBoletoResultFailResponse(c, result, "Error", err.Error())
 			return
 		}
-	}
 
-	setupGetBoletoSuccessResponse(c, result)
+		result.BoletoSource = "mongo"
+		boView.Signature = result.Signature
+		boletoHtml = boleto.MinifyHTML(boView)
+
+		if result.Format == "html" {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Writer.WriteString(boletoHtml)
+		} else {
+			c.Header("Content-Type", "application/pdf")
+			if boletoPdf, err := toPdf(c.Request.Context(), boletoHtml); err == nil {
+				c.Writer.Write(boletoPdf)
+			} else {
+				c.Header("Content-Type", "application/json")
+				setupGetBoletoResultFailResponse(c, result, "Error", err.Error())
+				return
+			}
+		}
+
+		setupGetBoletoSuccessResponse(c, result)
+	}
 }
 
 func setupGetBoletoResultFailResponse(c *gin.Context, result *models.GetBoletoResult, severity, errorMessage string) {
@@ -139,31 +177,87 @@ func getResponseStatusCode(response models.BoletoResponse) int {
 	return response.StatusCode
 }
 
-func toPdf(page string) ([]byte, error) {
-	url := config.Get().PdfAPIURL
-	payload := strings.NewReader(page)
-	if req, err := http.NewRequest("POST", url, payload); err != nil {
+func toPdf(ctx context.Context, page string) (pdf []byte, err error) {
+	var status int
+	ctx, span := tracing.Start(ctx, "pdf.render")
+	defer func() { tracing.EndHTTP(span, status, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.Get().PdfAPIURL, strings.NewReader(page))
+	if err != nil {
 		return nil, err
-	} else if res, err := http.DefaultClient.Do(req); err != nil {
+	}
+	tracing.InjectRequest(req)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
 		return nil, err
-	} else {
-		defer res.Body.Close()
-		return ioutil.ReadAll(res.Body)
 	}
+	defer res.Body.Close()
+
+	status = res.StatusCode
+	return ioutil.ReadAll(res.Body)
 }
 
-func getBoletoByID(c *gin.Context) {
-	id := c.Param("id")
-	pk := c.Param("pk")
-	log := log.CreateLog()
-	log.Operation = "GetBoletoV1"
+//getBoletoByID Recupera um boleto registrado pelo usuário autenticado
+//Boletos de outros usuários são tratados como não encontrados para não revelar sua existência
+func getBoletoByID(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := db.BoletoFilter{
+			ID:          c.Param("id"),
+			ServiceUser: getUserFromContext(c),
+			Limit:       1,
+		}
 
-	boleto, _, err := db.GetBoletoByID(id, pk)
-	if err != nil {
-		checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), nil)
-		return
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		l := log.CreateLog()
+		l.Operation = "GetBoletoByID"
+		l.ServiceUser = filter.ServiceUser
+		l.IPAddress = c.ClientIP()
+
+		boleto, ok := findOwnedBoleto(ctx, repository, filter)
+		if !ok {
+			checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), l)
+			return
+		}
+		c.JSON(http.StatusOK, boleto)
+		recordEvent(c, models.BoletoEvent{BoletoID: filter.ID, Type: models.EventRead, Outcome: models.OutcomeSuccess, StatusCode: http.StatusOK, Detail: "api"})
+	}
+}
+
+//getBoletoByIDV1 Mantém a consulta pública da v1, que só retorna boletos sem chave secreta ou com a chave pública informada.
+//A consulta restrita ao dono do boleto é exclusiva da v2
+func getBoletoByIDV1(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		l := log.CreateLog()
+		l.Operation = "GetBoletoV1"
+		l.IPAddress = c.ClientIP()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		boleto, _, err := repository.GetBoletoByID(ctx, id, c.Param("pk"))
+		if err != nil {
+			checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), l)
+			return
+		}
+		c.JSON(http.StatusOK, boleto)
+		recordEvent(c, models.BoletoEvent{BoletoID: id, Type: models.EventRead, Outcome: models.OutcomeSuccess, StatusCode: http.StatusOK, Detail: "api"})
+	}
+}
+
+// findOwnedBoleto busca o boleto do filtro, que só é retornado quando pertence ao usuário do filtro
+func findOwnedBoleto(ctx context.Context, repository db.BoletoRepository, filter db.BoletoFilter) (models.BoletoView, bool) {
+	if filter.ServiceUser == "" {
+		return models.BoletoView{}, false
+	}
+	page, err := repository.FindBoletos(ctx, filter)
+	if err != nil || len(page.Boletos) == 0 || !page.Boletos[0].IsOwnedBy(filter.ServiceUser) {
+		return models.BoletoView{}, false
 	}
-	c.JSON(http.StatusOK, boleto)
+	return page.Boletos[0], true
 }
 
 func confirmation(c *gin.Context) {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -96,6 +97,6 @@ func arrangeGetBoleto() (*gin.Context, *gin.Engine, *httptest.ResponseRecorder)
 	config.Install(true, false, true)
 	w := httptest.NewRecorder()
 	c, r := gin.CreateTestContext(w)
-	r.GET("/boleto", getBoleto)
+	r.GET("/boleto", getBoleto(db.NewMongoRepository()))
 	return c, r, w
 }
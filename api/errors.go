@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/util"
 )
 
 var validate = map[string]int{
@@ -33,6 +34,11 @@ func handleErrors(c *gin.Context) {
 	}
 
 	bankcode := response.Errors[0].Code
+	if util.IsHandshakeErrorCode(bankcode) {
+		c.JSON(http.StatusBadGateway, response)
+		return
+	}
+
 	if status, exist = validate[bankcode]; !exist {
 		status = getBankFromContext(c).GetErrorsMap()[bankcode]
 	}
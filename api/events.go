@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/audit"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+var events = audit.NewRecorder(db.NewEventRepository)
+
+//getBoletoEvents Lista os eventos de auditoria do boleto do usuário autenticado
+func getBoletoEvents(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		l := linksLog(c, "GetBoletoEvents")
+		boleto, ok := findOwnedBoleto(ctx, repository, db.BoletoFilter{ID: c.Param("id"), ServiceUser: getUserFromContext(c), Limit: 1})
+		if !ok {
+			checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), l)
+			return
+		}
+
+		found, err := events.Events(ctx, boleto.ID.Hex())
+		if err != nil {
+			l.Error(err.Error(), "Error listing boleto events")
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseToClient())
+			return
+		}
+
+		c.JSON(http.StatusOK, models.BoletoEvents{Events: found})
+	}
+}
+
+//getRequestEvents Lista os eventos de auditoria de uma requisição do usuário autenticado pelo RequestKey
+//É como se encontram as tentativas de registro que falharam, que não têm boleto
+func getRequestEvents(c *gin.Context) {
+	serviceUser := getUserFromContext(c)
+	if serviceUser == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, models.GetBoletoResponseError("MP401", "Unauthorized"))
+		return
+	}
+	requestKey := c.Query("requestKey")
+	if requestKey == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", "requestKey is required"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	found, err := events.EventsByRequestKey(ctx, serviceUser, requestKey)
+	if err != nil {
+		l := linksLog(c, "GetRequestEvents")
+		l.Error(err.Error(), "Error listing request events")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseToClient())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BoletoEvents{Events: found})
+}
+
+//auditRegistration Middleware que grava o evento de cada tentativa de registro com o status final da requisição
+//Fica logo após a autenticação para auditar também as recusas da autorização, dos limites, da validação e da cota
+func auditRegistration(c *gin.Context) {
+	c.Next()
+
+	response := getResponseFromContext(c)
+	recordEvent(c, models.BoletoEvent{
+		BoletoID:   response.ID,
+		Type:       models.EventRegistration,
+		Outcome:    models.OutcomeOf(c.Writer.Status() == http.StatusOK && !response.HasErrors()),
+		RequestKey: getBoletoFromContext(c).RequestKey,
+		StatusCode: c.Writer.Status(),
+		Detail:     getErrorCodeToLog(c),
+	})
+}
+
+// recordEvent grava o evento da operação com o usuário, o IP e o RequestKey da requisição
+func recordEvent(c *gin.Context, event models.BoletoEvent) {
+	if event.ServiceUser == "" {
+		event.ServiceUser = getUserFromContext(c)
+	}
+	if event.IPAddress == "" {
+		event.IPAddress = c.ClientIP()
+	}
+	if event.RequestKey == "" {
+		event.RequestKey = c.Request.Header.Get("RequestKey")
+	}
+	events.Record(event)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/audit"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeEventsRoute(t *testing.T, repository db.BoletoRepository, user string) *gin.Engine {
+	router := arrangeLinksRoute(t, repository, user)
+	router.GET("/boleto/:id/events", func(c *gin.Context) { c.Set(serviceUserKey, user) }, getBoletoEvents(repository))
+
+	recorder := events
+	events = audit.NewRecorder(func() (db.EventRepository, error) { return db.NewMemoryEventRepository(), nil })
+	t.Cleanup(func() { events = recorder })
+	return router
+}
+
+func Test_GetBoletoEvents_ListsOperationsOnTheBoleto(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	router := arrangeEventsRoute(t, repository, "user-a")
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+
+	for _, action := range []string{"reissue", "revoke"} {
+		req, _ := http.NewRequest(http.MethodPost, "/boleto/"+view.ID.Hex()+"/links/"+action, nil)
+		req.Header.Set("RequestKey", "key-"+action)
+		req.RemoteAddr = "10.0.0.1:4321"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Nil(t, events.Wait(context.Background()))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/boleto/"+view.ID.Hex()+"/events", nil)
+	router.ServeHTTP(w, req)
+
+	var response models.BoletoEvents
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(response.Events))
+	for _, e := range response.Events {
+		assert.Equal(t, view.ID.Hex(), e.BoletoID)
+		assert.Equal(t, "user-a", e.ServiceUser)
+		assert.Equal(t, models.OutcomeSuccess, e.Outcome)
+		assert.Equal(t, "10.0.0.1", e.IPAddress)
+	}
+	assert.ElementsMatch(t, []string{models.EventLinksReissued, models.EventLinksRevoked}, []string{response.Events[0].Type, response.Events[1].Type})
+	assert.ElementsMatch(t, []string{"key-reissue", "key-revoke"}, []string{response.Events[0].RequestKey, response.Events[1].RequestKey})
+}
+
+func Test_GetBoletoEvents_WhenNotOwner_ReturnNotFound(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	router := arrangeEventsRoute(t, repository, "user-b")
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/boleto/"+view.ID.Hex()+"/events", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_SaveToFallback_WhenEncryptionFails_DoesNotWriteTheBoleto(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("ENCRYPTION_KEY_SOURCE", "unknown")
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+
+	repository := db.NewMemoryEventRepository()
+	recorder := events
+	events = audit.NewRecorder(func() (db.EventRepository, error) { return repository, nil })
+	t.Cleanup(func() { events = recorder })
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v2/boleto/register", nil)
+	view := models.NewBoletoView(models.BoletoRequest{BankNumber: models.Caixa}, models.BoletoResponse{}, "", "user-a")
+
+	saveToFallback(c, log.CreateLog(), view, "key-1")
+
+	assert.Nil(t, events.Wait(context.Background()))
+	saved, _ := repository.FindEvents(context.Background(), view.ID.Hex())
+	assert.Equal(t, 1, len(saved), "sem a cifragem o boleto não vai para a fila nem para o blob storage")
+	assert.Equal(t, models.OutcomeFailure, saved[0].Outcome)
+	assert.Equal(t, "encryption", saved[0].Detail)
+}
+
+func Test_AuditRegistration_RecordsRejectedAttemptsFoundByRequestKey(t *testing.T) {
+	router := arrangeEventsRoute(t, db.NewMemoryRepository(), "user-a")
+	router.POST("/boleto/register", func(c *gin.Context) { c.Set(serviceUserKey, "user-a") }, auditRegistration, func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, models.GetBoletoResponseError("MP429", "Too many requests"))
+	})
+	router.GET("/events", func(c *gin.Context) { c.Set(serviceUserKey, c.Query("user")) }, getRequestEvents)
+
+	req, _ := http.NewRequest(http.MethodPost, "/boleto/register", nil)
+	req.Header.Set("RequestKey", "key-rejected")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(t, events.Wait(context.Background()))
+
+	w := httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/events?user=user-a&requestKey=key-rejected", nil)
+	router.ServeHTTP(w, req)
+
+	var response models.BoletoEvents
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(response.Events))
+	assert.Equal(t, models.EventRegistration, response.Events[0].Type)
+	assert.Equal(t, models.OutcomeFailure, response.Events[0].Outcome)
+	assert.Equal(t, http.StatusTooManyRequests, response.Events[0].StatusCode)
+	assert.Empty(t, response.Events[0].BoletoID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/events?user=user-b&requestKey=key-rejected", nil)
+	router.ServeHTTP(w, req)
+	response = models.BoletoEvents{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 0, len(response.Events), "os eventos de outro usuário não são listados")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/events?user=user-a", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
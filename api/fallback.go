@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"sync"
+
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/infrastructure/storage"
+	"github.com/mundipagg/boleto-api/metrics"
+	"github.com/mundipagg/boleto-api/models"
 )
 
 const persistenceErrorMessage = "failure during send boleto to fallback. This boleto can't be recovery until manual insert content into database."
@@ -13,16 +18,24 @@ type IFallback interface {
 	Save(context *gin.Context, registerId, payload string)
 }
 
-type Fallback struct{}
+type Fallback struct {
+	pending sync.WaitGroup
+}
 
 // Save resilience application
 func (f *Fallback) Save(context *gin.Context, registerId, payload string) {
+	f.pending.Add(1)
+	defer f.pending.Done()
+
 	lg := loadBankLog(context)
+	event := models.BoletoEvent{BoletoID: registerId, Type: models.EventFallbackSaved, Outcome: models.OutcomeFailure, RequestKey: getBoletoFromContext(context).RequestKey, Detail: "blob"}
+	defer func() { recordEvent(context, event) }()
 
 	client, err := storage.GetClient()
 
 	if err != nil {
 		lg.ErrorWithContent(getClientFallbackError, "Error", err, payload)
+		metrics.PushFallback(metrics.FallbackBlobFailure)
 		return
 	}
 
@@ -33,13 +46,33 @@ func (f *Fallback) Save(context *gin.Context, registerId, payload string) {
 
 	if err != nil {
 		lg.ErrorWithContent(persistenceErrorMessage, "Error", err, payload)
+		metrics.PushFallback(metrics.FallbackBlobFailure)
 		return
 	}
 
+	metrics.PushFallback(metrics.FallbackBlob)
+	event.Outcome = models.OutcomeSuccess
+
 	props := getLogUploadProperties(elapsedTime, registerId, payload)
 	lg.InfoWithParams(fallbackSaveSuccessfully, "Information", props)
 }
 
+// Wait aguarda as gravações no fallback em andamento até o fim do contexto
+func (f *Fallback) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		f.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func getLogUploadProperties(totalElapsedTimeInMilliseconds int64, registerId, payload string) map[string]interface{} {
 	props := make(map[string]interface{})
 	props["TotalElapsedTimeInMilliseconds"] = totalElapsedTimeInMilliseconds
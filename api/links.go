@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+//reissueLinks Emite novos links públicos para o boleto do usuário autenticado, com nova expiração
+//Os links emitidos antes continuam válidos até expirarem
+func reissueLinks(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		l := linksLog(c, "ReissueLinks")
+		boleto, ok := findOwnedBoleto(ctx, repository, db.BoletoFilter{ID: c.Param("id"), ServiceUser: getUserFromContext(c), Limit: 1})
+		if !ok {
+			checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), l)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.BoletoLinks{Links: boleto.CreateLinks()})
+		recordEvent(c, models.BoletoEvent{BoletoID: boleto.ID.Hex(), Type: models.EventLinksReissued, Outcome: models.OutcomeSuccess, StatusCode: http.StatusOK})
+	}
+}
+
+//revokeLinks Invalida todos os links públicos emitidos para o boleto do usuário autenticado
+//Novos links podem ser obtidos com reissueLinks
+func revokeLinks(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		l := linksLog(c, "RevokeLinks")
+		boleto, ok := findOwnedBoleto(ctx, repository, db.BoletoFilter{ID: c.Param("id"), ServiceUser: getUserFromContext(c), Limit: 1})
+		if !ok {
+			checkError(c, models.NewHTTPNotFound("MP404", "Boleto não encontrado"), l)
+			return
+		}
+
+		boleto.RotateKeys()
+		if err := repository.UpdateBoletoKeys(ctx, boleto); err != nil {
+			l.Error(err.Error(), "Error revoking boleto links")
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseToClient())
+			recordEvent(c, models.BoletoEvent{BoletoID: boleto.ID.Hex(), Type: models.EventLinksRevoked, Outcome: models.OutcomeFailure, StatusCode: http.StatusInternalServerError})
+			return
+		}
+
+		l.InfoWithBasic("Boleto links revoked", "Information", map[string]interface{}{"BoletoID": boleto.ID.Hex()})
+		c.Status(http.StatusNoContent)
+		recordEvent(c, models.BoletoEvent{BoletoID: boleto.ID.Hex(), Type: models.EventLinksRevoked, Outcome: models.OutcomeSuccess, StatusCode: http.StatusNoContent})
+	}
+}
+
+func linksLog(c *gin.Context, operation string) *log.Log {
+	l := log.CreateLog()
+	l.Operation = operation
+	l.ServiceUser = getUserFromContext(c)
+	l.IPAddress = c.ClientIP()
+	return l
+}
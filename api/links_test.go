@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeLinksRoute(t *testing.T, repository db.BoletoRepository, user string) *gin.Engine {
+	os.Clearenv()
+	os.Setenv("APP_URL", "http://localhost:3000/boleto")
+	os.Setenv("LINK_SIGNING_KEYS", "k1=secret")
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) { c.Set(serviceUserKey, user) }
+	router.POST("/boleto/:id/links/reissue", setUser, reissueLinks(repository))
+	router.POST("/boleto/:id/links/revoke", setUser, revokeLinks(repository))
+	router.GET("/boleto", getBoleto(repository))
+	return router
+}
+
+func Test_RevokeLinks_InvalidatesIssuedLinks(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	router := arrangeLinksRoute(t, repository, "user-a")
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/boleto/"+view.ID.Hex()+"/links/revoke", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	_, _, err := repository.GetBoletoByID(context.Background(), view.ID.Hex(), view.PublicKey)
+	assert.Equal(t, db.InvalidPK, err.Error())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/boleto/"+view.ID.Hex()+"/links/reissue", nil)
+	router.ServeHTTP(w, req)
+
+	var reissued models.BoletoLinks
+	json.Unmarshal(w.Body.Bytes(), &reissued)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(reissued.Links))
+	assert.NotContains(t, reissued.Links[0].Href, view.PublicKey)
+	assert.Contains(t, reissued.Links[0].Href, "&sig=")
+}
+
+func Test_RevokeLinks_WhenNotOwner_ReturnNotFound(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	router := arrangeLinksRoute(t, repository, "user-b")
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+
+	for _, action := range []string{"revoke", "reissue"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/boleto/"+view.ID.Hex()+"/links/"+action, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func Test_GetBoleto_WhenLinkSignatureIsInvalid_ReturnNotFound(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	router := arrangeLinksRoute(t, repository, "user-a")
+	response := models.BoletoResponse{BarCodeNumber: "10492726700000010002006561000100040992226984"}
+	view := models.NewBoletoView(*test.NewStubBoletoRequest(models.Caixa).Build(), response, "", "user-a")
+	repository.SaveBoleto(context.Background(), view)
+
+	links := map[string]string{
+		"sem assinatura": "/boleto?fmt=html&id=" + view.ID.Hex() + "&pk=" + view.PublicKey,
+		"expirado":       "/boleto?fmt=html&id=" + view.ID.Hex() + "&pk=" + view.PublicKey + models.SignLink(view.ID.Hex(), view.PublicKey, time.Now().Add(-31*24*time.Hour)).Query(),
+	}
+
+	for name, link := range links {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, link, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, name)
+	}
+
+	signed := strings.TrimPrefix(view.EncodeURL("html"), "http://localhost:3000")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, signed, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "&kid=k1&sig="), "o link do PDF mantém a assinatura do link aberto")
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net/http"
 	"strings"
 	"time"
 
@@ -37,8 +38,7 @@ func registerBoletoLogger(c *gin.Context) {
 		l.ResponseApplication(resp, c.Request.URL.RequestURI(), getErrorCodeToLog(c))
 	}
 
-	tag := bank.GetBankNameIntegration() + "-status"
-	metrics.PushBusinessMetric(tag, c.Writer.Status())
+	metrics.PushBankStatus(bank.GetBankNameIntegration(), c.Writer.Status())
 }
 
 //getBoletoLogger Middleware de log da operação de GetBoleto
@@ -56,6 +56,21 @@ func getBoletoLogger(c *gin.Context) {
 	log.RequestKey = getRequestKeyFromContext(c)
 
 	log.GetBoleto(result, result.LogSeverity)
+
+	if result.Id != "" {
+		event := models.BoletoEvent{
+			BoletoID:   result.Id,
+			Type:       models.EventRead,
+			Outcome:    models.OutcomeOf(c.Writer.Status() == http.StatusOK),
+			RequestKey: log.RequestKey,
+			StatusCode: c.Writer.Status(),
+			Detail:     result.Format,
+		}
+		if result.ErrorResponse.HasErrors() {
+			event.Detail = result.ErrorResponse.Errors[0].Code
+		}
+		recordEvent(c, event)
+	}
 }
 
 func loadBankLog(c *gin.Context) *log.Log {
@@ -6,11 +6,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/auth"
 	"github.com/mundipagg/boleto-api/bank"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
-	"github.com/mundipagg/boleto-api/usermanagement"
+	"github.com/mundipagg/boleto-api/util"
 )
 
 const (
@@ -18,6 +19,12 @@ const (
 	bankKey        = "bank"
 	serviceUserKey = "serviceuser"
 	responseKey    = "boletoResponse"
+	quotaKey       = "dailyRegistrationQuota"
+	rateLimitKey   = "registrationRateLimit"
+	// bankCredentialsKey guarda os certificados e credenciais próprios do usuário em cada banco
+	bankCredentialsKey = "bankCredentials"
+	// principalKey guarda a identidade autenticada e as permissões dela
+	principalKey = "principal"
 )
 
 func returnHeaders() gin.HandlerFunc {
@@ -37,6 +44,8 @@ func parseBoleto(c *gin.Context) {
 		return
 	}
 
+	applyBankCredentials(c, &boleto)
+
 	if bank, ok = getBank(c, boleto); !ok {
 		return
 	}
@@ -50,21 +59,48 @@ func parseBoleto(c *gin.Context) {
 }
 
 //authentication Middleware de autenticação para registro de boleto
+//As estratégias habilitadas em AUTH_STRATEGIES são tentadas em ordem: basic, chave de API ou token JWT
 func authentication(c *gin.Context) {
-
-	cred := getHeaderCredentials(c)
-
-	if cred == nil {
+	principal, err := auth.Authenticate(c.Request)
+	if err != nil {
 		c.AbortWithStatusJSON(401, models.GetBoletoResponseError("MP401", "Unauthorized"))
 		return
 	}
 
-	if !hasValidCredentials(cred) {
-		c.AbortWithStatusJSON(401, models.GetBoletoResponseError("MP401", "Unauthorized"))
+	cred := principal.Credentials
+	c.Set(principalKey, principal)
+	c.Set(serviceUserKey, cred.Username)
+	c.Set(quotaKey, cred.DailyRegistrationQuota)
+	c.Set(rateLimitKey, rateLimit{perMinute: cred.RegistrationsPerMinute, burst: cred.RegistrationBurst})
+	c.Set(bankCredentialsKey, models.Credentials{Banks: cred.Banks})
+}
+
+//authorize Middleware que exige a permissão na identidade autenticada
+func authorize(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get(principalKey)
+		if !exists || !value.(auth.Principal).Can(permission) {
+			c.AbortWithStatusJSON(403, models.GetBoletoResponseError("MP403", "Forbidden"))
+			return
+		}
+	}
+}
+
+// applyBankCredentials associa ao boleto o certificado e as credenciais do usuário no banco
+// As credenciais de cliente só são usadas quando o registro não envia as próprias
+func applyBankCredentials(c *gin.Context, boleto *models.BoletoRequest) {
+	value, exists := c.Get(bankCredentialsKey)
+	if !exists {
 		return
 	}
 
-	c.Set(serviceUserKey, cred.Username)
+	credentials := value.(models.Credentials).ForBank(boleto.BankNumber)
+	boleto.BankCredentials = credentials
+
+	if credentials.ClientID != "" && boleto.Authentication.Username == "" && boleto.Authentication.Password == "" {
+		boleto.Authentication.Username = credentials.ClientID
+		boleto.Authentication.Password = credentials.ClientSecret
+	}
 }
 
 //checkError Middleware de verificação de erros
@@ -96,6 +132,16 @@ func checkError(c *gin.Context, err error, l *log.Log) bool {
 			l.Warn(errResp, v.Error())
 			c.JSON(http.StatusBadGateway, errResp)
 
+		case util.HandshakeError:
+			errResp.Errors.Append(v.Code, v.Error())
+			l.Warn(errResp, v.Error())
+			c.JSON(http.StatusBadGateway, errResp)
+
+		case models.ServiceUnavailableError:
+			errResp.Errors.Append("MP503", v.Error())
+			l.Warn(errResp, v.Error())
+			c.JSON(http.StatusServiceUnavailable, errResp)
+
 		case models.FormatError:
 			errResp.Errors.Append("MP400", v.Error())
 			l.Warn(errResp, v.Error())
@@ -113,31 +159,6 @@ func checkError(c *gin.Context, err error, l *log.Log) bool {
 	return false
 }
 
-func hasValidCredentials(c *models.Credentials) bool {
-	u, hasUser := usermanagement.GetUser(c.UserKey)
-
-	if !hasUser {
-		return false
-	}
-
-	user := u.(models.Credentials)
-
-	if user.UserKey == c.UserKey && user.Password == c.Password {
-		c.Username = user.Username
-		return true
-	}
-
-	return false
-}
-
-func getHeaderCredentials(c *gin.Context) *models.Credentials {
-	userkey, pass, hasAuth := c.Request.BasicAuth()
-	if userkey == "" || pass == "" || !hasAuth {
-		return nil
-	}
-	return models.NewCredentials(userkey, pass)
-}
-
 func getBoletoRequest(c *gin.Context) (models.BoletoRequest, bool) {
 	boleto := models.BoletoRequest{}
 	errBind := c.BindJSON(&boleto)
@@ -164,7 +185,7 @@ func parseExpirationDate(c *gin.Context, boleto *models.BoletoRequest, bank bank
 	if errFmt != nil {
 		e := models.NewFormatError(errFmt.Error())
 		checkError(c, e, log.CreateLog())
-		metrics.PushBusinessMetric(bank.GetBankNameIntegration()+"-bad-request", 1)
+		metrics.PushBadRequest(bank.GetBankNameIntegration())
 		return false
 	}
 	return true
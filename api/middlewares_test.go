@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/auth"
 	"github.com/mundipagg/boleto-api/bank"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/models"
@@ -63,6 +64,27 @@ func Test_Authentication_WhenValidCredentials_AuthorizedRequestSuccessful(t *tes
 	assert.Equal(t, 200, w.Code)
 }
 
+func Test_Authorize_WhenPrincipalLacksPermission_ReturnForbidden(t *testing.T) {
+	principal := auth.Principal{Permissions: auth.ScopePermissions([]string{auth.PermissionRead})}
+	router, w := arrangeMiddlewareRoute("/authorize", func(c *gin.Context) { c.Set(principalKey, principal) }, authorize(auth.PermissionRegister))
+	req, _ := http.NewRequest("POST", "/authorize", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"MP403","message":"Forbidden"}]}`, w.Body.String())
+}
+
+func Test_Authorize_WhenPrincipalHasPermission_AuthorizedRequestSuccessful(t *testing.T) {
+	principal := auth.Principal{Permissions: auth.AllPermissions()}
+	router, w := arrangeMiddlewareRoute("/authorize", func(c *gin.Context) { c.Set(principalKey, principal) }, authorize(auth.PermissionRegister))
+	req, _ := http.NewRequest("POST", "/authorize", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
+
 func Test_ParseBoleto_WhenInvalidBody_ReturnBadRequest(t *testing.T) {
 	router, w := arrangeMiddlewareRoute("/parseboleto", parseBoleto)
 	req, _ := http.NewRequest("POST", "/parseboleto", bytes.NewBuffer([]byte(``)))
@@ -215,3 +237,22 @@ func arrangeMiddlewareRoute(route string, handlers ...gin.HandlerFunc) (*gin.Eng
 	w := httptest.NewRecorder()
 	return router, w
 }
+
+func Test_ParseBoleto_AppliesBankCredentialsOfAuthenticatedUser(t *testing.T) {
+	user := models.Credentials{Banks: map[string]models.BankCredentials{
+		"745": {Certificate: "merchant-citi", ClientID: "merchant-id", ClientSecret: "merchant-secret"},
+	}}
+	var parsed models.BoletoRequest
+	router, w := arrangeMiddlewareRoute("/parseboleto", func(c *gin.Context) { c.Set(bankCredentialsKey, user) }, parseBoleto, func(c *gin.Context) {
+		parsed = getBoletoFromContext(c)
+	})
+	body := test.NewStubBoletoRequest(models.Citibank).WithExpirationDate(time.Now()).Build()
+	body.Authentication = models.Authentication{}
+	req, _ := http.NewRequest("POST", "/parseboleto", bytes.NewBuffer([]byte(util.Stringify(body))))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "merchant-citi", parsed.BankCredentials.Certificate)
+	assert.Equal(t, "merchant-id", parsed.Authentication.Username)
+	assert.Equal(t, "merchant-secret", parsed.Authentication.Password)
+}
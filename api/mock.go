@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/env"
 )
 
@@ -14,9 +15,11 @@ func mockInstallApi() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
-	Base(r)
-	V1(r)
-	V2(r)
+	repository := db.NewMemoryRepository()
+	Base(r, repository)
+	V1(r, repository)
+	V2(r, repository)
+	Admin(r, repository)
 	return r
 }
 
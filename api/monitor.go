@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/metrics"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/newrelic/go-agent/v3/integrations/nrgin"
 	"github.com/newrelic/go-agent/v3/newrelic"
 )
@@ -35,6 +36,16 @@ func timingMetrics() gin.HandlerFunc {
 		end := time.Now()
 		total := end.Sub(start)
 		s := float64(total.Seconds())
-		metrics.PushTimingMetric("request-time", s)
+		metrics.PushRequestTime(c.Request.Method, c.FullPath(), c.Writer.Status(), s)
+	}
+}
+
+//traceRequest Cria o span raiz da requisição, que passa a ser o pai dos spans abertos a partir de c.Request.Context()
+func traceRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracing.StartRequest(c.Request, c.FullPath())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		tracing.EndHTTP(span, c.Writer.Status(), nil)
 	}
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+type registrationCounter interface {
+	IncrementDailyRegistrations(ctx context.Context, serviceUser string, day time.Time) (int64, error)
+}
+
+//registrationQuota Middleware que limita a quantidade de registros de boleto por dia de cada usuário
+//Só consomem a cota os registros que passaram pelos limites de taxa e pela validação. Se o contador estiver indisponível o registro é permitido
+func registrationQuota(counter registrationCounter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quota := getDailyRegistrationQuota(c)
+		if quota <= 0 {
+			return
+		}
+
+		user := getUserFromContext(c)
+		now := time.Now()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		count, err := counter.IncrementDailyRegistrations(ctx, user, now)
+		if err != nil {
+			l := log.CreateLog()
+			l.Operation = "RegistrationQuota"
+			l.ServiceUser = user
+			l.Warn(err.Error(), "Could not check daily registration quota")
+			return
+		}
+
+		if count > int64(quota) {
+			c.Header("Retry-After", strconv.Itoa(secondsUntilNextDay(now)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.GetBoletoResponseError("MP429", "Daily registration quota exceeded"))
+		}
+	}
+}
+
+//getDailyRegistrationQuota Retorna a cota do usuário autenticado ou a cota padrão DAILY_REGISTRATION_QUOTA
+func getDailyRegistrationQuota(c *gin.Context) int {
+	if quota, exists := c.Get(quotaKey); exists && quota.(int) > 0 {
+		return quota.(int)
+	}
+	return config.Get().DailyRegistrationQuota
+}
+
+func secondsUntilNextDay(now time.Time) int {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	return int(next.Sub(now).Seconds()) + 1
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRegistrationCounter struct {
+	count int64
+	err   error
+}
+
+func (f *fakeRegistrationCounter) IncrementDailyRegistrations(ctx context.Context, serviceUser string, day time.Time) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.count++
+	return f.count, nil
+}
+
+func Test_RegistrationQuota_WhenQuotaExceeded_ReturnTooManyRequests(t *testing.T) {
+	router := arrangeQuotaRoute(&fakeRegistrationCounter{}, 2)
+
+	codes := make([]int, 0)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/register", nil)
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+
+		if i == 2 {
+			assert.Equal(t, `{"errors":[{"code":"MP429","message":"Daily registration quota exceeded"}]}`, w.Body.String())
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		}
+	}
+
+	assert.Equal(t, []int{200, 200, 429}, codes)
+}
+
+func Test_RegistrationQuota_WhenCounterFails_AllowRegistration(t *testing.T) {
+	router := arrangeQuotaRoute(&fakeRegistrationCounter{err: errors.New("redis unavailable")}, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/register", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func Test_RegistrationQuota_WhenNoQuota_DoesNotCount(t *testing.T) {
+	counter := &fakeRegistrationCounter{}
+	router := arrangeQuotaRoute(counter, 0)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/register", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, int64(0), counter.count)
+}
+
+func Test_SecondsUntilNextDay(t *testing.T) {
+	now := time.Date(2021, 6, 10, 23, 59, 0, 0, time.UTC)
+
+	assert.Equal(t, 61, secondsUntilNextDay(now))
+}
+
+func arrangeQuotaRoute(counter registrationCounter, quota int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/register", func(c *gin.Context) {
+		c.Set(serviceUserKey, "user")
+		c.Set(quotaKey, quota)
+	}, registrationQuota(counter), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/bank"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+type rateLimiter interface {
+	TakeRateLimitToken(ctx context.Context, key string, perMinute, burst int, now time.Time) (bool, time.Duration, error)
+}
+
+// rateLimit é um token bucket recarregado com perMinute tokens por minuto, com capacidade de burst tokens
+type rateLimit struct {
+	perMinute int
+	burst     int
+}
+
+//userRateLimit Middleware que limita a taxa de registros de boleto de cada usuário
+//O limite da credencial sobrescreve RATE_LIMIT_PER_MINUTE e RATE_LIMIT_BURST
+func userRateLimit(limiter rateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := getUserFromContext(c)
+		takeRateLimitToken(c, limiter, "user:"+user, getUserRateLimit(c), user, "Rate limit exceeded")
+	}
+}
+
+//bankRateLimit Middleware que limita a taxa de registros de boleto enviados a cada banco, somando todos os usuários
+//RATE_LIMIT_BANKS sobrescreve RATE_LIMIT_BANK_PER_MINUTE e RATE_LIMIT_BANK_BURST por banco
+func bankRateLimit(limiter rateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, exists := c.Get(bankKey)
+		if !exists {
+			return
+		}
+		name := b.(bank.Bank).GetBankNameIntegration()
+		takeRateLimitToken(c, limiter, "bank:"+strings.ToLower(name), getBankRateLimit(name), getUserFromContext(c), "Rate limit exceeded for bank "+name)
+	}
+}
+
+// takeRateLimitToken responde 429 com Retry-After quando o bucket está vazio. Se o Redis estiver indisponível o registro é permitido
+func takeRateLimitToken(c *gin.Context, limiter rateLimiter, key string, limit rateLimit, user, message string) {
+	if limit.perMinute <= 0 {
+		return
+	}
+	if limit.burst <= 0 {
+		limit.burst = limit.perMinute
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+	defer cancel()
+
+	allowed, wait, err := limiter.TakeRateLimitToken(ctx, key, limit.perMinute, limit.burst, time.Now())
+	if err != nil {
+		l := log.CreateLog()
+		l.Operation = "RateLimit"
+		l.ServiceUser = user
+		l.Warn(err.Error(), "Could not check registration rate limit")
+		return
+	}
+
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, models.GetBoletoResponseError("MP429", message))
+	}
+}
+
+func getUserRateLimit(c *gin.Context) rateLimit {
+	limit := rateLimit{perMinute: config.Get().RateLimitPerMinute, burst: config.Get().RateLimitBurst}
+	if v, exists := c.Get(rateLimitKey); exists {
+		if credential := v.(rateLimit); credential.perMinute > 0 {
+			limit.perMinute = credential.perMinute
+			limit.burst = credential.burst
+		}
+	}
+	return limit
+}
+
+// getBankRateLimit lê RATE_LIMIT_BANKS no formato Banco=porMinuto[:burst], separado por vírgulas, como Itau=600:60,BancoDoBrasil=300
+func getBankRateLimit(name string) rateLimit {
+	limit := rateLimit{perMinute: config.Get().RateLimitBankPerMinute, burst: config.Get().RateLimitBankBurst}
+
+	for _, entry := range strings.Split(config.Get().RateLimitBanks, ",") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), name) {
+			continue
+		}
+
+		values := strings.SplitN(kv[1], ":", 2)
+		perMinute, err := strconv.Atoi(strings.TrimSpace(values[0]))
+		if err != nil {
+			continue
+		}
+		limit = rateLimit{perMinute: perMinute}
+		if len(values) == 2 {
+			limit.burst, _ = strconv.Atoi(strings.TrimSpace(values[1]))
+		}
+	}
+	return limit
+}
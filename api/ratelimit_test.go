@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/bank"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRateLimiter struct {
+	taken map[string]int
+	err   error
+}
+
+func (f *fakeRateLimiter) TakeRateLimitToken(ctx context.Context, key string, perMinute, burst int, now time.Time) (bool, time.Duration, error) {
+	if f.err != nil {
+		return false, 0, f.err
+	}
+	if f.taken[key] >= burst {
+		return false, 1500 * time.Millisecond, nil
+	}
+	f.taken[key]++
+	return true, 0, nil
+}
+
+func Test_UserRateLimit_WhenBucketIsEmpty_ReturnTooManyRequestsWithRetryAfter(t *testing.T) {
+	limiter := &fakeRateLimiter{taken: map[string]int{}}
+	router := arrangeRateLimitRoute(limiter, rateLimit{perMinute: 60, burst: 2}, "")
+
+	codes := make([]int, 0)
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		last = rateLimitRequest(router)
+		codes = append(codes, last.Code)
+	}
+
+	assert.Equal(t, []int{200, 200, 429}, codes)
+	assert.Equal(t, "2", last.Header().Get("Retry-After"))
+	assert.Equal(t, `{"errors":[{"code":"MP429","message":"Rate limit exceeded"}]}`, last.Body.String())
+	assert.Equal(t, 2, limiter.taken["user:user"])
+}
+
+func Test_UserRateLimit_WhenNoLimit_DoesNotTakeTokens(t *testing.T) {
+	limiter := &fakeRateLimiter{taken: map[string]int{}}
+	router := arrangeRateLimitRoute(limiter, rateLimit{}, "")
+
+	w := rateLimitRequest(router)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, limiter.taken)
+}
+
+func Test_RateLimit_WhenLimiterFails_AllowRegistration(t *testing.T) {
+	router := arrangeRateLimitRoute(&fakeRateLimiter{err: errors.New("redis unavailable")}, rateLimit{perMinute: 1, burst: 1}, "")
+
+	w := rateLimitRequest(router)
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func Test_BankRateLimit_UsesLimitConfiguredForBank(t *testing.T) {
+	limiter := &fakeRateLimiter{taken: map[string]int{}}
+	router := arrangeRateLimitRoute(limiter, rateLimit{}, "Stone=120:1,Itau=5")
+
+	first := rateLimitRequest(router)
+	second := rateLimitRequest(router)
+
+	assert.Equal(t, 200, first.Code)
+	assert.Equal(t, 429, second.Code)
+	assert.Equal(t, `{"errors":[{"code":"MP429","message":"Rate limit exceeded for bank Stone"}]}`, second.Body.String())
+	assert.Equal(t, 1, limiter.taken["bank:stone"])
+}
+
+func Test_GetBankRateLimit(t *testing.T) {
+	arrangeRateLimitConfig("Itau=600:60, BancoDoBrasil=300,Caixa=invalid")
+
+	assert.Equal(t, rateLimit{perMinute: 600, burst: 60}, getBankRateLimit("itau"))
+	assert.Equal(t, rateLimit{perMinute: 300}, getBankRateLimit("BancoDoBrasil"))
+	assert.Equal(t, rateLimit{}, getBankRateLimit("Caixa"))
+}
+
+func arrangeRateLimitConfig(banks string) {
+	os.Clearenv()
+	os.Setenv("RATE_LIMIT_BANKS", banks)
+	config.Install(true, false, true)
+}
+
+func arrangeRateLimitRoute(limiter rateLimiter, userLimit rateLimit, banks string) *gin.Engine {
+	arrangeRateLimitConfig(banks)
+	b, _ := bank.Get(models.BoletoRequest{BankNumber: models.Stone})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/register", func(c *gin.Context) {
+		c.Set(serviceUserKey, "user")
+		c.Set(rateLimitKey, userLimit)
+	}, userRateLimit(limiter), func(c *gin.Context) {
+		c.Set(bankKey, b)
+	}, bankRateLimit(limiter), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func rateLimitRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/register", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
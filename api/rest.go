@@ -7,16 +7,26 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/lifecycle"
 	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/queue"
+	"github.com/mundipagg/boleto-api/tracing"
 )
 
-//InstallRestAPI "instala" e sobe o servico de rest
-func InstallRestAPI() {
+const (
+	logFlushTimeout        = 10 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+//InstallRestAPI "instala" e sobe o servico de rest usando o repositório de boletos informado
+//As rotinas em segundo plano são encerradas junto com a API, depois que as requisições em andamento terminam
+func InstallRestAPI(repository db.BoletoRepository, background *lifecycle.Group) {
 
 	l := log.CreateLog()
 	l.Operation = "InstallAPI"
@@ -30,9 +40,10 @@ func InstallRestAPI() {
 		router.Use(gin.Logger())
 	}
 
-	Base(router)
-	V1(router)
-	V2(router)
+	Base(router, repository)
+	V1(router, repository)
+	V2(router, repository)
+	Admin(router, repository)
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -55,10 +66,53 @@ func InstallRestAPI() {
 	l.InfoWithBasic("start shutdown server...", "Information", nil)
 	stdlog.Println("start shutdown server...")
 
-	// Server Shutdown
-	err := server.Shutdown(context.Background())
+	shutdown(server, background, l)
+}
+
+// shutdown encerra a aplicação em ordem. A prontidão passa a falhar e, depois de SHUTDOWN_READINESS_DELAY_IN_SECONDS,
+// as requisições em andamento, as gravações em segundo plano e as rotinas são aguardadas até SHUTDOWN_TIMEOUT_IN_SECONDS.
+// Só então as dependências são fechadas. Os logs são escritos por último, com prazo próprio, para registrar todo o encerramento
+func shutdown(server *http.Server, background *lifecycle.Group, l *log.Log) {
+	// Readiness
+	config.Stop()
+	time.Sleep(time.Duration(config.Get().ShutdownReadinessDelayInSeconds) * time.Second)
+
+	timeout := defaultShutdownTimeout
+	if t := config.Get().ShutdownTimeoutInSeconds; t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Server Shutdown, draining in-flight requests
+	err := server.Shutdown(ctx)
 	if err != nil {
 		l.ErrorWithBasic("shutdown server with error", "Error", err)
+		server.Close()
+	}
+
+	// Wait fallback writes of requests that outlived the deadline
+	err = fallback.Wait(ctx)
+	if err != nil {
+		l.ErrorWithBasic("error waiting fallback writes", "Error", err)
+	}
+
+	// Stop background routines
+	err = background.Stop(ctx)
+	if err != nil {
+		l.ErrorWithBasic("error stopping background routines", "Error", err)
+	}
+
+	// Wait audit events
+	err = events.Wait(ctx)
+	if err != nil {
+		l.ErrorWithBasic("error waiting boleto events", "Error", err)
+	}
+
+	// Flush pending spans
+	err = tracing.Shutdown(ctx)
+	if err != nil {
+		l.ErrorWithBasic("error flushing tracing spans", "Error", err)
 	}
 
 	// Close DB Connection
@@ -77,7 +131,27 @@ func InstallRestAPI() {
 		l.InfoWithBasic("rabbitmq connection successfully closed", "Information", nil)
 	}
 
+	// Close Redis Pool
+	err = db.CloseRedis()
+	if err != nil {
+		l.ErrorWithBasic("error closing redis pool", "Error", err)
+	} else {
+		l.InfoWithBasic("redis pool successfully closed", "Information", nil)
+	}
+
+	// Flush pending metrics
+	err = metrics.Flush()
+	if err != nil {
+		l.ErrorWithBasic("error flushing metrics", "Error", err)
+	}
+
 	l.InfoWithBasic("shutdown completed", "Information", nil)
+
+	// Flush pending log entries
+	logCtx, logCancel := context.WithTimeout(context.Background(), logFlushTimeout)
+	defer logCancel()
+	if err := log.Flush(logCtx); err != nil {
+		stdlog.Println("error flushing log entries: ", err)
+	}
 	stdlog.Println("shutdown completed")
-	// time.Sleep(10 * time.Second)
 }
@@ -2,32 +2,46 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/auth"
+	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/healthcheck"
+	"github.com/mundipagg/boleto-api/metrics"
 )
 
-func Base(router *gin.Engine) {
+func Base(router *gin.Engine, repository db.BoletoRepository) {
 	router.StaticFile("/favicon.ico", "./boleto/favicon.ico")
-	router.GET("/boleto", getBoletoLogger, getBoleto)
+	router.GET("/boleto", traceRequest(), getBoletoLogger, getBoleto(repository))
 	router.GET("/boleto/memory-check/:unit", memory)
 	router.GET("/boleto/memory-check/", memory)
 	router.GET("/boleto/confirmation", confirmation)
 	router.POST("/boleto/confirmation", confirmation)
 	router.GET("/healthcheck", healthcheck.ExecuteOnAPI)
+	router.GET("/healthcheck/live", healthcheck.Liveness)
+	router.GET("/healthcheck/ready", healthcheck.Readiness)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 }
 
 //V1 configura as rotas da v1
-func V1(router *gin.Engine) {
+func V1(router *gin.Engine, repository db.BoletoRepository) {
 	v1 := router.Group("v1")
+	v1.Use(traceRequest())
 	v1.Use(timingMetrics())
 	v1.Use(returnHeaders())
-	v1.POST("/boleto/register", authentication, parseBoleto, validateRegisterV1, registerBoletoLogger, errorResponseToClient, panicRecoveryHandler, registerBoleto)
-	v1.GET("/boleto/:id", getBoletoByID)
+	v1.POST("/boleto/register", authentication, auditRegistration, authorize(auth.PermissionRegister), userRateLimit(db.CreateRedis()), parseBoleto, bankRateLimit(db.CreateRedis()), validateRegisterV1, registrationQuota(db.CreateRedis()), registerBoletoLogger, errorResponseToClient, panicRecoveryHandler, registerBoleto(repository))
+	v1.GET("/boleto/:id", getBoletoByIDV1(repository))
 }
 
 //V2 configura as rotas da v2
-func V2(router *gin.Engine) {
+func V2(router *gin.Engine, repository db.BoletoRepository) {
 	v2 := router.Group("v2")
+	v2.Use(traceRequest())
 	v2.Use(timingMetrics())
 	v2.Use(returnHeaders())
-	v2.POST("/boleto/register", authentication, parseBoleto, validateRegisterV2, registerBoletoLogger, handleErrors, panicRecoveryHandler, registerBoleto)
+	v2.POST("/boleto/register", authentication, auditRegistration, authorize(auth.PermissionRegister), userRateLimit(db.CreateRedis()), parseBoleto, bankRateLimit(db.CreateRedis()), validateRegisterV2, registrationQuota(db.CreateRedis()), registerBoletoLogger, handleErrors, panicRecoveryHandler, registerBoleto(repository))
+	v2.GET("/boleto/:id", authentication, authorize(auth.PermissionRead), getBoletoByID(repository))
+	v2.GET("/boletos", authentication, authorize(auth.PermissionRead), searchBoletos(repository))
+	v2.POST("/boleto/:id/links/reissue", authentication, authorize(auth.PermissionRegister), reissueLinks(repository))
+	v2.POST("/boleto/:id/links/revoke", authentication, authorize(auth.PermissionRegister), revokeLinks(repository))
+	v2.GET("/boleto/:id/events", authentication, authorize(auth.PermissionRead), getBoletoEvents(repository))
+	v2.GET("/events", authentication, authorize(auth.PermissionRead), getRequestEvents)
 }
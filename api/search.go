@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 100
+)
+
+//searchBoletos Lista os boletos registrados pelo usuário autenticado
+func searchBoletos(repository db.BoletoRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseBoletoFilter(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.GetBoletoResponseError("MP400", err.Error()))
+			return
+		}
+		// sem o usuário o filtro não teria dono e listaria os boletos de todos os usuários
+		if filter.ServiceUser == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.GetBoletoResponseError("MP401", "Unauthorized"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), db.ConnectionTimeout)
+		defer cancel()
+
+		page, err := repository.FindBoletos(ctx, filter)
+		if err != nil {
+			l := log.CreateLog()
+			l.Operation = "SearchBoletos"
+			l.ServiceUser = filter.ServiceUser
+			l.IPAddress = c.ClientIP()
+			l.Error(err.Error(), "Error searching boletos")
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseToClient())
+			return
+		}
+
+		response := models.BoletoSearchResponse{
+			Boletos:    make([]models.BoletoSummary, 0, len(page.Boletos)),
+			NextCursor: page.NextCursor,
+		}
+		for _, b := range page.Boletos {
+			response.Boletos = append(response.Boletos, models.NewBoletoSummary(b))
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func parseBoletoFilter(c *gin.Context) (db.BoletoFilter, error) {
+	var err error
+	filter := db.BoletoFilter{
+		ServiceUser:       getUserFromContext(c),
+		RecipientDocument: c.Query("recipientDocument"),
+		BuyerDocument:     c.Query("buyerDocument"),
+		OurNumber:         c.Query("ourNumber"),
+		DocumentNumber:    c.Query("documentNumber"),
+		Status:            c.Query("status"),
+		SortBy:            c.Query("sort"),
+		Limit:             defaultSearchLimit,
+	}
+
+	if filter.Status != "" && !models.IsValidBoletoStatus(filter.Status) {
+		return filter, fmt.Errorf("invalid status %s", filter.Status)
+	}
+
+	if !db.IsValidSort(filter.SortBy) {
+		return filter, fmt.Errorf("invalid sort %s, expected %s or %s", filter.SortBy, db.SortByCreateDate, db.SortByExpireDate)
+	}
+
+	switch strings.ToLower(c.DefaultQuery("order", "desc")) {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+		filter.Ascending = false
+	default:
+		return filter, fmt.Errorf("invalid order %s, expected asc or desc", c.Query("order"))
+	}
+
+	if v := c.Query("bankNumber"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !models.BankNumber(n).IsBankNumberValid() {
+			return filter, fmt.Errorf("invalid bankNumber %s", v)
+		}
+		filter.BankNumber = models.BankNumber(n)
+	}
+
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 || filter.Limit > maxSearchLimit {
+			return filter, fmt.Errorf("invalid limit %s, expected a value between 1 and %d", v, maxSearchLimit)
+		}
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		if filter.Cursor, err = db.DecodeCursor(v); err != nil {
+			return filter, err
+		}
+	}
+
+	dates := []struct {
+		param string
+		value *time.Time
+		end   bool
+	}{
+		{"expireDateFrom", &filter.ExpireDateFrom, false},
+		{"expireDateTo", &filter.ExpireDateTo, true},
+		{"createDateFrom", &filter.CreateDateFrom, false},
+		{"createDateTo", &filter.CreateDateTo, true},
+	}
+	for _, d := range dates {
+		if *d.value, err = parseSearchDate(c.Query(d.param), d.end); err != nil {
+			return filter, fmt.Errorf("invalid %s, expected YYYY-MM-DD or RFC3339", d.param)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseSearchDate accepts a date (YYYY-MM-DD) or a timestamp (RFC3339).
+// A date used as the end of a range includes the whole day
+func parseSearchDate(v string, endOfRange bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
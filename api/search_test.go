@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SearchBoletos_ReturnsOnlyBoletosOfServiceUser(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	mine := arrangeSearchBoleto(repository, "user-a", time.Now())
+	arrangeSearchBoleto(repository, "user-b", time.Now())
+	router, w := arrangeSearchRoute(repository, "user-a")
+
+	req, _ := http.NewRequest(http.MethodGet, "/boletos", nil)
+	router.ServeHTTP(w, req)
+
+	var response models.BoletoSearchResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, len(response.Boletos))
+	assert.Equal(t, mine.ID.Hex(), response.Boletos[0].ID)
+	assert.Empty(t, response.NextCursor)
+}
+
+func Test_SearchBoletos_PaginatesWithCursor(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		arrangeSearchBoleto(repository, "user-a", now.Add(time.Duration(i)*time.Minute))
+	}
+	router, w := arrangeSearchRoute(repository, "user-a")
+
+	req, _ := http.NewRequest(http.MethodGet, "/boletos?limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	var first models.BoletoSearchResponse
+	json.Unmarshal(w.Body.Bytes(), &first)
+	assert.Equal(t, 2, len(first.Boletos))
+	assert.NotEmpty(t, first.NextCursor)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/boletos?limit=2&cursor="+first.NextCursor, nil)
+	router.ServeHTTP(w, req)
+
+	var second models.BoletoSearchResponse
+	json.Unmarshal(w.Body.Bytes(), &second)
+	assert.Equal(t, 1, len(second.Boletos))
+	assert.Empty(t, second.NextCursor)
+}
+
+func Test_SearchBoletos_WhenInvalidParameters_ReturnBadRequest(t *testing.T) {
+	queries := []string{
+		"limit=0",
+		"limit=101",
+		"status=Unknown",
+		"sort=amount",
+		"order=up",
+		"bankNumber=999",
+		"expireDateFrom=01/01/2021",
+		"cursor=invalid",
+	}
+
+	for _, q := range queries {
+		router, w := arrangeSearchRoute(db.NewMemoryRepository(), "user-a")
+		req, _ := http.NewRequest(http.MethodGet, "/boletos?"+q, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, 400, w.Code, q)
+		assert.Contains(t, w.Body.String(), "MP400", q)
+	}
+}
+
+func Test_ParseSearchDate_EndOfRangeIncludesWholeDay(t *testing.T) {
+	got, err := parseSearchDate("2021-06-10", true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 6, 10, 23, 59, 59, 999999999, time.UTC), got)
+}
+
+func arrangeSearchRoute(repository db.BoletoRepository, user string) (*gin.Engine, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/boletos", func(c *gin.Context) { c.Set(serviceUserKey, user) }, searchBoletos(repository))
+	return router, httptest.NewRecorder()
+}
+
+func arrangeSearchBoleto(repository db.BoletoRepository, user string, createDate time.Time) models.BoletoView {
+	view := models.NewBoletoView(models.BoletoRequest{BankNumber: models.Caixa}, models.BoletoResponse{}, "", user)
+	view.CreateDate = createDate
+	repository.SaveBoleto(context.Background(), view)
+	return view
+}
+
+func Test_GetBoletoByID_WhenOwner_ReturnBoleto(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+	router, w := arrangeGetBoletoByIDRoute(repository, "user-a")
+
+	req, _ := http.NewRequest(http.MethodGet, "/boleto/"+view.ID.Hex(), nil)
+	router.ServeHTTP(w, req)
+
+	var response models.BoletoView
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, view.ID, response.ID)
+}
+
+func Test_GetBoletoByID_WhenNotOwner_ReturnNotFound(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+	legacy := arrangeSearchBoleto(repository, "", time.Now())
+
+	for _, id := range []string{view.ID.Hex(), legacy.ID.Hex()} {
+		router, w := arrangeGetBoletoByIDRoute(repository, "user-b")
+		req, _ := http.NewRequest(http.MethodGet, "/boleto/"+id, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, 404, w.Code)
+		assert.Contains(t, w.Body.String(), "MP404")
+	}
+}
+
+func Test_SearchBoletos_WithoutServiceUser_ReturnUnauthorized(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	arrangeSearchBoleto(repository, "user-a", time.Now())
+	arrangeSearchBoleto(repository, "", time.Now())
+	router, w := arrangeSearchRoute(repository, "")
+
+	req, _ := http.NewRequest(http.MethodGet, "/boletos", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+	assert.NotContains(t, w.Body.String(), "boletos")
+}
+
+func Test_GetBoletoByIDV1_IsPublicAndReturnsOnlyBoletosWithoutSecretKey(t *testing.T) {
+	config.Install(true, false, true)
+	repository := db.NewMemoryRepository()
+	view := arrangeSearchBoleto(repository, "user-a", time.Now())
+	legacy := models.NewBoletoView(models.BoletoRequest{BankNumber: models.Caixa}, models.BoletoResponse{}, "", "")
+	legacy.SecretKey = ""
+	repository.SaveBoleto(context.Background(), legacy)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/boleto/:id", getBoletoByIDV1(repository))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/boleto/"+legacy.ID.Hex(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), legacy.ID.Hex())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/v1/boleto/"+view.ID.Hex(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+	assert.Contains(t, w.Body.String(), "MP404")
+}
+
+func arrangeGetBoletoByIDRoute(repository db.BoletoRepository, user string) (*gin.Engine, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/boleto/:id", func(c *gin.Context) { c.Set(serviceUserKey, user) }, getBoletoByID(repository))
+	return router, httptest.NewRecorder()
+}
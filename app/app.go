@@ -1,17 +1,24 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/mundipagg/boleto-api/api"
 	"github.com/mundipagg/boleto-api/certificate"
 	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/env"
 	"github.com/mundipagg/boleto-api/healthcheck"
+	"github.com/mundipagg/boleto-api/lifecycle"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/mock"
+	"github.com/mundipagg/boleto-api/privacy"
+	"github.com/mundipagg/boleto-api/tokenmanager"
 	"github.com/mundipagg/boleto-api/usermanagement"
+	"github.com/mundipagg/boleto-api/util"
 )
 
 //Params this struct contains all execution parameters to run application
@@ -37,49 +44,196 @@ func Run(params *Params) {
 
 	log.Install()
 
+	background := lifecycle.NewGroup()
+	background.OnStop(watchConfiguration())
+	tokenmanager.Install(background)
+
 	start := time.Now()
 
-	healthcheck.ExecuteOnStartup()
+	installCertificates(background)
 
-	installCertificates()
+	healthcheck.ExecuteOnStartup()
 
+	installCredentialRepository()
 	usermanagement.LoadUserCredentials()
+	background.Go(func(ctx context.Context) {
+		usermanagement.RefreshUserCredentials(ctx, time.Duration(config.Get().CredentialsRefreshInSeconds)*time.Second)
+	})
+
+	repository := installBoletoRepository()
+	installEventRepository()
+	background.Go(func(ctx context.Context) {
+		privacy.RunRetention(ctx, repository, time.Duration(config.Get().RetentionIntervalInMinutes)*time.Minute)
+	})
 
 	props := getLoadDependenciesLogProp(start)
-	go log.CreateLog().InfoWithBasic("Load Dependencies with success", "Information", props)
+	log.CreateLog().InfoWithBasic("Load Dependencies with success", "Information", props)
 
-	api.InstallRestAPI()
+	api.InstallRestAPI(repository, background)
 }
 
-func installCertificates() {
+// EncryptPersonalDataMigration cifra os dados pessoais dos boletos e os client secrets das credenciais guardados antes da cifragem ser habilitada
+const EncryptPersonalDataMigration = "encrypt-personal-data"
+
+// migrationBatchSize é a quantidade de boletos lidos por vez durante a migração
+const migrationBatchSize = 500
+
+//Migrate executa a migração de dados informada em -migrate, sem subir a API
+func Migrate(params *Params, name string) error {
+	env.Config(params.DevMode, params.MockMode, params.DisableLog)
+	log.Install()
+
 	l := log.CreateLog()
-	l.Operation = "InstallCertificates"
+	l.Operation = "Migrate"
 
-	if config.Get().MockMode {
-		certificate.LoadMockCertificates()
-		return
+	if name != EncryptPersonalDataMigration {
+		return fmt.Errorf("unknown migration %s", name)
 	}
 
-	vaultCertificates := []string{config.Get().CertificateICPName, config.Get().CertificateSSLName, config.Get().CitibankCertificateSSLName, config.Get().SantanderCertificateSSLName}
+	repository, err := db.NewBoletoRepository()
+	if err != nil {
+		return err
+	}
+	encrypted, ok := repository.(db.EncryptedRepository)
+	if !ok {
+		return fmt.Errorf("boleto repository %s does not support %s", config.Get().BoletoRepository, name)
+	}
 
-	err := certificate.InstanceStoreCertificatesFromAzureVault(config.Get().VaultName, vaultCertificates...)
-	if err == nil {
-		l.InfoWithBasic("Success in load certificates from azureVault", "LoadFromAzureVault", nil)
-	} else {
-		l.ErrorWithBasic("Error in load certificates from azureVault", "LoadFromAzureVault", err)
+	start := time.Now()
+	total, err := encrypted.EncryptStoredBoletos(context.Background(), migrationBatchSize)
+	if err != nil {
+		l.ErrorWithBasic(fmt.Sprintf("Migration failed after %d boletos", total), "Error", err)
+		return err
+	}
+
+	credentials, err := usermanagement.EncryptStoredCredentials(context.Background())
+	if err != nil {
+		l.ErrorWithBasic(fmt.Sprintf("Migration failed after %d credentials", credentials), "Error", err)
+		return err
+	}
+
+	props := map[string]interface{}{"Migration": name, "Boletos": total, "Credentials": credentials, "TotalElapsedTimeInMilliseconds": time.Since(start).Milliseconds()}
+	l.InfoWithBasic("Migration finished", "Information", props)
+	return nil
+}
+
+func installBoletoRepository() db.BoletoRepository {
+	l := log.CreateLog()
+	l.Operation = "InstallBoletoRepository"
+
+	repository, err := db.NewBoletoRepository()
+	if err != nil {
+		l.ErrorWithBasic("Error creating boleto repository", "Error", err)
 		time.Sleep(10 * time.Second)
 		os.Exit(1)
 	}
 
-	blobCertificates := []string{config.Get().AzureStorageOpenBankSkName, config.Get().AzureStorageJPMorganPkName, config.Get().AzureStorageJPMorganCrtName, config.Get().AzureStorageJPMorganSignCrtName}
+	if indexed, ok := repository.(db.IndexedRepository); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), db.ConnectionTimeout)
+		defer cancel()
 
-	err = certificate.InstanceStoreCertificatesFromAzureBlob(blobCertificates...)
+		if err := indexed.EnsureIndexes(ctx); err != nil {
+			l.ErrorWithBasic("Error creating boleto repository indexes", "Error", err)
+		}
+	}
+
+	l.InfoWithBasic("Boleto repository installed", "Information", map[string]interface{}{"Repository": config.Get().BoletoRepository})
+	return repository
+}
+
+// installCredentialRepository cria os índices das credenciais, como o de username único
+func installCredentialRepository() {
+	l := log.CreateLog()
+	l.Operation = "InstallCredentialRepository"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.ConnectionTimeout)
+	defer cancel()
+
+	if err := usermanagement.EnsureIndexes(ctx); err != nil {
+		l.ErrorWithBasic("Error creating credential repository indexes", "Error", err)
+	}
+}
+
+// installEventRepository cria os índices do armazenamento dos eventos de auditoria dos boletos
+func installEventRepository() {
+	l := log.CreateLog()
+	l.Operation = "InstallEventRepository"
+
+	events, err := db.NewEventRepository()
 	if err != nil {
-		l.ErrorWithBasic("Error loading open bank secret key from blob", "LoadFromAzureBlob", err)
+		l.ErrorWithBasic("Error creating boleto event repository", "Error", err)
+		return
+	}
+
+	if indexed, ok := events.(db.IndexedRepository); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), db.ConnectionTimeout)
+		defer cancel()
+
+		if err := indexed.EnsureIndexes(ctx); err != nil {
+			l.ErrorWithBasic("Error creating boleto event repository indexes", "Error", err)
+		}
+	}
+}
+
+// watchConfiguration recarrega a configuração ao receber SIGHUP ou quando o CONFIG_FILE muda
+// Os writers de log são recriados quando as chaves deles mudam. Conexões já abertas, como as do Mongo e do Redis, exigem reinicialização
+func watchConfiguration() func() {
+	interval := time.Duration(config.Get().ConfigReloadIntervalInSeconds) * time.Second
+	return config.Watch(interval, func(changed []string, err error) {
+		l := log.CreateLog()
+		l.Operation = "ReloadConfiguration"
+
+		if err != nil {
+			l.ErrorWithBasic("Invalid configuration, keeping the current one", "Error", err)
+			return
+		}
+		if len(changed) == 0 {
+			return
+		}
+
+		log.Reconfigure(changed)
+		l.InfoWithBasic("Configuration reloaded", "Information", map[string]interface{}{"ChangedKeys": changed})
+	})
+}
+
+func installCertificates(background *lifecycle.Group) {
+	l := log.CreateLog()
+	l.Operation = "InstallCertificates"
+
+	if config.Get().MockMode {
+		certificate.LoadMockCertificates()
+		return
+	}
+
+	sources := certificateSources()
+	if err := certificate.Load(sources...); err != nil {
+		l.ErrorWithBasic("Error loading certificates", "LoadCertificates", err)
 		time.Sleep(10 * time.Second)
 		os.Exit(1)
 	}
+	l.InfoWithBasic("Success in load certificates", "LoadCertificates", map[string]interface{}{"Source": sources[0].Name})
+
+	background.Go(func(ctx context.Context) {
+		certificate.Refresh(ctx, time.Duration(config.Get().CertificateRefreshInSeconds)*time.Second, certificateSources)
+	})
+}
+
+// certificateSources retorna os certificados da aplicação na origem configurada em CERTIFICATE_SOURCE
+func certificateSources() []certificate.Source {
+	names := []string{
+		config.Get().CertificateICPName,
+		config.Get().CertificateSSLName,
+		config.Get().CitibankCertificateSSLName,
+		config.Get().SantanderCertificateSSLName,
+		config.Get().AzureStorageOpenBankSkName,
+		config.Get().AzureStorageJPMorganPkName,
+		config.Get().AzureStorageJPMorganCrtName,
+		config.Get().AzureStorageJPMorganSignCrtName,
+	}
+	// bundles de CAs das políticas de confiança configuradas em TLS_TRUST
+	names = append(names, util.TrustedCertificates()...)
 
+	return []certificate.Source{certificate.ConfiguredSource(names...)}
 }
 
 func getLoadDependenciesLogProp(start time.Time) map[string]interface{} {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mundipagg/boleto-api/certificate"
 	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/lifecycle"
 	"github.com/mundipagg/boleto-api/mock"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,7 +39,7 @@ func Test_installCertificates(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			installCertificates()
+			installCertificates(lifecycle.NewGroup())
 		})
 		sk, err := certificate.GetCertificateFromStore(tt.certificate)
 		assert.Nil(t, err)
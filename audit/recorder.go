@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Recorder Grava os eventos de auditoria dos boletos em segundo plano, para que a auditoria não atrase as requisições
+//O armazenamento é obtido no primeiro uso, depois que a configuração foi carregada
+type Recorder struct {
+	open    func() (db.EventRepository, error)
+	once    sync.Once
+	events  db.EventRepository
+	err     error
+	pending sync.WaitGroup
+}
+
+//NewRecorder Cria um Recorder que grava os eventos no armazenamento retornado por open
+func NewRecorder(open func() (db.EventRepository, error)) *Recorder {
+	return &Recorder{open: open}
+}
+
+//Record Grava o evento sem bloquear. Falhas na gravação são logadas e não interrompem a operação auditada
+func (r *Recorder) Record(event models.BoletoEvent) {
+	event.ID = primitive.NewObjectID()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), db.ConnectionTimeout)
+		defer cancel()
+
+		if err := r.append(ctx, event); err != nil {
+			l := log.CreateLog()
+			l.Operation = "RecordBoletoEvent"
+			l.ServiceUser = event.ServiceUser
+			l.RequestKey = event.RequestKey
+			l.Warn(event, "Error recording boleto event: "+err.Error())
+		}
+	}()
+}
+
+//Events Lista os eventos do boleto, do mais antigo ao mais recente
+func (r *Recorder) Events(ctx context.Context, boletoID string) ([]models.BoletoEvent, error) {
+	events, err := r.repository()
+	if err != nil {
+		return nil, err
+	}
+	return events.FindEvents(ctx, boletoID)
+}
+
+//EventsByRequestKey Lista os eventos da requisição do usuário, do mais antigo ao mais recente
+func (r *Recorder) EventsByRequestKey(ctx context.Context, serviceUser, requestKey string) ([]models.BoletoEvent, error) {
+	events, err := r.repository()
+	if err != nil {
+		return nil, err
+	}
+	return events.FindEventsByRequestKey(ctx, serviceUser, requestKey)
+}
+
+//Wait Aguarda as gravações em andamento até o fim do contexto
+func (r *Recorder) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Recorder) append(ctx context.Context, event models.BoletoEvent) error {
+	events, err := r.repository()
+	if err != nil {
+		return err
+	}
+	return events.AppendEvent(ctx, event)
+}
+
+func (r *Recorder) repository() (db.EventRepository, error) {
+	r.once.Do(func() {
+		r.events, r.err = r.open()
+	})
+	return r.events, r.err
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_RecordsEventsInOrder(t *testing.T) {
+	recorder := NewRecorder(func() (db.EventRepository, error) { return db.NewMemoryEventRepository(), nil })
+	now := time.Now()
+
+	recorder.Record(models.BoletoEvent{BoletoID: "b1", Type: models.EventRead, Outcome: models.OutcomeSuccess, CreatedAt: now.Add(time.Second)})
+	recorder.Record(models.BoletoEvent{BoletoID: "b1", Type: models.EventRegistration, Outcome: models.OutcomeSuccess, CreatedAt: now})
+	recorder.Record(models.BoletoEvent{BoletoID: "b2", Type: models.EventRead, Outcome: models.OutcomeFailure})
+	assert.Nil(t, recorder.Wait(context.Background()))
+
+	events, err := recorder.Events(context.Background(), "b1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, models.EventRegistration, events[0].Type)
+	assert.Equal(t, models.EventRead, events[1].Type)
+	assert.False(t, events[0].ID.IsZero())
+}
+
+func TestRecorder_WhenRepositoryFails_DoesNotBlock(t *testing.T) {
+	config.Install(true, false, true)
+	opened := 0
+	recorder := NewRecorder(func() (db.EventRepository, error) {
+		opened++
+		return nil, errors.New("mongo unavailable")
+	})
+
+	recorder.Record(models.BoletoEvent{BoletoID: "b1", Type: models.EventRead})
+	assert.Nil(t, recorder.Wait(context.Background()))
+
+	_, err := recorder.Events(context.Background(), "b1")
+	assert.EqualError(t, err, "mongo unavailable")
+	assert.Equal(t, 1, opened)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+// Permissões das rotas da API
+const (
+	PermissionRegister = "boleto:register"
+	PermissionRead     = "boleto:read"
+)
+
+// Estratégias de autenticação disponíveis em AUTH_STRATEGIES
+const (
+	BasicStrategy  = "basic"
+	APIKeyStrategy = "apikey"
+	JWTStrategy    = "jwt"
+)
+
+var (
+	//ErrNotApplicable A requisição não usa a estratégia de autenticação
+	ErrNotApplicable = errors.New("authentication strategy not applicable")
+	//ErrUnauthorized As credenciais da requisição são inválidas
+	ErrUnauthorized = errors.New("unauthorized")
+
+	permissions = []string{PermissionRegister, PermissionRead}
+)
+
+//Principal Identidade autenticada de uma requisição e as permissões dela
+type Principal struct {
+	// Credentials são as credenciais do usuário, que definem cotas, limites e credenciais de banco
+	Credentials models.Credentials
+	Strategy    string
+	Permissions map[string]bool
+}
+
+//Can Indica se a identidade tem a permissão
+func (p Principal) Can(permission string) bool {
+	return p.Permissions[permission]
+}
+
+//Strategy Forma de autenticação das requisições
+type Strategy interface {
+	// Authenticate retorna ErrNotApplicable quando a requisição não usa a estratégia
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{
+		BasicStrategy:  basicStrategy{},
+		APIKeyStrategy: apiKeyStrategy{},
+		JWTStrategy:    &jwtStrategy{},
+	}
+)
+
+//Register Registra uma estratégia de autenticação, que pode ser habilitada em AUTH_STRATEGIES
+func Register(name string, strategy Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = strategy
+}
+
+//Authenticate Autentica a requisição com a primeira estratégia habilitada que se aplica a ela
+//AUTH_STRATEGIES lista as estratégias separadas por vírgula; por padrão só a basic é habilitada
+func Authenticate(r *http.Request) (Principal, error) {
+	for _, name := range enabledStrategies() {
+		strategiesMu.RLock()
+		strategy, ok := strategies[name]
+		strategiesMu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		principal, err := strategy.Authenticate(r)
+		if err == ErrNotApplicable {
+			continue
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		principal.Strategy = name
+		return principal, nil
+	}
+	return Principal{}, ErrUnauthorized
+}
+
+func enabledStrategies() []string {
+	names := []string{}
+	for _, name := range strings.Split(config.Get().AuthStrategies, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, BasicStrategy)
+	}
+	return names
+}
+
+//IsPermission Indica se o escopo é uma das permissões da API
+func IsPermission(scope string) bool {
+	for _, p := range permissions {
+		if p == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//AllPermissions Retorna todas as permissões, dadas às credenciais que não têm escopos, como as da autenticação basic
+func AllPermissions() map[string]bool {
+	all := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		all[p] = true
+	}
+	return all
+}
+
+//ScopePermissions Converte escopos em permissões
+//Um escopo com o nome de uma permissão a concede. AUTH_SCOPE_PERMISSIONS mapeia outros escopos, no formato
+//escopo=permissao1|permissao2, com entradas separadas por ponto e vírgula
+func ScopePermissions(scopes []string) map[string]bool {
+	mapping := scopeMapping()
+	granted := make(map[string]bool)
+	for _, scope := range scopes {
+		if IsPermission(scope) {
+			granted[scope] = true
+		}
+		for _, p := range mapping[scope] {
+			granted[p] = true
+		}
+	}
+	return granted
+}
+
+func scopeMapping() map[string][]string {
+	mapping := make(map[string][]string)
+	for _, entry := range strings.Split(config.Get().AuthScopePermissions, ";") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		scope := strings.TrimSpace(kv[0])
+		for _, p := range strings.Split(kv[1], "|") {
+			if p = strings.TrimSpace(p); IsPermission(p) {
+				mapping[scope] = append(mapping[scope], p)
+			}
+		}
+	}
+	return mapping
+}
+
+//PermissionNames Retorna os nomes das permissões concedidas, em ordem
+func PermissionNames(granted map[string]bool) []string {
+	names := []string{}
+	for p, ok := range granted {
+		if ok {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
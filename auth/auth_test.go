@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/usermanagement"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeAuth(t *testing.T, env map[string]string) {
+	os.Clearenv()
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	config.Install(true, false, true)
+	usermanagement.Install(db.NewMemoryCredentialRepository())
+	t.Cleanup(os.Clearenv)
+}
+
+func arrangeJWKS(t *testing.T, key *rsa.PrivateKey) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func TestAuthenticate_WithBasicCredentials_GrantsAllPermissions(t *testing.T) {
+	arrangeAuth(t, nil)
+	c, password, _ := usermanagement.CreateCredentials(context.Background(), models.CredentialsRequest{Username: "gandalf"})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(c.UserKey, password)
+	principal, err := Authenticate(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, BasicStrategy, principal.Strategy)
+	assert.Equal(t, "gandalf", principal.Credentials.Username)
+	assert.Equal(t, []string{PermissionRead, PermissionRegister}, PermissionNames(principal.Permissions))
+}
+
+func TestAuthenticate_WithAPIKey_GrantsKeyScopes(t *testing.T) {
+	arrangeAuth(t, map[string]string{"AUTH_STRATEGIES": "basic,apikey"})
+	c, _, _ := usermanagement.CreateCredentials(context.Background(), models.CredentialsRequest{Username: "saruman"})
+	_, key, _ := usermanagement.CreateAPIKey(context.Background(), c.UserKey, []string{PermissionRead})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", key)
+	principal, err := Authenticate(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, APIKeyStrategy, principal.Strategy)
+	assert.True(t, principal.Can(PermissionRead))
+	assert.False(t, principal.Can(PermissionRegister))
+
+	req.Header.Del("X-API-Key")
+	req.Header.Set("Authorization", "ApiKey invalid")
+	_, err = Authenticate(req)
+	assert.Equal(t, ErrUnauthorized, err)
+}
+
+func TestAuthenticate_WhenStrategyIsDisabled_ReturnUnauthorized(t *testing.T) {
+	arrangeAuth(t, map[string]string{"AUTH_STRATEGIES": "basic"})
+	c, _, _ := usermanagement.CreateCredentials(context.Background(), models.CredentialsRequest{Username: "radagast"})
+	_, key, _ := usermanagement.CreateAPIKey(context.Background(), c.UserKey, []string{PermissionRead})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", key)
+	_, err := Authenticate(req)
+
+	assert.Equal(t, ErrUnauthorized, err)
+}
+
+func TestAuthenticate_WithJWT_MapsScopesToPermissions(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	arrangeAuth(t, map[string]string{
+		"AUTH_STRATEGIES":        "jwt",
+		"AUTH_JWKS_URL":          arrangeJWKS(t, key),
+		"AUTH_JWT_AUDIENCE":      "boleto-api",
+		"AUTH_SCOPE_PERMISSIONS": "boletos.write=boleto:register|boleto:read",
+	})
+	Register(JWTStrategy, &jwtStrategy{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, key, jwt.MapClaims{
+		"aud":       []string{"other", "boleto-api"},
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"client_id": "frodo",
+		"scope":     "boletos.write openid",
+	}))
+	principal, err := Authenticate(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, JWTStrategy, principal.Strategy)
+	assert.Equal(t, "frodo", principal.Credentials.Username)
+	assert.Equal(t, []string{PermissionRead, PermissionRegister}, PermissionNames(principal.Permissions))
+}
+
+func TestAuthenticate_WithJWT_WhenJWKSFails_ThrottlesTheRetries(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	arrangeAuth(t, map[string]string{
+		"AUTH_STRATEGIES":   "jwt",
+		"AUTH_JWKS_URL":     server.URL,
+		"AUTH_JWT_AUDIENCE": "boleto-api",
+	})
+	Register(JWTStrategy, &jwtStrategy{})
+	token := signToken(t, key, jwt.MapClaims{"aud": "boleto-api", "exp": time.Now().Add(time.Minute).Unix(), "sub": "frodo"})
+	authenticate := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, err := Authenticate(req)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, ErrUnauthorized, authenticate())
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, ErrUnauthorized, authenticate())
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "as requisições esperam a mesma busca e a falha limita as próximas")
+}
+
+func TestAuthenticate_WithJWT_RejectsInvalidTokens(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	arrangeAuth(t, map[string]string{
+		"AUTH_STRATEGIES":   "jwt",
+		"AUTH_JWKS_URL":     arrangeJWKS(t, key),
+		"AUTH_JWT_AUDIENCE": "boleto-api",
+	})
+	Register(JWTStrategy, &jwtStrategy{})
+
+	tokens := map[string]string{
+		"audiência diferente": signToken(t, key, jwt.MapClaims{"aud": "other", "exp": time.Now().Add(time.Minute).Unix(), "sub": "frodo"}),
+		"expirado":            signToken(t, key, jwt.MapClaims{"aud": "boleto-api", "exp": time.Now().Add(-time.Minute).Unix(), "sub": "frodo"}),
+		"sem expiração":       signToken(t, key, jwt.MapClaims{"aud": "boleto-api", "sub": "frodo"}),
+		"outra chave":         signToken(t, other, jwt.MapClaims{"aud": "boleto-api", "exp": time.Now().Add(time.Minute).Unix(), "sub": "frodo"}),
+		"sem usuário":         signToken(t, key, jwt.MapClaims{"aud": "boleto-api", "exp": time.Now().Add(time.Minute).Unix()}),
+		"usuário vazio":       signToken(t, key, jwt.MapClaims{"aud": "boleto-api", "exp": time.Now().Add(time.Minute).Unix(), "client_id": "", "sub": ""}),
+	}
+
+	for name, token := range tokens {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, err := Authenticate(req)
+		assert.Equal(t, ErrUnauthorized, err, name)
+	}
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/usermanagement"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMaxAge é o tempo em que as chaves do JWKS são usadas sem buscar de novo
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh limita a busca do JWKS quando um token traz um kid desconhecido ou quando a última busca falhou
+	jwksMinRefresh = 30 * time.Second
+	// defaultUserClaim é a claim com o usuário do token quando AUTH_JWT_USER_CLAIM não está definido
+	defaultUserClaim = "client_id"
+)
+
+// jwtStrategy autentica com um token JWT emitido pelo gateway, enviado como Authorization: Bearer <token>
+// A assinatura é verificada com as chaves de AUTH_JWKS_URL e o token precisa ter a audiência AUTH_JWT_AUDIENCE
+// As permissões são os escopos do token, nas claims scope ou scp
+type jwtStrategy struct {
+	mu        sync.Mutex
+	url       string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// triedURL e triedAt são a última busca do JWKS, mesmo a que falhou, e lastErr o erro dela
+	triedURL string
+	triedAt  time.Time
+	lastErr  error
+	refresh  singleflight.Group
+	client   http.Client
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *jwtStrategy) Authenticate(r *http.Request) (Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return Principal{}, ErrNotApplicable
+	}
+
+	claims, err := s.parse(r.Context(), strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")))
+	if err != nil {
+		l := log.CreateLog()
+		l.Operation = "AuthenticateJWT"
+		l.Warn(err.Error(), "Invalid bearer token")
+		return Principal{}, ErrUnauthorized
+	}
+
+	username := claimString(claims, userClaim())
+	if username == "" {
+		username = claimString(claims, "sub")
+	}
+	// sem usuário o token não teria dono para os boletos registrados nem escopo nas buscas
+	if username == "" {
+		l := log.CreateLog()
+		l.Operation = "AuthenticateJWT"
+		l.Warn(userClaim(), "Bearer token without user claim")
+		return Principal{}, ErrUnauthorized
+	}
+
+	// o usuário do token usa as cotas, os limites e as credenciais de banco das credenciais de mesmo nome, quando existem
+	user, ok := usermanagement.GetUserByUsername(username)
+	if !ok {
+		user = models.Credentials{Username: username}
+	}
+
+	return Principal{Credentials: user, Permissions: ScopePermissions(tokenScopes(claims))}, nil
+}
+
+func (s *jwtStrategy) parse(ctx context.Context, token string) (jwt.MapClaims, error) {
+	cfg := config.Get()
+	if cfg.AuthJWKSURL == "" || cfg.AuthJWTAudience == "" {
+		return nil, errors.New("AUTH_JWKS_URL and AUTH_JWT_AUDIENCE must be configured")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return s.key(ctx, cfg.AuthJWKSURL, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasAudience(claims, cfg.AuthJWTAudience) {
+		return nil, fmt.Errorf("token audience is not %s", cfg.AuthJWTAudience)
+	}
+	if cfg.AuthJWTIssuer != "" && !claims.VerifyIssuer(cfg.AuthJWTIssuer, true) {
+		return nil, fmt.Errorf("token issuer is not %s", cfg.AuthJWTIssuer)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("token has no expiration")
+	}
+	return claims, nil
+}
+
+// key busca a chave do kid no JWKS em cache, que é buscado de novo quando expira ou quando o kid é desconhecido
+// A busca é feita fora do lock e uma só por vez. As demais requisições esperam por ela ou, até jwksMinRefresh
+// depois da última tentativa, usam as chaves que já estão em cache
+func (s *jwtStrategy) key(ctx context.Context, url, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	expired := s.url != url || time.Since(s.fetchedAt) > jwksMaxAge
+	key, found := s.keys[kid]
+	found = found && s.url == url
+	throttled := s.triedURL == url && time.Since(s.triedAt) <= jwksMinRefresh
+	lastErr := s.lastErr
+	s.mu.Unlock()
+
+	if !expired && found {
+		return key, nil
+	}
+	if throttled {
+		if found {
+			return key, nil
+		}
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	_, err, _ := s.refresh.Do(url, func() (interface{}, error) {
+		return nil, s.refreshKeys(ctx, url)
+	})
+	if err != nil {
+		if found {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok && s.url == url {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refreshKeys busca o JWKS e guarda o horário da tentativa, para que uma falha também limite as próximas buscas
+func (s *jwtStrategy) refreshKeys(ctx context.Context, url string) error {
+	keys, err := s.fetch(ctx, url)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triedURL, s.triedAt, s.lastErr = url, time.Now(), err
+	if err != nil {
+		return err
+	}
+	s.url, s.keys, s.fetchedAt = url, keys, time.Now()
+	return nil
+}
+
+func (s *jwtStrategy) fetch(ctx context.Context, url string) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Transport: s.client.Transport, Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS returned status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func userClaim() string {
+	if claim := config.Get().AuthJWTUserClaim; claim != "" {
+		return claim
+	}
+	return defaultUserClaim
+}
+
+// hasAudience aceita a claim aud como texto ou lista
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tokenScopes lê os escopos da claim scope, separados por espaço, ou da claim scp, em lista
+func tokenScopes(claims jwt.MapClaims) []string {
+	scopes := []string{}
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(scope)...)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(scp)...)
+	case []interface{}:
+		for _, s := range scp {
+			if v, ok := s.(string); ok {
+				scopes = append(scopes, v)
+			}
+		}
+	}
+	return scopes
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mundipagg/boleto-api/usermanagement"
+)
+
+// apiKeyHeader é o header alternativo ao Authorization para enviar a chave de API
+const apiKeyHeader = "X-API-Key"
+
+// basicStrategy autentica com a chave e a senha do usuário. As credenciais têm todas as permissões
+type basicStrategy struct{}
+
+func (basicStrategy) Authenticate(r *http.Request) (Principal, error) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+		return Principal{}, ErrNotApplicable
+	}
+
+	userKey, password, ok := r.BasicAuth()
+	if !ok || userKey == "" || password == "" {
+		return Principal{}, ErrUnauthorized
+	}
+
+	user, ok := usermanagement.Authenticate(userKey, password)
+	if !ok {
+		return Principal{}, ErrUnauthorized
+	}
+	return Principal{Credentials: user, Permissions: AllPermissions()}, nil
+}
+
+// apiKeyStrategy autentica com uma chave de API, enviada no header X-API-Key ou como Authorization: ApiKey <chave>
+// As permissões são os escopos da chave
+type apiKeyStrategy struct{}
+
+func (apiKeyStrategy) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if authorization := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(authorization, "ApiKey ") {
+		key = strings.TrimSpace(strings.TrimPrefix(authorization, "ApiKey "))
+	}
+	if key == "" {
+		return Principal{}, ErrNotApplicable
+	}
+
+	user, apiKey, ok := usermanagement.AuthenticateAPIKey(key)
+	if !ok {
+		return Principal{}, ErrUnauthorized
+	}
+	return Principal{Credentials: user, Permissions: ScopePermissions(apiKey.Scopes)}, nil
+}
@@ -1,6 +1,7 @@
 package bank
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/mundipagg/boleto-api/bank/services/jpmorgan"
@@ -10,8 +11,8 @@ import (
 
 //Bank é a interface que vai oferecer os serviços em comum entre os bancos
 type Bank interface {
-	ProcessBoleto(*models.BoletoRequest) (models.BoletoResponse, error)
-	RegisterBoleto(*models.BoletoRequest) (models.BoletoResponse, error)
+	ProcessBoleto(context.Context, *models.BoletoRequest) (models.BoletoResponse, error)
+	RegisterBoleto(context.Context, *models.BoletoRequest) (models.BoletoResponse, error)
 	ValidateBoleto(*models.BoletoRequest) models.Errors
 	GetBankNumber() models.BankNumber
 	GetBankNameIntegration() string
@@ -39,7 +40,7 @@ func Get(boleto models.BoletoRequest) (Bank, error) {
 	case models.Stone:
 		return getIntegrationStone(boleto)
 	case models.JPMorgan:
-		return jpmorgan.New()
+		return jpmorgan.New(boleto.BankCredentials)
 	default:
 		return nil, models.NewErrorResponse("MPBankNumber", fmt.Sprintf("Banco %d não existe", boleto.BankNumber))
 	}
@@ -28,13 +28,13 @@ type dataTest struct {
 var bradescoNetEmpresaInstance = bradescoNetEmpresa.New()
 var bradescoShopFacilInstance = bradescoShopFacil.New()
 var bancoDoBrasilInstance = bb.New()
-var citibankInstance, _ = citibank.New()
-var santanderInstance, _ = santander.New()
+var citibankInstance, _ = citibank.New(models.BankCredentials{})
+var santanderInstance, _ = santander.New(models.BankCredentials{})
 var itauInstance = itau.New()
 var caixaInstance = caixa.New()
 var pefisaInstance = pefisa.New()
 var stoneInstance = stone.New()
-var jpInstanceInstance, _ = jpmorgan.New()
+var jpInstanceInstance, _ = jpmorgan.New(models.BankCredentials{})
 
 var getBankTestData = []dataTest{
 	{models.BoletoRequest{BankNumber: models.Bradesco, Agreement: models.Agreement{Wallet: 9}}, models.Bradesco, bradescoNetEmpresaInstance},
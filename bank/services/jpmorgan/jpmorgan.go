@@ -1,12 +1,13 @@
 package jpmorgan
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/certificate"
@@ -15,14 +16,15 @@ import (
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
 
 var (
-	onceTransport = &sync.Once{}
-	transportTLS  *http.Transport
+	transports = &util.TLSTransports{}
 )
 
 type bankJPMorgan struct {
@@ -32,22 +34,17 @@ type bankJPMorgan struct {
 	jwtSigner token.JwtGenerator
 }
 
-func New() (bankJPMorgan, error) {
+func New(credentials models.BankCredentials) (bankJPMorgan, error) {
 	var err error
 	b := bankJPMorgan{
 		validate: models.NewValidator(),
 		log:      log.CreateLog(),
 	}
 
-	certificates := certificate.TLSCertificate{
-		Crt: config.Get().AzureStorageJPMorganCrtName,
-		Key: config.Get().AzureStorageJPMorganPkName,
-	}
+	crt, key := credentials.ClientCertificate(config.Get().AzureStorageJPMorganCrtName, config.Get().AzureStorageJPMorganPkName)
+	certificates := certificate.TLSCertificate{Crt: crt, Key: key}
 
-	onceTransport.Do(func() {
-		transportTLS, err = util.BuildTLSTransport(certificates)
-	})
-	b.transport = transportTLS
+	b.transport, err = transports.Get(b.GetBankNameIntegration(), certificates)
 
 	if err != nil || (b.transport == nil && !config.Get().MockMode) {
 		return bankJPMorgan{}, fmt.Errorf("fail on load TLSTransport: %v", err)
@@ -63,13 +60,13 @@ func New() (bankJPMorgan, error) {
 	return b, nil
 }
 
-func (b bankJPMorgan) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankJPMorgan) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
 
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankJPMorgan) ValidateBoleto(request *models.BoletoRequest) models.Errors {
@@ -107,7 +104,7 @@ func (b bankJPMorgan) Log() *log.Log {
 	return b.log
 }
 
-func (b bankJPMorgan) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankJPMorgan) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	var response string
 	var respHeader map[string]interface{}
 	var status int
@@ -126,15 +123,24 @@ func (b bankJPMorgan) RegisterBoleto(boleto *models.BoletoRequest) (models.Bolet
 
 	b.log.Request(body, JPMorganURL, getLogRequestProperties(head, bodyEncripted))
 
-	duration := util.Duration(func() {
-		if config.Get().MockMode {
-			response, respHeader, status, err = util.PostWithHeader(JPMorganURL, body, config.Get().TimeoutDefault, head)
-		} else {
-			response, respHeader, status, err = util.PostTLSWithHeader(JPMorganURL, bodyEncripted, config.Get().TimeoutDefault, head, b.transport)
-		}
-
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	var duration time.Duration
+	callErr := resilience.Call(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, func() resilience.Attempt {
+		duration = util.Duration(func() {
+			if config.Get().MockMode {
+				response, respHeader, status, err = util.PostWithHeader(ctx, b.GetBankNameIntegration(), JPMorganURL, body, config.Get().TimeoutDefault, head)
+			} else {
+				response, respHeader, status, err = util.PostTLSWithHeader(ctx, b.GetBankNameIntegration(), JPMorganURL, bodyEncripted, config.Get().TimeoutDefault, head, b.transport)
+			}
+		})
+		return resilience.Attempt{Status: status, Err: err}
 	})
-	metrics.PushTimingMetric("jpmorgan-register-boleto-time", duration.Seconds())
+	if resilience.Rejected(callErr) {
+		tracing.End(span, callErr)
+		return models.BoletoResponse{}, callErr
+	}
+	tracing.EndHTTP(span, status, err)
+	metrics.PushBankTime("jpmorgan-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 
 	b.log.Response(response, JPMorganURL, nil)
 
@@ -176,6 +182,10 @@ func getBoletoType(boleto *models.BoletoRequest) (bt string, btc string) {
 }
 
 func mapJPMorganResponse(request *models.BoletoRequest, contentType string, response string, status int, httpErr error) models.BoletoResponse {
+	if code, ok := util.HandshakeErrorCode(httpErr); ok {
+		return models.GetBoletoResponseError(code, httpErr.Error())
+	}
+
 	f := flow.NewFlow().To("set://?prop=body", response)
 	switch status {
 	case 200:
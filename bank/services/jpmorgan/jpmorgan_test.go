@@ -1,6 +1,7 @@
 package jpmorgan
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -29,9 +30,9 @@ func Test_ProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBolet
 	mock.StartMockService("9003")
 	certificate.LoadMockCertificates()
 	input := newStubBoletoRequestJPMorgan().Build()
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	output, err := bank.ProcessBoleto(input)
+	output, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, err, "Não deve haver um erro")
 	assert.Equal(t, 12, len(output.OurNumber))
@@ -43,9 +44,9 @@ func Test_ProcessBoleto_WhenServiceRespondsWithShortOurNUmber_ShouldHasPadZerosA
 	mock.StartMockService("9005")
 	certificate.LoadMockCertificates()
 	input := newStubBoletoRequestJPMorgan().WithAmountInCents(211).Build()
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	output, err := bank.ProcessBoleto(input)
+	output, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, err, "Não deve haver um erro")
 	assert.Equal(t, 12, len(output.OurNumber))
@@ -56,11 +57,11 @@ func Test_ProcessBoleto_WhenServiceRespondsWithShortOurNUmber_ShouldHasPadZerosA
 func Test_ProcessBoleto_WhenServiceRespondsUnsuccessful_ShouldHasErrorResponse(t *testing.T) {
 	mock.StartMockService("9004")
 	certificate.LoadMockCertificates()
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
 	for _, fact := range boletoResponseFailParameters {
 		request := fact.Input.(*models.BoletoRequest)
-		response, err := bank.ProcessBoleto(request)
+		response, err := bank.ProcessBoleto(context.Background(), request)
 		assert.Nil(t, err, "Não deve haver um erro fora do objeto de response")
 
 		test.AssertProcessBoletoFailed(t, response)
@@ -73,9 +74,9 @@ func TestTemplateResponse_WhenRequestHasSpecialCharacter_ShouldBeParsedSuccessfu
 	mock.StartMockService("9006")
 	certificate.LoadMockCertificates()
 	input := newStubBoletoRequestJPMorgan().WithBuyerName("Nome do \tComprador (Cliente)").Build()
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -1,16 +1,21 @@
 package bb
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tokenmanager"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 
 	"github.com/mundipagg/boleto-api/validations"
@@ -49,7 +54,7 @@ func (b bankBB) Log() *log.Log {
 	return b.log
 }
 
-func (b *bankBB) login(boleto *models.BoletoRequest) (string, error) {
+func (b *bankBB) login(ctx context.Context, boleto *models.BoletoRequest) (tokenmanager.Token, error) {
 	type errorAuth struct {
 		Error            string `json:"error"`
 		ErrorDescription string `json:"error_description"`
@@ -60,40 +65,56 @@ func (b *bankBB) login(boleto *models.BoletoRequest) (string, error) {
 	from, resp := GetBBAuthLetters()
 	bod := r.From("message://?source=inline", boleto, from, tmpl.GetFuncMaps())
 	r = r.To("log://?type=request&url="+url, b.log)
-	duration := util.Duration(func() {
-		bod = bod.To(url, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutToken})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationToken)
+	tracing.InjectFlow(ctx, bod)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationToken, b.log, bod, func() {
+		duration = util.Duration(func() {
+			bod = bod.To(url, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutToken})
+		})
 	})
-	metrics.PushTimingMetric("bb-login-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return tokenmanager.Token{}, err
+	}
+	tracing.EndFlow(span, bod)
+	metrics.PushBankTime("bb-login-time", b.GetBankNameIntegration(), metrics.OperationToken, duration.Seconds())
 	r = r.To("log://?type=response&url="+url, b.log)
-	ch := bod.Choice().When(flow.Header("status").IsEqualTo("200")).To("transform://?format=json", resp, `{{.authToken}}`)
+	ch := bod.Choice().When(flow.Header("status").IsEqualTo("200")).To("transform://?format=json", resp, `{{.authToken}}:::{{.expiresIn}}`)
 	ch = ch.Otherwise().To("unmarshall://?format=json", new(errorAuth))
 	result := bod.GetBody()
 	switch t := result.(type) {
 	case string:
-		return t, nil
+		parts := strings.Split(t, ":::")
+		return tokenmanager.NewToken(parts[0], parts[len(parts)-1]), nil
 	case error:
-		return "", t
+		return tokenmanager.Token{}, t
 	case *errorAuth:
-		return "", errors.New(t.ErrorDescription)
+		return tokenmanager.Token{}, errors.New(t.ErrorDescription)
 	}
-	return "", errors.New("Saída inválida")
+	return tokenmanager.Token{}, errors.New("Saída inválida")
 }
 
 //ProcessBoleto faz o processamento de registro de boleto
-func (b bankBB) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBB) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	tok, err := b.login(boleto)
+	// a cópia evita que a renovação do token em segundo plano leia o boleto alterado pelo registro
+	credentials := *boleto
+	clientID := tokenmanager.ClientKey(boleto.Authentication.Username, boleto.Authentication.Password)
+	tok, err := tokenmanager.Get(ctx, b.GetBankNameIntegration(), clientID, b.log, func(ctx context.Context) (tokenmanager.Token, error) {
+		return b.login(ctx, &credentials)
+	})
 	if err != nil {
 		return models.BoletoResponse{}, err
 	}
 	boleto.Authentication.AuthorizationToken = tok
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
-func (b bankBB) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBB) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	r := flow.NewFlow()
 	url := config.Get().URLBBRegisterBoleto
 	from := getRequest()
@@ -102,10 +123,20 @@ func (b bankBB) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRespo
 
 	r = r.From("message://?source=inline", boleto, from, tmpl.GetFuncMaps())
 	r.To("log://?type=request&url="+url, b.log)
-	duration := util.Duration(func() {
-		r.To(url, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutRegister})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, r)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, r, func() {
+		duration = util.Duration(func() {
+			r.To(url, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutRegister})
+		})
 	})
-	metrics.PushTimingMetric("bb-register-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, r)
+	metrics.PushBankTime("bb-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	r.To("log://?type=response&url="+url, b.log)
 	ch := r.Choice()
 	ch.When(flow.Header("status").IsEqualTo("200"))
@@ -1,6 +1,7 @@
 package bb
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -86,7 +87,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	util.FromJSON(baseMockJSON, input)
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -98,7 +99,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 	input.Title.AmountInCents = 400
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -110,7 +111,7 @@ func TestProcessBoleto_WhenAccountInvalid_ShouldHasFailedBoletoResponse(t *testi
 	input.Agreement.Account = ""
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -12,7 +12,8 @@ grant_type=client_credentials&scope=cobranca.registro-boletos`
 
 const authLetterBBResponse = `
 {
-	"access_token":"{{authToken}}"	
+	"access_token":"{{authToken}}",
+	"expires_in":"{{expiresIn}}"
 }
 `
 
@@ -168,7 +168,7 @@ const templateBoletoBradescoShopFacil = `
                 <i class="icss-print"></i>
                 <span class="align">&nbspImprimir</span>
             </button>
-            <button class="no-print btnDefault print" onclick="window.location='./boleto?fmt=pdf&id={{bsonMongoToString .View.ID}}&pk={{.View.PublicKey}}'">
+            <button class="no-print btnDefault print" onclick="window.location='./boleto?fmt=pdf&id={{bsonMongoToString .View.ID}}&pk={{.View.PublicKey}}{{with .View.Signature}}&exp={{.Expires}}&kid={{.KeyID}}&sig={{.Value}}{{end}}'">
                 <i class="icss-files"></i>
                 <span class="align">&nbspGerar PDF</span>
             </button>            
@@ -1,6 +1,7 @@
 package bradescoNetEmpresa
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"html"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/mundipagg/boleto-api/metrics"
 
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 
 	"github.com/PMoneda/flow"
@@ -65,7 +68,7 @@ func (b bankBradescoNetEmpresa) Log() *log.Log {
 	return b.log
 }
 
-func (b bankBradescoNetEmpresa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBradescoNetEmpresa) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType(boleto)
 	r := flow.NewFlow()
@@ -78,16 +81,26 @@ func (b bankBradescoNetEmpresa) RegisterBoleto(boleto *models.BoletoRequest) (mo
 	bod := r.From("message://?source=inline", boleto, getRequestBradescoNetEmpresa(), tmpl.GetFuncMaps())
 	bod.To("log://?type=request&url="+serviceURL, b.log)
 
-	err := signRequest(bod)
+	err := signRequest(bod, boleto.BankCredentials.SigningCertificate)
 	if err != nil {
 		return models.BoletoResponse{}, err
 	}
 
-	duration := util.Duration(func() {
-		bod.To(serviceURL, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutDefault})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, bod)
+	var duration time.Duration
+	err = resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, bod, func() {
+		duration = util.Duration(func() {
+			bod.To(serviceURL, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutDefault})
+		})
 	})
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, bod)
 
-	metrics.PushTimingMetric("bradesco-netempresa-register-boleto-online", duration.Seconds())
+	metrics.PushBankTime("bradesco-netempresa-register-boleto-online", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	bod.To("log://?type=response&url="+serviceURL, b.log)
 
 	bod.To("transform://?format=xml", xmlResponse, jsonReponse)
@@ -120,12 +133,12 @@ func (b bankBradescoNetEmpresa) RegisterBoleto(boleto *models.BoletoRequest) (mo
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
 
-func (b bankBradescoNetEmpresa) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBradescoNetEmpresa) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankBradescoNetEmpresa) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
@@ -144,11 +157,11 @@ func (b bankBradescoNetEmpresa) GetErrorsMap() map[string]int {
 	return nil
 }
 
-func signRequest(bod *flow.Flow) error {
+func signRequest(bod *flow.Flow, certificateName string) error {
 
 	if !config.Get().MockMode {
 		bodyToSign := fmt.Sprintf("%v", bod.GetBody())
-		signedRequest, err := util.SignRequest(bodyToSign)
+		signedRequest, err := util.SignRequest(bodyToSign, certificateName)
 		if err != nil {
 			return err
 		}
@@ -1,6 +1,7 @@
 package bradescoNetEmpresa
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -26,7 +27,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	input := newStubBoletoRequestBradescoNetEmpresa().WithAmountInCents(200).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -36,7 +37,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 	input := newStubBoletoRequestBradescoNetEmpresa().WithAmountInCents(201).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -46,7 +47,7 @@ func TestProcessBoleto_WhenServiceRespondsCertificateFailed_ShouldHasFailedBolet
 	input := newStubBoletoRequestBradescoNetEmpresa().WithAmountInCents(202).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -65,7 +66,7 @@ func TestTemplateResponse_WhenRequestHasSpecialCharacter_ShouldBeParsedSuccessfu
 	input := newStubBoletoRequestBradescoNetEmpresa().WithAmountInCents(204).WithBuyerName("Usuario 	Teste").Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -1,6 +1,7 @@
 package bradescoShopFacil
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,7 +14,9 @@ import (
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
@@ -66,7 +69,7 @@ func (b bankBradescoShopFacil) Log() *log.Log {
 	return b.log
 }
 
-func (b bankBradescoShopFacil) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBradescoShopFacil) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType(boleto)
 	r := flow.NewFlow()
 	serviceURL := config.Get().URLBradescoShopFacil
@@ -74,10 +77,20 @@ func (b bankBradescoShopFacil) RegisterBoleto(boleto *models.BoletoRequest) (mod
 	to := getAPIResponseBradescoShopFacil()
 	bod := r.From("message://?source=inline", boleto, getRequestBradescoShopFacil(), tmpl.GetFuncMaps())
 	bod.To("log://?type=request&url="+serviceURL, b.log)
-	duration := util.Duration(func() {
-		bod.To(serviceURL, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutDefault})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, bod)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, bod, func() {
+		duration = util.Duration(func() {
+			bod.To(serviceURL, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutDefault})
+		})
 	})
-	metrics.PushTimingMetric("bradesco-shopfacil-register-boleto-online", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, bod)
+	metrics.PushBankTime("bradesco-shopfacil-register-boleto-online", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	bod.To("log://?type=response&url="+serviceURL, b.log)
 	ch := bod.Choice()
 	ch.When(flow.Header("status").IsEqualTo("201"))
@@ -100,12 +113,12 @@ func (b bankBradescoShopFacil) RegisterBoleto(boleto *models.BoletoRequest) (mod
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
 
-func (b bankBradescoShopFacil) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankBradescoShopFacil) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankBradescoShopFacil) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
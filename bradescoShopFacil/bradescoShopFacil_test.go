@@ -1,6 +1,7 @@
 package bradescoShopFacil
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -26,7 +27,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	input := newStubBoletoRequestBradescoShopFacil().Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -36,7 +37,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 	input := newStubBoletoRequestBradescoShopFacil().WithAmountInCents(400).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -1,9 +1,11 @@
 package caixa
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mundipagg/boleto-api/metrics"
 
@@ -12,7 +14,9 @@ import (
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
@@ -48,7 +52,7 @@ func New() bankCaixa {
 func (b bankCaixa) Log() *log.Log {
 	return b.log
 }
-func (b bankCaixa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankCaixa) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType(boleto)
 
@@ -59,10 +63,20 @@ func (b bankCaixa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRe
 
 	bod := r.From("message://?source=inline", boleto, getRequestCaixa(), tmpl.GetFuncMaps())
 	bod = bod.To("log://?type=request&url="+urlCaixa, b.log)
-	duration := util.Duration(func() {
-		bod = bod.To(urlCaixa, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutDefault})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, bod)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, bod, func() {
+		duration = util.Duration(func() {
+			bod = bod.To(urlCaixa, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutDefault})
+		})
 	})
-	metrics.PushTimingMetric("caixa-register-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, bod)
+	metrics.PushBankTime("caixa-register-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	bod = bod.To("log://?type=response&url="+urlCaixa, b.log)
 	ch := bod.Choice()
 	ch = ch.When(flow.Header("status").IsEqualTo("200"))
@@ -79,7 +93,7 @@ func (b bankCaixa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRe
 	}
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
-func (b bankCaixa) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankCaixa) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
@@ -90,7 +104,7 @@ func (b bankCaixa) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoRes
 	checkSum := b.getCheckSumCode(*boleto)
 
 	boleto.Authentication.AuthorizationToken = b.getAuthToken(checkSum)
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankCaixa) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
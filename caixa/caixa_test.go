@@ -1,6 +1,7 @@
 package caixa
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -51,7 +52,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	input := newStubBoletoRequestCaixa().Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -62,7 +63,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 	input := newStubBoletoRequestCaixa().WithAmountInCents(400).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -75,7 +76,7 @@ func TestProcessBoleto_WhenRequestContainsInvalidOurNumberParameter_ShouldHasFai
 
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -362,7 +363,7 @@ func TestTemplateRequestCaixa_WhenRequestWithPayeeGuarantorDocumentInNotValidCPF
 
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -375,7 +376,7 @@ func TestTemplateRequestCaixa_WhenRequestWithPayeeGuarantorDocumentInNotValidCNP
 
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -3,6 +3,7 @@ package certificate
 import (
 	"bytes"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 
@@ -39,6 +40,21 @@ func GetICPCertificate(name string, pfxBytes []byte, pass string) (ICPCertificat
 	return *iCPCertificate, nil
 }
 
+//GetICPCertificateFromPEM Monta o certificado ICP a partir da chave privada e do certificado em PEM. Chaves PEM cifradas não são suportadas
+func GetICPCertificateFromPEM(name string, pemData []byte, pass string) (ICPCertificate, error) {
+	pair, err := tls.X509KeyPair(pemData, pemData)
+	if err != nil {
+		return ICPCertificate{}, err
+	}
+
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return ICPCertificate{}, err
+	}
+
+	return ICPCertificate{Name: name, RsaPrivateKey: pair.PrivateKey, Certificate: certificate}, nil
+}
+
 type SSLCertificate struct {
 	Name    string
 	PemData []byte
@@ -57,6 +73,18 @@ func GetSSLCertificate(name string, pfxBytes []byte, pass string) (SSLCertificat
 	return *sslCertificate, nil
 }
 
+//GetSSLCertificateFromPEM Monta o certificado SSL a partir da chave privada e da cadeia em PEM, validando que a chave corresponde ao certificado
+func GetSSLCertificateFromPEM(name string, pemData []byte, pass string) (SSLCertificate, error) {
+	if _, err := tls.X509KeyPair(pemData, pemData); err != nil {
+		return SSLCertificate{}, err
+	}
+	return SSLCertificate{Name: name, PemData: pemData}, nil
+}
+
+func isPEM(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN "))
+}
+
 func localToPEM(pfxBytes []byte, pass string) ([]byte, error) {
 	privateKey, certificate, caChain, err := pkcs122.DecodeChain(pfxBytes, pass)
 
@@ -3,30 +3,107 @@ package certificate
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 )
 
 const fileServerEnv = "fileServer"
-const formatCertificate = ".pfx"
+
+// passwordExtension é a extensão do arquivo com a senha do certificado PKCS#12 de mesmo nome
+const passwordExtension = ".password"
+
+// directoryProvider lê os certificados de um diretório, como os secrets montados como arquivos no Kubernetes
+type directoryProvider struct {
+	path string
+}
+
+func newDirectoryProvider() Provider {
+	return directoryProvider{path: config.Get().CertificatesPath}
+}
 
 func InstanceStoreCertificatesFromFileServer(certificatesName ...string) (err error) {
+	return newDirectoryProvider().Load(certificatesName...)
+}
+
+//Load Carrega os certificados do diretório PATH_CERTIFICATES
+//Para cada nome é lido o arquivo com o próprio nome ou com as extensões .pfx, .p12, .pem ou o par .crt e .key
+//A senha de um PKCS#12 vem do arquivo nome.password e, na falta dele, de PSW_CERTIFICATE_ICP_NAME ou PSW_CERTIFICATE_SSL_NAME
+func (p directoryProvider) Load(names ...string) error {
 	l := log.CreateLog()
+	l.Operation = "InstanceStoreCertificatesFromDirectory"
 
-	for _, certificateName := range certificatesName {
-		pfxCertificate, err := ioutil.ReadFile(config.Get().CertificatesPath + certificateName + formatCertificate)
+	for _, name := range names {
+		data, err := p.read(name)
 		if err != nil {
 			return err
 		}
 
-		err = loadCertificatesOnStorage(fileServerEnv, certificateName, pfxCertificate)
+		pass, err := p.password(name)
 		if err != nil {
 			return err
 		}
 
-		l.Info(fmt.Sprintf("Success in load certificate [%s] from azureVault", certificateName))
+		if err := storeCertificate(name, data, pass); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		l.InfoWithBasic(fmt.Sprintf("Success in load certificate [%s] from directory", name), "LoadFromDirectory", nil)
 	}
 
 	return nil
 }
+
+func (p directoryProvider) read(name string) ([]byte, error) {
+	base := filepath.Join(p.path, name)
+
+	for _, ext := range []string{"", ".pfx", ".p12", ".pem"} {
+		data, err := readFile(base + ext)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			return data, nil
+		}
+	}
+
+	crt, err := readFile(base + ".crt")
+	if err != nil {
+		return nil, err
+	}
+	key, err := readFile(base + ".key")
+	if err != nil {
+		return nil, err
+	}
+	if crt != nil && key != nil {
+		return append(append(key, '\n'), crt...), nil
+	}
+
+	return nil, fmt.Errorf("certificate [%s] not found in %s", name, p.path)
+}
+
+func (p directoryProvider) password(name string) (string, error) {
+	pass, err := readFile(filepath.Join(p.path, name+passwordExtension))
+	if err != nil {
+		return "", err
+	}
+	if pass != nil {
+		return strings.TrimRight(string(pass), "\r\n"), nil
+	}
+	return getPassWordToCertificate(fileServerEnv, certificateType(name)), nil
+}
+
+// readFile retorna nil quando o arquivo não existe ou é um diretório
+func readFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,231 @@
+package certificate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/log"
+)
+
+// Engines do HashiCorp Vault suportadas em HASHICORP_VAULT_ENGINE
+const (
+	kvEngine  = "kv"
+	pkiEngine = "pki"
+)
+
+// hashicorpVaultProvider busca os certificados no HashiCorp Vault
+// No engine KV (versão 2) cada certificado é um secret com os campos pfx e password, certificate e private_key ou value
+// No engine PKI os certificados ICP e SSL são roles, usados para emitir um certificado quando o atual não existe ou está
+// perto de expirar. Os demais nomes, como bundles de CAs e chaves dos bancos, não são emitidos e continuam sendo lidos do KV
+type hashicorpVaultProvider struct {
+	address    string
+	token      string
+	tokenFile  string
+	engine     string
+	mount      string
+	kvMount    string
+	path       string
+	commonName string
+	client     *http.Client
+}
+
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+func newHashicorpVaultProvider() Provider {
+	c := config.Get()
+	p := hashicorpVaultProvider{
+		address:    strings.TrimRight(c.HashicorpVaultAddress, "/"),
+		token:      c.HashicorpVaultToken,
+		tokenFile:  c.HashicorpVaultTokenFile,
+		engine:     c.HashicorpVaultEngine,
+		mount:      strings.Trim(c.HashicorpVaultMount, "/"),
+		kvMount:    strings.Trim(c.HashicorpVaultKVMount, "/"),
+		path:       strings.Trim(c.HashicorpVaultPath, "/"),
+		commonName: c.HashicorpVaultPKICommonName,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if p.engine == "" {
+		p.engine = kvEngine
+	}
+	if p.mount == "" {
+		p.mount = map[string]string{kvEngine: "secret", pkiEngine: "pki"}[p.engine]
+	}
+	// HASHICORP_VAULT_KV_MOUNT só é usado com o engine PKI, para ler os nomes que não são emitidos
+	switch {
+	case p.engine == kvEngine:
+		p.kvMount = p.mount
+	case p.kvMount == "":
+		p.kvMount = "secret"
+	}
+	return p
+}
+
+//Load Carrega os certificados do HashiCorp Vault configurado em HASHICORP_VAULT_ADDRESS
+func (p hashicorpVaultProvider) Load(names ...string) error {
+	l := log.CreateLog()
+	l.Operation = "InstanceStoreCertificatesFromHashicorpVault"
+
+	for _, name := range names {
+		var data []byte
+		var pass string
+		var err error
+
+		switch p.engine {
+		case kvEngine:
+			data, pass, err = p.readSecret(name)
+		case pkiEngine:
+			if certificateType(name) == "" {
+				data, pass, err = p.readSecret(name)
+				break
+			}
+			if !p.shouldIssue(name) {
+				continue
+			}
+			data, err = p.issue(name)
+		default:
+			err = fmt.Errorf("unknown engine %q", p.engine)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		if err := storeCertificate(name, data, pass); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		l.InfoWithBasic(fmt.Sprintf("Success in load certificate [%s] from hashicorpVault", name), "LoadFromHashicorpVault", nil)
+	}
+
+	return nil
+}
+
+func (p hashicorpVaultProvider) readSecret(name string) ([]byte, string, error) {
+	secret := strings.TrimPrefix(p.path+"/"+name, "/")
+	resp, err := p.request(http.MethodGet, fmt.Sprintf("%s/data/%s", p.kvMount, secret), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fields, _ := resp.Data["data"].(map[string]interface{})
+	field := func(key string) string {
+		value, _ := fields[key].(string)
+		return value
+	}
+
+	switch {
+	case field("pfx") != "":
+		pfx, err := base64.StdEncoding.DecodeString(field("pfx"))
+		if err != nil {
+			return nil, "", fmt.Errorf("pfx is not base64: %v", err)
+		}
+		return pfx, field("password"), nil
+	case field("certificate") != "":
+		return joinPEM(field("private_key"), field("certificate"), field("ca_chain")), "", nil
+	case field("value") != "":
+		return []byte(field("value")), "", nil
+	}
+	return nil, "", errors.New("secret has no pfx, certificate or value field")
+}
+
+// shouldIssue evita emitir um certificado a cada recarga: só emite quando não há certificado ou ele atingiu o menor limite de alerta
+func (p hashicorpVaultProvider) shouldIssue(name string) bool {
+	days, ok := DaysToExpiry(name, time.Now())
+	if !ok {
+		return true
+	}
+	thresholds := WarningDays()
+	return days <= float64(thresholds[len(thresholds)-1])
+}
+
+func (p hashicorpVaultProvider) issue(role string) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{"common_name": p.commonName})
+	resp, err := p.request(http.MethodPost, fmt.Sprintf("%s/issue/%s", p.mount, role), body)
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(key string) string {
+		value, _ := resp.Data[key].(string)
+		return value
+	}
+
+	chain := []string{}
+	if values, ok := resp.Data["ca_chain"].([]interface{}); ok {
+		for _, value := range values {
+			if cert, ok := value.(string); ok {
+				chain = append(chain, cert)
+			}
+		}
+	}
+
+	if field("certificate") == "" || field("private_key") == "" {
+		return nil, errors.New("issued certificate has no certificate or private key")
+	}
+	return joinPEM(field("private_key"), field("certificate"), strings.Join(chain, "\n")), nil
+}
+
+func (p hashicorpVaultProvider) request(method, path string, body []byte) (vaultResponse, error) {
+	var response vaultResponse
+
+	token, err := p.readToken()
+	if err != nil {
+		return response, err
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", p.address, path), bytes.NewReader(body))
+	if err != nil {
+		return response, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	json.Unmarshal(data, &response)
+
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(response.Errors, "; "))
+	}
+	return response, nil
+}
+
+// readToken lê o token a cada requisição, então a renovação do arquivo montado pelo agente do Vault é respeitada
+func (p hashicorpVaultProvider) readToken() (string, error) {
+	if p.tokenFile == "" {
+		return p.token, nil
+	}
+	token, err := ioutil.ReadFile(p.tokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func joinPEM(blocks ...string) []byte {
+	var joined []string
+	for _, block := range blocks {
+		if block = strings.TrimSpace(block); block != "" {
+			joined = append(joined, block)
+		}
+	}
+	return []byte(strings.Join(joined, "\n") + "\n")
+}
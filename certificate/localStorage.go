@@ -2,15 +2,37 @@ package certificate
 
 import (
 	"errors"
+	"reflect"
 	"sync"
 
 	"github.com/mundipagg/boleto-api/config"
 )
 
-var localCertificateStorage = sync.Map{}
+var (
+	localCertificateStorage = sync.Map{}
+	versionsMu              sync.Mutex
+	versions                = map[string]uint64{}
+	// declaredTypes guarda o tipo dos certificados que não estão nas variáveis de ambiente, como os próprios de um recebedor
+	declaredTypes = sync.Map{}
+)
 
+//SetCertificateOnStore Grava o certificado no store, incrementando a versão dele quando o conteúdo muda
 func SetCertificateOnStore(key string, value interface{}) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	if current, ok := localCertificateStorage.Load(key); ok && reflect.DeepEqual(current, value) {
+		return
+	}
 	localCertificateStorage.Store(key, value)
+	versions[key]++
+}
+
+//Version Retorna a versão do certificado no store, usada para recriar o que depende dele quando ele é renovado
+func Version(key string) uint64 {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	return versions[key]
 }
 
 func GetCertificateFromStore(key string) (interface{}, error) {
@@ -20,6 +42,17 @@ func GetCertificateFromStore(key string) (interface{}, error) {
 	return nil, errors.New("Certificate not found.")
 }
 
+//MissingFromStore Retorna os certificados informados que não estão carregados no store
+func MissingFromStore(keys ...string) []string {
+	missing := []string{}
+	for _, key := range keys {
+		if _, ok := localCertificateStorage.Load(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
 func getPassWordToCertificate(from string, certType string) string {
 	if from == azureVaultEnv {
 		return ""
@@ -31,24 +64,68 @@ func getPassWordToCertificate(from string, certType string) string {
 	return ""
 }
 
-func loadCertificatesOnStorage(from string, certificateName string, pfxBytes []byte) error {
+//DeclareICP Indica que os certificados são ICP, gravados no store prontos para assinar as requisições
+func DeclareICP(names ...string) {
+	for _, name := range names {
+		declaredTypes.Store(name, icp)
+	}
+}
+
+//DeclareTLS Indica como gravar o certificado de cliente TLS: quando o certificado e a chave têm o mesmo nome,
+//ele é um PKCS#12 ou PEM com os dois e é gravado como SSL; senão cada um é gravado com o conteúdo original
+func DeclareTLS(crt, key string) {
+	if crt != "" && crt == key {
+		declaredTypes.Store(crt, ssl)
+	}
+}
+
+// certificateType indica como o certificado é gravado no store: ICP, SSL ou, para os demais nomes, o conteúdo original
+func certificateType(certificateName string) string {
+	if t, ok := declaredTypes.Load(certificateName); ok {
+		return t.(string)
+	}
 	switch certificateName {
+	case "":
+		return ""
 	case config.Get().CertificateICPName:
-		var certificateICP, err = GetICPCertificate(certificateName, pfxBytes, getPassWordToCertificate(from, icp))
+		return icp
+	case config.Get().CertificateSSLName, config.Get().CitibankCertificateSSLName, config.Get().SantanderCertificateSSLName:
+		return ssl
+	}
+	return ""
+}
+
+func loadCertificatesOnStorage(from string, certificateName string, pfxBytes []byte) error {
+	return storeCertificate(certificateName, pfxBytes, getPassWordToCertificate(from, certificateType(certificateName)))
+}
+
+// storeCertificate grava o certificado no store. Os certificados ICP e SSL podem estar em PKCS#12 ou em PEM, com a chave e a cadeia
+func storeCertificate(certificateName string, data []byte, pass string) error {
+	switch certificateType(certificateName) {
+	case icp:
+		getICP := GetICPCertificate
+		if isPEM(data) {
+			getICP = GetICPCertificateFromPEM
+		}
+		var certificateICP, err = getICP(certificateName, data, pass)
 		if err != nil {
 			return err
 		}
 		SetCertificateOnStore(certificateName, certificateICP)
 
-	case config.Get().CertificateSSLName, config.Get().CitibankCertificateSSLName, config.Get().SantanderCertificateSSLName:
-		var certificateSSL, err = GetSSLCertificate(certificateName, pfxBytes, getPassWordToCertificate(from, ssl))
+	case ssl:
+		getSSL := GetSSLCertificate
+		if isPEM(data) {
+			getSSL = GetSSLCertificateFromPEM
+		}
+		var certificateSSL, err = getSSL(certificateName, data, pass)
 		if err != nil {
 			return err
 		}
 		SetCertificateOnStore(certificateName, certificateSSL)
 
 	default:
-		SetCertificateOnStore(certificateName, pfxBytes)
+		SetCertificateOnStore(certificateName, data)
 	}
 
 	return nil
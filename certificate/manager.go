@@ -0,0 +1,338 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/metrics"
+)
+
+var (
+	// defaultWarningDays são os limites de alerta de expiração usados quando CERTIFICATE_EXPIRY_WARNING_DAYS não está definido
+	defaultWarningDays = []int{30, 7}
+	// expiryCheckInterval é o intervalo da verificação de expiração, feita mesmo quando a recarga está desabilitada
+	expiryCheckInterval = time.Hour
+)
+
+//Source Conjunto de certificados e a função que os carrega de uma origem, como o Azure ou um diretório
+type Source struct {
+	Name  string
+	Names []string
+	Load  func(names ...string) error
+}
+
+var (
+	monitoredMu sync.Mutex
+	monitored   []string
+	// ensured são os certificados carregados sob demanda por Ensure, como os próprios de cada recebedor
+	ensured  = map[string]bool{}
+	ensureMu sync.Mutex
+	// warned guarda o menor limite de alerta já logado de cada certificado, na versão em que foi logado
+	warned = map[string]warning{}
+)
+
+type warning struct {
+	version uint64
+	days    int
+}
+
+//Load Carrega no store os certificados de todas as origens e verifica a validade deles
+//Os certificados carregados passam a ser monitorados por CheckExpiry e pelo health check
+func Load(sources ...Source) error {
+	names := []string{}
+	for _, source := range sources {
+		sourceNames := []string{}
+		for _, name := range source.Names {
+			if name != "" {
+				sourceNames = append(sourceNames, name)
+			}
+		}
+		if len(sourceNames) == 0 {
+			continue
+		}
+		if err := source.Load(sourceNames...); err != nil {
+			return fmt.Errorf("%s: %v", source.Name, err)
+		}
+		names = append(names, sourceNames...)
+	}
+
+	monitoredMu.Lock()
+	monitored = names
+	monitoredMu.Unlock()
+
+	CheckExpiry(time.Now())
+	return nil
+}
+
+//Ensure Carrega da origem configurada em CERTIFICATE_SOURCE os certificados que ainda não estão no store
+//Os certificados carregados passam a ser monitorados e recarregados com os demais
+func Ensure(names ...string) error {
+	ensureMu.Lock()
+	defer ensureMu.Unlock()
+
+	missing := []string{}
+	for _, name := range MissingFromStore(names...) {
+		if name != "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	source := ConfiguredSource(missing...)
+	if err := source.Load(missing...); err != nil {
+		return fmt.Errorf("%s: %v", source.Name, err)
+	}
+
+	monitoredMu.Lock()
+	for _, name := range missing {
+		ensured[name] = true
+	}
+	monitoredMu.Unlock()
+
+	CheckExpiry(time.Now())
+	return nil
+}
+
+//EnsureTLS Carrega sob demanda o certificado de cliente TLS e a chave dele
+func EnsureTLS(crt, key string) error {
+	DeclareTLS(crt, key)
+	if crt == key {
+		return Ensure(crt)
+	}
+	return Ensure(crt, key)
+}
+
+//EnsureICP Carrega sob demanda o certificado ICP usado para assinar as requisições
+func EnsureICP(name string) error {
+	DeclareICP(name)
+	return Ensure(name)
+}
+
+//Refresh Recarrega periodicamente os certificados das origens retornadas por sources, avaliadas a cada recarga
+//Uma falha mantém os certificados atuais; os transports que dependem de um certificado são recriados quando a versão dele muda
+//Com o intervalo zerado os certificados não são recarregados, mas a expiração continua verificada a cada expiryCheckInterval
+func Refresh(ctx context.Context, interval time.Duration, sources func() []Source) {
+	var reloads <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		reloads = ticker.C
+	}
+
+	expiry := time.NewTicker(expiryCheckInterval)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reloads:
+			reload(sources())
+		case now := <-expiry.C:
+			CheckExpiry(now)
+		}
+	}
+}
+
+func reload(sources []Source) {
+	l := log.CreateLog()
+	l.Operation = "RefreshCertificates"
+
+	before := make(map[string]uint64)
+	for _, name := range Monitored() {
+		before[name] = Version(name)
+	}
+
+	if err := Load(sources...); err != nil {
+		l.ErrorWithBasic("Error refreshing certificates, keeping the current ones", "Error", err)
+		return
+	}
+
+	// os certificados carregados sob demanda são recarregados um a um, para que a falha de um não impeça a renovação dos demais
+	for _, name := range ensuredNames() {
+		source := ConfiguredSource(name)
+		if err := source.Load(name); err != nil {
+			l.ErrorWithBasic(fmt.Sprintf("Error refreshing certificate [%s], keeping the current one", name), "Error", err)
+		}
+	}
+	CheckExpiry(time.Now())
+
+	rotated := []string{}
+	for _, name := range Monitored() {
+		if Version(name) != before[name] {
+			rotated = append(rotated, name)
+		}
+	}
+	if len(rotated) > 0 {
+		l.InfoWithBasic("Certificates rotated", "Information", map[string]interface{}{"Certificates": rotated})
+	}
+}
+
+//Monitored Retorna os nomes dos certificados carregados por Load e por Ensure
+func Monitored() []string {
+	names := ensuredNames()
+
+	monitoredMu.Lock()
+	defer monitoredMu.Unlock()
+
+	seen := make(map[string]bool, len(monitored))
+	for _, name := range monitored {
+		seen[name] = true
+	}
+	all := append([]string{}, monitored...)
+	for _, name := range names {
+		if !seen[name] {
+			all = append(all, name)
+		}
+	}
+	return all
+}
+
+func ensuredNames() []string {
+	monitoredMu.Lock()
+	defer monitoredMu.Unlock()
+
+	names := make([]string, 0, len(ensured))
+	for name := range ensured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Expiry Retorna a expiração mais próxima entre os certificados X.509 gravados no store com o nome
+//Chaves privadas e valores sem certificado retornam false
+func Expiry(name string) (time.Time, bool) {
+	value, err := GetCertificateFromStore(name)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var certs []*x509.Certificate
+	switch v := value.(type) {
+	case ICPCertificate:
+		if v.Certificate != nil {
+			certs = append(certs, v.Certificate)
+		}
+	case SSLCertificate:
+		certs = parsePEMCertificates(v.PemData)
+	case []byte:
+		certs = parsePEMCertificates(v)
+	}
+
+	var expiry time.Time
+	for _, cert := range certs {
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	return expiry, !expiry.IsZero()
+}
+
+func parsePEMCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+//DaysToExpiry Retorna quantos dias faltam para o certificado expirar, negativo quando já expirou
+func DaysToExpiry(name string, now time.Time) (float64, bool) {
+	expiry, ok := Expiry(name)
+	if !ok {
+		return 0, false
+	}
+	return expiry.Sub(now).Hours() / 24, true
+}
+
+//WarningDays Retorna os limites de alerta de expiração, em dias, do maior para o menor
+//CERTIFICATE_EXPIRY_WARNING_DAYS recebe os limites separados por vírgula, como 30,7
+func WarningDays() []int {
+	days := []int{}
+	for _, value := range strings.Split(config.Get().CertificateExpiryWarningDays, ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && d > 0 {
+			days = append(days, d)
+		}
+	}
+	if len(days) == 0 {
+		days = append(days, defaultWarningDays...)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(days)))
+	return days
+}
+
+//CheckExpiry Publica os dias para a expiração dos certificados monitorados e loga um alerta a cada limite atingido
+//O alerta de um limite é logado uma vez por versão do certificado; certificados expirados são logados como erro
+func CheckExpiry(now time.Time) {
+	thresholds := WarningDays()
+
+	for _, name := range Monitored() {
+		days, ok := DaysToExpiry(name, now)
+		if !ok {
+			continue
+		}
+		metrics.PushCertificateExpiry(name, math.Floor(days))
+
+		reached := 0
+		for _, threshold := range thresholds {
+			if days <= float64(threshold) {
+				reached = threshold
+			}
+		}
+		if reached == 0 {
+			continue
+		}
+		if days < 0 {
+			reached = -1
+		}
+
+		if !shouldWarn(name, reached) {
+			continue
+		}
+
+		l := log.CreateLog()
+		l.Operation = "CheckCertificateExpiry"
+		props := map[string]interface{}{"Certificate": name, "DaysToExpiry": math.Floor(days)}
+		if days < 0 {
+			l.Error(props, fmt.Sprintf("Certificate [%s] has expired", name))
+		} else {
+			l.Warn(props, fmt.Sprintf("Certificate [%s] expires in less than %d days", name, reached))
+		}
+	}
+}
+
+// shouldWarn indica se o limite ainda não foi logado na versão atual do certificado. Um certificado renovado volta a ser alertado
+func shouldWarn(name string, reached int) bool {
+	monitoredMu.Lock()
+	defer monitoredMu.Unlock()
+
+	version := Version(name)
+	last, ok := warned[name]
+	if ok && last.version == version && last.days <= reached {
+		return false
+	}
+	warned[name] = warning{version: version, days: reached}
+	return true
+}
@@ -0,0 +1,109 @@
+package certificate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeWarningDays(days string) {
+	os.Clearenv()
+	os.Setenv("CERTIFICATE_EXPIRY_WARNING_DAYS", days)
+	config.Install(true, false, true)
+}
+
+func TestSetCertificateOnStore_IncrementsVersionOnlyWhenValueChanges(t *testing.T) {
+	SetCertificateOnStore("versioned", []byte("a"))
+	first := Version("versioned")
+
+	SetCertificateOnStore("versioned", []byte("a"))
+	assert.Equal(t, first, Version("versioned"))
+
+	SetCertificateOnStore("versioned", []byte("b"))
+	assert.Equal(t, first+1, Version("versioned"))
+}
+
+func TestExpiry_ReturnsEarliestCertificate(t *testing.T) {
+	now := time.Now().Truncate(time.Second).UTC()
+	bundle := append(GenerateTestCertificate(now.AddDate(0, 0, 90)), GenerateTestCertificate(now.AddDate(0, 0, 10))...)
+	SetCertificateOnStore("bundle", SSLCertificate{PemData: bundle})
+	SetCertificateOnStore("private-key", GenerateTestPK())
+
+	expiry, ok := Expiry("bundle")
+	_, keyOk := Expiry("private-key")
+	_, missingOk := Expiry("missing")
+
+	assert.True(t, ok)
+	assert.Equal(t, now.AddDate(0, 0, 10), expiry)
+	assert.False(t, keyOk)
+	assert.False(t, missingOk)
+}
+
+func TestWarningDays(t *testing.T) {
+	arrangeWarningDays("7, 60,x,-1")
+	assert.Equal(t, []int{60, 7}, WarningDays())
+
+	arrangeWarningDays("")
+	assert.Equal(t, []int{30, 7}, WarningDays())
+}
+
+func TestShouldWarn_WarnsOncePerThresholdAndAgainAfterRotation(t *testing.T) {
+	SetCertificateOnStore("warned", []byte("v1"))
+
+	assert.True(t, shouldWarn("warned", 30))
+	assert.False(t, shouldWarn("warned", 30))
+	assert.True(t, shouldWarn("warned", 7))
+	assert.False(t, shouldWarn("warned", 30))
+
+	SetCertificateOnStore("warned", []byte("v2"))
+	assert.True(t, shouldWarn("warned", 30))
+}
+
+func TestLoad_MonitorsLoadedCertificatesAndReturnsSourceError(t *testing.T) {
+	arrangeWarningDays("")
+	store := func(names ...string) error {
+		for _, name := range names {
+			SetCertificateOnStore(name, GenerateTestCertificate(time.Now().AddDate(1, 0, 0)))
+		}
+		return nil
+	}
+
+	err := Load(Source{Name: "vault", Names: []string{"crt", "key"}, Load: store}, Source{Name: "blob", Load: store})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"crt", "key"}, Monitored())
+
+	err = Load(Source{Name: "blob", Names: []string{"ca"}, Load: func(names ...string) error { return errors.New("forbidden") }})
+	assert.EqualError(t, err, "blob: forbidden")
+	assert.Equal(t, []string{"crt", "key"}, Monitored())
+}
+
+func TestRefresh_WhenReloadIsDisabled_StillChecksExpiry(t *testing.T) {
+	arrangeWarningDays("")
+	expiring := func(names ...string) error {
+		SetCertificateOnStore(names[0], GenerateTestCertificate(time.Now().AddDate(0, 0, 20)))
+		return nil
+	}
+	assert.Nil(t, Load(Source{Name: "vault", Names: []string{"expiring"}, Load: expiring}))
+	monitoredMu.Lock()
+	delete(warned, "expiring")
+	monitoredMu.Unlock()
+
+	previous := expiryCheckInterval
+	expiryCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { expiryCheckInterval = previous })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Refresh(ctx, 0, func() []Source { return nil })
+
+	assert.Eventually(t, func() bool {
+		monitoredMu.Lock()
+		defer monitoredMu.Unlock()
+		return warned["expiring"].days == 30
+	}, time.Second, 10*time.Millisecond, "o certificado que expira em 20 dias deve ser alertado sem recarga")
+}
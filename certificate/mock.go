@@ -4,7 +4,10 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
+	"math/big"
+	"time"
 
 	"github.com/mundipagg/boleto-api/config"
 )
@@ -21,6 +24,24 @@ func GenerateTestPK() []byte {
 	return pem.EncodeToMemory(privateKeyBlock)
 }
 
+//GenerateTestCertificate Gera um certificado autoassinado em PEM, seguido da chave privada, que expira em notAfter
+func GenerateTestCertificate(notAfter time.Time) []byte {
+	privatekey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(notAfter.UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &privatekey.PublicKey, privatekey)
+
+	certBlock := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyBlock := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privatekey)})
+	return append(certBlock, keyBlock...)
+}
+
 func LoadMockCertificates() {
 	SetCertificateOnStore(config.Get().AzureStorageOpenBankSkName, GenerateTestPK())
 	SetCertificateOnStore(config.Get().AzureStorageJPMorganPkName, GenerateTestPK())
@@ -28,4 +49,6 @@ func LoadMockCertificates() {
 	SetCertificateOnStore(config.Get().AzureStorageJPMorganSignCrtName, GenerateTestPK())
 	SetCertificateOnStore(config.Get().CertificateSSLName, GenerateTestPK())
 	SetCertificateOnStore(config.Get().CertificateICPName, GenerateTestPK())
+	SetCertificateOnStore(config.Get().CitibankCertificateSSLName, GenerateTestPK())
+	SetCertificateOnStore(config.Get().SantanderCertificateSSLName, GenerateTestPK())
 }
@@ -0,0 +1,93 @@
+package certificate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/config"
+)
+
+// Origens de certificados disponíveis em CERTIFICATE_SOURCE
+const (
+	AzureSource          = "azure"
+	DirectorySource      = "directory"
+	HashicorpVaultSource = "hashicorpVault"
+)
+
+//Provider Origem de certificados, que busca os certificados pedidos e os grava no store
+type Provider interface {
+	Load(names ...string) error
+}
+
+//ProviderFunc Permite usar uma função como Provider
+type ProviderFunc func(names ...string) error
+
+//Load Carrega os certificados chamando a função
+func (f ProviderFunc) Load(names ...string) error {
+	return f(names...)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]func() Provider{
+		AzureSource:          newAzureProvider,
+		DirectorySource:      newDirectoryProvider,
+		HashicorpVaultSource: newHashicorpVaultProvider,
+	}
+)
+
+//RegisterProvider Registra uma origem de certificados, que pode ser escolhida em CERTIFICATE_SOURCE
+//A fábrica é chamada a cada carga, então a origem usa a configuração vigente
+func RegisterProvider(name string, factory func() Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+//ConfiguredSource Retorna os certificados informados na origem configurada em CERTIFICATE_SOURCE, que por padrão é o Azure
+func ConfiguredSource(names ...string) Source {
+	name := config.Get().CertificateSource
+	if name == "" {
+		name = AzureSource
+	}
+
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+
+	source := Source{Name: name, Names: names}
+	if !ok {
+		source.Load = func(...string) error {
+			return fmt.Errorf("unknown certificate source %q", name)
+		}
+		return source
+	}
+	source.Load = factory().Load
+	return source
+}
+
+// newAzureProvider busca no Azure Key Vault os certificados ICP e SSL, em PKCS#12, e os demais no Azure Blob Storage
+func newAzureProvider() Provider {
+	return ProviderFunc(func(names ...string) error {
+		var vault, blob []string
+		for _, name := range names {
+			if certificateType(name) == "" {
+				blob = append(blob, name)
+			} else {
+				vault = append(vault, name)
+			}
+		}
+
+		if len(vault) > 0 {
+			if err := InstanceStoreCertificatesFromAzureVault(config.Get().VaultName, vault...); err != nil {
+				return fmt.Errorf("azureVault: %v", err)
+			}
+		}
+		if len(blob) > 0 {
+			if err := InstanceStoreCertificatesFromAzureBlob(blob...); err != nil {
+				return fmt.Errorf("azureBlob: %v", err)
+			}
+		}
+		return nil
+	})
+}
@@ -1,10 +1,11 @@
 package citibank
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
 
 	"github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/certificate"
@@ -13,14 +14,15 @@ import (
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
 
 var (
-	onceTransport = &sync.Once{}
-	transportTLS  *http.Transport
+	transports = &util.TLSTransports{}
 )
 
 type bankCiti struct {
@@ -29,22 +31,17 @@ type bankCiti struct {
 	transport *http.Transport
 }
 
-func New() (bankCiti, error) {
+func New(credentials models.BankCredentials) (bankCiti, error) {
 	var err error
 	b := bankCiti{
 		validate: models.NewValidator(),
 		log:      log.CreateLog(),
 	}
 
-	certificates := certificate.TLSCertificate{
-		Crt: config.Get().CitibankCertificateSSLName,
-		Key: config.Get().CitibankCertificateSSLName,
-	}
+	crt, key := credentials.ClientCertificate(config.Get().CitibankCertificateSSLName, config.Get().CitibankCertificateSSLName)
+	certificates := certificate.TLSCertificate{Crt: crt, Key: key}
 
-	onceTransport.Do(func() {
-		transportTLS, err = util.BuildTLSTransport(certificates)
-	})
-	b.transport = transportTLS
+	b.transport, err = transports.Get(b.GetBankNameIntegration(), certificates)
 
 	if err != nil || (b.transport == nil && !config.Get().MockMode) {
 		return bankCiti{}, fmt.Errorf("fail on load TLSTransport: %v", err)
@@ -66,7 +63,7 @@ func (b bankCiti) Log() *log.Log {
 	return b.log
 }
 
-func (b bankCiti) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankCiti) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType()
 
@@ -80,13 +77,23 @@ func (b bankCiti) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRes
 	var responseCiti string
 	var status int
 	var err error
-	duration := util.Duration(func() {
-		responseCiti, status, err = b.sendRequest(bod.GetBody().(string))
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	var duration time.Duration
+	callErr := resilience.Call(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, func() resilience.Attempt {
+		duration = util.Duration(func() {
+			responseCiti, status, err = b.sendRequest(ctx, bod.GetBody().(string))
+		})
+		return resilience.Attempt{Status: status, Err: err}
 	})
+	if resilience.Rejected(callErr) {
+		tracing.End(span, callErr)
+		return models.BoletoResponse{}, callErr
+	}
+	tracing.EndHTTP(span, status, err)
 	if err != nil {
 		return models.BoletoResponse{}, err
 	}
-	metrics.PushTimingMetric("citibank-register-boleto-online", duration.Seconds())
+	metrics.PushBankTime("citibank-register-boleto-online", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	bod.To("set://?prop=header", map[string]string{"status": strconv.Itoa(status)})
 	bod.To("set://?prop=body", responseCiti)
 	bod.To("log://?type=response&url="+serviceURL, b.log)
@@ -110,24 +117,24 @@ func (b bankCiti) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRes
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
 
-func (b bankCiti) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankCiti) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankCiti) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
 	return models.Errors(b.validate.Assert(boleto))
 }
 
-func (b bankCiti) sendRequest(body string) (string, int, error) {
+func (b bankCiti) sendRequest(ctx context.Context, body string) (string, int, error) {
 	serviceURL := config.Get().URLCiti
 	if config.Get().MockMode {
-		return util.Post(serviceURL, body, config.Get().TimeoutDefault, map[string]string{"Soapaction": "RegisterBoleto"})
+		return util.Post(ctx, b.GetBankNameIntegration(), serviceURL, body, config.Get().TimeoutDefault, map[string]string{"Soapaction": "RegisterBoleto"})
 	} else {
-		return util.PostTLS(serviceURL, body, config.Get().TimeoutDefault, map[string]string{"Soapaction": "RegisterBoleto"}, b.transport)
+		return util.PostTLS(ctx, b.GetBankNameIntegration(), serviceURL, body, config.Get().TimeoutDefault, map[string]string{"Soapaction": "RegisterBoleto"}, b.transport)
 	}
 }
 
@@ -1,6 +1,7 @@
 package citibank
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -57,9 +58,9 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	mock.StartMockService("9021")
 	input := new(models.BoletoRequest)
 	errConvert := util.FromJSON(baseMockJSON, input)
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 	
 	assert.Nil(t, errConvert)
 	test.AssertProcessBoletoWithSuccess(t, output)
@@ -71,9 +72,9 @@ func TestProcessBoleto_WhenServiceRespondsWithDataWhitespaces_ShouldHasBadGatewa
 	errConvert := util.FromJSON(baseMockJSON, input)
 
 	input.Title.AmountInCents = 100
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	_, err := bank.ProcessBoleto(input)
+	_, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, errConvert)
 	test.AssertError(t, err, models.BadGatewayError{})
@@ -85,9 +86,9 @@ func TestProcessBoleto_WhenServiceRespondsWithDataEmpty_ShouldHasBadGatewayError
 	errConvert := util.FromJSON(baseMockJSON, input)
 
 	input.Title.AmountInCents = 101
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	_, err := bank.ProcessBoleto(input)
+	_, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, errConvert)
 	test.AssertError(t, err, models.BadGatewayError{})
@@ -99,9 +100,9 @@ func TestProcessBoleto_WhenServiceRespondsWithDataNil_ShouldHasInternalServerErr
 	errConvert := util.FromJSON(baseMockJSON, input)
 
 	input.Title.AmountInCents = 102
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	_, err := bank.ProcessBoleto(input)
+	_, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, errConvert)
 	test.AssertError(t, err, models.InternalServerError{})
@@ -2,9 +2,7 @@ package config
 
 import (
 	"os"
-	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 )
 
@@ -55,14 +53,82 @@ type Config struct {
 	MongoTokenCollection             string
 	MongoAuthSource                  string
 	MongoTimeoutConnection           int
+	BoletoRepository                 string
+	DailyRegistrationQuota           int
+	RateLimitPerMinute               int
+	RateLimitBurst                   int
+	RateLimitBankPerMinute           int
+	RateLimitBankBurst               int
+	RateLimitBanks                   string
+	CredentialsRefreshInSeconds      int
+	AdminUsername                    string
+	AdminPasswordHash                string
+	PostgresURL                      string
 	TokenSafeDurationInMinutes       int
+	TokenStore                       string
+	TLSTrust                         string
+	CertificateRefreshInSeconds      int
+	CertificateExpiryWarningDays     string
+	CertificateSource                string
+	HashicorpVaultAddress            string
+	HashicorpVaultToken              string
+	HashicorpVaultTokenFile          string
+	HashicorpVaultEngine             string
+	HashicorpVaultMount              string
+	HashicorpVaultKVMount            string
+	HashicorpVaultPath               string
+	HashicorpVaultPKICommonName      string
+	AuthStrategies                   string
+	AuthJWKSURL                      string
+	AuthJWTAudience                  string
+	AuthJWTIssuer                    string
+	AuthJWTUserClaim                 string
+	AuthScopePermissions             string
+	LinkSigningKeys                  string
+	LinkTTLInHours                   int
+	LinkKeyRotatedAt                 string
+	LinkRotationWindowInHours        int
+	LinkUnsignedUntil                string
+	EncryptionKeySource              string
+	EncryptionKeyring                string
+	EncryptionAzureVaultName         string
+	EncryptionAzureKeyName           string
+	EncryptionBlindIndexKey          string
+	RetentionDays                    int
+	RetentionAction                  string
+	RetentionIntervalInMinutes       int
+	MongoArchiveCollection           string
+	MongoErasureCollection           string
+	MongoEventCollection             string
+	ShutdownTimeoutInSeconds         int
+	ShutdownReadinessDelayInSeconds  int
+	ConfigReloadIntervalInSeconds    int
 	RedisURL                         string
 	RedisPassword                    string
 	RedisDatabase                    string
 	RedisExpirationTime              string
 	RedisSSL                         bool
+	RedisMode                        string
+	RedisAddresses                   string
+	RedisSentinelMasterName          string
+	RedisSentinelPassword            string
+	RedisMaxIdle                     int
+	RedisMaxActive                   int
+	RedisIdleTimeoutInSeconds        int
 	BoletoJSONFileStore              string
 	DisableLog                       bool
+	LogStdoutEnabled                 bool
+	LogRedactedFields                string
+	LogQueueSize                     int
+	LogQueueWorkers                  int
+	LogQueuePolicy                   string
+	HealthCheckCritical              string
+	HealthCheckTimeoutInSeconds      int
+	HealthCheckBanks                 string
+	BreakerFailureThreshold          int
+	BreakerOpenTimeoutInSeconds      int
+	BulkheadMaxConcurrent            int
+	RetryPolicies                    string
 	CertBoletoPathCrt                string
 	CertBoletoPathKey                string
 	CertBoletoPathCa                 string
@@ -84,6 +150,10 @@ type Config struct {
 	URLPefisaToken                   string
 	URLPefisaRegister                string
 	EnableMetrics                    bool
+	EnablePrometheus                 bool
+	TracingExporter                  string
+	TracingOTLPEndpoint              string
+	TracingOTLPInsecure              bool
 	CertificatesPath                 string
 	AzureTenantId                    string
 	AzureClientId                    string
@@ -112,7 +182,6 @@ type Config struct {
 	RetryNumberGetBoleto             int
 	QueueMaxTLS                      string
 	QueueMinTLS                      string
-	QueueByPassCertificate           bool
 	ForceTLS                         bool
 	NewRelicAppName                  string
 	NewRelicLicence                  string
@@ -124,133 +193,225 @@ type Config struct {
 	URLJPMorgan                      string
 }
 
-var cnf Config
-var scnf sync.Once
 var running uint64
-var mutex sync.Mutex
 
 //Get retorna o objeto de configurações da aplicação
 func Get() Config {
-	return cnf
+	if c, ok := current.Load().(Config); ok {
+		return c
+	}
+	return Config{}
 }
 
+//Install Carrega a configuração das variáveis de ambiente e do arquivo CONFIG_FILE
+//Um arquivo inválido impede a inicialização da aplicação
 func Install(mockMode, devMode, disableLog bool) {
-	atomic.StoreUint64(&running, 0)
+	atomic.StoreUint64(&running, 1)
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	mode = installMode{mockMode: mockMode, devMode: devMode, disableLog: disableLog}
+	values, err := loadSources()
+	if err != nil {
+		panic(err)
+	}
+
+	c, _ := build(values)
+	apply(c, values)
+}
+
+// build cria a configuração a partir dos valores das fontes, indexados pelo nome da variável de ambiente
+// Retorna também os valores inválidos, que são lidos como zero
+func build(values map[string]string) (Config, error) {
+	v := &reader{values: values}
 	hostName := getHostName()
 
-	cnf = Config{
-		APIPort:                         ":" + os.Getenv("API_PORT"),
-		PdfAPIURL:                       os.Getenv("PDF_API"),
-		Version:                         os.Getenv("API_VERSION"),
+	c := Config{
+		APIPort:                         ":" + v.get("API_PORT"),
+		PdfAPIURL:                       v.get("PDF_API"),
+		Version:                         v.get("API_VERSION"),
 		MachineName:                     hostName,
-		SEQUrl:                          os.Getenv("SEQ_URL"),     //Pegar o SEQ de dev
-		SEQAPIKey:                       os.Getenv("SEQ_API_KEY"), //Staging Key:
-		SeqEnabled:                      os.Getenv("SEQ_ENABLED") == "true",
-		EnableRequestLog:                os.Getenv("ENABLE_REQUEST_LOG") == "true",   // Log a cada request no SEQ
-		EnablePrintRequest:              os.Getenv("ENABLE_PRINT_REQUEST") == "true", // Imprime algumas informacoes da request no console
-		Environment:                     os.Getenv("ENVIRONMENT"),
+		SEQUrl:                          v.get("SEQ_URL"),     //Pegar o SEQ de dev
+		SEQAPIKey:                       v.get("SEQ_API_KEY"), //Staging Key:
+		SeqEnabled:                      v.get("SEQ_ENABLED") == "true",
+		EnableRequestLog:                v.get("ENABLE_REQUEST_LOG") == "true",   // Log a cada request no SEQ
+		EnablePrintRequest:              v.get("ENABLE_PRINT_REQUEST") == "true", // Imprime algumas informacoes da request no console
+		Environment:                     v.get("ENVIRONMENT"),
 		SEQDomain:                       "One",
 		ApplicationName:                 "BoletoOnline",
-		URLBBRegisterBoleto:             os.Getenv("URL_BB_REGISTER_BOLETO"),
-		CaixaEnv:                        os.Getenv("CAIXA_ENV"),
-		URLCaixaRegisterBoleto:          os.Getenv("URL_CAIXA"),
-		URLBBToken:                      os.Getenv("URL_BB_TOKEN"),
-		URLCitiBoleto:                   os.Getenv("URL_CITI_BOLETO"),
-		URLCiti:                         os.Getenv("URL_CITI"),
-		URLStoneToken:                   os.Getenv("URL_STONE_TOKEN"),
-		URLJPMorgan:                     os.Getenv("URL_JPMORGAN"),
-		StoneTokenDurationInMinutes:     getValueInt(os.Getenv("STONE_TOKEN_DURATION_IN_MINUTES")),
-		StoneAudience:                   os.Getenv("STONE_AUDIENCE"),
-		StoneClientID:                   os.Getenv("STONE_CLIENT_ID"),
-		AzureStorageAccount:             os.Getenv("AZURE_STORAGE_ACCOUNT"),
-		AzureStorageAccessKey:           os.Getenv("AZURE_STORAGE_ACCESS_KEY"),
-		AzureStorageContainerName:       os.Getenv("AZURE_STORAGE_CONTAINER_NAME"),
-		AzureStorageOpenBankSkPath:      os.Getenv("AZURE_STORAGE_OPEN_BANK_SK_PATH"),
-		AzureStorageOpenBankSkName:      os.Getenv("AZURE_STORAGE_OPEN_BANK_SK_NAME"),
-		AzureStorageJPMorganPkName:      os.Getenv("AZURE_STORAGE_JP_MORGAN_PK_NAME"),
-		AzureStorageJPMorganCrtName:     os.Getenv("AZURE_STORAGE_JP_MORGAN_CRT_NAME"),
-		AzureStorageJPMorganSignCrtName: os.Getenv("AZURE_STORAGE_JP_MORGAN_SIGN_NAME"),
-		AzureStorageUploadPath:          os.Getenv("AZURE_STORAGE_UPLOAD_PATH"),
-		AzureStorageFallbackFolder:      os.Getenv("AZURE_STORAGE_FALLBACK_FOLDER"),
+		URLBBRegisterBoleto:             v.get("URL_BB_REGISTER_BOLETO"),
+		CaixaEnv:                        v.get("CAIXA_ENV"),
+		URLCaixaRegisterBoleto:          v.get("URL_CAIXA"),
+		URLBBToken:                      v.get("URL_BB_TOKEN"),
+		URLCitiBoleto:                   v.get("URL_CITI_BOLETO"),
+		URLCiti:                         v.get("URL_CITI"),
+		URLStoneToken:                   v.get("URL_STONE_TOKEN"),
+		URLJPMorgan:                     v.get("URL_JPMORGAN"),
+		StoneTokenDurationInMinutes:     v.int("STONE_TOKEN_DURATION_IN_MINUTES"),
+		StoneAudience:                   v.get("STONE_AUDIENCE"),
+		StoneClientID:                   v.get("STONE_CLIENT_ID"),
+		AzureStorageAccount:             v.get("AZURE_STORAGE_ACCOUNT"),
+		AzureStorageAccessKey:           v.get("AZURE_STORAGE_ACCESS_KEY"),
+		AzureStorageContainerName:       v.get("AZURE_STORAGE_CONTAINER_NAME"),
+		AzureStorageOpenBankSkPath:      v.get("AZURE_STORAGE_OPEN_BANK_SK_PATH"),
+		AzureStorageOpenBankSkName:      v.get("AZURE_STORAGE_OPEN_BANK_SK_NAME"),
+		AzureStorageJPMorganPkName:      v.get("AZURE_STORAGE_JP_MORGAN_PK_NAME"),
+		AzureStorageJPMorganCrtName:     v.get("AZURE_STORAGE_JP_MORGAN_CRT_NAME"),
+		AzureStorageJPMorganSignCrtName: v.get("AZURE_STORAGE_JP_MORGAN_SIGN_NAME"),
+		AzureStorageUploadPath:          v.get("AZURE_STORAGE_UPLOAD_PATH"),
+		AzureStorageFallbackFolder:      v.get("AZURE_STORAGE_FALLBACK_FOLDER"),
 
-		MockMode:                         mockMode,
-		AppURL:                           os.Getenv("APP_URL"),
-		ElasticURL:                       os.Getenv("ELASTIC_URL"),
-		DevMode:                          devMode,
-		DisableLog:                       disableLog,
-		MongoURL:                         os.Getenv("MONGODB_URL"),
-		MongoUser:                        os.Getenv("MONGODB_USER"),
-		MongoPassword:                    os.Getenv("MONGODB_PASSWORD"),
-		MongoDatabase:                    os.Getenv("MONGODB_DATABASE"),
-		MongoBoletoCollection:            os.Getenv("MONGODB_BOLETO_COLLECTION"),
-		MongoTokenCollection:             os.Getenv("MONGODB_TOKEN_COLLECTION"),
-		MongoCredentialsCollection:       os.Getenv("MONGODB_CREDENTIALS_COLLECTION"),
-		MongoAuthSource:                  os.Getenv("MONGODB_AUTH_SOURCE"),
-		MongoTimeoutConnection:           getValueInt(os.Getenv("MONGODB_TIMEOUT_CONNECTION")),
-		TokenSafeDurationInMinutes:       getValueInt(os.Getenv("TOKEN_SAFE_DURATION_IN_MINUTES")),
-		RetryNumberGetBoleto:             getValueInt(os.Getenv("RETRY_NUMBER_GET_BOLETO")),
-		RedisURL:                         os.Getenv("REDIS_URL"),
-		RedisPassword:                    os.Getenv("REDIS_PASSWORD"),
-		RedisDatabase:                    os.Getenv("REDIS_DATABASE"),
-		RedisExpirationTime:              os.Getenv("REDIS_EXPIRATION_TIME_IN_SECONDS"),
-		RedisSSL:                         os.Getenv("REDIS_SSL") == "true",
-		CertBoletoPathCrt:                os.Getenv("CERT_BOLETO_CRT"),
-		CertBoletoPathKey:                os.Getenv("CERT_BOLETO_KEY"),
-		CertBoletoPathCa:                 os.Getenv("CERT_BOLETO_CA"),
-		CertICP_PathPkey:                 os.Getenv("CERT_ICP_BOLETO_KEY"),
-		CertICP_PathChainCertificates:    os.Getenv("CERT_ICP_BOLETO_CHAIN_CA"),
-		URLTicketSantander:               os.Getenv("URL_SANTANDER_TICKET"),
-		URLRegisterBoletoSantander:       os.Getenv("URL_SANTANDER_REGISTER"),
-		ItauEnv:                          os.Getenv("ITAU_ENV"),
-		SantanderEnv:                     os.Getenv("SANTANDER_ENV"),
-		URLTicketItau:                    os.Getenv("URL_ITAU_TICKET"),
-		URLRegisterBoletoItau:            os.Getenv("URL_ITAU_REGISTER"),
-		URLBradescoShopFacil:             os.Getenv("URL_BRADESCO_SHOPFACIL"),
-		URLBradescoNetEmpresa:            os.Getenv("URL_BRADESCO_NET_EMPRESA"),
-		InfluxDBHost:                     os.Getenv("INFLUXDB_HOST"),
-		InfluxDBPort:                     os.Getenv("INFLUXDB_PORT"),
-		RecoveryRobotExecutionEnabled:    os.Getenv("RECOVERYROBOT_EXECUTION_ENABLED"),
-		RecoveryRobotExecutionInMinutes:  os.Getenv("RECOVERYROBOT_EXECUTION_IN_MINUTES"),
-		TimeoutRegister:                  os.Getenv("TIMEOUT_REGISTER"),
-		TimeoutToken:                     os.Getenv("TIMEOUT_TOKEN"),
-		TimeoutDefault:                   os.Getenv("TIMEOUT_DEFAULT"),
-		URLPefisaToken:                   os.Getenv("URL_PEFISA_TOKEN"),
-		URLPefisaRegister:                os.Getenv("URL_PEFISA_REGISTER"),
-		EnableMetrics:                    os.Getenv("ENABLE_METRICS") == "true",
-		CertificatesPath:                 os.Getenv("PATH_CERTIFICATES"),
-		AzureTenantId:                    os.Getenv("AZURE_TENANT_ID"),
-		AzureClientId:                    os.Getenv("AZURE_CLIENT_ID"),
-		AzureClientSecret:                os.Getenv("AZURE_CLIENT_SECRET"),
-		VaultName:                        os.Getenv("VAULT_NAME"),
-		CertificateICPName:               os.Getenv("CERTIFICATE_ICP_NAME"),
-		PswCertificateICP:                os.Getenv("PSW_CERTIFICATE_ICP_NAME"),
-		CertificateSSLName:               os.Getenv("CERTIFICATE_SSL_NAME"),
-		PswCertificateSSL:                os.Getenv("PSW_CERTIFICATE_SSL_NAME"),
-		CitibankCertificateSSLName:       os.Getenv("CITIBANK_CERTIFICATE_SSL_NAME"),
-		SantanderCertificateSSLName:      os.Getenv("SANTANDER_CERTIFICATE_SSL_NAME"),
-		EnableFileServerCertificate:      os.Getenv("ENABLE_FILESERVER_CERTIFICATE") == "true",
-		SplunkSourceType:                 os.Getenv("SPLUNK_SOURCE_TYPE"),
-		SplunkIndex:                      os.Getenv("SPLUNK_SOURCE_INDEX"),
-		SplunkEnabled:                    os.Getenv("SPLUNK_ENABLED") == "true",
-		SplunkAddress:                    os.Getenv("SPLUNK_ADDRESS"),
-		SplunkKey:                        os.Getenv("SPLUNK_KEY"),
-		WaitSecondsRetentationLog:        os.Getenv("WAIT_SECONDS_RETENTATION_LOG"),
-		ConnQueue:                        os.Getenv("CONN_QUEUE"),
-		OriginExchange:                   os.Getenv("ORIGIN_EXCHANGE"),
-		OriginQueue:                      os.Getenv("ORIGIN_QUEUE"),
-		OriginRoutingKey:                 os.Getenv("ORIGIN_ROUTING_KEY"),
-		TimeToRecoveryWithQueueInSeconds: os.Getenv("TIME_TO_RECOVERY_WITH_QUEUE_IN_SECONDS"),
-		Heartbeat:                        os.Getenv("HEARTBEAT"),
-		QueueMaxTLS:                      os.Getenv("QUEUE_MAX_TLS"),
-		QueueMinTLS:                      os.Getenv("QUEUE_MIN_TLS"),
-		QueueByPassCertificate:           os.Getenv("QUEUE_BYPASS_CERTIFICATE") == "true",
-		ForceTLS:                         strings.ToLower(os.Getenv("FORCE_TLS")) == "true",
-		NewRelicAppName:                  os.Getenv("NEW_RELIC_APP_NAME"),
-		NewRelicLicence:                  os.Getenv("NEW_RELIC_LICENCE"),
-		TelemetryEnabled:                 os.Getenv("TELEMETRY_ENABLED") == "true",
-		URLStoneRegister:                 os.Getenv("URL_STONE_REGISTER"),
-		BuildVersion:                     os.Getenv("BUILD_VERSION"),
+		MockMode:                         mode.mockMode,
+		AppURL:                           v.get("APP_URL"),
+		ElasticURL:                       v.get("ELASTIC_URL"),
+		DevMode:                          mode.devMode,
+		DisableLog:                       mode.disableLog,
+		LogStdoutEnabled:                 v.get("LOG_STDOUT_ENABLED") == "true",
+		LogRedactedFields:                v.get("LOG_REDACTED_FIELDS"),
+		LogQueueSize:                     v.int("LOG_QUEUE_SIZE"),
+		LogQueueWorkers:                  v.int("LOG_QUEUE_WORKERS"),
+		LogQueuePolicy:                   v.get("LOG_QUEUE_POLICY"),
+		HealthCheckCritical:              v.get("HEALTHCHECK_CRITICAL"),
+		HealthCheckTimeoutInSeconds:      v.int("HEALTHCHECK_TIMEOUT_IN_SECONDS"),
+		HealthCheckBanks:                 v.get("HEALTHCHECK_BANKS"),
+		BreakerFailureThreshold:          v.int("BREAKER_FAILURE_THRESHOLD"),
+		BreakerOpenTimeoutInSeconds:      v.int("BREAKER_OPEN_TIMEOUT_IN_SECONDS"),
+		BulkheadMaxConcurrent:            v.int("BULKHEAD_MAX_CONCURRENT"),
+		RetryPolicies:                    v.get("RETRY_POLICIES"),
+		MongoURL:                         v.get("MONGODB_URL"),
+		MongoUser:                        v.get("MONGODB_USER"),
+		MongoPassword:                    v.get("MONGODB_PASSWORD"),
+		MongoDatabase:                    v.get("MONGODB_DATABASE"),
+		MongoBoletoCollection:            v.get("MONGODB_BOLETO_COLLECTION"),
+		MongoTokenCollection:             v.get("MONGODB_TOKEN_COLLECTION"),
+		MongoCredentialsCollection:       v.get("MONGODB_CREDENTIALS_COLLECTION"),
+		MongoAuthSource:                  v.get("MONGODB_AUTH_SOURCE"),
+		MongoTimeoutConnection:           v.int("MONGODB_TIMEOUT_CONNECTION"),
+		BoletoRepository:                 v.get("BOLETO_REPOSITORY"),
+		PostgresURL:                      v.get("POSTGRES_URL"),
+		DailyRegistrationQuota:           v.int("DAILY_REGISTRATION_QUOTA"),
+		RateLimitPerMinute:               v.int("RATE_LIMIT_PER_MINUTE"),
+		RateLimitBurst:                   v.int("RATE_LIMIT_BURST"),
+		RateLimitBankPerMinute:           v.int("RATE_LIMIT_BANK_PER_MINUTE"),
+		RateLimitBankBurst:               v.int("RATE_LIMIT_BANK_BURST"),
+		RateLimitBanks:                   v.get("RATE_LIMIT_BANKS"),
+		CredentialsRefreshInSeconds:      v.int("CREDENTIALS_REFRESH_INTERVAL_IN_SECONDS"),
+		AdminUsername:                    v.get("ADMIN_USERNAME"),
+		AdminPasswordHash:                v.get("ADMIN_PASSWORD_HASH"),
+		TokenSafeDurationInMinutes:       v.int("TOKEN_SAFE_DURATION_IN_MINUTES"),
+		TokenStore:                       v.get("TOKEN_STORE"),
+		TLSTrust:                         v.get("TLS_TRUST"),
+		CertificateRefreshInSeconds:      v.int("CERTIFICATE_REFRESH_INTERVAL_IN_SECONDS"),
+		CertificateExpiryWarningDays:     v.get("CERTIFICATE_EXPIRY_WARNING_DAYS"),
+		CertificateSource:                v.get("CERTIFICATE_SOURCE"),
+		HashicorpVaultAddress:            v.get("HASHICORP_VAULT_ADDRESS"),
+		HashicorpVaultToken:              v.get("HASHICORP_VAULT_TOKEN"),
+		HashicorpVaultTokenFile:          v.get("HASHICORP_VAULT_TOKEN_FILE"),
+		HashicorpVaultEngine:             v.get("HASHICORP_VAULT_ENGINE"),
+		HashicorpVaultMount:              v.get("HASHICORP_VAULT_MOUNT"),
+		HashicorpVaultKVMount:            v.get("HASHICORP_VAULT_KV_MOUNT"),
+		HashicorpVaultPath:               v.get("HASHICORP_VAULT_PATH"),
+		HashicorpVaultPKICommonName:      v.get("HASHICORP_VAULT_PKI_COMMON_NAME"),
+		AuthStrategies:                   v.get("AUTH_STRATEGIES"),
+		AuthJWKSURL:                      v.get("AUTH_JWKS_URL"),
+		AuthJWTAudience:                  v.get("AUTH_JWT_AUDIENCE"),
+		AuthJWTIssuer:                    v.get("AUTH_JWT_ISSUER"),
+		AuthJWTUserClaim:                 v.get("AUTH_JWT_USER_CLAIM"),
+		AuthScopePermissions:             v.get("AUTH_SCOPE_PERMISSIONS"),
+		LinkSigningKeys:                  v.get("LINK_SIGNING_KEYS"),
+		LinkTTLInHours:                   v.int("LINK_TTL_IN_HOURS"),
+		LinkKeyRotatedAt:                 v.get("LINK_KEY_ROTATED_AT"),
+		LinkRotationWindowInHours:        v.int("LINK_ROTATION_WINDOW_IN_HOURS"),
+		LinkUnsignedUntil:                v.get("LINK_UNSIGNED_UNTIL"),
+		EncryptionKeySource:              v.get("ENCRYPTION_KEY_SOURCE"),
+		EncryptionKeyring:                v.get("ENCRYPTION_KEYRING"),
+		EncryptionAzureVaultName:         v.get("ENCRYPTION_AZURE_VAULT_NAME"),
+		EncryptionAzureKeyName:           v.get("ENCRYPTION_AZURE_KEY_NAME"),
+		EncryptionBlindIndexKey:          v.get("ENCRYPTION_BLIND_INDEX_KEY"),
+		RetentionDays:                    v.int("RETENTION_DAYS"),
+		RetentionAction:                  v.get("RETENTION_ACTION"),
+		RetentionIntervalInMinutes:       v.int("RETENTION_INTERVAL_IN_MINUTES"),
+		MongoArchiveCollection:           v.get("MONGODB_ARCHIVE_COLLECTION"),
+		MongoErasureCollection:           v.get("MONGODB_ERASURE_COLLECTION"),
+		MongoEventCollection:             v.get("MONGODB_EVENT_COLLECTION"),
+		ShutdownTimeoutInSeconds:         v.int("SHUTDOWN_TIMEOUT_IN_SECONDS"),
+		ShutdownReadinessDelayInSeconds:  v.int("SHUTDOWN_READINESS_DELAY_IN_SECONDS"),
+		ConfigReloadIntervalInSeconds:    v.int("CONFIG_RELOAD_INTERVAL_IN_SECONDS"),
+		RetryNumberGetBoleto:             v.int("RETRY_NUMBER_GET_BOLETO"),
+		RedisURL:                         v.get("REDIS_URL"),
+		RedisPassword:                    v.get("REDIS_PASSWORD"),
+		RedisDatabase:                    v.get("REDIS_DATABASE"),
+		RedisExpirationTime:              v.get("REDIS_EXPIRATION_TIME_IN_SECONDS"),
+		RedisSSL:                         v.get("REDIS_SSL") == "true",
+		RedisMode:                        v.get("REDIS_MODE"),
+		RedisAddresses:                   v.get("REDIS_ADDRESSES"),
+		RedisSentinelMasterName:          v.get("REDIS_SENTINEL_MASTER_NAME"),
+		RedisSentinelPassword:            v.get("REDIS_SENTINEL_PASSWORD"),
+		RedisMaxIdle:                     v.int("REDIS_MAX_IDLE"),
+		RedisMaxActive:                   v.int("REDIS_MAX_ACTIVE"),
+		RedisIdleTimeoutInSeconds:        v.int("REDIS_IDLE_TIMEOUT_IN_SECONDS"),
+		CertBoletoPathCrt:                v.get("CERT_BOLETO_CRT"),
+		CertBoletoPathKey:                v.get("CERT_BOLETO_KEY"),
+		CertBoletoPathCa:                 v.get("CERT_BOLETO_CA"),
+		CertICP_PathPkey:                 v.get("CERT_ICP_BOLETO_KEY"),
+		CertICP_PathChainCertificates:    v.get("CERT_ICP_BOLETO_CHAIN_CA"),
+		URLTicketSantander:               v.get("URL_SANTANDER_TICKET"),
+		URLRegisterBoletoSantander:       v.get("URL_SANTANDER_REGISTER"),
+		ItauEnv:                          v.get("ITAU_ENV"),
+		SantanderEnv:                     v.get("SANTANDER_ENV"),
+		URLTicketItau:                    v.get("URL_ITAU_TICKET"),
+		URLRegisterBoletoItau:            v.get("URL_ITAU_REGISTER"),
+		URLBradescoShopFacil:             v.get("URL_BRADESCO_SHOPFACIL"),
+		URLBradescoNetEmpresa:            v.get("URL_BRADESCO_NET_EMPRESA"),
+		InfluxDBHost:                     v.get("INFLUXDB_HOST"),
+		InfluxDBPort:                     v.get("INFLUXDB_PORT"),
+		RecoveryRobotExecutionEnabled:    v.get("RECOVERYROBOT_EXECUTION_ENABLED"),
+		RecoveryRobotExecutionInMinutes:  v.get("RECOVERYROBOT_EXECUTION_IN_MINUTES"),
+		TimeoutRegister:                  v.get("TIMEOUT_REGISTER"),
+		TimeoutToken:                     v.get("TIMEOUT_TOKEN"),
+		TimeoutDefault:                   v.get("TIMEOUT_DEFAULT"),
+		URLPefisaToken:                   v.get("URL_PEFISA_TOKEN"),
+		URLPefisaRegister:                v.get("URL_PEFISA_REGISTER"),
+		EnableMetrics:                    v.get("ENABLE_METRICS") == "true",
+		EnablePrometheus:                 v.get("ENABLE_PROMETHEUS") == "true",
+		TracingExporter:                  v.get("TRACING_EXPORTER"),
+		TracingOTLPEndpoint:              v.get("TRACING_OTLP_ENDPOINT"),
+		TracingOTLPInsecure:              v.get("TRACING_OTLP_INSECURE") == "true",
+		CertificatesPath:                 v.get("PATH_CERTIFICATES"),
+		AzureTenantId:                    v.get("AZURE_TENANT_ID"),
+		AzureClientId:                    v.get("AZURE_CLIENT_ID"),
+		AzureClientSecret:                v.get("AZURE_CLIENT_SECRET"),
+		VaultName:                        v.get("VAULT_NAME"),
+		CertificateICPName:               v.get("CERTIFICATE_ICP_NAME"),
+		PswCertificateICP:                v.get("PSW_CERTIFICATE_ICP_NAME"),
+		CertificateSSLName:               v.get("CERTIFICATE_SSL_NAME"),
+		PswCertificateSSL:                v.get("PSW_CERTIFICATE_SSL_NAME"),
+		CitibankCertificateSSLName:       v.get("CITIBANK_CERTIFICATE_SSL_NAME"),
+		SantanderCertificateSSLName:      v.get("SANTANDER_CERTIFICATE_SSL_NAME"),
+		EnableFileServerCertificate:      v.get("ENABLE_FILESERVER_CERTIFICATE") == "true",
+		SplunkSourceType:                 v.get("SPLUNK_SOURCE_TYPE"),
+		SplunkIndex:                      v.get("SPLUNK_SOURCE_INDEX"),
+		SplunkEnabled:                    v.get("SPLUNK_ENABLED") == "true",
+		SplunkAddress:                    v.get("SPLUNK_ADDRESS"),
+		SplunkKey:                        v.get("SPLUNK_KEY"),
+		WaitSecondsRetentationLog:        v.get("WAIT_SECONDS_RETENTATION_LOG"),
+		ConnQueue:                        v.get("CONN_QUEUE"),
+		OriginExchange:                   v.get("ORIGIN_EXCHANGE"),
+		OriginQueue:                      v.get("ORIGIN_QUEUE"),
+		OriginRoutingKey:                 v.get("ORIGIN_ROUTING_KEY"),
+		TimeToRecoveryWithQueueInSeconds: v.get("TIME_TO_RECOVERY_WITH_QUEUE_IN_SECONDS"),
+		Heartbeat:                        v.get("HEARTBEAT"),
+		QueueMaxTLS:                      v.get("QUEUE_MAX_TLS"),
+		QueueMinTLS:                      v.get("QUEUE_MIN_TLS"),
+		ForceTLS:                         strings.ToLower(v.get("FORCE_TLS")) == "true",
+		NewRelicAppName:                  v.get("NEW_RELIC_APP_NAME"),
+		NewRelicLicence:                  v.get("NEW_RELIC_LICENCE"),
+		TelemetryEnabled:                 v.get("TELEMETRY_ENABLED") == "true",
+		URLStoneRegister:                 v.get("URL_STONE_REGISTER"),
+		BuildVersion:                     v.get("BUILD_VERSION"),
 	}
+	return c, v.err()
 }
 
 //IsRunning verifica se a aplicação tem que aceitar requisições
@@ -260,12 +421,12 @@ func IsRunning() bool {
 
 //IsNotProduction returns true if application is running in DevMode or MockMode
 func IsNotProduction() bool {
-	return cnf.DevMode || cnf.MockMode
+	return Get().DevMode || Get().MockMode
 }
 
-//Stop faz a aplicação parar de receber requisições
+//Stop faz a aplicação parar de receber requisições. A prontidão passa a responder 503
 func Stop() {
-	atomic.StoreUint64(&running, 1)
+	atomic.StoreUint64(&running, 0)
 }
 
 func getHostName() string {
@@ -275,8 +436,3 @@ func getHostName() string {
 	}
 	return machineName
 }
-
-func getValueInt(v string) int {
-	t, _ := strconv.Atoi(v)
-	return t
-}
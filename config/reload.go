@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type installMode struct {
+	mockMode   bool
+	devMode    bool
+	disableLog bool
+}
+
+var (
+	current  atomic.Value
+	reloadMu sync.Mutex
+	mode     installMode
+	// loaded são os valores das fontes usados pela configuração atual, comparados na recarga
+	loaded map[string]string
+)
+
+// reader lê os valores das fontes guardando os que não puderam ser convertidos
+type reader struct {
+	values  map[string]string
+	invalid []string
+}
+
+func (r *reader) get(key string) string {
+	return r.values[key]
+}
+
+func (r *reader) int(key string) int {
+	value := strings.TrimSpace(r.values[key])
+	if value == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid = append(r.invalid, key)
+	}
+	return i
+}
+
+func (r *reader) err() error {
+	if len(r.invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid integer values: %s", strings.Join(r.invalid, ", "))
+}
+
+func apply(c Config, values map[string]string) {
+	current.Store(c)
+	loaded = values
+}
+
+//Reload Relê as fontes de configuração, valida a nova configuração e a aplica de uma vez
+//Retorna os nomes das chaves alteradas, sem os valores, que podem ser segredos. Em caso de erro a configuração atual é mantida
+//Conexões e clientes criados na inicialização, como os do Mongo e do Redis, mantêm a configuração anterior
+func Reload() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	values, err := loadSources()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := build(values)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(c, values); err != nil {
+		return nil, err
+	}
+
+	changed := changedKeys(loaded, values)
+	if len(changed) > 0 {
+		apply(c, values)
+	}
+	return changed, nil
+}
+
+//Validate Verifica se a configuração pode ser aplicada
+func Validate(c Config, values map[string]string) error {
+	var problems []string
+
+	if strings.TrimPrefix(c.APIPort, ":") == "" {
+		problems = append(problems, "API_PORT cannot be empty")
+	}
+
+	for key, value := range values {
+		if !strings.HasPrefix(key, "URL_") || value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, key+" is not an absolute URL")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func changedKeys(before, after map[string]string) []string {
+	changed := []string{}
+	for key, value := range after {
+		if old, ok := before[key]; !ok || old != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+//Watch Recarrega a configuração ao receber SIGHUP e, quando interval é maior que zero, ao detectar alteração no CONFIG_FILE
+//onReload recebe o resultado de cada recarga. A função retornada encerra a observação
+func Watch(interval time.Duration, onReload func(changed []string, err error)) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 && os.Getenv("CONFIG_FILE") != "" {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	done := make(chan struct{})
+	go func() {
+		version := fileVersion()
+		// pending é a versão alterada vista no último tick, recarregada só quando se mantém até o próximo,
+		// evitando ler o arquivo no meio de uma escrita
+		pending := version
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				version = fileVersion()
+				pending = version
+			case <-tick:
+				v := fileVersion()
+				if v == version {
+					continue
+				}
+				if v != pending {
+					pending = v
+					continue
+				}
+				version = v
+			}
+			onReload(Reload())
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(hup)
+			if ticker != nil {
+				ticker.Stop()
+			}
+			close(done)
+		})
+	}
+}
+
+// fileVersion identifica a versão do CONFIG_FILE pela data de modificação e pelo tamanho
+// O Stat segue links simbólicos, então a troca do arquivo de um ConfigMap também é detectada
+func fileVersion() string {
+	info, err := os.Stat(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
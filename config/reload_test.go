@@ -0,0 +1,164 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func arrangeEnv(t *testing.T, file string) {
+	os.Clearenv()
+	os.Setenv("API_PORT", "3000")
+	os.Setenv("URL_ITAU_TICKET", "https://env.itau.com/ticket")
+	os.Setenv("CONFIG_FILE", file)
+	Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+}
+
+func TestFileSource_LoadsYAMLAndJSONScalars(t *testing.T) {
+	yamlFile := arrangeConfigFile(t, "config.yaml", "API_PORT: 3000\nSEQ_ENABLED: true\nRETRY_POLICIES: \"token:attempts=3\"\nEMPTY:\n")
+	jsonFile := arrangeConfigFile(t, "config.json", `{"API_PORT": 3000, "SEQ_ENABLED": true, "RATE": 1.5}`)
+
+	fromYAML, err := FileSource{Path: yamlFile}.Load()
+	assert.Nil(t, err)
+	fromJSON, err := FileSource{Path: jsonFile}.Load()
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{"API_PORT": "3000", "SEQ_ENABLED": "true", "RETRY_POLICIES": "token:attempts=3", "EMPTY": ""}, fromYAML)
+	assert.Equal(t, map[string]string{"API_PORT": "3000", "SEQ_ENABLED": "true", "RATE": "1.5"}, fromJSON)
+}
+
+func TestFileSource_RejectsNestedValues(t *testing.T) {
+	path := arrangeConfigFile(t, "config.yaml", "BANKS:\n  itau: true\n")
+
+	_, err := FileSource{Path: path}.Load()
+
+	assert.EqualError(t, err, path+": BANKS must be a string, number or boolean")
+}
+
+func TestInstall_FileOverridesEnv(t *testing.T) {
+	path := arrangeConfigFile(t, "config.yaml", "URL_ITAU_TICKET: https://file.itau.com/ticket\n")
+
+	arrangeEnv(t, path)
+
+	assert.Equal(t, "https://file.itau.com/ticket", Get().URLTicketItau)
+	assert.Equal(t, ":3000", Get().APIPort)
+}
+
+func TestReload_AppliesConfigurationAndReturnsChangedKeys(t *testing.T) {
+	path := arrangeConfigFile(t, "config.yaml", "TOKEN_STORE: memory\n")
+	arrangeEnv(t, path)
+	ioutil.WriteFile(path, []byte("TOKEN_STORE: mongo\nRATE_LIMIT_PER_MINUTE: 60\n"), 0600)
+
+	changed, err := Reload()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"RATE_LIMIT_PER_MINUTE", "TOKEN_STORE"}, changed)
+	assert.Equal(t, "mongo", Get().TokenStore)
+	assert.Equal(t, 60, Get().RateLimitPerMinute)
+
+	changed, err = Reload()
+
+	assert.Nil(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestReload_WhenConfigurationIsInvalid_KeepsCurrentConfiguration(t *testing.T) {
+	cases := []struct {
+		content string
+		err     string
+	}{
+		{"RATE_LIMIT_PER_MINUTE: sixty\n", "invalid integer values: RATE_LIMIT_PER_MINUTE"},
+		{"URL_ITAU_TICKET: itau/ticket\nAPI_PORT: \"\"\n", "API_PORT cannot be empty; URL_ITAU_TICKET is not an absolute URL"},
+		{"API_PORT: [3000]\n", "API_PORT must be a string, number or boolean"},
+	}
+
+	for _, c := range cases {
+		path := arrangeConfigFile(t, "config.yaml", "RATE_LIMIT_PER_MINUTE: 10\n")
+		arrangeEnv(t, path)
+		ioutil.WriteFile(path, []byte(c.content), 0600)
+
+		changed, err := Reload()
+
+		assert.Nil(t, changed)
+		assert.Contains(t, err.Error(), c.err)
+		assert.Equal(t, 10, Get().RateLimitPerMinute)
+		assert.Equal(t, "https://env.itau.com/ticket", Get().URLTicketItau)
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	before := map[string]string{"A": "1", "B": "2", "C": "3"}
+	after := map[string]string{"A": "1", "B": "20", "D": "4"}
+
+	assert.Equal(t, []string{"B", "C", "D"}, changedKeys(before, after))
+}
+
+func TestWatch_ReloadsWhenFileChanges(t *testing.T) {
+	path := arrangeConfigFile(t, "config.json", `{"TOKEN_STORE": "memory"}`)
+	arrangeEnv(t, path)
+	reloaded := make(chan []string, 1)
+
+	stop := Watch(10*time.Millisecond, func(changed []string, err error) {
+		assert.Nil(t, err)
+		reloaded <- changed
+	})
+	defer stop()
+
+	time.Sleep(20 * time.Millisecond)
+	ioutil.WriteFile(path, []byte(`{"TOKEN_STORE": "mongo"}`), 0600)
+
+	select {
+	case changed := <-reloaded:
+		assert.Equal(t, []string{"TOKEN_STORE"}, changed)
+		assert.Equal(t, "mongo", Get().TokenStore)
+	case <-time.After(2 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+}
+
+func TestWatch_WaitsForFileWritesToSettle(t *testing.T) {
+	path := arrangeConfigFile(t, "config.json", `{"TOKEN_STORE": "memory"}`)
+	arrangeEnv(t, path)
+	reloaded := make(chan []string, 2)
+
+	stop := Watch(100*time.Millisecond, func(changed []string, err error) {
+		assert.Nil(t, err, "a configuração não deve ser lida no meio da escrita")
+		reloaded <- changed
+	})
+	defer stop()
+
+	// escrita lenta: cada parte dura menos que um tick, mas a escrita toda dura mais
+	time.Sleep(150 * time.Millisecond)
+	for _, partial := range []string{`{"TOKEN_STORE": `, `{"TOKEN_STORE": "mo`, `{"TOKEN_STORE": "mongo", "API_`} {
+		ioutil.WriteFile(path, []byte(partial), 0600)
+		time.Sleep(45 * time.Millisecond)
+	}
+	ioutil.WriteFile(path, []byte(`{"TOKEN_STORE": "mongo", "API_PORT": "3000"}`), 0600)
+
+	select {
+	case changed := <-reloaded:
+		assert.Equal(t, []string{"TOKEN_STORE"}, changed)
+		assert.Equal(t, "mongo", Get().TokenStore)
+	case <-time.After(2 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+
+	select {
+	case changed := <-reloaded:
+		t.Fatalf("configuration reloaded again with %v", changed)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//Source Origem dos valores de configuração, indexados pelo nome da variável de ambiente, como URL_ITAU_TICKET
+type Source interface {
+	Name() string
+	Load() (map[string]string, error)
+}
+
+type envSource struct{}
+
+func (envSource) Name() string {
+	return "env"
+}
+
+func (envSource) Load() (map[string]string, error) {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			values[kv[:i]] = kv[i+1:]
+		}
+	}
+	return values, nil
+}
+
+//FileSource Lê os valores de um arquivo JSON, quando a extensão é .json, ou YAML
+//O arquivo é um objeto plano em que cada chave é o nome de uma variável de ambiente
+type FileSource struct {
+	Path string
+}
+
+//Name Retorna o caminho do arquivo
+func (f FileSource) Name() string {
+	return f.Path
+}
+
+//Load Lê o arquivo. Valores que não são escalares são rejeitados
+func (f FileSource) Load() (map[string]string, error) {
+	content, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if strings.EqualFold(filepath.Ext(f.Path), ".json") {
+		err = json.Unmarshal(content, &raw)
+	} else {
+		err = yaml.Unmarshal(content, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch t := value.(type) {
+		case nil:
+			values[key] = ""
+		case string:
+			values[key] = t
+		case bool:
+			values[key] = strconv.FormatBool(t)
+		case int:
+			values[key] = strconv.Itoa(t)
+		case float64:
+			values[key] = strconv.FormatFloat(t, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("%s: %s must be a string, number or boolean", f.Path, key)
+		}
+	}
+	return values, nil
+}
+
+// sources retorna as variáveis de ambiente e, quando CONFIG_FILE está definido, o arquivo, que tem precedência
+func sources() []Source {
+	list := []Source{envSource{}}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		list = append(list, FileSource{Path: path})
+	}
+	return list
+}
+
+func loadSources() (map[string]string, error) {
+	values := make(map[string]string)
+	for _, source := range sources() {
+		loaded, err := source.Load()
+		if err != nil {
+			return nil, fmt.Errorf("could not load configuration from %s: %v", source.Name(), err)
+		}
+		for key, value := range loaded {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
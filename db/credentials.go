@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+var (
+	// ErrCredentialsNotFound is returned when no credentials match the user key
+	ErrCredentialsNotFound = errors.New("credentials not found")
+	// ErrUsernameTaken is returned when saving credentials with the username of existing ones.
+	// Boleto ownership, quotas, rate limits and JWT subjects are keyed by username, so it must be unique
+	ErrUsernameTaken = errors.New("username already exists")
+)
+
+// CredentialRepository abstracts the persistence of the service users credentials
+type CredentialRepository interface {
+	// GetCredentials lists every credential, including the disabled ones
+	GetCredentials(ctx context.Context) ([]models.Credentials, error)
+	// GetCredentialsByKey fetches the credentials of a user key
+	GetCredentialsByKey(ctx context.Context, key string) (models.Credentials, error)
+	// SaveCredentials stores new credentials. It returns ErrUsernameTaken when the username is in use
+	SaveCredentials(ctx context.Context, c models.Credentials) error
+	// UpdateCredentials replaces existing credentials
+	UpdateCredentials(ctx context.Context, c models.Credentials) error
+}
+
+type memoryCredentialRepository struct {
+	mu          sync.RWMutex
+	credentials map[string]models.Credentials
+}
+
+// NewMemoryCredentialRepository creates a CredentialRepository that keeps credentials in process memory
+func NewMemoryCredentialRepository() CredentialRepository {
+	return &memoryCredentialRepository{credentials: make(map[string]models.Credentials)}
+}
+
+func (m *memoryCredentialRepository) GetCredentials(ctx context.Context) ([]models.Credentials, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.Credentials, 0, len(m.credentials))
+	for _, c := range m.credentials {
+		if err := encryption.DecryptCredentials(ctx, &c); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (m *memoryCredentialRepository) GetCredentialsByKey(ctx context.Context, key string) (models.Credentials, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.credentials[key]
+	if !ok {
+		return models.Credentials{}, ErrCredentialsNotFound
+	}
+	return c, encryption.DecryptCredentials(ctx, &c)
+}
+
+func (m *memoryCredentialRepository) SaveCredentials(ctx context.Context, c models.Credentials) error {
+	if err := encryption.EncryptCredentials(ctx, &c); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := c.ID.Hex()
+	if _, exists := m.credentials[key]; exists {
+		return errors.New("duplicate credentials " + key)
+	}
+	for _, existing := range m.credentials {
+		if existing.Username == c.Username {
+			return ErrUsernameTaken
+		}
+	}
+	m.credentials[key] = c
+	return nil
+}
+
+func (m *memoryCredentialRepository) UpdateCredentials(ctx context.Context, c models.Credentials) error {
+	if err := encryption.EncryptCredentials(ctx, &c); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := c.ID.Hex()
+	if _, exists := m.credentials[key]; !exists {
+		return ErrCredentialsNotFound
+	}
+	m.credentials[key] = c
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/models"
+)
+
+// ErasureRepository keeps the audit records of the personal data erasures. Records are never updated or deleted
+type ErasureRepository interface {
+	// SaveErasure stores the record of a finished erasure
+	SaveErasure(ctx context.Context, record models.ErasureRecord) error
+	// FindErasures lists the erasures of a buyer document hash, the oldest first
+	FindErasures(ctx context.Context, documentHash string) ([]models.ErasureRecord, error)
+}
+
+type memoryErasureRepository struct {
+	mu      sync.RWMutex
+	records []models.ErasureRecord
+}
+
+// NewMemoryErasureRepository creates an ErasureRepository that keeps the records in process memory
+func NewMemoryErasureRepository() ErasureRepository {
+	return &memoryErasureRepository{}
+}
+
+func (m *memoryErasureRepository) SaveErasure(ctx context.Context, record models.ErasureRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *memoryErasureRepository) FindErasures(ctx context.Context, documentHash string) ([]models.ErasureRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.ErasureRecord, 0)
+	for _, r := range m.records {
+		if r.DocumentHash == documentHash {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
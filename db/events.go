@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+// EventRepository is the append-only store of the boleto audit events. Events are never updated or deleted
+type EventRepository interface {
+	// AppendEvent stores a new event
+	AppendEvent(ctx context.Context, event models.BoletoEvent) error
+	// FindEvents lists the events of a boleto, the oldest first
+	FindEvents(ctx context.Context, boletoID string) ([]models.BoletoEvent, error)
+	// FindEventsByRequestKey lists the events of a request of the service user, the oldest first.
+	// It is how failed registrations, which have no boleto, are found
+	FindEventsByRequestKey(ctx context.Context, serviceUser, requestKey string) ([]models.BoletoEvent, error)
+}
+
+// NewEventRepository creates the event store. Events are kept in Mongo, as the credentials are,
+// unless BOLETO_REPOSITORY is memory
+func NewEventRepository() (EventRepository, error) {
+	if strings.ToLower(config.Get().BoletoRepository) == MemoryRepository {
+		return NewMemoryEventRepository(), nil
+	}
+	return NewMongoEventRepository(), nil
+}
+
+type memoryEventRepository struct {
+	mu     sync.RWMutex
+	events []models.BoletoEvent
+}
+
+// NewMemoryEventRepository creates an EventRepository that keeps the events in process memory
+func NewMemoryEventRepository() EventRepository {
+	return &memoryEventRepository{}
+}
+
+func (m *memoryEventRepository) AppendEvent(ctx context.Context, event models.BoletoEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryEventRepository) FindEvents(ctx context.Context, boletoID string) ([]models.BoletoEvent, error) {
+	return m.find(func(e models.BoletoEvent) bool { return e.BoletoID == boletoID }), nil
+}
+
+func (m *memoryEventRepository) FindEventsByRequestKey(ctx context.Context, serviceUser, requestKey string) ([]models.BoletoEvent, error) {
+	return m.find(func(e models.BoletoEvent) bool { return e.ServiceUser == serviceUser && e.RequestKey == requestKey }), nil
+}
+
+func (m *memoryEventRepository) find(match func(models.BoletoEvent) bool) []models.BoletoEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.BoletoEvent, 0)
+	for _, e := range m.events {
+		if match(e) {
+			result = append(result, e)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
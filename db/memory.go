@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+type memoryRepository struct {
+	mu       sync.RWMutex
+	boletos  map[string]models.BoletoView
+	archived map[string]models.BoletoView
+}
+
+// NewMemoryRepository creates a BoletoRepository that keeps boletos in process memory
+func NewMemoryRepository() BoletoRepository {
+	return &memoryRepository{boletos: make(map[string]models.BoletoView), archived: make(map[string]models.BoletoView)}
+}
+
+func (m *memoryRepository) SaveBoleto(ctx context.Context, boleto models.BoletoView) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := boleto.ID.Hex()
+	if _, exists := m.boletos[id]; exists {
+		return errors.New("duplicate boleto id " + id)
+	}
+	if err := encryption.EncryptBoleto(ctx, &boleto); err != nil {
+		return err
+	}
+	m.boletos[id] = boleto
+	return nil
+}
+
+func (m *memoryRepository) GetBoletoByID(ctx context.Context, id, pk string) (models.BoletoView, int64, error) {
+	start := time.Now()
+
+	m.mu.RLock()
+	result, ok := m.boletos[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), ErrBoletoNotFound
+	}
+	if !hasValidKey(result, pk) {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), errors.New(InvalidPK)
+	}
+	if err := encryption.DecryptBoleto(ctx, &result); err != nil {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), err
+	}
+	return result, time.Since(start).Milliseconds(), nil
+}
+
+func (m *memoryRepository) FindBoletos(ctx context.Context, filter BoletoFilter) (BoletoPage, error) {
+	m.mu.RLock()
+	result := make([]models.BoletoView, 0)
+	for _, b := range m.boletos {
+		if matchesFilter(b, filter) {
+			result = append(result, b)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		vi, vj := filter.SortValue(result[i]), filter.SortValue(result[j])
+		if !vi.Equal(vj) {
+			return vi.Before(vj) == filter.Ascending
+		}
+		return (result[i].ID.Hex() < result[j].ID.Hex()) == filter.Ascending
+	})
+
+	if len(result) > filter.GetLimit()+1 {
+		result = result[:filter.GetLimit()+1]
+	}
+	for i := range result {
+		if err := encryption.DecryptBoleto(ctx, &result[i]); err != nil {
+			return BoletoPage{}, err
+		}
+	}
+	return newBoletoPage(result, filter), nil
+}
+
+func (m *memoryRepository) UpdateBoletoStatus(ctx context.Context, id, status string) error {
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.boletos[id]
+	if !ok {
+		return ErrBoletoNotFound
+	}
+	b.Status = status
+	if status == models.BoletoStatusPaid {
+		now := time.Now()
+		b.PaymentDate = &now
+	}
+	m.boletos[id] = b
+	return nil
+}
+
+func (m *memoryRepository) UpdateBoletoKeys(ctx context.Context, boleto models.BoletoView) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.boletos[boleto.ID.Hex()]
+	if !ok {
+		return ErrBoletoNotFound
+	}
+	b.SecretKey, b.PublicKey, b.Links = boleto.SecretKey, boleto.PublicKey, boleto.Links
+	m.boletos[boleto.ID.Hex()] = b
+	return nil
+}
+
+func (m *memoryRepository) EraseBuyerData(ctx context.Context, serviceUser, buyerDocument string, erasedAt time.Time) ([]models.BoletoView, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	filter := BoletoFilter{ServiceUser: serviceUser, BuyerDocument: buyerDocument}
+	erased := make([]models.BoletoView, 0)
+	for _, boletos := range []map[string]models.BoletoView{m.boletos, m.archived} {
+		for id, b := range boletos {
+			if !matchesFilter(b, filter) {
+				continue
+			}
+			b.AnonymizeBuyer(erasedAt)
+			boletos[id] = b
+			erased = append(erased, b)
+		}
+	}
+	return erased, nil
+}
+
+func (m *memoryRepository) ApplyRetention(ctx context.Context, rule RetentionRule) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, b := range m.boletos {
+		if !rule.matches(b) {
+			continue
+		}
+		if rule.Action == models.RetentionArchive {
+			m.archived[id] = b
+		}
+		delete(m.boletos, id)
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,179 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepository_SaveAndGetBoletoByID(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	view := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+
+	err := repository.SaveBoleto(context.Background(), view)
+	assert.Nil(t, err)
+
+	got, _, err := repository.GetBoletoByID(context.Background(), view.ID.Hex(), view.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, view.UID, got.UID)
+
+	_, _, err = repository.GetBoletoByID(context.Background(), view.ID.Hex(), "invalid")
+	assert.Equal(t, db.InvalidPK, err.Error())
+
+	_, _, err = repository.GetBoletoByID(context.Background(), "60be988b3193b131b8061835", view.PublicKey)
+	assert.Equal(t, db.ErrBoletoNotFound, err)
+}
+
+func TestMemoryRepository_FindBoletos(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	now := time.Now()
+	repository.SaveBoleto(context.Background(), newMemoryBoletoView(models.Caixa, "12123123000112", now.Add(-2*time.Hour)))
+	repository.SaveBoleto(context.Background(), newMemoryBoletoView(models.Caixa, "12123123000112", now.Add(-1*time.Hour)))
+	repository.SaveBoleto(context.Background(), newMemoryBoletoView(models.BancoDoBrasil, "00000000000191", now))
+
+	got, err := repository.FindBoletos(context.Background(), db.BoletoFilter{BankNumber: models.Caixa})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(got.Boletos))
+	assert.True(t, got.Boletos[0].CreateDate.After(got.Boletos[1].CreateDate), "results must be sorted by create date desc")
+	assert.Empty(t, got.NextCursor)
+
+	got, err = repository.FindBoletos(context.Background(), db.BoletoFilter{CreateDateFrom: now.Add(-90 * time.Minute)})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(got.Boletos))
+
+	got, err = repository.FindBoletos(context.Background(), db.BoletoFilter{RecipientDocument: "00000000000191", Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(got.Boletos))
+}
+
+func TestMemoryRepository_FindBoletosScopedByServiceUser(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	mine := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+	mine.ServiceUser = "user-a"
+	other := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+	other.ServiceUser = "user-b"
+	repository.SaveBoleto(context.Background(), mine)
+	repository.SaveBoleto(context.Background(), other)
+
+	got, err := repository.FindBoletos(context.Background(), db.BoletoFilter{ServiceUser: "user-a"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(got.Boletos))
+	assert.Equal(t, mine.ID, got.Boletos[0].ID)
+
+	got, err = repository.FindBoletos(context.Background(), db.BoletoFilter{ID: other.ID.Hex(), ServiceUser: "user-a"})
+
+	assert.Nil(t, err)
+	assert.Empty(t, got.Boletos)
+}
+
+func TestMemoryRepository_FindBoletosPagination(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		repository.SaveBoleto(context.Background(), newMemoryBoletoView(models.Caixa, "12123123000112", now.Add(time.Duration(i)*time.Minute)))
+	}
+
+	filter := db.BoletoFilter{Limit: 2, Ascending: true}
+	seen := make([]time.Time, 0)
+	for {
+		page, err := repository.FindBoletos(context.Background(), filter)
+		assert.Nil(t, err)
+		for _, b := range page.Boletos {
+			seen = append(seen, b.CreateDate)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor, err = db.DecodeCursor(page.NextCursor)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 5, len(seen))
+	for i := 1; i < len(seen); i++ {
+		assert.True(t, seen[i].After(seen[i-1]), "pages must follow the ascending order without repetition")
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, err := db.DecodeCursor("not a cursor")
+	assert.NotNil(t, err)
+}
+
+func TestMemoryRepository_UpdateBoletoStatus(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	view := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+	repository.SaveBoleto(context.Background(), view)
+
+	err := repository.UpdateBoletoStatus(context.Background(), view.ID.Hex(), models.BoletoStatusCancelled)
+	assert.Nil(t, err)
+
+	got, _, _ := repository.GetBoletoByID(context.Background(), view.ID.Hex(), view.PublicKey)
+	assert.Equal(t, models.BoletoStatusCancelled, got.Status)
+
+	err = repository.UpdateBoletoStatus(context.Background(), view.ID.Hex(), "Unknown")
+	assert.NotNil(t, err)
+
+	err = repository.UpdateBoletoStatus(context.Background(), "60be988b3193b131b8061835", models.BoletoStatusPaid)
+	assert.Equal(t, db.ErrBoletoNotFound, err)
+}
+
+func TestMemoryRepository_UpdateBoletoKeys(t *testing.T) {
+	repository := db.NewMemoryRepository()
+	view := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+	repository.SaveBoleto(context.Background(), view)
+	previous := view.PublicKey
+
+	view.RotateKeys()
+	err := repository.UpdateBoletoKeys(context.Background(), view)
+	assert.Nil(t, err)
+
+	_, _, err = repository.GetBoletoByID(context.Background(), view.ID.Hex(), previous)
+	assert.Equal(t, db.InvalidPK, err.Error())
+	got, _, err := repository.GetBoletoByID(context.Background(), view.ID.Hex(), view.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, view.Links, got.Links)
+}
+
+func TestMemoryRepository_WhenEncrypted_FindsByBuyerDocument(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("ENCRYPTION_KEY_SOURCE", "local")
+	os.Setenv("ENCRYPTION_KEYRING", "k1=a2V5LW9uZS1lbmNyeXB0aW9uLWtleS0zMi1ieXRlcyE=")
+	os.Setenv("ENCRYPTION_BLIND_INDEX_KEY", "index-key")
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+
+	repository := db.NewMemoryRepository()
+	view := newMemoryBoletoView(models.Caixa, "12123123000112", time.Now())
+	view.Boleto.Buyer.Document = models.Document{Type: "CPF", Number: "12345678909"}
+	view.Boleto.Buyer.Name = "Fulano de Tal"
+	repository.SaveBoleto(context.Background(), view)
+
+	page, err := repository.FindBoletos(context.Background(), db.BoletoFilter{BuyerDocument: "12345678909"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(page.Boletos))
+	assert.Equal(t, "Fulano de Tal", page.Boletos[0].Boleto.Buyer.Name)
+	assert.Nil(t, page.Boletos[0].Encryption)
+
+	page, _ = repository.FindBoletos(context.Background(), db.BoletoFilter{BuyerDocument: "98765432100"})
+	assert.Equal(t, 0, len(page.Boletos))
+
+	got, _, err := repository.GetBoletoByID(context.Background(), view.ID.Hex(), view.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, view.Boleto.Buyer, got.Boleto.Buyer)
+}
+
+func newMemoryBoletoView(bank models.BankNumber, recipientDocument string, createDate time.Time) models.BoletoView {
+	request := models.BoletoRequest{BankNumber: bank}
+	request.Recipient.Document = models.Document{Type: "CNPJ", Number: recipientDocument}
+
+	view := models.NewBoletoView(request, models.BoletoResponse{}, "", "")
+	view.CreateDate = createDate
+	return view
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/encryption"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/models"
 	"github.com/mundipagg/boleto-api/util"
@@ -33,6 +34,9 @@ const (
 	NotFoundDoc = "mongo: no documents in result"
 	InvalidPK   = "invalid pk"
 	emptyConn   = "Connection is empty"
+	// retentionBatchSize is how many boletos are archived or purged per round trip
+	retentionBatchSize = 500
+	duplicateKeyCode   = 11000
 )
 
 // CheckMongo checks if Mongo is up and running
@@ -79,6 +83,7 @@ func getClientOptions() *options.ClientOptions {
 	co.SetConnectTimeout(time.Duration(mongoTimeoutConnection) * time.Second)
 	co.SetMaxConnIdleTime(10 * time.Second)
 	co.SetMaxPoolSize(512)
+	co.SetMonitor(newMongoMonitor())
 
 	if config.Get().ForceTLS {
 		co.SetTLSConfig(&tls.Config{})
@@ -110,54 +115,86 @@ func mongoCredential() options.Credential {
 	return credential
 }
 
+type mongoRepository struct{}
+
+// NewMongoRepository creates a BoletoRepository backed by the MongoDB boleto collection
+func NewMongoRepository() BoletoRepository {
+	return &mongoRepository{}
+}
+
 //SaveBoleto salva um boleto no mongoDB
 func SaveBoleto(boleto models.BoletoView) error {
 	ctx, cancel := context.WithTimeout(context.Background(), ConnectionTimeout)
 	defer cancel()
 
-	l := log.CreateLog()
+	return NewMongoRepository().SaveBoleto(ctx, boleto)
+}
+
+//GetBoletoByID busca um boleto pelo ID que vem na URL
+//O retorno será um objeto BoletoView, o tempo decorrido da operação (em milisegundos) e algum erro ocorrido durante a operação
+func GetBoletoByID(id, pk string) (models.BoletoView, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ConnectionTimeout)
+	defer cancel()
+
+	return NewMongoRepository().GetBoletoByID(ctx, id, pk)
+}
+
+func boletoCollection() (*mongo.Collection, error) {
 	conn, err := CreateMongo()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Database(config.Get().MongoDatabase).Collection(config.Get().MongoBoletoCollection), nil
+}
+
+// archiveCollection keeps the boletos archived by the retention policy, MONGODB_ARCHIVE_COLLECTION or the boleto collection with the _archive suffix
+func archiveCollection() (*mongo.Collection, error) {
+	conn, err := CreateMongo()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.Get().MongoArchiveCollection
+	if name == "" {
+		name = config.Get().MongoBoletoCollection + "_archive"
+	}
+	return conn.Database(config.Get().MongoDatabase).Collection(name), nil
+}
+
+func (r *mongoRepository) SaveBoleto(ctx context.Context, boleto models.BoletoView) error {
+	l := log.CreateLog()
+	collection, err := boletoCollection()
 	if err != nil {
 		l.Error(err.Error(), fmt.Sprintf("mongodb.CreateMongo - Error creating mongo connection while saving boleto %v", boleto))
 		return err
 	}
 
-	collection := conn.Database(config.Get().MongoDatabase).Collection(config.Get().MongoBoletoCollection)
+	if err = encryption.EncryptBoleto(ctx, &boleto); err != nil {
+		return err
+	}
 	_, err = collection.InsertOne(ctx, boleto)
 
 	return err
 }
 
-//GetBoletoByID busca um boleto pelo ID que vem na URL
-//O retorno será um objeto BoletoView, o tempo decorrido da operação (em milisegundos) e algum erro ocorrido durante a operação
-func GetBoletoByID(id, pk string) (models.BoletoView, int64, error) {
+func (r *mongoRepository) GetBoletoByID(ctx context.Context, id, pk string) (models.BoletoView, int64, error) {
 	start := time.Now()
 
 	result := models.BoletoView{}
 
-	ctx, cancel := context.WithTimeout(context.Background(), ConnectionTimeout)
-	defer cancel()
-
 	l := log.CreateLog()
-	conn, err := CreateMongo()
+	collection, err := boletoCollection()
 	if err != nil {
 		l.Error(err.Error(), fmt.Sprintf("mongodb.GetBoletoByID - Error creating mongo connection for id %s and pk %s", id, pk))
 		return result, time.Since(start).Milliseconds(), err
 	}
-	collection := conn.Database(config.Get().MongoDatabase).Collection(config.Get().MongoBoletoCollection)
 
-	for i := 0; i <= config.Get().RetryNumberGetBoleto; i++ {
+	filter, err := idFilter(id)
+	if err != nil {
+		return result, time.Since(start).Milliseconds(), fmt.Errorf("Error: %s\n", err)
+	}
 
-		var filter primitive.M
-		if len(id) == 24 {
-			d, err := primitive.ObjectIDFromHex(id)
-			if err != nil {
-				return result, time.Since(start).Milliseconds(), fmt.Errorf("Error: %s\n", err)
-			}
-			filter = bson.M{"_id": d}
-		} else {
-			filter = bson.M{"id": id}
-		}
+	for i := 0; i <= config.Get().RetryNumberGetBoleto; i++ {
 		err = collection.FindOne(ctx, filter).Decode(&result)
 
 		if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
@@ -167,18 +204,626 @@ func GetBoletoByID(id, pk string) (models.BoletoView, int64, error) {
 		}
 	}
 
-	if err != nil {
+	if err == mongo.ErrNoDocuments {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), ErrBoletoNotFound
+	} else if err != nil {
 		return models.BoletoView{}, time.Since(start).Milliseconds(), err
 	} else if !hasValidKey(result, pk) {
 		return models.BoletoView{}, time.Since(start).Milliseconds(), errors.New(InvalidPK)
+	} else if err = encryption.DecryptBoleto(ctx, &result); err != nil {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), err
+	}
+
+	return toLocalTime(result), time.Since(start).Milliseconds(), nil
+}
+
+func (r *mongoRepository) FindBoletos(ctx context.Context, filter BoletoFilter) (BoletoPage, error) {
+	collection, err := boletoCollection()
+	if err != nil {
+		return BoletoPage{}, err
+	}
+
+	direction := -1
+	if filter.Ascending {
+		direction = 1
+	}
+	sortField := mongoSortField(filter)
+
+	opts := options.Find().
+		SetSort(bson.D{primitive.E{Key: sortField, Value: direction}, primitive.E{Key: "_id", Value: direction}}).
+		SetLimit(int64(filter.GetLimit() + 1))
+
+	query, err := mongoFilter(filter)
+	if err != nil {
+		return BoletoPage{}, err
+	}
+
+	cur, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return BoletoPage{}, err
+	}
+
+	result := []models.BoletoView{}
+	if err = cur.All(ctx, &result); err != nil {
+		return BoletoPage{}, err
+	}
+
+	for i := range result {
+		if err = encryption.DecryptBoleto(ctx, &result[i]); err != nil {
+			return BoletoPage{}, err
+		}
+		result[i] = toLocalTime(result[i])
+	}
+	return newBoletoPage(result, filter), nil
+}
+
+// EnsureIndexes creates the indexes used by the boleto search. Every index starts with
+// the service user, since searches are always scoped to the owner of the boletos
+func (r *mongoRepository) EnsureIndexes(ctx context.Context) error {
+	collection, err := boletoCollection()
+	if err != nil {
+		return err
+	}
+
+	keys := []bson.D{
+		{{Key: "serviceuser", Value: 1}, {Key: "createdate", Value: -1}, {Key: "_id", Value: -1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "boleto.title.expiredatetime", Value: -1}, {Key: "_id", Value: -1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "bankid", Value: 1}, {Key: "createdate", Value: -1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "status", Value: 1}, {Key: "createdate", Value: -1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "boleto.recipient.document.number", Value: 1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "boleto.buyer.document.number", Value: 1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "buyerdocumenthash", Value: 1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "ournumber", Value: 1}},
+		{{Key: "serviceuser", Value: 1}, {Key: "boleto.title.documentnumber", Value: 1}},
+	}
+
+	indexes := make([]mongo.IndexModel, 0, len(keys))
+	for _, k := range keys {
+		indexes = append(indexes, mongo.IndexModel{Keys: k, Options: options.Index().SetBackground(true)})
+	}
+
+	_, err = collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+func (r *mongoRepository) UpdateBoletoStatus(ctx context.Context, id, status string) error {
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+
+	collection, err := boletoCollection()
+	if err != nil {
+		return err
+	}
+
+	filter, err := idFilter(id)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"status": status}
+	if status == models.BoletoStatusPaid {
+		set["paymentdate"] = time.Now()
+	}
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrBoletoNotFound
+	}
+	return nil
+}
+
+func (r *mongoRepository) UpdateBoletoKeys(ctx context.Context, boleto models.BoletoView) error {
+	collection, err := boletoCollection()
+	if err != nil {
+		return err
+	}
+
+	filter, err := idFilter(boleto.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"secretkey": boleto.SecretKey, "publickey": boleto.PublicKey, "links": boleto.Links}}
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrBoletoNotFound
+	}
+	return nil
+}
+
+// EncryptStoredBoletos encrypts, in batches, the boletos saved without the encryption key
+func (r *mongoRepository) EncryptStoredBoletos(ctx context.Context, batchSize int) (int, error) {
+	if !encryption.Enabled() {
+		return 0, ErrEncryptionDisabled
+	}
+
+	collection, err := boletoCollection()
+	if err != nil {
+		return 0, err
+	}
+
+	pending := bson.M{"encryption": bson.M{"$exists": false}}
+	total := 0
+	for {
+		cur, err := collection.Find(ctx, pending, options.Find().SetLimit(int64(batchSize)))
+		if err != nil {
+			return total, err
+		}
+
+		batch := []models.BoletoView{}
+		if err = cur.All(ctx, &batch); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		for _, b := range batch {
+			if err = encryption.EncryptBoleto(ctx, &b); err != nil {
+				return total, err
+			}
+
+			update := bson.M{"$set": bson.M{
+				"boleto.buyer":          b.Boleto.Buyer,
+				"boleto.payeeguarantor": b.Boleto.PayeeGuarantor,
+				"encryption":            b.Encryption,
+				"buyerdocumenthash":     b.BuyerDocumentHash,
+			}}
+			if _, err = collection.UpdateOne(ctx, bson.M{"_id": b.ID, "encryption": bson.M{"$exists": false}}, update); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+}
+
+func (r *mongoRepository) EraseBuyerData(ctx context.Context, serviceUser, buyerDocument string, erasedAt time.Time) ([]models.BoletoView, error) {
+	filter, err := mongoFilter(BoletoFilter{ServiceUser: serviceUser, BuyerDocument: buyerDocument})
+	if err != nil {
+		return nil, err
+	}
+
+	erased := make([]models.BoletoView, 0)
+	for _, open := range []func() (*mongo.Collection, error){boletoCollection, archiveCollection} {
+		collection, err := open()
+		if err != nil {
+			return erased, err
+		}
+
+		cur, err := collection.Find(ctx, filter)
+		if err != nil {
+			return erased, err
+		}
+		found := []models.BoletoView{}
+		if err = cur.All(ctx, &found); err != nil {
+			return erased, err
+		}
+
+		for _, b := range found {
+			b.AnonymizeBuyer(erasedAt)
+			update := bson.M{
+				"$set":   bson.M{"boleto.buyer": b.Boleto.Buyer, "erasedat": erasedAt},
+				"$unset": bson.M{"buyerdocumenthash": ""},
+			}
+			if _, err = collection.UpdateOne(ctx, bson.M{"_id": b.ID}, update); err != nil {
+				return erased, err
+			}
+			erased = append(erased, b)
+		}
+	}
+	return erased, nil
+}
+
+// ApplyRetention removes the boletos past their retention period in batches, copying them to the archive collection first when archiving
+func (r *mongoRepository) ApplyRetention(ctx context.Context, rule RetentionRule) (int, error) {
+	collection, err := boletoCollection()
+	if err != nil {
+		return 0, err
+	}
+	archive, err := archiveCollection()
+	if err != nil {
+		return 0, err
+	}
+
+	users := bson.M{"$in": append([]string{}, rule.ServiceUsers...)}
+	if rule.Except {
+		users = bson.M{"$nin": append([]string{}, rule.ServiceUsers...)}
+	}
+	filter := bson.M{
+		"serviceuser":                 users,
+		"boleto.title.expiredatetime": bson.M{"$lt": rule.Before},
+		"paymentdate":                 bson.M{"$not": bson.M{"$gte": rule.Before}},
+	}
+
+	removed := 0
+	for {
+		cur, err := collection.Find(ctx, filter, options.Find().SetLimit(retentionBatchSize))
+		if err != nil {
+			return removed, err
+		}
+		batch := []bson.M{}
+		if err = cur.All(ctx, &batch); err != nil {
+			return removed, err
+		}
+		if len(batch) == 0 {
+			return removed, nil
+		}
+
+		ids := make(bson.A, 0, len(batch))
+		docs := make([]interface{}, 0, len(batch))
+		for _, doc := range batch {
+			ids = append(ids, doc["_id"])
+			docs = append(docs, doc)
+		}
+
+		if rule.Action == models.RetentionArchive {
+			// an interrupted batch may have been archived without being deleted, so already archived boletos are skipped
+			_, err = archive.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+			if err != nil && !onlyDuplicateKeys(err) {
+				return removed, err
+			}
+		}
+
+		res, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return removed, err
+		}
+		removed += int(res.DeletedCount)
+	}
+}
+
+// onlyDuplicateKeys reports whether every failed write of an unordered insert failed because the document already exists
+func onlyDuplicateKeys(err error) bool {
+	bulk, ok := err.(mongo.BulkWriteException)
+	if !ok || bulk.WriteConcernError != nil {
+		return false
+	}
+	for _, e := range bulk.WriteErrors {
+		if e.Code != duplicateKeyCode {
+			return false
+		}
+	}
+	return true
+}
+
+func idFilter(id string) (primitive.M, error) {
+	if len(id) == 24 {
+		d, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"_id": d}, nil
+	}
+	return bson.M{"id": id}, nil
+}
+
+func mongoSortField(f BoletoFilter) string {
+	if f.SortBy == SortByExpireDate {
+		return "boleto.title.expiredatetime"
+	}
+	return "createdate"
+}
+
+func mongoFilter(f BoletoFilter) (primitive.M, error) {
+	filter := bson.M{}
+
+	if f.ID != "" {
+		id, err := idFilter(f.ID)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range id {
+			filter[k] = v
+		}
+	}
+	if f.ServiceUser != "" {
+		filter["serviceuser"] = f.ServiceUser
+	}
+	if f.BankNumber != 0 {
+		filter["bankid"] = f.BankNumber
+	}
+	if f.RecipientDocument != "" {
+		filter["boleto.recipient.document.number"] = f.RecipientDocument
+	}
+	if f.BuyerDocument != "" {
+		if hash := encryption.BlindIndex(f.BuyerDocument); hash != "" {
+			filter["$or"] = bson.A{bson.M{"boleto.buyer.document.number": f.BuyerDocument}, bson.M{"buyerdocumenthash": hash}}
+		} else {
+			filter["boleto.buyer.document.number"] = f.BuyerDocument
+		}
+	}
+	if f.OurNumber != "" {
+		filter["ournumber"] = f.OurNumber
+	}
+	if f.DocumentNumber != "" {
+		filter["boleto.title.documentnumber"] = f.DocumentNumber
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if r := mongoRange(f.ExpireDateFrom, f.ExpireDateTo); r != nil {
+		filter["boleto.title.expiredatetime"] = r
+	}
+	if r := mongoRange(f.CreateDateFrom, f.CreateDateTo); r != nil {
+		filter["createdate"] = r
+	}
+
+	if f.Cursor != nil {
+		id, err := primitive.ObjectIDFromHex(f.Cursor.ID)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+
+		op := "$lt"
+		if f.Ascending {
+			op = "$gt"
+		}
+		field := mongoSortField(f)
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: f.Cursor.Value}},
+			bson.M{field: f.Cursor.Value, "_id": bson.M{op: id}},
+		}}}}
+	}
+
+	return filter, nil
+}
+
+func mongoRange(from, to time.Time) primitive.M {
+	if from.IsZero() && to.IsZero() {
+		return nil
 	}
 
-	// Changing dates as LocalDateTime, in order to keep the same time.Time attributes the mgo used return
+	r := bson.M{}
+	if !from.IsZero() {
+		r["$gte"] = from
+	}
+	if !to.IsZero() {
+		r["$lte"] = to
+	}
+	return r
+}
+
+// toLocalTime changes dates as LocalDateTime, in order to keep the same time.Time attributes the mgo used return
+func toLocalTime(result models.BoletoView) models.BoletoView {
 	result.Boleto.Title.ExpireDateTime = util.TimeToLocalTime(result.Boleto.Title.ExpireDateTime)
 	result.Boleto.Title.CreateDate = util.TimeToLocalTime(result.Boleto.Title.CreateDate)
 	result.CreateDate = util.TimeToLocalTime(result.CreateDate)
+	return result
+}
 
-	return result, time.Since(start).Milliseconds(), nil
+type mongoCredentialRepository struct{}
+
+// NewMongoCredentialRepository creates a CredentialRepository backed by the collection MONGODB_CREDENTIALS_COLLECTION
+func NewMongoCredentialRepository() CredentialRepository {
+	return &mongoCredentialRepository{}
+}
+
+func credentialsCollection() (*mongo.Collection, error) {
+	conn, err := CreateMongo()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Database(config.Get().MongoDatabase).Collection(config.Get().MongoCredentialsCollection), nil
+}
+
+// EnsureIndexes creates the unique index on username. It fails while duplicated usernames exist, which must be fixed by hand
+func (r *mongoCredentialRepository) EnsureIndexes(ctx context.Context) error {
+	collection, err := credentialsCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true).SetBackground(true),
+	})
+	return err
+}
+
+func (r *mongoCredentialRepository) GetCredentials(ctx context.Context) ([]models.Credentials, error) {
+	collection, err := credentialsCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Credentials{}
+	if err = cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	for i := range result {
+		if err = encryption.DecryptCredentials(ctx, &result[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (r *mongoCredentialRepository) GetCredentialsByKey(ctx context.Context, key string) (models.Credentials, error) {
+	result := models.Credentials{}
+
+	id, err := primitive.ObjectIDFromHex(key)
+	if err != nil {
+		return result, ErrCredentialsNotFound
+	}
+
+	collection, err := credentialsCollection()
+	if err != nil {
+		return result, err
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": id}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrCredentialsNotFound
+	} else if err != nil {
+		return result, err
+	}
+	return result, encryption.DecryptCredentials(ctx, &result)
+}
+
+func (r *mongoCredentialRepository) SaveCredentials(ctx context.Context, c models.Credentials) error {
+	if err := encryption.EncryptCredentials(ctx, &c); err != nil {
+		return err
+	}
+	collection, err := credentialsCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, c)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+func (r *mongoCredentialRepository) UpdateCredentials(ctx context.Context, c models.Credentials) error {
+	if err := encryption.EncryptCredentials(ctx, &c); err != nil {
+		return err
+	}
+	collection, err := credentialsCollection()
+	if err != nil {
+		return err
+	}
+
+	res, err := collection.ReplaceOne(ctx, bson.M{"_id": c.ID}, c)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrCredentialsNotFound
+	}
+	return nil
+}
+
+type mongoErasureRepository struct{}
+
+// NewMongoErasureRepository creates an ErasureRepository backed by the collection MONGODB_ERASURE_COLLECTION
+func NewMongoErasureRepository() ErasureRepository {
+	return &mongoErasureRepository{}
+}
+
+func erasuresCollection() (*mongo.Collection, error) {
+	conn, err := CreateMongo()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.Get().MongoErasureCollection
+	if name == "" {
+		name = "erasures"
+	}
+	return conn.Database(config.Get().MongoDatabase).Collection(name), nil
+}
+
+func (r *mongoErasureRepository) SaveErasure(ctx context.Context, record models.ErasureRecord) error {
+	collection, err := erasuresCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, record)
+	return err
+}
+
+func (r *mongoErasureRepository) FindErasures(ctx context.Context, documentHash string) ([]models.ErasureRecord, error) {
+	collection, err := erasuresCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{primitive.E{Key: "createdat", Value: 1}})
+	cur, err := collection.Find(ctx, bson.M{"documenthash": documentHash}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.ErasureRecord{}
+	if err = cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type mongoEventRepository struct{}
+
+// NewMongoEventRepository creates an EventRepository backed by the collection MONGODB_EVENT_COLLECTION
+func NewMongoEventRepository() EventRepository {
+	return &mongoEventRepository{}
+}
+
+func eventsCollection() (*mongo.Collection, error) {
+	conn, err := CreateMongo()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.Get().MongoEventCollection
+	if name == "" {
+		name = "events"
+	}
+	return conn.Database(config.Get().MongoDatabase).Collection(name), nil
+}
+
+// EnsureIndexes creates the indexes used to list the events of a boleto and to find failed registrations by request key
+func (r *mongoEventRepository) EnsureIndexes(ctx context.Context) error {
+	collection, err := eventsCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "boletoid", Value: 1}, {Key: "createdat", Value: 1}}, Options: options.Index().SetBackground(true)},
+		{Keys: bson.D{{Key: "requestkey", Value: 1}}, Options: options.Index().SetBackground(true)},
+	})
+	return err
+}
+
+func (r *mongoEventRepository) AppendEvent(ctx context.Context, event models.BoletoEvent) error {
+	collection, err := eventsCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *mongoEventRepository) FindEvents(ctx context.Context, boletoID string) ([]models.BoletoEvent, error) {
+	return r.find(ctx, bson.M{"boletoid": boletoID})
+}
+
+func (r *mongoEventRepository) FindEventsByRequestKey(ctx context.Context, serviceUser, requestKey string) ([]models.BoletoEvent, error) {
+	return r.find(ctx, bson.M{"requestkey": requestKey, "serviceuser": serviceUser})
+}
+
+func (r *mongoEventRepository) find(ctx context.Context, filter bson.M) ([]models.BoletoEvent, error) {
+	collection, err := eventsCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.BoletoEvent{}
+	if err = cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 //GetUserCredentials Busca as Credenciais dos Usuários
@@ -236,7 +881,11 @@ func GetTokenByClientIDAndIssuerBank(clientID, issuerBank string) (models.Token,
 		return result, err
 	}
 
-	if time.Now().After(result.CreatedAt.Add(time.Duration(config.Get().TokenSafeDurationInMinutes) * time.Minute)) { // safety margin
+	expiresAt := result.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = result.CreatedAt.Add(time.Duration(config.Get().TokenSafeDurationInMinutes) * time.Minute) // safety margin
+	}
+	if time.Now().After(expiresAt) {
 		result = models.Token{} // a little help for GC
 	}
 
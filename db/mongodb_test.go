@@ -4,6 +4,7 @@
 package db_test
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -53,10 +54,10 @@ func TestGetBoletoById(t *testing.T) {
 
 	bank := caixa.New()
 	input := newStubBoletoRequestDb(models.Caixa).Build()
-	resp, err := bank.ProcessBoleto(input)
+	resp, err := bank.ProcessBoleto(context.Background(), input)
 	assert.Nil(t, err)
 
-	boView := models.NewBoletoView(*input, resp, bank.GetBankNameIntegration())
+	boView := models.NewBoletoView(*input, resp, bank.GetBankNameIntegration(), "")
 	// Set values in order to force attributes that can be checked
 	oldID := boView.ID.Hex()
 	oldPk := boView.PublicKey
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// registers the "postgres" driver used by database/sql
+	_ "github.com/lib/pq"
+
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS boletos (
+	id                 VARCHAR(24) PRIMARY KEY,
+	public_key         TEXT        NOT NULL,
+	secret_key         TEXT        NOT NULL,
+	bank_id            INTEGER     NOT NULL,
+	our_number         TEXT        NOT NULL,
+	document_number    TEXT        NOT NULL,
+	recipient_document TEXT        NOT NULL,
+	buyer_document     TEXT        NOT NULL,
+	status             TEXT        NOT NULL,
+	expire_date        TIMESTAMPTZ NOT NULL,
+	create_date        TIMESTAMPTZ NOT NULL,
+	content            JSONB       NOT NULL
+);
+CREATE INDEX IF NOT EXISTS boletos_bank_id_idx ON boletos (bank_id);
+CREATE INDEX IF NOT EXISTS boletos_recipient_document_idx ON boletos (recipient_document);
+CREATE INDEX IF NOT EXISTS boletos_buyer_document_idx ON boletos (buyer_document);
+CREATE INDEX IF NOT EXISTS boletos_create_date_idx ON boletos (create_date);
+ALTER TABLE boletos ADD COLUMN IF NOT EXISTS service_user TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS boletos_service_user_create_date_idx ON boletos (service_user, create_date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS boletos_service_user_expire_date_idx ON boletos (service_user, expire_date DESC, id DESC);
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository opens a connection pool to PostgreSQL and creates the boletos table when missing
+func NewPostgresRepository(url string) (BoletoRepository, error) {
+	if url == "" {
+		return nil, errors.New("POSTGRES_URL cannot be empty")
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ConnectionTimeout)
+	defer cancel()
+
+	if _, err = conn.ExecContext(ctx, postgresSchema); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &postgresRepository{db: conn}, nil
+}
+
+func (p *postgresRepository) SaveBoleto(ctx context.Context, boleto models.BoletoView) error {
+	if err := encryption.EncryptBoleto(ctx, &boleto); err != nil {
+		return err
+	}
+	content, err := json.Marshal(boleto)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO boletos (id, public_key, secret_key, bank_id, our_number, document_number,
+			recipient_document, buyer_document, status, expire_date, create_date, service_user, content)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		boleto.ID.Hex(), boleto.PublicKey, boleto.SecretKey, int(boleto.BankID), boleto.OurNumber,
+		boleto.Boleto.Title.DocumentNumber, boleto.Boleto.Recipient.Document.Number,
+		postgresBuyerDocument(boleto), boleto.Status, boleto.Boleto.Title.ExpireDateTime,
+		boleto.CreateDate, boleto.ServiceUser, content)
+
+	return err
+}
+
+func (p *postgresRepository) GetBoletoByID(ctx context.Context, id, pk string) (models.BoletoView, int64, error) {
+	start := time.Now()
+
+	row := p.db.QueryRowContext(ctx, `SELECT content, status FROM boletos WHERE id = $1`, id)
+	result, err := scanBoleto(row)
+
+	if err == sql.ErrNoRows {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), ErrBoletoNotFound
+	} else if err != nil {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), err
+	} else if !hasValidKey(result, pk) {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), errors.New(InvalidPK)
+	} else if err = encryption.DecryptBoleto(ctx, &result); err != nil {
+		return models.BoletoView{}, time.Since(start).Milliseconds(), err
+	}
+
+	return result, time.Since(start).Milliseconds(), nil
+}
+
+func (p *postgresRepository) FindBoletos(ctx context.Context, filter BoletoFilter) (BoletoPage, error) {
+	where, args := postgresFilter(filter)
+	args = append(args, filter.GetLimit()+1)
+
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+	column := postgresSortColumn(filter)
+
+	query := fmt.Sprintf(`SELECT content, status, %s FROM boletos %s ORDER BY %s %s, id %s LIMIT $%d`,
+		column, where, column, direction, direction, len(args))
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return BoletoPage{}, err
+	}
+	defer rows.Close()
+
+	result := []models.BoletoView{}
+	positions := []time.Time{}
+	for rows.Next() {
+		var position time.Time
+		b, err := scanBoleto(rows, &position)
+		if err != nil {
+			return BoletoPage{}, err
+		}
+		if err = encryption.DecryptBoleto(ctx, &b); err != nil {
+			return BoletoPage{}, err
+		}
+		result = append(result, b)
+		positions = append(positions, position)
+	}
+	if err := rows.Err(); err != nil {
+		return BoletoPage{}, err
+	}
+
+	// The cursor must come from the column, which keeps only microseconds, and not from the
+	// content, otherwise the last row of a descending page is matched again by the next one
+	page := newBoletoPage(result, filter)
+	if page.NextCursor != "" {
+		last := len(page.Boletos) - 1
+		page.NextCursor = encodeCursor(positions[last], page.Boletos[last].ID.Hex())
+	}
+	return page, nil
+}
+
+func (p *postgresRepository) UpdateBoletoStatus(ctx context.Context, id, status string) error {
+	if err := validateStatus(status); err != nil {
+		return err
+	}
+
+	query := `UPDATE boletos SET status = $1 WHERE id = $2`
+	args := []interface{}{status, id}
+	if status == models.BoletoStatusPaid {
+		query = `UPDATE boletos SET status = $1, content = content || jsonb_build_object('paymentDate', $3::timestamptz) WHERE id = $2`
+		args = append(args, time.Now())
+	}
+
+	res, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBoletoNotFound
+	}
+	return nil
+}
+
+func (p *postgresRepository) UpdateBoletoKeys(ctx context.Context, boleto models.BoletoView) error {
+	links, err := json.Marshal(boleto.Links)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE boletos SET public_key = $1, secret_key = $2,
+			content = content || jsonb_build_object('publickey', $1::text, 'secretkey', $2::text, 'links', $3::jsonb)
+		WHERE id = $4`,
+		boleto.PublicKey, boleto.SecretKey, string(links), boleto.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBoletoNotFound
+	}
+	return nil
+}
+
+// EncryptStoredBoletos encrypts, in batches, the boletos saved without the encryption key
+func (p *postgresRepository) EncryptStoredBoletos(ctx context.Context, batchSize int) (int, error) {
+	if !encryption.Enabled() {
+		return 0, ErrEncryptionDisabled
+	}
+
+	total := 0
+	for {
+		rows, err := p.db.QueryContext(ctx,
+			`SELECT content, status FROM boletos WHERE NOT content ? 'encryption' ORDER BY id LIMIT $1`, batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		batch := []models.BoletoView{}
+		for rows.Next() {
+			b, err := scanBoleto(rows)
+			if err != nil {
+				rows.Close()
+				return total, err
+			}
+			batch = append(batch, b)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		for _, b := range batch {
+			if err := encryption.EncryptBoleto(ctx, &b); err != nil {
+				return total, err
+			}
+			content, err := json.Marshal(b)
+			if err != nil {
+				return total, err
+			}
+
+			_, err = p.db.ExecContext(ctx,
+				`UPDATE boletos SET buyer_document = $1, content = $2 WHERE id = $3 AND NOT content ? 'encryption'`,
+				postgresBuyerDocument(b), content, b.ID.Hex())
+			if err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+}
+
+func (p *postgresRepository) EraseBuyerData(ctx context.Context, serviceUser, buyerDocument string, erasedAt time.Time) ([]models.BoletoView, error) {
+	where, args := postgresFilter(BoletoFilter{ServiceUser: serviceUser, BuyerDocument: buyerDocument})
+	rows, err := p.db.QueryContext(ctx, `SELECT content, status FROM boletos `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []models.BoletoView{}
+	for rows.Next() {
+		b, err := scanBoleto(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found = append(found, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	erased := make([]models.BoletoView, 0, len(found))
+	for _, b := range found {
+		b.AnonymizeBuyer(erasedAt)
+		content, err := json.Marshal(b)
+		if err != nil {
+			return erased, err
+		}
+		if _, err = p.db.ExecContext(ctx, `UPDATE boletos SET buyer_document = '', content = $1 WHERE id = $2`, content, b.ID.Hex()); err != nil {
+			return erased, err
+		}
+		erased = append(erased, b)
+	}
+	return erased, nil
+}
+
+// postgresBuyerDocument is the searchable buyer document, the blind index when the personal data is encrypted
+func postgresBuyerDocument(b models.BoletoView) string {
+	if b.BuyerDocumentHash != "" {
+		return b.BuyerDocumentHash
+	}
+	return b.Boleto.Buyer.Document.Number
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBoleto reads the content and status columns, followed by the extra columns selected into columns
+func scanBoleto(row rowScanner, columns ...interface{}) (models.BoletoView, error) {
+	var content []byte
+	var status string
+
+	if err := row.Scan(append([]interface{}{&content, &status}, columns...)...); err != nil {
+		return models.BoletoView{}, err
+	}
+
+	result := models.BoletoView{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return models.BoletoView{}, err
+	}
+	result.Status = status
+
+	return toLocalTime(result), nil
+}
+
+func postgresSortColumn(f BoletoFilter) string {
+	if f.SortBy == SortByExpireDate {
+		return "expire_date"
+	}
+	return "create_date"
+}
+
+func postgresFilter(f BoletoFilter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ID != "" {
+		add("id = $%d", f.ID)
+	}
+	if f.ServiceUser != "" {
+		add("service_user = $%d", f.ServiceUser)
+	}
+	if f.BankNumber != 0 {
+		add("bank_id = $%d", int(f.BankNumber))
+	}
+	if f.RecipientDocument != "" {
+		add("recipient_document = $%d", f.RecipientDocument)
+	}
+	if hash := encryption.BlindIndex(f.BuyerDocument); hash != "" {
+		args = append(args, f.BuyerDocument, hash)
+		conditions = append(conditions, fmt.Sprintf("buyer_document IN ($%d, $%d)", len(args)-1, len(args)))
+	} else if f.BuyerDocument != "" {
+		add("buyer_document = $%d", f.BuyerDocument)
+	}
+	if f.OurNumber != "" {
+		add("our_number = $%d", f.OurNumber)
+	}
+	if f.DocumentNumber != "" {
+		add("document_number = $%d", f.DocumentNumber)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if !f.ExpireDateFrom.IsZero() {
+		add("expire_date >= $%d", f.ExpireDateFrom)
+	}
+	if !f.ExpireDateTo.IsZero() {
+		add("expire_date <= $%d", f.ExpireDateTo)
+	}
+	if !f.CreateDateFrom.IsZero() {
+		add("create_date >= $%d", f.CreateDateFrom)
+	}
+	if !f.CreateDateTo.IsZero() {
+		add("create_date <= $%d", f.CreateDateTo)
+	}
+	if f.Cursor != nil {
+		op := "<"
+		if f.Ascending {
+			op = ">"
+		}
+		args = append(args, f.Cursor.Value, f.Cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", postgresSortColumn(f), op, len(args)-1, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
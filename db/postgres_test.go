@@ -0,0 +1,48 @@
+//go:build integration || !unit
+// +build integration !unit
+
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostgresRepository_FindBoletosDescendingPages(t *testing.T) {
+	config.Install(true, false, true)
+	repository, err := db.NewPostgresRepository(config.Get().PostgresURL)
+	assert.Nil(t, err)
+
+	owner := "postgres-pages-" + primitive.NewObjectID().Hex()
+	// Nanoseconds are kept in the content but the create_date column keeps only microseconds
+	now := time.Date(2021, 6, 7, 10, 0, 0, 123456789, time.UTC)
+	saved := make([]models.BoletoView, 0)
+	for i := 0; i < 3; i++ {
+		view := newMemoryBoletoView(models.Caixa, "12123123000112", now.Add(time.Duration(i)*time.Minute))
+		view.ServiceUser = owner
+		assert.Nil(t, repository.SaveBoleto(context.Background(), view))
+		saved = append(saved, view)
+	}
+
+	filter := db.BoletoFilter{ServiceUser: owner, Limit: 2}
+	first, err := repository.FindBoletos(context.Background(), filter)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(first.Boletos))
+	assert.NotEmpty(t, first.NextCursor)
+
+	filter.Cursor, err = db.DecodeCursor(first.NextCursor)
+	assert.Nil(t, err)
+	second, err := repository.FindBoletos(context.Background(), filter)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(second.Boletos), "the last boleto of the first page must not be repeated")
+	assert.Equal(t, saved[0].ID, second.Boletos[0].ID)
+	assert.Empty(t, second.NextCursor)
+}
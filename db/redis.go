@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,80 +13,94 @@ import (
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const (
+	redisScanCount = 500
+	// registrationCounterExpiration mantém o contador diário por dois dias para cobrir a virada do dia entre instâncias
+	registrationCounterExpiration = 48 * 60 * 60
+	// rateLimitExpirationMargin mantém o bucket um segundo além do tempo necessário para enchê-lo
+	rateLimitExpirationMargin = 1000
+)
+
+// rateLimitScript implementa o token bucket de forma atômica. KEYS[1] guarda os tokens disponíveis e o instante da
+// última recarga. ARGV são os tokens por milissegundo, a capacidade, o instante atual e a expiração em milissegundos.
+// Retorna zero quando o token foi consumido ou os milissegundos até o próximo token
+const rateLimitScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return wait
+`
+
 //Redis Classe de Conexão com o Banco REDIS
 type Redis struct {
-	conn redis.Conn
+	client redisClient
 }
 
-//CreateRedis Cria instancia do Struct Redis
+//CreateRedis Cria instancia do Struct Redis usando o pool de conexões compartilhado
 func CreateRedis() *Redis {
-	return new(Redis)
+	return &Redis{client: getRedisClient()}
 }
 
-func (r *Redis) openConnection() error {
-	dbID, _ := strconv.Atoi(config.Get().RedisDatabase)
-	o := redis.DialDatabase(dbID)
-	ps := redis.DialPassword(config.Get().RedisPassword)
-	tOut := redis.DialConnectTimeout(15 * time.Second)
-	userTLS := redis.DialUseTLS(config.Get().RedisSSL)
+// do executa um comando no cliente registrando um span da operação
+func (r *Redis) do(ctx context.Context, key, cmd string, args ...interface{}) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "redis."+cmd,
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", cmd))
 
-	c, err := redis.Dial("tcp", config.Get().RedisURL, o, ps, tOut, userTLS)
-	if err != nil {
-		return err
-	}
-
-	r.conn = c
-	return nil
-}
-
-func (r *Redis) closeConnection() {
-	r.conn.Close()
+	ret, err := r.client.do(ctx, key, cmd, args...)
+	tracing.End(span, err)
+	return ret, err
 }
 
 //SetBoletoHTML Grava um boleto em formato Html no Redis
-func (r *Redis) SetBoletoHTML(b, mID, pk string, lg *log.Log) {
-	err := r.openConnection()
-	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [SetBoletoHTML] - Could not connection to Redis Database "))
-	} else {
+func (r *Redis) SetBoletoHTML(ctx context.Context, b, mID, pk string, lg *log.Log) {
+	key := fmt.Sprintf("%s:%s:%s", "boleto:html", mID, pk)
+	ret, err := r.do(ctx, key, "SETEX", key, config.Get().RedisExpirationTime, b)
 
-		key := fmt.Sprintf("%s:%s:%s", "boleto:html", mID, pk)
-		ret, err := r.conn.Do("SETEX", key, config.Get().RedisExpirationTime, b)
-
-		res := fmt.Sprintf("%s", ret)
-
-		if res != "OK" {
-			lg.Warn(res, fmt.Sprintf("SetBoletoHTML [SetBoletoHTML] - Could not record HTML in Redis Database: %s", key))
-		} else if err != nil {
-			lg.Warn(err.Error(), fmt.Sprintf("Error Redis [SetBoletoHTML] - Could not record HTML in Redis Database: %s", key))
-		}
+	if err != nil {
+		lg.Warn(err.Error(), fmt.Sprintf("Error Redis [SetBoletoHTML] - Could not record HTML in Redis Database: %s", key))
+		return
+	}
 
-		r.closeConnection()
+	if res := fmt.Sprintf("%s", ret); res != "OK" {
+		lg.Warn(res, fmt.Sprintf("SetBoletoHTML [SetBoletoHTML] - Could not record HTML in Redis Database: %s", key))
 	}
 }
 
 //GetBoletoHTMLByID busca um boleto pelo ID que vem na URL
 //O retorno será um objeto HTML do Boleto (caso ainda esteja em cache) e o tempo decorrido da operação (em milisegundos)
-func (r *Redis) GetBoletoHTMLByID(id string, pk string, lg *log.Log) (string, int64) {
-
+func (r *Redis) GetBoletoHTMLByID(ctx context.Context, id string, pk string, lg *log.Log) (string, int64) {
 	start := time.Now()
 
-	err := r.openConnection()
-
-	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [GetBoletoHTMLByID] - Could not connection to Redis Database"))
-		return "", time.Since(start).Milliseconds()
-	}
-
 	key := fmt.Sprintf("%s:%s:%s", "boleto:html", id, pk)
-	ret, err := r.conn.Do("GET", key)
-	r.closeConnection()
+	ret, err := r.do(ctx, key, "GET", key)
 
 	// TODO: handle error better than just log and return an empty string
 	if err != nil {
-		lg.Error(err.Error(), fmt.Sprintf("OpenConnection [GetBoletoHTMLByID] - Error executing redis command %s", err))
+		lg.Error(err.Error(), fmt.Sprintf("GetData [GetBoletoHTMLByID] - Error executing redis command %s", err))
 		return "", time.Since(start).Milliseconds()
 	}
 
@@ -96,27 +111,34 @@ func (r *Redis) GetBoletoHTMLByID(id string, pk string, lg *log.Log) (string, in
 	return fmt.Sprintf("%s", ret), time.Since(start).Milliseconds()
 }
 
-//SetBoletoJSON Grava um boleto em formato JSON no Redis
-func (r *Redis) SetBoletoJSON(b, mID, pk string, lg *log.Log) error {
-	err := r.openConnection()
+//DeleteBoletoHTML Remove o HTML de um boleto do Redis e retorna se ele estava em cache
+func (r *Redis) DeleteBoletoHTML(ctx context.Context, id, pk string, lg *log.Log) (bool, error) {
+	key := fmt.Sprintf("%s:%s:%s", "boleto:html", id, pk)
+	ret, err := redis.Int(r.do(ctx, key, "DEL", key))
 
 	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [SetBoletoJSON] - Could not connection to Redis Database "))
-		return err
+		lg.Warn(err.Error(), fmt.Sprintf("Delete data [DeleteBoletoHTML] - Error on delete key: %s", key))
+		return false, err
 	}
 
-	key := fmt.Sprintf("%s:%s:%s", "boleto:json", mID, pk)
-	ret, err := r.conn.Do("SET", key, b)
-	res := fmt.Sprintf("%s", ret)
+	return ret > 0, nil
+}
+
+//SetBoletoJSON Grava um boleto em formato JSON no Redis
+func (r *Redis) SetBoletoJSON(ctx context.Context, b, mID, pk string, lg *log.Log) error {
+	return r.SetBoletoJSONByKey(ctx, fmt.Sprintf("%s:%s:%s", "boleto:json", mID, pk), b, lg)
+}
 
-	r.closeConnection()
+//SetBoletoJSONByKey Grava um boleto em formato JSON no Redis substituindo o conteúdo da chave
+func (r *Redis) SetBoletoJSONByKey(ctx context.Context, key, b string, lg *log.Log) error {
+	ret, err := r.do(ctx, key, "SET", key, b)
 
 	if err != nil {
 		lg.Warn(err.Error(), fmt.Sprintf("SetBoletoJSON [SetBoletoJSON] - Could not record JSON in Redis Database: %s", key))
 		return err
 	}
 
-	if res != "OK" {
+	if res := fmt.Sprintf("%s", ret); res != "OK" {
 		lg.Warn("could not record JSON", fmt.Sprintf("SetBoletoJSON [SetBoletoJSON] - Error could not record JSON in Redis Database: %s", key))
 		return errors.New("could not record JSON")
 	}
@@ -125,84 +147,129 @@ func (r *Redis) SetBoletoJSON(b, mID, pk string, lg *log.Log) error {
 }
 
 // GetBoletoJSONByKey Recupera um boleto do tipo JSON do Redis
-func (r *Redis) GetBoletoJSONByKey(key string, lg *log.Log) (models.BoletoView, error) {
-	err := r.openConnection()
-
-	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [GetBoletoJSONByKey] - Could not connection to Redis Database "))
-		return models.BoletoView{}, err
-	}
-
-	ret, err := r.conn.Do("GET", key)
-	r.closeConnection()
+func (r *Redis) GetBoletoJSONByKey(ctx context.Context, key string, lg *log.Log) (models.BoletoView, error) {
+	ret, err := r.do(ctx, key, "GET", key)
 
 	if err != nil {
 		lg.Warn(err.Error(), fmt.Sprintf("GetData [GetBoletoJSONByKey] - Error could not to get data - "+key))
 		return models.BoletoView{}, err
 	}
 
-	if ret != nil {
-		result := models.BoletoView{}
-		r := fmt.Sprintf("%s", ret)
-		err = json.Unmarshal([]byte(r), &result)
-
-		if err != nil {
-			lg.Warn(err.Error(), fmt.Sprintf("Deserialize [GetBoletoJSONByKey] - Could not deserialize json - "+key))
-		}
-
-		return result, err
-	} else {
+	if ret == nil {
 		lg.Warn("not found data", fmt.Sprintf("GetData [GetBoletoJSONByKey] - Data not found - "+key))
 		return models.BoletoView{}, errors.New("not found data")
 	}
+
+	result := models.BoletoView{}
+	err = json.Unmarshal([]byte(fmt.Sprintf("%s", ret)), &result)
+	if err != nil {
+		lg.Warn(err.Error(), fmt.Sprintf("Deserialize [GetBoletoJSONByKey] - Could not deserialize json - "+key))
+	}
+
+	return result, err
 }
 
 // DeleteBoletoJSONByKey Deleta um boleto do tipo JSON do Redis
-func (r *Redis) DeleteBoletoJSONByKey(key string, lg *log.Log) error {
-	err := r.openConnection()
+func (r *Redis) DeleteBoletoJSONByKey(ctx context.Context, key string, lg *log.Log) error {
+	if _, err := r.do(ctx, key, "DEL", key); err != nil {
+		lg.Warn(err.Error(), fmt.Sprintf("Delete data [DeleteBoletoJSONByKey] - Error on delete key: "+key))
+		return err
+	}
 
+	return nil
+}
+
+// GetAllJSON Recupera todas as keys JSON do Redis, percorrendo o cursor do SCAN em todos os nós master
+func (r *Redis) GetAllJSON(ctx context.Context, lg *log.Log) ([]string, error) {
+	pools, err := r.client.masters(ctx)
 	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [DeleteBoletoJSONByKey] - Could not connection to Redis Database "))
-		return err
-	} else {
-		_, err := r.conn.Do("DEL", key)
-		r.closeConnection()
+		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [GetAllJson] - Could not connection to Redis Database "))
+		return nil, err
+	}
 
+	keys := make([]string, 0)
+	for _, pool := range pools {
+		nodeKeys, err := scanKeys(ctx, pool, "boleto:json:*")
 		if err != nil {
-			lg.Warn(err.Error(), fmt.Sprintf("Delete data [DeleteBoletoJSONByKey] - Error on delete key: "+key))
-			return err
+			lg.Warn(err.Error(), fmt.Sprintf("ReadDb [GetAllJson] - Could not get all json from database"))
+			return nil, err
 		}
+		keys = append(keys, nodeKeys...)
 	}
 
-	return nil
+	return keys, nil
 }
 
-// GetAllJSON Recupera todas as keys JSON do Redis
-func (r *Redis) GetAllJSON(lg *log.Log) ([]string, error) {
+// scanKeys iterates the SCAN cursor of a single node until it returns to zero
+func scanKeys(ctx context.Context, pool *redis.Pool, match string) ([]string, error) {
+	keys := make([]string, 0)
+	cursor := 0
 
-	err := r.openConnection()
-	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("OpenConnection [GetAllJson] - Could not connection to Redis Database "))
-		return nil, err
+	for {
+		arr, err := redis.Values(doWithContext(ctx, pool, "SCAN", cursor, "MATCH", match, "COUNT", redisScanCount))
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor, err = redis.Int(arr[0], nil); err != nil {
+			return nil, err
+		}
+
+		page, err := redis.Strings(arr[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+
+		if cursor == 0 {
+			return keys, nil
+		}
 	}
+}
 
-	var keys []string
+//IncrementDailyRegistrations incrementa e retorna a quantidade de boletos registrados pelo usuário no dia
+func (r *Redis) IncrementDailyRegistrations(ctx context.Context, serviceUser string, day time.Time) (int64, error) {
+	key := fmt.Sprintf("%s:%s:%s", "quota:registration", serviceUser, day.Format("20060102"))
 
-	arr, err := redis.Values(r.conn.Do("SCAN", 0, "MATCH", "boleto:json:*", "COUNT", 500))
+	count, err := redis.Int64(r.do(ctx, key, "INCR", key))
 	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("ReadDb [GetAllJson] - Could not get all json from database"))
-		return nil, err
+		return 0, err
 	}
 
-	keys, err = redis.Strings(arr[1], nil)
+	if count == 1 {
+		if _, err := r.do(ctx, key, "EXPIRE", key, registrationCounterExpiration); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
 
-	r.closeConnection()
+//TakeRateLimitToken Consome um token do bucket da chave, recarregado com perMinute tokens por minuto até o limite de burst tokens
+//Quando o bucket está vazio retorna false e o tempo até o próximo token
+func (r *Redis) TakeRateLimitToken(ctx context.Context, key string, perMinute, burst int, now time.Time) (bool, time.Duration, error) {
+	key = "ratelimit:" + key
+	rate := float64(perMinute) / float64(time.Minute/time.Millisecond)
+	ttl := int64(float64(burst)/rate) + rateLimitExpirationMargin
 
+	wait, err := redis.Int64(r.do(ctx, key, "EVAL", rateLimitScript, 1, key,
+		strconv.FormatFloat(rate, 'f', -1, 64), burst, now.UnixNano()/int64(time.Millisecond), ttl))
 	if err != nil {
-		lg.Warn(err.Error(), fmt.Sprintf("Stringify Keys [GetAllJson] - Error when setting keys in the array"))
-		return nil, err
+		return false, 0, err
 	}
+	return wait == 0, time.Duration(wait) * time.Millisecond, nil
+}
 
-	return keys, nil
+//Ping Verifica a conexão com todos os nós master do Redis
+func (r *Redis) Ping(ctx context.Context) error {
+	pools, err := r.client.masters(ctx)
+	if err != nil {
+		return err
+	}
 
+	for _, pool := range pools {
+		if _, err := doWithContext(ctx, pool, "PING"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
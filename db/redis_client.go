@@ -0,0 +1,496 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/mundipagg/boleto-api/config"
+)
+
+const (
+	// RedisStandalone connects to a single Redis node through REDIS_URL
+	RedisStandalone = "standalone"
+	// RedisSentinel resolves the current master through the sentinels in REDIS_ADDRESSES
+	RedisSentinel = "sentinel"
+	// RedisCluster routes commands by hash slot across the nodes in REDIS_ADDRESSES
+	RedisCluster = "cluster"
+
+	redisClusterSlots     = 16384
+	redisMaxRedirects     = 3
+	defaultRedisMaxIdle   = 10
+	defaultRedisIdleTime  = 240 * time.Second
+	redisConnectTimeout   = 15 * time.Second
+	redisHealthCheckAfter = time.Minute
+)
+
+var (
+	redisMu     sync.Mutex
+	redisShared redisClient
+)
+
+// redisClient hides the Redis topology behind the operations used by the Redis struct
+type redisClient interface {
+	// do executes a command on the node that owns key, bounded by the context deadline
+	do(ctx context.Context, key, cmd string, args ...interface{}) (interface{}, error)
+	// masters returns one pool per master node, for commands that must visit the whole keyspace
+	masters(ctx context.Context) ([]*redis.Pool, error)
+	close() error
+}
+
+// getRedisClient returns the shared Redis client, creating it on first use
+func getRedisClient() redisClient {
+	redisMu.Lock()
+	defer redisMu.Unlock()
+
+	if redisShared == nil {
+		redisShared = newRedisClient()
+	}
+	return redisShared
+}
+
+// CloseRedis closes every pooled connection of the shared Redis client
+func CloseRedis() error {
+	redisMu.Lock()
+	defer redisMu.Unlock()
+
+	if redisShared == nil {
+		return nil
+	}
+
+	err := redisShared.close()
+	redisShared = nil
+	return err
+}
+
+func newRedisClient() redisClient {
+	switch strings.ToLower(config.Get().RedisMode) {
+	case RedisSentinel:
+		return newSentinelClient(redisAddresses(), config.Get().RedisSentinelMasterName)
+	case RedisCluster:
+		return newClusterClient(redisAddresses())
+	default:
+		return &standaloneClient{pool: newRedisPool(func() (redis.Conn, error) {
+			return dialRedis(config.Get().RedisURL, true)
+		})}
+	}
+}
+
+func redisAddresses() []string {
+	addresses := make([]string, 0)
+	for _, a := range strings.Split(config.Get().RedisAddresses, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addresses = append(addresses, a)
+		}
+	}
+	if len(addresses) == 0 && config.Get().RedisURL != "" {
+		addresses = append(addresses, config.Get().RedisURL)
+	}
+	return addresses
+}
+
+func newRedisPool(dial func() (redis.Conn, error)) *redis.Pool {
+	maxIdle := config.Get().RedisMaxIdle
+	if maxIdle <= 0 {
+		maxIdle = defaultRedisMaxIdle
+	}
+
+	idleTimeout := time.Duration(config.Get().RedisIdleTimeoutInSeconds) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = defaultRedisIdleTime
+	}
+
+	return &redis.Pool{
+		Dial:        dial,
+		MaxIdle:     maxIdle,
+		MaxActive:   config.Get().RedisMaxActive,
+		IdleTimeout: idleTimeout,
+		Wait:        config.Get().RedisMaxActive > 0,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < redisHealthCheckAfter {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+// dialRedis opens a connection to a data node. Cluster nodes only have database 0, so selectDB is false for them
+func dialRedis(address string, selectDB bool) (redis.Conn, error) {
+	options := []redis.DialOption{
+		redis.DialPassword(config.Get().RedisPassword),
+		redis.DialConnectTimeout(redisConnectTimeout),
+		redis.DialUseTLS(config.Get().RedisSSL),
+	}
+
+	if selectDB {
+		dbID, _ := strconv.Atoi(config.Get().RedisDatabase)
+		options = append(options, redis.DialDatabase(dbID))
+	}
+
+	return redis.Dial("tcp", address, options...)
+}
+
+// doWithContext borrows a connection from the pool and runs the command within the context deadline
+func doWithContext(ctx context.Context, pool *redis.Pool, cmd string, args ...interface{}) (interface{}, error) {
+	c, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return doOnConn(ctx, c, cmd, args...)
+}
+
+func doOnConn(ctx context.Context, c redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return c.Do(cmd, args...)
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return redis.DoWithTimeout(c, timeout, cmd, args...)
+}
+
+type standaloneClient struct {
+	pool *redis.Pool
+}
+
+func (s *standaloneClient) do(ctx context.Context, key, cmd string, args ...interface{}) (interface{}, error) {
+	return doWithContext(ctx, s.pool, cmd, args...)
+}
+
+func (s *standaloneClient) masters(ctx context.Context) ([]*redis.Pool, error) {
+	return []*redis.Pool{s.pool}, nil
+}
+
+func (s *standaloneClient) close() error {
+	return s.pool.Close()
+}
+
+// sentinelClient keeps a pool to the master announced by the sentinels and
+// replaces it when the node it points to is demoted after a failover
+type sentinelClient struct {
+	mu         sync.RWMutex
+	sentinels  []string
+	masterName string
+	pool       *redis.Pool
+}
+
+func newSentinelClient(sentinels []string, masterName string) *sentinelClient {
+	s := &sentinelClient{sentinels: sentinels, masterName: masterName}
+	s.pool = s.newPool()
+	return s
+}
+
+func (s *sentinelClient) newPool() *redis.Pool {
+	return newRedisPool(func() (redis.Conn, error) {
+		address, err := s.masterAddress()
+		if err != nil {
+			return nil, err
+		}
+		return dialRedis(address, true)
+	})
+}
+
+func (s *sentinelClient) masterAddress() (string, error) {
+	for _, sentinel := range s.sentinels {
+		c, err := redis.Dial("tcp", sentinel,
+			redis.DialPassword(config.Get().RedisSentinelPassword),
+			redis.DialConnectTimeout(redisConnectTimeout),
+			redis.DialReadTimeout(redisConnectTimeout))
+		if err != nil {
+			continue
+		}
+
+		master, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+		c.Close()
+
+		if err == nil && len(master) == 2 {
+			return net.JoinHostPort(master[0], master[1]), nil
+		}
+	}
+	return "", fmt.Errorf("redis sentinel: could not resolve master %s from %v", s.masterName, s.sentinels)
+}
+
+func (s *sentinelClient) currentPool() *redis.Pool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pool
+}
+
+func (s *sentinelClient) do(ctx context.Context, key, cmd string, args ...interface{}) (interface{}, error) {
+	pool := s.currentPool()
+	ret, err := doWithContext(ctx, pool, cmd, args...)
+
+	if isReadOnlyError(err) {
+		s.resetPool(pool)
+		return doWithContext(ctx, s.currentPool(), cmd, args...)
+	}
+	return ret, err
+}
+
+// resetPool discards the connections to a demoted master, unless another call already did it
+func (s *sentinelClient) resetPool(stale *redis.Pool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pool != stale {
+		return
+	}
+	s.pool = s.newPool()
+	stale.Close()
+}
+
+func (s *sentinelClient) masters(ctx context.Context) ([]*redis.Pool, error) {
+	return []*redis.Pool{s.currentPool()}, nil
+}
+
+func (s *sentinelClient) close() error {
+	return s.currentPool().Close()
+}
+
+// clusterClient keeps one pool per node and routes each command to the master that owns the key slot
+type clusterClient struct {
+	mu    sync.RWMutex
+	seeds []string
+	pools map[string]*redis.Pool
+	slots [redisClusterSlots]string
+}
+
+func newClusterClient(seeds []string) *clusterClient {
+	return &clusterClient{
+		seeds: seeds,
+		pools: make(map[string]*redis.Pool),
+	}
+}
+
+func (cl *clusterClient) poolFor(address string) *redis.Pool {
+	cl.mu.RLock()
+	pool, ok := cl.pools[address]
+	cl.mu.RUnlock()
+	if ok {
+		return pool
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if pool, ok = cl.pools[address]; !ok {
+		pool = newRedisPool(func() (redis.Conn, error) {
+			return dialRedis(address, false)
+		})
+		cl.pools[address] = pool
+	}
+	return pool
+}
+
+func (cl *clusterClient) addressForSlot(slot int) string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.slots[slot]
+}
+
+// refresh reloads the slot map with CLUSTER SLOTS from the first node that answers
+func (cl *clusterClient) refresh(ctx context.Context) error {
+	cl.mu.RLock()
+	candidates := append([]string{}, cl.seeds...)
+	for address := range cl.pools {
+		candidates = append(candidates, address)
+	}
+	cl.mu.RUnlock()
+
+	var lastErr error = errors.New("redis cluster: no node addresses configured")
+	for _, address := range candidates {
+		reply, err := redis.Values(doWithContext(ctx, cl.poolFor(address), "CLUSTER", "SLOTS"))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		slots, err := parseClusterSlots(reply)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		cl.mu.Lock()
+		cl.slots = slots
+		cl.mu.Unlock()
+		return nil
+	}
+	return lastErr
+}
+
+func (cl *clusterClient) do(ctx context.Context, key, cmd string, args ...interface{}) (interface{}, error) {
+	slot := clusterSlot(key)
+	address := cl.addressForSlot(slot)
+	if address == "" {
+		if err := cl.refresh(ctx); err != nil {
+			return nil, err
+		}
+		address = cl.addressForSlot(slot)
+	}
+
+	asking := false
+	for i := 0; i <= redisMaxRedirects; i++ {
+		ret, err := cl.doOnNode(ctx, address, asking, cmd, args...)
+
+		redirect, target := parseRedirect(err)
+		switch redirect {
+		case "MOVED":
+			cl.refresh(ctx)
+			address, asking = target, false
+		case "ASK":
+			address, asking = target, true
+		default:
+			return ret, err
+		}
+	}
+	return nil, fmt.Errorf("redis cluster: too many redirects for key %s", key)
+}
+
+func (cl *clusterClient) doOnNode(ctx context.Context, address string, asking bool, cmd string, args ...interface{}) (interface{}, error) {
+	if !asking {
+		return doWithContext(ctx, cl.poolFor(address), cmd, args...)
+	}
+
+	c, err := cl.poolFor(address).GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if _, err := doOnConn(ctx, c, "ASKING"); err != nil {
+		return nil, err
+	}
+	return doOnConn(ctx, c, cmd, args...)
+}
+
+func (cl *clusterClient) masters(ctx context.Context) ([]*redis.Pool, error) {
+	if err := cl.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	cl.mu.RLock()
+	seen := make(map[string]bool)
+	addresses := make([]string, 0)
+	for _, address := range cl.slots {
+		if address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	cl.mu.RUnlock()
+
+	pools := make([]*redis.Pool, 0, len(addresses))
+	for _, address := range addresses {
+		pools = append(pools, cl.poolFor(address))
+	}
+	return pools, nil
+}
+
+func (cl *clusterClient) close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var err error
+	for address, pool := range cl.pools {
+		if e := pool.Close(); e != nil {
+			err = e
+		}
+		delete(cl.pools, address)
+	}
+	return err
+}
+
+// parseClusterSlots converts a CLUSTER SLOTS reply into a slot -> master address table
+func parseClusterSlots(reply []interface{}) ([redisClusterSlots]string, error) {
+	var slots [redisClusterSlots]string
+
+	for _, r := range reply {
+		entry, err := redis.Values(r, nil)
+		if err != nil || len(entry) < 3 {
+			return slots, errors.New("redis cluster: unexpected CLUSTER SLOTS reply")
+		}
+
+		start, err := redis.Int(entry[0], nil)
+		if err != nil {
+			return slots, err
+		}
+		end, err := redis.Int(entry[1], nil)
+		if err != nil {
+			return slots, err
+		}
+
+		master, err := redis.Values(entry[2], nil)
+		if err != nil || len(master) < 2 {
+			return slots, errors.New("redis cluster: unexpected master node in CLUSTER SLOTS reply")
+		}
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+
+		if start < 0 || end >= redisClusterSlots || start > end {
+			return slots, fmt.Errorf("redis cluster: invalid slot range %d-%d", start, end)
+		}
+		for s := start; s <= end; s++ {
+			slots[s] = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+	}
+	return slots, nil
+}
+
+// parseRedirect extracts the kind (MOVED or ASK) and target address of a cluster redirection error
+func parseRedirect(err error) (string, string) {
+	rErr, ok := err.(redis.Error)
+	if !ok {
+		return "", ""
+	}
+
+	parts := strings.Fields(string(rErr))
+	if len(parts) != 3 || (parts[0] != "MOVED" && parts[0] != "ASK") {
+		return "", ""
+	}
+	return parts[0], parts[2]
+}
+
+func isReadOnlyError(err error) bool {
+	rErr, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(rErr), "READONLY")
+}
+
+// clusterSlot computes the hash slot of a key, honoring {hash tags}
+func clusterSlot(key string) int {
+	if start := strings.Index(key, "{"); start >= 0 {
+		if end := strings.Index(key[start+1:], "}"); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16([]byte(key)) % redisClusterSlots)
+}
+
+// crc16 implements CRC16-CCITT (XMODEM), the checksum used by Redis Cluster
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterSlot(t *testing.T) {
+	assert.Equal(t, 12739, clusterSlot("123456789"))
+	assert.Equal(t, clusterSlot("user1000"), clusterSlot("{user1000}.following"))
+	assert.Equal(t, clusterSlot("{}.foo"), clusterSlot("{}.foo"))
+	assert.NotEqual(t, clusterSlot("boleto:json:1"), clusterSlot("boleto:json:2"))
+}
+
+func TestParseClusterSlots(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{int64(0), int64(8191), []interface{}{[]byte("10.0.0.1"), int64(7000), []byte("id1")}},
+		[]interface{}{int64(8192), int64(16383), []interface{}{[]byte("10.0.0.2"), int64(7001), []byte("id2")}},
+	}
+
+	slots, err := parseClusterSlots(reply)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:7000", slots[0])
+	assert.Equal(t, "10.0.0.1:7000", slots[8191])
+	assert.Equal(t, "10.0.0.2:7001", slots[8192])
+	assert.Equal(t, "10.0.0.2:7001", slots[16383])
+}
+
+func TestParseRedirect(t *testing.T) {
+	kind, address := parseRedirect(redis.Error("MOVED 3999 127.0.0.1:6381"))
+	assert.Equal(t, "MOVED", kind)
+	assert.Equal(t, "127.0.0.1:6381", address)
+
+	kind, address = parseRedirect(redis.Error("ASK 3999 127.0.0.1:6382"))
+	assert.Equal(t, "ASK", kind)
+	assert.Equal(t, "127.0.0.1:6382", address)
+
+	kind, _ = parseRedirect(redis.Error("ERR unknown command"))
+	assert.Equal(t, "", kind)
+}
+
+// fakeRedis is a minimal RESP server whose replies come from handle. A time.Duration reply delays the next one
+type fakeRedis struct {
+	listener net.Listener
+	handle   func(args []string) interface{}
+}
+
+func startFakeRedis(t *testing.T, handle func(args []string) interface{}) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{listener: listener, handle: handle}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) address() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		reply := f.handle(args)
+		if d, ok := reply.(time.Duration); ok {
+			time.Sleep(d)
+			reply = "OK"
+		}
+		conn.Write(encodeReply(reply))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func encodeReply(reply interface{}) []byte {
+	switch v := reply.(type) {
+	case nil:
+		return []byte("$-1\r\n")
+	case redis.Error:
+		return []byte("-" + string(v) + "\r\n")
+	case int:
+		return []byte(":" + strconv.Itoa(v) + "\r\n")
+	case string:
+		return []byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	case []interface{}:
+		out := []byte("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			out = append(out, encodeReply(item)...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("unsupported reply %T", reply))
+}
+
+func fakePool(address string) *redis.Pool {
+	return &redis.Pool{
+		Dial:      func() (redis.Conn, error) { return redis.Dial("tcp", address) },
+		MaxActive: 1,
+		MaxIdle:   1,
+		Wait:      true,
+	}
+}
+
+func TestScanKeys_FollowsCursorUntilZero(t *testing.T) {
+	pages := map[string][]interface{}{
+		"0": {"7", []interface{}{"boleto:json:1", "boleto:json:2"}},
+		"7": {"3", []interface{}{}},
+		"3": {"0", []interface{}{"boleto:json:3"}},
+	}
+	cursors := make(chan string, 10)
+	server := startFakeRedis(t, func(args []string) interface{} {
+		cursors <- args[1]
+		return pages[args[1]]
+	})
+
+	keys, err := scanKeys(context.Background(), fakePool(server.address()), "boleto:json:*")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"boleto:json:1", "boleto:json:2", "boleto:json:3"}, keys)
+	close(cursors)
+	requested := []string{}
+	for c := range cursors {
+		requested = append(requested, c)
+	}
+	assert.Equal(t, []string{"0", "7", "3"}, requested)
+}
+
+func TestDoWithContext_ReturnsConnectionToPool(t *testing.T) {
+	server := startFakeRedis(t, func(args []string) interface{} { return "PONG" })
+	pool := fakePool(server.address())
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := doWithContext(ctx, pool, "PING")
+		cancel()
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 1, pool.ActiveCount())
+	assert.Equal(t, 1, pool.IdleCount())
+}
+
+func TestDoWithContext_WhenPoolIsExhausted_StopsAtDeadline(t *testing.T) {
+	server := startFakeRedis(t, func(args []string) interface{} { return "PONG" })
+	pool := fakePool(server.address())
+	borrowed := pool.Get()
+	defer borrowed.Close()
+	borrowed.Do("PING")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := doWithContext(ctx, pool, "PING")
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestDoWithContext_WhenCommandOutlivesDeadline_ReturnsTimeout(t *testing.T) {
+	server := startFakeRedis(t, func(args []string) interface{} { return 500 * time.Millisecond })
+	pool := fakePool(server.address())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := doWithContext(ctx, pool, "GET", "key")
+
+	assert.NotNil(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(400*time.Millisecond))
+}
+
+func TestNewRedisClient_SelectsConfiguredTopology(t *testing.T) {
+	for mode, expected := range map[string]interface{}{"": &standaloneClient{}, RedisSentinel: &sentinelClient{}, RedisCluster: &clusterClient{}} {
+		os.Clearenv()
+		os.Setenv("REDIS_MODE", mode)
+		os.Setenv("REDIS_ADDRESSES", "127.0.0.1:1, 127.0.0.1:2")
+		config.Install(true, false, true)
+
+		client := newRedisClient()
+
+		assert.IsType(t, expected, client, mode)
+		client.close()
+	}
+	os.Clearenv()
+}
+
+func TestSentinelClient_SendsCommandsToResolvedMaster(t *testing.T) {
+	master := startFakeRedis(t, func(args []string) interface{} { return "from-master" })
+	host, port, _ := net.SplitHostPort(master.address())
+	sentinel := startFakeRedis(t, func(args []string) interface{} {
+		if strings.EqualFold(args[0], "SENTINEL") && args[2] == "boletos" {
+			return []interface{}{host, port}
+		}
+		return redis.Error("ERR unknown master")
+	})
+
+	client := newSentinelClient([]string{"127.0.0.1:1", sentinel.address()}, "boletos")
+	defer client.close()
+	value, err := redis.String(client.do(context.Background(), "key", "GET", "key"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "from-master", value)
+}
+
+func TestClusterClient_RoutesBySlotAndFollowsMoved(t *testing.T) {
+	target := startFakeRedis(t, func(args []string) interface{} { return "from-target" })
+	var owner *fakeRedis
+	owner = startFakeRedis(t, func(args []string) interface{} {
+		if strings.EqualFold(args[0], "CLUSTER") {
+			host, port, _ := net.SplitHostPort(owner.address())
+			p, _ := strconv.Atoi(port)
+			return []interface{}{[]interface{}{0, redisClusterSlots - 1, []interface{}{host, p}}}
+		}
+		return redis.Error("MOVED " + strconv.Itoa(clusterSlot(args[1])) + " " + target.address())
+	})
+
+	client := newClusterClient([]string{owner.address()})
+	defer client.close()
+	value, err := redis.String(client.do(context.Background(), "boleto:json:1", "GET", "boleto:json:1"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "from-target", value)
+	assert.Equal(t, owner.address(), client.addressForSlot(clusterSlot("boleto:json:1")))
+}
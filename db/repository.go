@@ -0,0 +1,262 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+const (
+	// MongoRepository persists boletos in the MongoDB collection MONGODB_BOLETO_COLLECTION
+	MongoRepository = "mongo"
+	// MemoryRepository keeps boletos in process memory, for tests and dev mode
+	MemoryRepository = "memory"
+	// PostgresRepository persists boletos in the PostgreSQL database at POSTGRES_URL
+	PostgresRepository = "postgres"
+
+	// SortByCreateDate orders the search by the registration date of the boleto
+	SortByCreateDate = "createDate"
+	// SortByExpireDate orders the search by the due date of the boleto
+	SortByExpireDate = "expireDate"
+
+	defaultFindLimit = 100
+)
+
+// ErrBoletoNotFound is returned by every repository when no boleto matches the query.
+// Its message is NotFoundDoc so callers comparing error messages keep working
+var ErrBoletoNotFound = errors.New(NotFoundDoc)
+
+// ErrEncryptionDisabled is returned when stored boletos are encrypted without ENCRYPTION_KEY_SOURCE
+var ErrEncryptionDisabled = errors.New("encryption is disabled")
+
+// BoletoFilter holds the optional criteria used to search registered boletos.
+// Empty fields are ignored and date ranges are inclusive.
+// Results are ordered by SortBy (create date by default) and then by id, which makes
+// Cursor, the value returned as NextCursor by the previous page, a stable keyset position
+type BoletoFilter struct {
+	ID                string
+	ServiceUser       string
+	BankNumber        models.BankNumber
+	RecipientDocument string
+	BuyerDocument     string
+	OurNumber         string
+	DocumentNumber    string
+	Status            string
+	ExpireDateFrom    time.Time
+	ExpireDateTo      time.Time
+	CreateDateFrom    time.Time
+	CreateDateTo      time.Time
+	Limit             int
+	SortBy            string
+	Ascending         bool
+	Cursor            *BoletoCursor
+}
+
+// BoletoPage is a page of a boleto search
+type BoletoPage struct {
+	Boletos    []models.BoletoView
+	NextCursor string
+}
+
+// BoletoCursor is the position of the last boleto of a page in the search order
+type BoletoCursor struct {
+	Value time.Time `json:"v"`
+	ID    string    `json:"id"`
+}
+
+// EncodeCursor serializes the position of a boleto as an opaque cursor
+func (f BoletoFilter) EncodeCursor(b models.BoletoView) string {
+	return encodeCursor(f.SortValue(b), b.ID.Hex())
+}
+
+func encodeCursor(value time.Time, id string) string {
+	c, _ := json.Marshal(BoletoCursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(c)
+}
+
+// DecodeCursor parses a cursor created by EncodeCursor
+func DecodeCursor(cursor string) (*BoletoCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	c := &BoletoCursor{}
+	if err := json.Unmarshal(raw, c); err != nil || c.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// IsValidSort checks if the boletos can be ordered by the field
+func IsValidSort(sortBy string) bool {
+	return sortBy == "" || sortBy == SortByCreateDate || sortBy == SortByExpireDate
+}
+
+// SortValue returns the value of the boleto used to order the search
+func (f BoletoFilter) SortValue(b models.BoletoView) time.Time {
+	if f.SortBy == SortByExpireDate {
+		return b.Boleto.Title.ExpireDateTime
+	}
+	return b.CreateDate
+}
+
+// GetLimit returns the maximum number of boletos to fetch, falling back to a default
+func (f BoletoFilter) GetLimit() int {
+	if f.Limit <= 0 {
+		return defaultFindLimit
+	}
+	return f.Limit
+}
+
+// BoletoRepository abstracts the persistence of registered boletos
+type BoletoRepository interface {
+	// SaveBoleto stores a newly registered boleto
+	SaveBoleto(ctx context.Context, boleto models.BoletoView) error
+	// GetBoletoByID fetches a boleto by id validating its public key.
+	// It also returns the elapsed time of the operation in milliseconds
+	GetBoletoByID(ctx context.Context, id, pk string) (models.BoletoView, int64, error)
+	// FindBoletos lists a page of the boletos matching the filter
+	FindBoletos(ctx context.Context, filter BoletoFilter) (BoletoPage, error)
+	// UpdateBoletoStatus changes the status of a boleto
+	UpdateBoletoStatus(ctx context.Context, id, status string) error
+	// UpdateBoletoKeys stores the secret key, public key and links of a boleto, replacing the ones its public links were issued with
+	UpdateBoletoKeys(ctx context.Context, boleto models.BoletoView) error
+	// EraseBuyerData anonymizes the buyer of every boleto, archived ones included, with the buyer document.
+	// An empty service user erases the boletos of every owner. It returns the anonymized boletos
+	EraseBuyerData(ctx context.Context, serviceUser, buyerDocument string, erasedAt time.Time) ([]models.BoletoView, error)
+}
+
+// NewBoletoRepository creates the repository configured in BOLETO_REPOSITORY. Mongo is the default backend
+func NewBoletoRepository() (BoletoRepository, error) {
+	switch strings.ToLower(config.Get().BoletoRepository) {
+	case "", MongoRepository:
+		return NewMongoRepository(), nil
+	case MemoryRepository:
+		return NewMemoryRepository(), nil
+	case PostgresRepository:
+		return NewPostgresRepository(config.Get().PostgresURL)
+	default:
+		return nil, fmt.Errorf("unknown boleto repository %s", config.Get().BoletoRepository)
+	}
+}
+
+func validateStatus(status string) error {
+	if !models.IsValidBoletoStatus(status) {
+		return fmt.Errorf("invalid boleto status %s", status)
+	}
+	return nil
+}
+
+// IndexedRepository is implemented by repositories that need their indexes created at startup
+type IndexedRepository interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// newBoletoPage trims the extra boleto fetched to know whether there is a next page
+func newBoletoPage(boletos []models.BoletoView, f BoletoFilter) BoletoPage {
+	page := BoletoPage{Boletos: boletos}
+	if len(boletos) > f.GetLimit() {
+		page.Boletos = boletos[:f.GetLimit()]
+		page.NextCursor = f.EncodeCursor(page.Boletos[len(page.Boletos)-1])
+	}
+	return page
+}
+
+// isAfterCursor checks if the boleto comes after the cursor in the search order
+func isAfterCursor(b models.BoletoView, f BoletoFilter) bool {
+	if f.Cursor == nil {
+		return true
+	}
+
+	v, id := f.SortValue(b), b.ID.Hex()
+	if f.Ascending {
+		return v.After(f.Cursor.Value) || (v.Equal(f.Cursor.Value) && id > f.Cursor.ID)
+	}
+	return v.Before(f.Cursor.Value) || (v.Equal(f.Cursor.Value) && id < f.Cursor.ID)
+}
+
+func matchesFilter(b models.BoletoView, f BoletoFilter) bool {
+	switch {
+	case f.ID != "" && b.ID.Hex() != f.ID:
+		return false
+	case f.ServiceUser != "" && b.ServiceUser != f.ServiceUser:
+		return false
+	case f.BankNumber != 0 && b.BankID != f.BankNumber:
+		return false
+	case f.RecipientDocument != "" && b.Boleto.Recipient.Document.Number != f.RecipientDocument:
+		return false
+	case f.BuyerDocument != "" && !matchesBuyerDocument(b, f.BuyerDocument):
+		return false
+	case f.OurNumber != "" && b.OurNumber != f.OurNumber:
+		return false
+	case f.DocumentNumber != "" && b.Boleto.Title.DocumentNumber != f.DocumentNumber:
+		return false
+	case f.Status != "" && b.Status != f.Status:
+		return false
+	}
+
+	return inRange(b.Boleto.Title.ExpireDateTime, f.ExpireDateFrom, f.ExpireDateTo) &&
+		inRange(b.CreateDate, f.CreateDateFrom, f.CreateDateTo) &&
+		isAfterCursor(b, f)
+}
+
+// matchesBuyerDocument compares the buyer document in clear or, when the boleto is encrypted, its blind index
+func matchesBuyerDocument(b models.BoletoView, document string) bool {
+	if b.BuyerDocumentHash != "" {
+		return b.BuyerDocumentHash == encryption.BlindIndex(document)
+	}
+	return b.Boleto.Buyer.Document.Number == document
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
+
+// EncryptedRepository is implemented by repositories that can encrypt the personal data of the boletos
+// stored before encryption was enabled. It returns how many boletos were encrypted
+type EncryptedRepository interface {
+	EncryptStoredBoletos(ctx context.Context, batchSize int) (int, error)
+}
+
+// RetentionRule selects the boletos past their retention period, the ones whose due date and,
+// when paid, payment date are before Before
+type RetentionRule struct {
+	// ServiceUsers are the owners the rule applies to. With Except it applies to every other owner instead
+	ServiceUsers []string
+	Except       bool
+	Before       time.Time
+	// Action is models.RetentionArchive or models.RetentionPurge
+	Action string
+}
+
+func (r RetentionRule) matches(b models.BoletoView) bool {
+	owner := false
+	for _, user := range r.ServiceUsers {
+		owner = owner || user == b.ServiceUser
+	}
+	if owner == r.Except || !b.Boleto.Title.ExpireDateTime.Before(r.Before) {
+		return false
+	}
+	return b.PaymentDate == nil || b.PaymentDate.Before(r.Before)
+}
+
+// RetentionRepository is implemented by repositories that can archive or purge the boletos past their
+// retention period. It returns how many boletos were removed from the boletos in use
+type RetentionRepository interface {
+	ApplyRetention(ctx context.Context, rule RetentionRule) (int, error)
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/tracing"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoTracer opens a span for every Mongo command issued inside a traced request.
+// Spans are kept by request id until the driver reports the command outcome
+type mongoTracer struct {
+	spans sync.Map
+}
+
+func newMongoMonitor() *event.CommandMonitor {
+	t := &mongoTracer{}
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *mongoTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	_, span := tracing.Start(ctx, "mongodb."+e.CommandName,
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", e.DatabaseName),
+		attribute.String("db.operation", e.CommandName))
+	t.spans.Store(e.RequestID, span)
+}
+
+func (t *mongoTracer) succeeded(ctx context.Context, e *event.CommandSucceededEvent) {
+	t.end(e.RequestID, nil)
+}
+
+func (t *mongoTracer) failed(ctx context.Context, e *event.CommandFailedEvent) {
+	t.end(e.RequestID, errors.New(e.Failure))
+}
+
+func (t *mongoTracer) end(requestID int64, err error) {
+	if span, ok := t.spans.Load(requestID); ok {
+		t.spans.Delete(requestID)
+		tracing.End(span.(trace.Span), err)
+	}
+}
@@ -0,0 +1,95 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/2016-10-01/keyvault"
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/auth"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/mundipagg/boleto-api/config"
+)
+
+// azureKeyVault cifra as chaves de dados com a chave RSA ENCRYPTION_AZURE_KEY_NAME do cofre ENCRYPTION_AZURE_VAULT_NAME
+// O identificador guardado é o kid da versão usada, então rotacionar a chave no cofre não invalida os boletos antigos
+type azureKeyVault struct {
+	client       keyvault.BaseClient
+	vaultBaseURL string
+	keyName      string
+}
+
+func newAzureKeyVault() (KeyProvider, error) {
+	c := config.Get()
+	if c.EncryptionAzureVaultName == "" || c.EncryptionAzureKeyName == "" {
+		return nil, errors.New("ENCRYPTION_AZURE_VAULT_NAME and ENCRYPTION_AZURE_KEY_NAME are required")
+	}
+
+	authorizer, err := auth.NewAuthorizerFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	client := keyvault.New()
+	client.Authorizer = authorizer
+
+	return azureKeyVault{
+		client:       client,
+		vaultBaseURL: fmt.Sprintf("https://%s.%s", c.EncryptionAzureVaultName, azure.PublicCloud.KeyVaultDNSSuffix),
+		keyName:      c.EncryptionAzureKeyName,
+	}, nil
+}
+
+func (a azureKeyVault) WrapKey(ctx context.Context, key []byte) (string, []byte, error) {
+	value := base64.RawURLEncoding.EncodeToString(key)
+	result, err := a.client.WrapKey(ctx, a.vaultBaseURL, a.keyName, "", keyvault.KeyOperationsParameters{
+		Algorithm: keyvault.RSAOAEP256,
+		Value:     &value,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if result.Kid == nil || result.Result == nil {
+		return "", nil, errors.New("empty response from azure key vault")
+	}
+
+	wrapped, err := base64.RawURLEncoding.DecodeString(*result.Result)
+	if err != nil {
+		return "", nil, err
+	}
+	return *result.Kid, wrapped, nil
+}
+
+func (a azureKeyVault) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	name, version, err := parseKeyID(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(wrapped)
+	result, err := a.client.UnwrapKey(ctx, a.vaultBaseURL, name, version, keyvault.KeyOperationsParameters{
+		Algorithm: keyvault.RSAOAEP256,
+		Value:     &value,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Result == nil {
+		return nil, errors.New("empty response from azure key vault")
+	}
+	return base64.RawURLEncoding.DecodeString(*result.Result)
+}
+
+// parseKeyID extrai o nome e a versão de um kid no formato https://{cofre}/keys/{nome}/{versão}
+func parseKeyID(keyID string) (name, version string, err error) {
+	parts := strings.Split(keyID, "/keys/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid azure key id %s", keyID)
+	}
+	segments := strings.Split(parts[1], "/")
+	if len(segments) != 2 || segments[0] == "" {
+		return "", "", fmt.Errorf("invalid azure key id %s", keyID)
+	}
+	return segments[0], segments[1], nil
+}
@@ -0,0 +1,338 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+// Origens das chaves mestras em ENCRYPTION_KEY_SOURCE
+const (
+	LocalSource         = "local"
+	AzureKeyVaultSource = "azureKeyVault"
+)
+
+const (
+	dataKeySize = 32
+	// encryptedPrefix marca os campos cifrados, que ficam no lugar do valor original
+	encryptedPrefix = "enc:"
+	// maxCachedKeys limita as chaves de dados decifradas mantidas em memória, evitando uma chamada ao KMS por leitura
+	maxCachedKeys = 10000
+)
+
+//KeyProvider Cifra e decifra as chaves de dados dos boletos com uma chave mestra
+type KeyProvider interface {
+	// WrapKey cifra a chave de dados com a chave mestra atual e retorna o identificador dela
+	WrapKey(ctx context.Context, key []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decifra a chave de dados com a chave mestra keyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]func() (KeyProvider, error){
+		LocalSource:         newLocalKeyring,
+		AzureKeyVaultSource: newAzureKeyVault,
+	}
+
+	cachedKeys     sync.Map
+	cachedKeysSize int
+	cachedKeysMu   sync.Mutex
+)
+
+//RegisterProvider Registra uma origem de chaves mestras, que pode ser escolhida em ENCRYPTION_KEY_SOURCE
+func RegisterProvider(name string, factory func() (KeyProvider, error)) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+//Enabled Indica se os dados pessoais dos boletos devem ser cifrados
+func Enabled() bool {
+	return config.Get().EncryptionKeySource != ""
+}
+
+func provider() (KeyProvider, error) {
+	source := config.Get().EncryptionKeySource
+	providersMu.RLock()
+	factory, ok := providers[source]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key source %q", source)
+	}
+	return factory()
+}
+
+//EncryptBoleto Cifra os dados pessoais do boleto com uma nova chave de dados, guardada cifrada no próprio boleto
+//Não faz nada quando a cifragem está desabilitada ou o boleto já está cifrado
+func EncryptBoleto(ctx context.Context, view *models.BoletoView) error {
+	if !Enabled() || view.Encryption != nil {
+		return nil
+	}
+
+	copyPayeeGuarantor(view)
+	view.BuyerDocumentHash = BlindIndex(view.Boleto.Buyer.Document.Number)
+	encrypted, err := sealFields(ctx, personalFields(view))
+	if err != nil {
+		return err
+	}
+
+	view.Encryption = encrypted
+	return nil
+}
+
+//DecryptBoleto Decifra os dados pessoais de um boleto cifrado por EncryptBoleto
+//Boletos guardados antes da cifragem são retornados como estão
+func DecryptBoleto(ctx context.Context, view *models.BoletoView) error {
+	// o índice cego só serve para a busca no banco e não deve sair na resposta ou nos logs
+	view.BuyerDocumentHash = ""
+	if view.Encryption == nil {
+		return nil
+	}
+
+	copyPayeeGuarantor(view)
+	if err := openFields(ctx, view.Encryption, personalFields(view)); err != nil {
+		return err
+	}
+
+	view.Encryption = nil
+	return nil
+}
+
+//EncryptCredentials Cifra os client secrets do usuário nos bancos com uma nova chave de dados, guardada cifrada nas credenciais
+//Não faz nada quando a cifragem está desabilitada ou as credenciais já estão cifradas
+func EncryptCredentials(ctx context.Context, c *models.Credentials) error {
+	if !Enabled() || c.Encryption != nil || len(c.Banks) == 0 {
+		return nil
+	}
+
+	return withBankSecrets(c, func(fields []*string) error {
+		encrypted, err := sealFields(ctx, fields)
+		c.Encryption = encrypted
+		return err
+	})
+}
+
+//DecryptCredentials Decifra os client secrets cifrados por EncryptCredentials
+//Credenciais guardadas antes da cifragem são retornadas como estão
+func DecryptCredentials(ctx context.Context, c *models.Credentials) error {
+	if c.Encryption == nil {
+		return nil
+	}
+
+	err := withBankSecrets(c, func(fields []*string) error {
+		return openFields(ctx, c.Encryption, fields)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Encryption = nil
+	return nil
+}
+
+// sealFields cifra os campos preenchidos com uma nova chave de dados e retorna essa chave cifrada pela chave mestra
+func sealFields(ctx context.Context, fields []*string) (*models.EncryptedKey, error) {
+	p, err := provider()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := p.WrapKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		if *field == "" || strings.HasPrefix(*field, encryptedPrefix) {
+			continue
+		}
+		if *field, err = seal(aead, *field); err != nil {
+			return nil, err
+		}
+	}
+
+	encrypted := &models.EncryptedKey{KeyID: keyID, Key: base64.StdEncoding.EncodeToString(wrapped)}
+	cacheKey(encrypted.Key, key)
+	return encrypted, nil
+}
+
+// openFields decifra os campos cifrados com a chave de dados informada
+func openFields(ctx context.Context, encrypted *models.EncryptedKey, fields []*string) error {
+	key, err := dataKey(ctx, encrypted)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if !strings.HasPrefix(*field, encryptedPrefix) {
+			continue
+		}
+		if *field, err = open(aead, *field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//BlindIndex Retorna o HMAC do valor com ENCRYPTION_BLIND_INDEX_KEY, que permite buscar um documento cifrado
+//Retorna vazio quando a chave não está configurada
+func BlindIndex(value string) string {
+	secret := config.Get().EncryptionBlindIndexKey
+	if secret == "" || value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// personalFields são os dados pessoais do comprador e do sacador avalista
+func personalFields(view *models.BoletoView) []*string {
+	buyer := &view.Boleto.Buyer
+	fields := []*string{
+		&buyer.Name,
+		&buyer.Email,
+		&buyer.Document.Number,
+		&buyer.Address.Street,
+		&buyer.Address.Number,
+		&buyer.Address.Complement,
+		&buyer.Address.ZipCode,
+		&buyer.Address.City,
+		&buyer.Address.District,
+		&buyer.Address.StateCode,
+	}
+	if guarantor := view.Boleto.PayeeGuarantor; guarantor != nil {
+		fields = append(fields, &guarantor.Name, &guarantor.Document.Number)
+	}
+	return fields
+}
+
+// copyPayeeGuarantor evita alterar o sacador avalista compartilhado com outras cópias do boleto
+func copyPayeeGuarantor(view *models.BoletoView) {
+	if guarantor := view.Boleto.PayeeGuarantor; guarantor != nil {
+		c := *guarantor
+		view.Boleto.PayeeGuarantor = &c
+	}
+}
+
+// withBankSecrets chama apply com os client secrets do usuário em cada banco e grava os valores alterados em uma cópia
+// do mapa de bancos, que pode estar compartilhado com as credenciais em memória
+func withBankSecrets(c *models.Credentials, apply func(fields []*string) error) error {
+	numbers := make([]string, 0, len(c.Banks))
+	banks := make([]models.BankCredentials, 0, len(c.Banks))
+	for number, b := range c.Banks {
+		numbers = append(numbers, number)
+		banks = append(banks, b)
+	}
+
+	fields := make([]*string, len(banks))
+	for i := range banks {
+		fields[i] = &banks[i].ClientSecret
+	}
+	if err := apply(fields); err != nil {
+		return err
+	}
+
+	c.Banks = make(map[string]models.BankCredentials, len(banks))
+	for i, number := range numbers {
+		c.Banks[number] = banks[i]
+	}
+	return nil
+}
+
+func dataKey(ctx context.Context, encrypted *models.EncryptedKey) ([]byte, error) {
+	if key, ok := cachedKeys.Load(encrypted.Key); ok {
+		return key.([]byte), nil
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(encrypted.Key)
+	if err != nil {
+		return nil, err
+	}
+	p, err := provider()
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.UnwrapKey(ctx, encrypted.KeyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey(encrypted.Key, key)
+	return key, nil
+}
+
+func cacheKey(wrapped string, key []byte) {
+	cachedKeysMu.Lock()
+	defer cachedKeysMu.Unlock()
+
+	if cachedKeysSize >= maxCachedKeys {
+		cachedKeys.Range(func(k, _ interface{}) bool {
+			cachedKeys.Delete(k)
+			return true
+		})
+		cachedKeysSize = 0
+	}
+	if _, loaded := cachedKeys.LoadOrStore(wrapped, key); !loaded {
+		cachedKeysSize++
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func open(aead cipher.AEAD, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted field is too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
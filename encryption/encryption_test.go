@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testKey1 = "a2V5LW9uZS1lbmNyeXB0aW9uLWtleS0zMi1ieXRlcyE="
+	testKey2 = "a2V5LXR3by1lbmNyeXB0aW9uLWtleS0zMi1ieXRlcyE="
+)
+
+func arrangeEncryption(t *testing.T, env map[string]string) {
+	os.Clearenv()
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+}
+
+func newPersonalBoletoView() models.BoletoView {
+	request := models.BoletoRequest{BankNumber: models.Caixa}
+	request.Buyer.Name = "Fulano de Tal"
+	request.Buyer.Email = "fulano@example.com"
+	request.Buyer.Document = models.Document{Type: "CPF", Number: "12345678909"}
+	request.Buyer.Address.Street = "Rua Um"
+	request.Buyer.Address.ZipCode = "20000000"
+	request.PayeeGuarantor = &models.PayeeGuarantor{Name: "Lojista", Document: models.Document{Type: "CNPJ", Number: "12123123000112"}}
+	return models.NewBoletoView(request, models.BoletoResponse{}, "", "")
+}
+
+func TestEncryptBoleto_RoundTrip(t *testing.T) {
+	arrangeEncryption(t, map[string]string{
+		"ENCRYPTION_KEY_SOURCE":      LocalSource,
+		"ENCRYPTION_KEYRING":         "k1=" + testKey1,
+		"ENCRYPTION_BLIND_INDEX_KEY": "index-key",
+	})
+	original := newPersonalBoletoView()
+	view := original
+
+	err := EncryptBoleto(context.Background(), &view)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "k1", view.Encryption.KeyID)
+	assert.Equal(t, BlindIndex("12345678909"), view.BuyerDocumentHash)
+	assert.True(t, strings.HasPrefix(view.Boleto.Buyer.Name, encryptedPrefix))
+	assert.True(t, strings.HasPrefix(view.Boleto.Buyer.Document.Number, encryptedPrefix))
+	assert.True(t, strings.HasPrefix(view.Boleto.PayeeGuarantor.Name, encryptedPrefix))
+	assert.Equal(t, "", view.Boleto.Buyer.Address.City, "campos vazios continuam vazios")
+	assert.Equal(t, "Lojista", original.Boleto.PayeeGuarantor.Name, "o sacador avalista do boleto original não é alterado")
+
+	cachedKeys.Delete(view.Encryption.Key)
+	err = DecryptBoleto(context.Background(), &view)
+
+	assert.Nil(t, err)
+	assert.Nil(t, view.Encryption)
+	assert.Empty(t, view.BuyerDocumentHash, "o índice cego não sai do repositório")
+	assert.Equal(t, original.Boleto.Buyer, view.Boleto.Buyer)
+	assert.Equal(t, *original.Boleto.PayeeGuarantor, *view.Boleto.PayeeGuarantor)
+}
+
+func TestDecryptBoleto_AfterKeyRotation(t *testing.T) {
+	arrangeEncryption(t, map[string]string{"ENCRYPTION_KEY_SOURCE": LocalSource, "ENCRYPTION_KEYRING": "k1=" + testKey1})
+	view := newPersonalBoletoView()
+	assert.Nil(t, EncryptBoleto(context.Background(), &view))
+	cachedKeys.Delete(view.Encryption.Key)
+
+	arrangeEncryption(t, map[string]string{"ENCRYPTION_KEY_SOURCE": LocalSource, "ENCRYPTION_KEYRING": "k2=" + testKey2 + ",k1=" + testKey1})
+	rotated := newPersonalBoletoView()
+	assert.Nil(t, EncryptBoleto(context.Background(), &rotated))
+	assert.Equal(t, "k2", rotated.Encryption.KeyID)
+
+	assert.Nil(t, DecryptBoleto(context.Background(), &view))
+	assert.Equal(t, "Fulano de Tal", view.Boleto.Buyer.Name)
+
+	retired := newPersonalBoletoView()
+	arrangeEncryption(t, map[string]string{"ENCRYPTION_KEY_SOURCE": LocalSource, "ENCRYPTION_KEYRING": "k1=" + testKey1})
+	assert.Nil(t, EncryptBoleto(context.Background(), &retired))
+	cachedKeys.Delete(retired.Encryption.Key)
+	arrangeEncryption(t, map[string]string{"ENCRYPTION_KEY_SOURCE": LocalSource, "ENCRYPTION_KEYRING": "k2=" + testKey2})
+	assert.NotNil(t, DecryptBoleto(context.Background(), &retired), "a chave mestra removida do keyring não decifra mais")
+}
+
+func TestEncryptBoleto_WhenDisabled_KeepsPlainData(t *testing.T) {
+	arrangeEncryption(t, nil)
+	view := newPersonalBoletoView()
+
+	err := EncryptBoleto(context.Background(), &view)
+
+	assert.Nil(t, err)
+	assert.Nil(t, view.Encryption)
+	assert.Equal(t, "Fulano de Tal", view.Boleto.Buyer.Name)
+	assert.Equal(t, "", BlindIndex("12345678909"))
+}
+
+func TestEncryptCredentials_RoundTrip(t *testing.T) {
+	arrangeEncryption(t, map[string]string{"ENCRYPTION_KEY_SOURCE": LocalSource, "ENCRYPTION_KEYRING": "k1=" + testKey1})
+	original := models.Credentials{Banks: map[string]models.BankCredentials{
+		"33":  {ClientID: "santander-id", ClientSecret: "santander-secret"},
+		"237": {Certificate: "bradesco-crt"},
+	}}
+	c := original
+
+	err := EncryptCredentials(context.Background(), &c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "k1", c.Encryption.KeyID)
+	assert.True(t, strings.HasPrefix(c.Banks["33"].ClientSecret, encryptedPrefix))
+	assert.Equal(t, "santander-id", c.Banks["33"].ClientID)
+	assert.Equal(t, "", c.Banks["237"].ClientSecret, "campos vazios continuam vazios")
+	assert.Equal(t, "santander-secret", original.Banks["33"].ClientSecret, "os bancos das credenciais originais não são alterados")
+
+	cachedKeys.Delete(c.Encryption.Key)
+	err = DecryptCredentials(context.Background(), &c)
+
+	assert.Nil(t, err)
+	assert.Nil(t, c.Encryption)
+	assert.Equal(t, original.Banks, c.Banks)
+}
+
+func TestParseKeyID(t *testing.T) {
+	name, version, err := parseKeyID("https://vault.vault.azure.net/keys/boletos/4f6c2b")
+	assert.Nil(t, err)
+	assert.Equal(t, "boletos", name)
+	assert.Equal(t, "4f6c2b", version)
+
+	_, _, err = parseKeyID("https://vault.vault.azure.net/secrets/boletos")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,66 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mundipagg/boleto-api/config"
+)
+
+// localKeyring guarda as chaves mestras na configuração, em ENCRYPTION_KEYRING no formato kid=chave separado por vírgula
+// As chaves têm 32 bytes em base64. A primeira cifra as novas chaves de dados e as outras continuam decifrando as antigas
+type localKeyring struct {
+	ids  []string
+	keys map[string][]byte
+}
+
+func newLocalKeyring() (KeyProvider, error) {
+	k := localKeyring{keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(config.Get().EncryptionKeyring, ",") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(kv[1])
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("encryption key %s must be %d bytes in base64", kv[0], dataKeySize)
+		}
+		k.ids = append(k.ids, kv[0])
+		k.keys[kv[0]] = key
+	}
+	if len(k.ids) == 0 {
+		return nil, errors.New("ENCRYPTION_KEYRING has no keys")
+	}
+	return k, nil
+}
+
+func (k localKeyring) WrapKey(ctx context.Context, key []byte) (string, []byte, error) {
+	aead, err := newAEAD(k.keys[k.ids[0]])
+	if err != nil {
+		return "", nil, err
+	}
+	sealed, err := seal(aead, string(key))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.ids[0], []byte(sealed), nil
+}
+
+func (k localKeyring) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %s", keyID)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	key, err := open(aead, string(wrapped))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(key), nil
+}
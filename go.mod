@@ -27,9 +27,9 @@ require (
 	github.com/gopherjs/gopherjs v0.0.0-20210901121439-eee08aaf2717 // indirect
 	github.com/iancoleman/strcase v0.2.0 // indirect
 	github.com/influxdata/influxdb v1.2.3-0.20170831151503-0ef033f5dd9b // indirect
-	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/kennygrant/sanitize v1.2.4
 	github.com/klauspost/compress v1.13.2-0.20210614094421-d1ca57f19428 // indirect
+	github.com/lib/pq v1.9.0
 	github.com/mralves/tracer v1.8.0
 	github.com/mundipagg/healthcheck-go v0.0.0-20210906144607-e91a033227a1
 	github.com/mundipagg/tracer-seq-writer v1.1.17
@@ -39,6 +39,7 @@ require (
 	github.com/pact-foundation/pact-go v1.6.4
 	github.com/pkg/errors v0.9.2-0.20201214064552-5dd12d0cfe7f // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/prometheus/client_golang v1.12.2
 	github.com/streadway/amqp v1.0.1-0.20200716223359-e6b33f460591
 	github.com/stretchr/objx v0.3.0 // indirect
 	github.com/stretchr/testify v1.7.0
@@ -47,7 +48,14 @@ require (
 	github.com/tdewolff/test v1.0.6 // indirect
 	github.com/v2pro/plz v0.0.0-20200805122259-422184e41b6e // indirect
 	go.mongodb.org/mongo-driver v1.7.1
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
 	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	software.sslmate.com/src/go-pkcs12 v0.0.0-20190322163127-6e380ad96778
 )
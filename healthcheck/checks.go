@@ -0,0 +1,236 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mundipagg/boleto-api/certificate"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/infrastructure/storage"
+	"github.com/mundipagg/boleto-api/queue"
+	"github.com/mundipagg/boleto-api/resilience"
+	"github.com/mundipagg/boleto-api/util"
+
+	checks "github.com/mundipagg/healthcheck-go/checks"
+)
+
+const (
+	MongoCheck        = "mongo"
+	RabbitCheck       = "rabbit"
+	RedisCheck        = "redis"
+	PdfCheck          = "pdf"
+	StorageCheck      = "storage"
+	CertificatesCheck = "certificates"
+	// ShutdownCheck indica na prontidão que a aplicação está sendo encerrada
+	ShutdownCheck = "shutdown"
+	// bankCheckPrefix identifica as verificações dos endpoints de token dos bancos, como bank-bb
+	bankCheckPrefix = "bank-"
+	// breakerCheckPrefix identifica o estado do circuit breaker dos bancos, como breaker-itau
+	breakerCheckPrefix = "breaker-"
+	// certificateCheckPrefix identifica a validade de cada certificado carregado, como certificate-santander-ssl
+	certificateCheckPrefix = "certificate-"
+)
+
+//Check Verifica a disponibilidade de uma dependência da aplicação
+type Check interface {
+	Name() string
+	Execute(ctx context.Context) error
+}
+
+// describer é implementado pelas verificações que descrevem a dependência mesmo quando ela está disponível
+type describer interface {
+	Describe() string
+}
+
+type checkFunc struct {
+	name     string
+	execute  func(ctx context.Context) error
+	describe func() string
+}
+
+func (c checkFunc) Name() string {
+	return c.name
+}
+
+func (c checkFunc) Execute(ctx context.Context) error {
+	return c.execute(ctx)
+}
+
+func (c checkFunc) Describe() string {
+	if c.describe == nil {
+		return ""
+	}
+	return c.describe()
+}
+
+//NewCheck Cria uma verificação a partir de uma função
+func NewCheck(name string, execute func(ctx context.Context) error) Check {
+	return checkFunc{name: name, execute: execute}
+}
+
+func dependencyChecks() []Check {
+	list := []Check{
+		libraryCheck(MongoCheck, db.GetDatabaseConfiguration()),
+		libraryCheck(RabbitCheck, queue.GetQueueConfiguration()),
+		NewCheck(RedisCheck, func(ctx context.Context) error {
+			return db.CreateRedis().Ping(ctx)
+		}),
+		NewCheck(StorageCheck, func(ctx context.Context) error {
+			client, err := storage.GetClient()
+			if err != nil {
+				return err
+			}
+			return client.Ping(ctx)
+		}),
+		NewCheck(CertificatesCheck, checkCertificates),
+	}
+
+	if config.Get().PdfAPIURL != "" {
+		list = append(list, reachableCheck(PdfCheck, "", config.Get().PdfAPIURL))
+	}
+
+	for _, bank := range resilience.Banks() {
+		list = append(list, breakerCheck(bank))
+	}
+
+	for _, name := range certificate.Monitored() {
+		if _, ok := certificate.Expiry(name); ok {
+			list = append(list, certificateExpiryCheck(name))
+		}
+	}
+
+	tokenURLs := bankTokenURLs()
+	for _, bank := range splitNames(config.Get().HealthCheckBanks) {
+		url, ok := tokenURLs[bank]
+		if !ok || url == "" {
+			continue
+		}
+		list = append(list, reachableCheck(bankCheckPrefix+bank, bank, url))
+	}
+
+	return list
+}
+
+// libraryCheck adapta as verificações da healthcheck-go, que não recebem contexto
+func libraryCheck(name string, cnf checks.Config) Check {
+	return NewCheck(name, func(ctx context.Context) error {
+		result := cnf.CreateCheck().Execute()
+		if result.Status == Unhealthy {
+			return errors.New(result.Description)
+		}
+		return nil
+	})
+}
+
+// reachableCheck considera a dependência disponível quando ela responde com status abaixo de 500
+// O certificado dos bancos é verificado com a política de confiança deles em TLS_TRUST, como nas chamadas de registro
+func reachableCheck(name, bank, url string) Check {
+	return NewCheck(name, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+
+		client := &http.Client{Timeout: timeout()}
+		if bank != "" {
+			transport, err := util.BankTransport(bank)
+			if err != nil {
+				return err
+			}
+			client.Transport = transport
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// breakerCheck reporta o circuito aberto de um banco, sem executar chamadas
+func breakerCheck(bank string) Check {
+	return NewCheck(breakerCheckPrefix+strings.ToLower(bank), func(ctx context.Context) error {
+		if state := resilience.Get(bank).State(); state != resilience.Closed {
+			return fmt.Errorf("circuit is %s", state)
+		}
+		return nil
+	})
+}
+
+// certificateExpiryCheck informa os dias para a expiração do certificado
+// A verificação falha quando o certificado expirou ou atingiu o menor limite de CERTIFICATE_EXPIRY_WARNING_DAYS
+func certificateExpiryCheck(name string) Check {
+	return checkFunc{
+		name: certificateCheckPrefix + strings.ToLower(name),
+		execute: func(ctx context.Context) error {
+			days, _ := certificate.DaysToExpiry(name, time.Now())
+			thresholds := certificate.WarningDays()
+			if days < 0 {
+				return fmt.Errorf("expired %d days ago", int(math.Ceil(-days)))
+			}
+			if days <= float64(thresholds[len(thresholds)-1]) {
+				return fmt.Errorf("expires in %d days", int(math.Floor(days)))
+			}
+			return nil
+		},
+		describe: func() string {
+			days, _ := certificate.DaysToExpiry(name, time.Now())
+			return fmt.Sprintf("expires in %d days", int(math.Floor(days)))
+		},
+	}
+}
+
+func checkCertificates(ctx context.Context) error {
+	names := []string{}
+	for _, name := range []string{
+		config.Get().CertificateICPName,
+		config.Get().CertificateSSLName,
+		config.Get().CitibankCertificateSSLName,
+		config.Get().SantanderCertificateSSLName,
+		config.Get().AzureStorageOpenBankSkName,
+		config.Get().AzureStorageJPMorganPkName,
+		config.Get().AzureStorageJPMorganCrtName,
+		config.Get().AzureStorageJPMorganSignCrtName,
+	} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if missing := certificate.MissingFromStore(names...); len(missing) > 0 {
+		return fmt.Errorf("certificates not loaded: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func bankTokenURLs() map[string]string {
+	return map[string]string{
+		"bb":        config.Get().URLBBToken,
+		"itau":      config.Get().URLTicketItau,
+		"santander": config.Get().URLTicketSantander,
+		"pefisa":    config.Get().URLPefisaToken,
+		"stone":     config.Get().URLStoneToken,
+	}
+}
+
+func splitNames(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
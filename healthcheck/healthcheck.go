@@ -1,64 +1,169 @@
 package healthcheck
 
 import (
+	"context"
 	"errors"
 	stdlog "log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
-	"github.com/mundipagg/boleto-api/queue"
-
-	HealthCheckLib "github.com/mundipagg/healthcheck-go"
-	checks "github.com/mundipagg/healthcheck-go/checks"
 )
 
 const (
 	Unhealthy string = "Unhealthy"
+	Healthy   string = "Healthy"
+	//Degraded Alguma dependência não crítica está indisponível
+	Degraded string = "Degraded"
+
+	defaultTimeout = 5 * time.Second
+	// noCriticalChecks desabilita as dependências críticas em HEALTHCHECK_CRITICAL
+	noCriticalChecks = "none"
 )
 
+var defaultCriticalChecks = []string{MongoCheck, RabbitCheck}
+
 type HealthCheckResponse struct {
-	Status string `json:"status"`
+	Status  string                     `json:"status"`
+	Results map[string]ComponentResult `json:"results,omitempty"`
 }
 
-func newHealthCheckResponse(healthCheckResult *checks.HealthCheckResult) HealthCheckResponse {
-	return HealthCheckResponse{
-		Status: healthCheckResult.Status,
+//ComponentResult Resultado da verificação de uma dependência
+type ComponentResult struct {
+	Status                string `json:"status"`
+	Description           string `json:"description,omitempty"`
+	Critical              bool   `json:"critical"`
+	LatencyInMilliseconds int64  `json:"latencyInMilliseconds"`
+}
+
+//Execute Executa as verificações em paralelo, limitadas pelo timeout
+//O resultado é Unhealthy quando alguma dependência crítica falha e Degraded quando apenas dependências não críticas falham
+func Execute(ctx context.Context, list []Check, critical map[string]bool, timeout time.Duration) HealthCheckResponse {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	response := HealthCheckResponse{Status: Healthy, Results: make(map[string]ComponentResult, len(list))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range list {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			result := execute(ctx, c)
+			result.Critical = critical[c.Name()]
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Results[c.Name()] = result
+			if result.Status == Healthy {
+				return
+			}
+			if result.Critical {
+				response.Status = Unhealthy
+			} else if response.Status == Healthy {
+				response.Status = Degraded
+			}
+		}(c)
 	}
+
+	wg.Wait()
+	return response
 }
 
-func createHealthCheck() HealthCheckLib.HealthCheck {
-	mongoConfig := db.GetDatabaseConfiguration()
-	rabbitConfig := queue.GetQueueConfiguration()
+func execute(ctx context.Context, c Check) ComponentResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Execute(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := ComponentResult{Status: Healthy, LatencyInMilliseconds: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = Unhealthy
+		result.Description = "ERROR: " + err.Error()
+	} else if d, ok := c.(describer); ok {
+		result.Description = d.Describe()
+	}
+	return result
+}
+
+func criticalChecks() map[string]bool {
+	names := splitNames(config.Get().HealthCheckCritical)
+	if len(names) == 0 {
+		names = defaultCriticalChecks
+	}
+
+	critical := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != noCriticalChecks {
+			critical[name] = true
+		}
+	}
+	return critical
+}
 
-	healthCheck := HealthCheckLib.New()
-	healthCheck.AddService(mongoConfig)
-	healthCheck.AddService(rabbitConfig)
+func timeout() time.Duration {
+	if t := config.Get().HealthCheckTimeoutInSeconds; t > 0 {
+		return time.Duration(t) * time.Second
+	}
+	return defaultTimeout
+}
 
-	return healthCheck
+func readiness(ctx context.Context) HealthCheckResponse {
+	return Execute(ctx, dependencyChecks(), criticalChecks(), timeout())
 }
 
+//ExecuteOnAPI Verifica a prontidão da aplicação. Mantido em /healthcheck por compatibilidade
 func ExecuteOnAPI(c *gin.Context) {
-	healtcheck := createHealthCheck()
-	result := healtcheck.Execute()
+	Readiness(c)
+}
+
+//Liveness Indica que o processo está de pé, sem verificar as dependências
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthCheckResponse{Status: Healthy})
+}
+
+//Readiness Verifica as dependências e responde 503 quando alguma dependência crítica está indisponível
+//Durante o encerramento responde 503 sem verificar as dependências, para que a aplicação deixe de receber tráfego
+func Readiness(c *gin.Context) {
+	if !config.IsRunning() {
+		c.JSON(http.StatusServiceUnavailable, HealthCheckResponse{Status: Unhealthy, Results: map[string]ComponentResult{
+			ShutdownCheck: {Status: Unhealthy, Description: "ERROR: application is shutting down", Critical: true},
+		}})
+		return
+	}
+
+	result := readiness(c.Request.Context())
 	status := http.StatusOK
 
-	if result.Status == Unhealthy {
-		logInstance("ExecuteOnAPI").ErrorBasicWithContent("Healthcheck is Unhealthy", "HealthCheck", result)
+	switch result.Status {
+	case Unhealthy:
+		logInstance("Readiness").ErrorBasicWithContent("Healthcheck is Unhealthy", "HealthCheck", result)
 		status = http.StatusServiceUnavailable
+	case Degraded:
+		logInstance("Readiness").Warn(result, "Healthcheck is Degraded")
 	}
 
-	c.JSON(status, newHealthCheckResponse(&result))
+	c.JSON(status, result)
 }
 
+//ExecuteOnStartup Verifica as dependências na subida da aplicação, que é encerrada quando uma dependência crítica está indisponível
 func ExecuteOnStartup() bool {
 	var logger = logInstance("ExecuteOnStartup")
 	logger.InfoWithBasic("Starting HealthCheck", "HealthCheck", nil)
 
-	healtcheck := createHealthCheck()
-	result := healtcheck.Execute()
+	result := readiness(context.Background())
 
 	if result.Status == Unhealthy {
 		stdlog.Println("Healthcheck is Unhealthy", result)
@@ -68,6 +173,10 @@ func ExecuteOnStartup() bool {
 		return false
 	}
 
+	if result.Status == Degraded {
+		stdlog.Println("Healthcheck is Degraded", result)
+	}
+
 	logger.InfoWithBasic("Result of check dependecies execution", "HealthCheck", map[string]interface{}{"Content": result})
 	return true
 }
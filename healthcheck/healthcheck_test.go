@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mundipagg/boleto-api/certificate"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func healthy(name string) Check {
+	return NewCheck(name, func(ctx context.Context) error { return nil })
+}
+
+func failing(name string) Check {
+	return NewCheck(name, func(ctx context.Context) error { return errors.New("connection refused") })
+}
+
+func TestExecute_WhenAllChecksPass_ShouldBeHealthy(t *testing.T) {
+	result := Execute(context.Background(), []Check{healthy(MongoCheck), healthy(RedisCheck)}, map[string]bool{MongoCheck: true}, time.Second)
+
+	assert.Equal(t, Healthy, result.Status)
+	assert.Equal(t, 2, len(result.Results))
+	assert.True(t, result.Results[MongoCheck].Critical)
+	assert.False(t, result.Results[RedisCheck].Critical)
+}
+
+func TestExecute_WhenNonCriticalCheckFails_ShouldBeDegraded(t *testing.T) {
+	result := Execute(context.Background(), []Check{healthy(MongoCheck), failing(RedisCheck)}, map[string]bool{MongoCheck: true}, time.Second)
+
+	assert.Equal(t, Degraded, result.Status)
+	assert.Equal(t, Unhealthy, result.Results[RedisCheck].Status)
+	assert.Equal(t, "ERROR: connection refused", result.Results[RedisCheck].Description)
+}
+
+func TestExecute_WhenCriticalCheckFails_ShouldBeUnhealthy(t *testing.T) {
+	result := Execute(context.Background(), []Check{failing(MongoCheck), failing(RedisCheck)}, map[string]bool{MongoCheck: true}, time.Second)
+
+	assert.Equal(t, Unhealthy, result.Status)
+}
+
+func TestExecute_WhenCheckExceedsTimeout_ShouldBeUnhealthyWithLatency(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := NewCheck(PdfCheck, func(ctx context.Context) error { <-release; return nil })
+
+	result := Execute(context.Background(), []Check{slow}, map[string]bool{PdfCheck: true}, 20*time.Millisecond)
+
+	assert.Equal(t, Unhealthy, result.Status)
+	assert.Contains(t, result.Results[PdfCheck].Description, "deadline exceeded")
+	assert.True(t, result.Results[PdfCheck].LatencyInMilliseconds >= 20)
+}
+
+func TestReachableCheck_ShouldFailOnlyOnServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	assert.Nil(t, reachableCheck(PdfCheck, "", server.URL+"/token").Execute(context.Background()))
+	assert.NotNil(t, reachableCheck(PdfCheck, "", server.URL+"/down").Execute(context.Background()))
+}
+
+func TestReachableCheck_VerifiesBankWithItsTrustPolicy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	certificate.SetCertificateOnStore("bank-private-ca", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	os.Clearenv()
+	os.Setenv("TLS_TRUST", "itau:ca=bank-private-ca")
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+
+	assert.Nil(t, reachableCheck(bankCheckPrefix+"itau", "itau", server.URL).Execute(context.Background()))
+	assert.NotNil(t, reachableCheck(bankCheckPrefix+"bb", "bb", server.URL).Execute(context.Background()), "sem a CA privada o certificado do servidor não é confiável")
+}
+
+func TestCertificateExpiryCheck_FailsWhenExpiredOrBelowSmallestThreshold(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CERTIFICATE_EXPIRY_WARNING_DAYS", "30,7")
+	config.Install(true, false, true)
+	certificate.SetCertificateOnStore("valid", certificate.GenerateTestCertificate(time.Now().Add(20*24*time.Hour+time.Hour)))
+	certificate.SetCertificateOnStore("expiring", certificate.GenerateTestCertificate(time.Now().Add(3*24*time.Hour+time.Hour)))
+	certificate.SetCertificateOnStore("expired", certificate.GenerateTestCertificate(time.Now().Add(-2*24*time.Hour+time.Hour)))
+
+	result := Execute(context.Background(), []Check{certificateExpiryCheck("valid"), certificateExpiryCheck("expiring"), certificateExpiryCheck("expired")}, map[string]bool{}, time.Second)
+
+	assert.Equal(t, Degraded, result.Status)
+	assert.Equal(t, Healthy, result.Results["certificate-valid"].Status)
+	assert.Equal(t, "expires in 20 days", result.Results["certificate-valid"].Description)
+	assert.Equal(t, "ERROR: expires in 3 days", result.Results["certificate-expiring"].Description)
+	assert.Equal(t, "ERROR: expired 2 days ago", result.Results["certificate-expired"].Description)
+}
+
+func TestReadiness_WhenStopped_ShouldBeUnavailableWithoutCheckingDependencies(t *testing.T) {
+	os.Clearenv()
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+	assert.True(t, config.IsRunning())
+	config.Stop()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/healthcheck/ready", nil)
+
+	Readiness(c)
+
+	var response HealthCheckResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, Unhealthy, response.Status)
+	assert.Equal(t, 1, len(response.Results))
+	assert.Equal(t, Unhealthy, response.Results[ShutdownCheck].Status)
+	assert.False(t, config.IsRunning())
+}
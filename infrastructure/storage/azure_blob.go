@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -108,14 +109,62 @@ func (ab *AzureBlob) UploadAsJson(ctx context.Context, fileNamePrefix, payload s
 		return
 	}
 
-	filename := fileNamePrefix + ".json"
-	fullpath := config.Get().AzureStorageUploadPath + "/" + config.Get().AzureStorageFallbackFolder + "/" + filename
+	fullpath := fallbackFolder() + fileNamePrefix + ".json"
 
 	elapsedTime, err = upload(ctx, ab, fullpath, payload)
 
 	return
 }
 
+// ListFallback lista os nomes, sem a extensão .json, dos boletos guardados no fallback
+func (ab *AzureBlob) ListFallback(ctx context.Context) ([]string, error) {
+	if err := ab.connect(); err != nil {
+		return nil, err
+	}
+
+	folder := fallbackFolder()
+	names := make([]string, 0)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		segment, err := ab.containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: folder})
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range segment.Segment.BlobItems {
+			if strings.HasSuffix(blob.Name, ".json") {
+				names = append(names, strings.TrimSuffix(strings.TrimPrefix(blob.Name, folder), ".json"))
+			}
+		}
+		marker = segment.NextMarker
+	}
+	return names, nil
+}
+
+// DownloadFallback retorna o conteúdo de um boleto guardado no fallback
+func (ab *AzureBlob) DownloadFallback(ctx context.Context, fileNamePrefix string) (string, error) {
+	if err := ab.connect(); err != nil {
+		return "", err
+	}
+
+	blobURL := ab.containerURL.NewBlockBlobURL(fallbackFolder() + fileNamePrefix + ".json")
+	response, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	body := response.Body(azblob.RetryReaderOptions{MaxRetryRequests: 20})
+	defer body.Close()
+
+	data := bytes.Buffer{}
+	if _, err = data.ReadFrom(body); err != nil {
+		return "", err
+	}
+	return data.String(), nil
+}
+
+func fallbackFolder() string {
+	return config.Get().AzureStorageUploadPath + "/" + config.Get().AzureStorageFallbackFolder + "/"
+}
+
 func upload(ctx context.Context, ab *AzureBlob, fullpath, payload string) (elapsedTime int64, err error) {
 	data := []byte(payload)
 
@@ -129,3 +178,13 @@ func upload(ctx context.Context, ab *AzureBlob, fullpath, payload string) (elaps
 
 	return
 }
+
+//Ping checks that the container is reachable with the configured credentials
+func (ab *AzureBlob) Ping(ctx context.Context) error {
+	if err := ab.connect(); err != nil {
+		return err
+	}
+
+	_, err := ab.containerURL.GetProperties(ctx, azblob.LeaseAccessConditions{})
+	return err
+}
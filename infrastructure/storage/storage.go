@@ -8,6 +8,9 @@ import (
 
 type IStorage interface {
 	UploadAsJson(ctx context.Context, fullpath, payload string) (totalElapsedTimeInMilliseconds int64, err error)
+	Ping(ctx context.Context) error
+	ListFallback(ctx context.Context) ([]string, error)
+	DownloadFallback(ctx context.Context, fileNamePrefix string) (string, error)
 }
 
 // GetClient factory storage
@@ -1,9 +1,11 @@
 package itau
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	. "github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/config"
@@ -11,7 +13,10 @@ import (
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tokenmanager"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
@@ -44,19 +49,29 @@ func (b bankItau) Log() *log.Log {
 	return b.log
 }
 
-func (b bankItau) GetTicket(boleto *models.BoletoRequest) (string, error) {
+func (b bankItau) GetTicket(ctx context.Context, boleto *models.BoletoRequest) (tokenmanager.Token, error) {
 	pipe := NewFlow()
 	url := config.Get().URLTicketItau
 	pipe.From("message://?source=inline", boleto, getRequestTicket(), tmpl.GetFuncMaps())
 	pipe.To("log://?type=request&url="+url, b.log)
-	duration := util.Duration(func() {
-		pipe.To(url, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutToken})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationToken)
+	tracing.InjectFlow(ctx, pipe)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationToken, b.log, pipe, func() {
+		duration = util.Duration(func() {
+			pipe.To(url, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutToken})
+		})
 	})
-	metrics.PushTimingMetric("itau-get-ticket-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return tokenmanager.Token{}, err
+	}
+	tracing.EndFlow(span, pipe)
+	metrics.PushBankTime("itau-get-ticket-boleto-time", b.GetBankNameIntegration(), metrics.OperationToken, duration.Seconds())
 	pipe.To("log://?type=response&url="+url, b.log)
 	ch := pipe.Choice()
 	ch.When(Header("status").IsEqualTo("200"))
-	ch.To("transform://?format=json", getTicketResponse(), `{{.access_token}}`, tmpl.GetFuncMaps())
+	ch.To("transform://?format=json", getTicketResponse(), `{{.access_token}}:::{{.expires_in}}`, tmpl.GetFuncMaps())
 	ch.When(Header("status").IsEqualTo("400"))
 	ch.To("transform://?format=json", getTicketResponse(), `{{.errorMessage}}`, tmpl.GetFuncMaps())
 	ch.To("set://?prop=body", errors.New(pipe.GetBody().(string)))
@@ -69,14 +84,15 @@ func (b bankItau) GetTicket(boleto *models.BoletoRequest) (string, error) {
 	ch.To("log://?type=request&url="+url, b.log).To("print://?msg=${body}").To("set://?prop=body", errors.New("integration error"))
 	switch t := pipe.GetBody().(type) {
 	case string:
-		return t, nil
+		parts := strings.Split(t, ":::")
+		return tokenmanager.NewToken(parts[0], parts[len(parts)-1]), nil
 	case error:
-		return "", t
+		return tokenmanager.Token{}, t
 	}
-	return "", nil
+	return tokenmanager.Token{}, nil
 }
 
-func (b bankItau) RegisterBoleto(input *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankItau) RegisterBoleto(ctx context.Context, input *models.BoletoRequest) (models.BoletoResponse, error) {
 	itauURL := config.Get().URLRegisterBoletoItau
 	fromResponse := getResponseItau()
 	fromResponseError := getResponseErrorItau()
@@ -86,10 +102,20 @@ func (b bankItau) RegisterBoleto(input *models.BoletoRequest) (models.BoletoResp
 	input.Title.BoletoType, input.Title.BoletoTypeCode = getBoletoType(input)
 	exec := NewFlow().From("message://?source=inline", input, inputTemplate, tmpl.GetFuncMaps())
 	exec.To("log://?type=request&url="+itauURL, b.log)
-	duration := util.Duration(func() {
-		exec.To(itauURL, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutRegister})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, exec)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, exec, func() {
+		duration = util.Duration(func() {
+			exec.To(itauURL, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutRegister})
+		})
 	})
-	metrics.PushTimingMetric("itau-register-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, exec)
+	metrics.PushBankTime("itau-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	b.log.Response(exec.GetBody().(string), itauURL, convertHeadertoLogEntry(exec.GetHeader()))
 
 	ch := exec.Choice()
@@ -132,17 +158,22 @@ func convertHeadertoLogEntry(header HeaderMap) log.LogEntry {
 	return log
 }
 
-func (b bankItau) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankItau) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	if ticket, err := b.GetTicket(boleto); err != nil {
+	// a cópia evita que a renovação do token em segundo plano leia o boleto alterado pelo registro
+	credentials := *boleto
+	clientID := tokenmanager.ClientKey(boleto.Authentication.Username, boleto.Authentication.Password)
+	ticket, err := tokenmanager.Get(ctx, b.GetBankNameIntegration(), clientID, b.log, func(ctx context.Context) (tokenmanager.Token, error) {
+		return b.GetTicket(ctx, &credentials)
+	})
+	if err != nil {
 		return models.BoletoResponse{Errors: errs}, err
-	} else {
-		boleto.Authentication.AuthorizationToken = ticket
 	}
-	return b.RegisterBoleto(boleto)
+	boleto.Authentication.AuthorizationToken = ticket
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankItau) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
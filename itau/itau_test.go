@@ -1,6 +1,7 @@
 package itau
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -25,7 +26,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	input := newStubBoletoRequestItau().Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -36,7 +37,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 
 	bank := New()
 
-	output, err := bank.ProcessBoleto(input)
+	output, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, err, "Não deve haver um erro")
 	test.AssertProcessBoletoFailed(t, output)
@@ -48,7 +49,7 @@ func TestProcessBoleto_WhenServiceRespondsFailedWithWrongContentAndStatusCodeIs5
 
 	bank := New()
 
-	_, errProcessBoleto := bank.ProcessBoleto(input)
+	_, errProcessBoleto := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertError(t, errProcessBoleto, models.BadGatewayError{})
 }
@@ -59,7 +60,7 @@ func TestProcessBoleto_WhenRequestHasInvalidAccountParameters_ShouldHasFailedBol
 
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -70,7 +71,7 @@ func TestProcessBoleto_WhenRequestHasInvalidUserNameParameter_ShouldHasFailedBol
 
 	bank := New()
 
-	_, err := bank.ProcessBoleto(input)
+	_, err := bank.ProcessBoleto(context.Background(), input)
 
 	assert.NotNil(t, err, "Deve ocorrer um erro")
 }
@@ -89,7 +90,7 @@ func TestTemplateResponse_WhenRequestHasSpecialCharacter_ShouldBeParsedSuccessfu
 	input := newStubBoletoRequestItau().WithBuyerName("Usuario \tTeste").Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
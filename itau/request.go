@@ -102,6 +102,7 @@ const ticketResponse = `{
     "codigo":"{{errorCode}}",
     "mensagem":"{{errorMessage}}",
     "access_token": "{{access_token}}",
+    "expires_in": "{{expires_in}}",
     "Message":"{{errorMessage}}"
 }`
 
@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+//Group Executa as rotinas em segundo plano da aplicação, como os refreshs periódicos, e as encerra juntas no desligamento
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	mu      sync.Mutex
+	stops   []func()
+}
+
+//NewGroup Cria um Group vazio
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+//Go Executa a rotina em uma goroutine. O contexto recebido é cancelado no Stop, quando a rotina deve retornar
+func (g *Group) Go(run func(ctx context.Context)) {
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+		run(g.ctx)
+	}()
+}
+
+//OnStop Registra a função de encerramento de uma rotina que não recebe contexto, como a observação da configuração
+func (g *Group) OnStop(stop func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stops = append(g.stops, stop)
+}
+
+//Stop Cancela as rotinas e aguarda o retorno delas até o fim do contexto
+func (g *Group) Stop(ctx context.Context) error {
+	g.cancel()
+
+	g.mu.Lock()
+	stops := g.stops
+	g.stops = nil
+	g.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
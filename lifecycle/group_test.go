@@ -0,0 +1,43 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStop_CancelsAndWaitsRoutines(t *testing.T) {
+	g := NewGroup()
+	finished := make(chan struct{})
+	stopped := false
+
+	g.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(finished)
+	})
+	g.OnStop(func() { stopped = true })
+
+	err := g.Stop(context.Background())
+
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+	select {
+	case <-finished:
+	default:
+		t.Fatal("a rotina deveria ter terminado antes do retorno do Stop")
+	}
+}
+
+func TestStop_WhenRoutineIgnoresCancel_ReturnsAtDeadline(t *testing.T) {
+	g := NewGroup()
+	release := make(chan struct{})
+	defer close(release)
+	g.Go(func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, g.Stop(ctx))
+}
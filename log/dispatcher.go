@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mundipagg/boleto-api/metrics"
+)
+
+const (
+	//PolicyDrop Descarta a entrada quando a fila de logs está cheia
+	PolicyDrop = "drop"
+	//PolicyBlock Bloqueia quem está logando até haver espaço na fila
+	PolicyBlock = "block"
+
+	defaultQueueSize    = 10000
+	defaultQueueWorkers = 2
+)
+
+var dispatch *dispatcher
+
+// dispatcher entrega as entradas de log aos writers a partir de uma fila limitada, com um número fixo de workers
+type dispatcher struct {
+	mu      sync.RWMutex
+	closed  bool
+	block   bool
+	queue   chan func()
+	wg      sync.WaitGroup
+	dropped uint64
+}
+
+func newDispatcher(size, workers int, policy string) *dispatcher {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	if workers <= 0 {
+		workers = defaultQueueWorkers
+	}
+
+	d := &dispatcher{
+		block: strings.EqualFold(policy, PolicyBlock),
+		queue: make(chan func(), size),
+	}
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.run()
+	}
+	return d
+}
+
+func (d *dispatcher) run() {
+	defer d.wg.Done()
+	for write := range d.queue {
+		write()
+	}
+}
+
+// enqueue agenda a escrita. Com a fila cheia, a entrada é descartada ou aguarda, conforme a política configurada
+// Depois do Flush as escritas são feitas na própria goroutine, para não perder os logs do encerramento
+func (d *dispatcher) enqueue(write func()) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		write()
+		return
+	}
+
+	if d.block {
+		d.queue <- write
+		return
+	}
+
+	select {
+	case d.queue <- write:
+	default:
+		atomic.AddUint64(&d.dropped, 1)
+		metrics.PushLogDropped()
+	}
+}
+
+// flush fecha a fila e aguarda os workers escreverem as entradas pendentes ou o contexto expirar
+func (d *dispatcher) flush(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func enqueue(write func()) {
+	if dispatch == nil {
+		write()
+		return
+	}
+	dispatch.enqueue(write)
+}
+
+//Flush Escreve as entradas de log pendentes na fila. Deve ser chamado no encerramento da aplicação
+func Flush(ctx context.Context) error {
+	if dispatch == nil {
+		return nil
+	}
+	return dispatch.flush(ctx)
+}
+
+//Dropped Retorna o total de entradas descartadas por fila cheia
+func Dropped() uint64 {
+	if dispatch == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&dispatch.dropped)
+}
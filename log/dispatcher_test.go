@@ -0,0 +1,61 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher_DropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	var written int32
+	d := newDispatcher(1, 1, PolicyDrop)
+
+	d.enqueue(func() { <-release; atomic.AddInt32(&written, 1) })
+	time.Sleep(10 * time.Millisecond)
+	d.enqueue(func() { atomic.AddInt32(&written, 1) })
+	d.enqueue(func() { atomic.AddInt32(&written, 1) })
+	close(release)
+
+	assert.Nil(t, d.flush(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&written))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&d.dropped))
+}
+
+func TestDispatcher_BlocksWhenQueueIsFull(t *testing.T) {
+	var written int32
+	d := newDispatcher(1, 1, PolicyBlock)
+
+	for i := 0; i < 10; i++ {
+		d.enqueue(func() { time.Sleep(time.Millisecond); atomic.AddInt32(&written, 1) })
+	}
+
+	assert.Nil(t, d.flush(context.Background()))
+	assert.Equal(t, int32(10), atomic.LoadInt32(&written))
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&d.dropped))
+}
+
+func TestDispatcher_WritesSynchronouslyAfterFlush(t *testing.T) {
+	var written int32
+	d := newDispatcher(1, 1, PolicyDrop)
+	assert.Nil(t, d.flush(context.Background()))
+
+	d.enqueue(func() { atomic.AddInt32(&written, 1) })
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&written))
+}
+
+func TestDispatcher_FlushHonorsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	d := newDispatcher(1, 1, PolicyDrop)
+	d.enqueue(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, d.flush(ctx))
+}
@@ -39,9 +39,11 @@ type Log struct {
 }
 
 //Install instala o "servico" de log do SEQ
+//As entradas são escritas por um dispatcher com fila limitada, configurado por LOG_QUEUE_SIZE, LOG_QUEUE_WORKERS e LOG_QUEUE_POLICY
 func Install() {
 	configureTracer()
 	logger = tracer.GetLogger("boleto")
+	dispatch = newDispatcher(config.Get().LogQueueSize, config.Get().LogQueueWorkers, config.Get().LogQueuePolicy)
 }
 
 func formatter(message string) string {
@@ -61,7 +63,7 @@ func (l *Log) Request(content interface{}, url string, headers map[string]string
 		return
 	}
 
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Request", content)
 		props["Headers"] = headers
 		props["URL"] = url
@@ -70,7 +72,7 @@ func (l *Log) Request(content interface{}, url string, headers map[string]string
 		msg := formatter(fmt.Sprintf("to {BankName} (%s) | {Recipient}", action[len(action)-1]))
 
 		l.logger.Info(msg, props)
-	})()
+	})
 }
 
 //Response loga o response para algum banco
@@ -78,7 +80,7 @@ func (l *Log) Response(content interface{}, url string, params LogEntry) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 
 		action := strings.Split(url, "/")
 		msg := formatter(fmt.Sprintf("from {BankName} (%s) | {Recipient}", action[len(action)-1]))
@@ -90,7 +92,7 @@ func (l *Log) Response(content interface{}, url string, params LogEntry) {
 		}
 
 		l.logger.Info(msg, props)
-	})()
+	})
 }
 
 //RequestApplication loga o request que chega na boleto api
@@ -98,7 +100,7 @@ func (l *Log) RequestApplication(content interface{}, url string, headers map[st
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 
 		props := l.defaultProperties("Request", content)
 		props["Headers"] = headers
@@ -107,7 +109,7 @@ func (l *Log) RequestApplication(content interface{}, url string, headers map[st
 		msg := formatter("from {IPAddress} | {Recipient}")
 
 		l.logger.Info(msg, props)
-	})()
+	})
 }
 
 //ResponseApplication loga o response que sai da boleto api
@@ -115,7 +117,7 @@ func (l *Log) ResponseApplication(content interface{}, url string, errorCode str
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Response", content)
 		props["URL"] = url
 
@@ -126,7 +128,7 @@ func (l *Log) ResponseApplication(content interface{}, url string, errorCode str
 		msg := formatter("{Operation} | {Recipient}")
 
 		l.logger.Info(msg, props)
-	})()
+	})
 }
 
 //ResponseApplicationFatal loga o response que sai do panic recovery
@@ -134,7 +136,7 @@ func (l *Log) ResponseApplicationFatal(content interface{}, url string, errorCod
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Response", content)
 		props["URL"] = url
 
@@ -145,7 +147,7 @@ func (l *Log) ResponseApplicationFatal(content interface{}, url string, errorCod
 		msg := formatter("{Operation} | {Recipient}")
 
 		l.logger.Fatal(msg, props)
-	})()
+	})
 }
 
 //Info loga mensagem do level INFO
@@ -153,7 +155,7 @@ func (l *Log) Info(msg string) {
 	if config.Get().DisableLog {
 		return
 	}
-	go l.logger.Info(msg, nil)
+	enqueue(func() { l.logger.Info(msg, nil) })
 }
 
 // InfoWithParams cria log generico para um map
@@ -161,13 +163,13 @@ func (l *Log) InfoWithParams(msg, msgType string, params map[string]interface{})
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties(msgType, "")
 		for k, v := range params {
 			props[k] = v
 		}
 		l.logger.Info(formatter(msg), props)
-	})()
+	})
 }
 
 // InfoWithBasic  Cria um log de information com as informações básicas do log
@@ -175,13 +177,13 @@ func (l *Log) InfoWithBasic(msg, msgType string, params map[string]interface{})
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.basicProperties(msgType)
 		for k, v := range params {
 			props[k] = v
 		}
 		l.logger.Info(formatter(msg), props)
-	})()
+	})
 }
 
 //Warn loga mensagem do leve Warning
@@ -189,24 +191,24 @@ func (l *Log) Warn(content interface{}, msg string) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Warning", content)
 		m := formatter(msg)
 
 		l.logger.Warn(m, props)
-	})()
+	})
 }
 
 func (l *Log) Error(content interface{}, msg string) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Error", content)
 		m := formatter(msg)
 
 		l.logger.Error(m, props)
-	})()
+	})
 }
 
 // ErrorWithBasic Cria um log de erro com as informações básicas do log
@@ -214,11 +216,11 @@ func (l *Log) ErrorWithBasic(msg, msgType string, err error) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.basicProperties(msgType)
 		props["Error"] = fmt.Sprintf("%v", err)
 		l.logger.Error(formatter(msg), props)
-	})()
+	})
 }
 
 // FallbackErrorWithBasic Cria um log de erro com as informações básicas do log de fallback
@@ -226,11 +228,11 @@ func (l *Log) ErrorWithContent(msg, msgType string, err error, content interface
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties(msgType, content)
 		props["Error"] = fmt.Sprintf("%v", err)
 		l.logger.Error(formatter(msg), props)
-	})()
+	})
 }
 
 // Fatal loga erros da aplicação
@@ -238,12 +240,12 @@ func (l *Log) Fatal(content interface{}, msg string) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.defaultProperties("Fatal", content)
 		m := formatter(msg)
 
 		l.logger.Fatal(m, props)
-	})()
+	})
 }
 
 // ErrorBasicWithContent Cria um log de erro com as informações básicas e o conteúdo
@@ -251,37 +253,38 @@ func (l *Log) ErrorBasicWithContent(msg, msgType string, content interface{}) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.basicProperties(msgType)
 		props["Content"] = content
 		l.logger.Error(formatter(msg), props)
-	})()
+	})
 }
 
 //InitRobot loga o inicio da execução do robô de recovery
 func (l *Log) InitRobot(totalRecords int) {
 	msg := formatter("- Starting execution")
-	go func() {
+	enqueue(func() {
 		props := defaultRobotProperties("Execute", l.Operation, "")
 		props["TotalRecords"] = totalRecords
 		logger.Info(msg, props)
-	}()
+	})
 }
 
 //ResumeRobot loga um resumo de Recovery do robô de recovery
 func (l *Log) ResumeRobot(key string) {
 	msg := formatter(key)
-	go func() {
+	enqueue(func() {
 		props := defaultRobotProperties("RecoveryBoleto", l.Operation, key)
 		props["RequestKey"] = l.RequestKey
 		logger.Info(msg, props)
-	}()
+	})
 }
 
 //EndRobot loga o fim da execução do robô de recovery
 func (l *Log) EndRobot() {
 	msg := formatter("- Finishing execution")
-	go logger.Info(msg, defaultRobotProperties("Finish", l.Operation, ""))
+	props := defaultRobotProperties("Finish", l.Operation, "")
+	enqueue(func() { logger.Info(msg, props) })
 }
 
 func (l *Log) defaultProperties(messageType string, content interface{}) LogEntry {
@@ -311,7 +314,7 @@ func (l *Log) GetBoleto(content interface{}, msgType string) {
 	if config.Get().DisableLog {
 		return
 	}
-	go (func() {
+	enqueue(func() {
 		props := l.getBoletoProperties(msgType, content)
 
 		switch msgType {
@@ -322,7 +325,7 @@ func (l *Log) GetBoleto(content interface{}, msgType string) {
 		default:
 			l.logger.Info(formatter(SuccessGetBoletoMessage), props)
 		}
-	})()
+	})
 }
 
 func (l *Log) basicProperties(messageType string) LogEntry {
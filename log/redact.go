@@ -0,0 +1,196 @@
+package log
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mralves/tracer"
+)
+
+//RedactedValue Valor gravado no lugar dos campos sensíveis
+const RedactedValue = "***"
+
+//DefaultRedactedFields Caminhos mascarados por padrão: credenciais, tokens, certificados e documentos pessoais
+var DefaultRedactedFields = []string{
+	"Authentication",
+	"Authorization",
+	"*password*",
+	"*secret*",
+	"*token*",
+	"ticket",
+	"client_assertion",
+	"accessKey",
+	"*certificate*",
+	"PemData",
+	"*privateKey*",
+	"Document.Number",
+	"cpf",
+	"cnpj",
+	"numeroInscricao*",
+}
+
+//Redactor Mascara os valores sensíveis das entradas de log
+//Cada caminho é uma sequência de campos separados por ponto, comparada com o final do caminho do valor na entrada,
+//sem diferenciar maiúsculas e aceitando curingas por segmento. "Document.Number" mascara Buyer.Document.Number e
+//Recipient.Document.Number. Campos de itens de listas usam o mesmo caminho da lista
+type Redactor struct {
+	paths [][]string
+	xml   *regexp.Regexp
+}
+
+//NewRedactor Cria um Redactor com os caminhos padrão ajustados pela configuração LOG_REDACTED_FIELDS
+//A configuração é uma lista separada por vírgulas; caminhos iniciados por "-" removem um caminho padrão
+func NewRedactor(spec string) *Redactor {
+	fields := make([]string, 0, len(DefaultRedactedFields))
+	fields = append(fields, DefaultRedactedFields...)
+
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		switch {
+		case f == "":
+		case strings.HasPrefix(f, "-"):
+			fields = removeField(fields, f[1:])
+		default:
+			fields = append(fields, f)
+		}
+	}
+
+	r := &Redactor{}
+	tags := make([]string, 0, len(fields))
+	for _, f := range fields {
+		segments := strings.Split(strings.ToLower(f), ".")
+		r.paths = append(r.paths, segments)
+		// em XML só é possível identificar o elemento pelo nome, sem o caminho completo
+		if len(segments) == 1 {
+			tags = append(tags, globToRegexp(segments[0]))
+		}
+	}
+
+	if len(tags) > 0 {
+		r.xml = regexp.MustCompile(`(?is)(<(?:[\w-]+:)?(?:` + strings.Join(tags, "|") + `)(?:\s[^>]*)?>)[^<]*(</)`)
+	}
+	return r
+}
+
+//RedactArgs Retorna uma cópia dos argumentos de uma entrada com os campos sensíveis mascarados
+func (r *Redactor) RedactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, a := range args {
+		redacted[i] = r.redact(nil, a)
+	}
+	return redacted
+}
+
+func (r *Redactor) redact(p []string, value interface{}) interface{} {
+	if len(p) > 0 && r.matches(p) {
+		return RedactedValue
+	}
+
+	switch v := value.(type) {
+	case nil, bool, int, int64, float64:
+		return v
+	case error:
+		return v.Error()
+	case string:
+		return r.redactString(p, v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = r.redact(append(p[:len(p):len(p)], k), item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = r.redact(p, item)
+		}
+		return result
+	}
+
+	normalized, ok := normalize(value)
+	if !ok {
+		return value
+	}
+	return r.redact(p, normalized)
+}
+
+// redactString mascara conteúdos JSON e XML enviados ou recebidos dos bancos
+func (r *Redactor) redactString(p []string, s string) string {
+	trimmed := strings.TrimSpace(s)
+
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var content interface{}
+		if err := json.Unmarshal([]byte(trimmed), &content); err == nil {
+			if b, err := json.Marshal(r.redact(p, content)); err == nil {
+				return string(b)
+			}
+		}
+	}
+
+	if r.xml != nil && strings.HasPrefix(trimmed, "<") {
+		return r.xml.ReplaceAllString(s, "${1}"+RedactedValue+"${2}")
+	}
+	return s
+}
+
+func (r *Redactor) matches(p []string) bool {
+	for _, pattern := range r.paths {
+		if len(pattern) > len(p) {
+			continue
+		}
+
+		offset := len(p) - len(pattern)
+		matched := true
+		for i, segment := range pattern {
+			if ok, _ := path.Match(segment, strings.ToLower(p[offset+i])); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize converte structs, ponteiros e mapas tipados na representação JSON genérica
+func normalize(value interface{}) (interface{}, bool) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, false
+	}
+	return normalized, true
+}
+
+func removeField(fields []string, field string) []string {
+	result := fields[:0]
+	for _, f := range fields {
+		if !strings.EqualFold(f, field) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func globToRegexp(glob string) string {
+	return strings.ReplaceAll(regexp.QuoteMeta(glob), `\*`, `[\w-]*`)
+}
+
+//Redacted Writer que mascara os campos sensíveis antes de repassar a entrada
+type Redacted struct {
+	tracer.Writer
+	redactor *Redactor
+}
+
+func (r *Redacted) Write(entry tracer.Entry) {
+	entry.Args = r.redactor.RedactArgs(entry.Args)
+	r.Writer.Write(entry)
+}
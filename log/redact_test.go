@@ -0,0 +1,125 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mralves/tracer"
+	"github.com/stretchr/testify/assert"
+)
+
+type document struct {
+	Type   string `json:"type"`
+	Number string `json:"number"`
+}
+
+type request struct {
+	Authentication map[string]string `json:"authentication"`
+	Buyer          struct {
+		Name     string   `json:"name"`
+		Document document `json:"document"`
+	} `json:"buyer"`
+}
+
+type recordingWriter struct {
+	entries []tracer.Entry
+}
+
+func (w *recordingWriter) Write(entry tracer.Entry) {
+	w.entries = append(w.entries, entry)
+}
+
+func TestRedactor_MasksDefaultFields(t *testing.T) {
+	r := NewRedactor("")
+	req := request{Authentication: map[string]string{"Username": "user", "Password": "pass"}}
+	req.Buyer.Name = "Fulano"
+	req.Buyer.Document = document{Type: "CPF", Number: "12345678901"}
+
+	args := r.RedactArgs([]interface{}{LogEntry{
+		"Content": req,
+		"Headers": map[string]string{"Authorization": "Bearer abc", "Content-Type": "application/json"},
+		"Error":   errors.New("timeout"),
+	}})
+
+	props := args[0].(map[string]interface{})
+	content := props["Content"].(map[string]interface{})
+	buyer := content["buyer"].(map[string]interface{})
+	headers := props["Headers"].(map[string]interface{})
+
+	assert.Equal(t, RedactedValue, content["authentication"])
+	assert.Equal(t, "Fulano", buyer["name"])
+	assert.Equal(t, RedactedValue, buyer["document"].(map[string]interface{})["number"])
+	assert.Equal(t, "CPF", buyer["document"].(map[string]interface{})["type"])
+	assert.Equal(t, RedactedValue, headers["Authorization"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+	assert.Equal(t, "timeout", props["Error"])
+}
+
+func TestRedactor_MasksSerializedContent(t *testing.T) {
+	r := NewRedactor("")
+
+	args := r.RedactArgs([]interface{}{LogEntry{
+		"Json": `{"access_token":"abc","expires_in":3600}`,
+		"Xml":  `<soap:Body><ns:cnpj>12345678000199</ns:cnpj><valor>10</valor></soap:Body>`,
+		"Text": "registro efetuado",
+	}})
+
+	props := args[0].(map[string]interface{})
+	assert.Equal(t, `{"access_token":"***","expires_in":3600}`, props["Json"])
+	assert.Equal(t, `<soap:Body><ns:cnpj>***</ns:cnpj><valor>10</valor></soap:Body>`, props["Xml"])
+	assert.Equal(t, "registro efetuado", props["Text"])
+}
+
+func TestRedactor_AppliesConfiguredFields(t *testing.T) {
+	r := NewRedactor("-Authorization, Buyer.Name")
+
+	args := r.RedactArgs([]interface{}{LogEntry{
+		"Headers": map[string]string{"Authorization": "Bearer abc"},
+		"Buyer":   map[string]string{"Name": "Fulano"},
+		"Name":    "boleto-api",
+	}})
+
+	props := args[0].(map[string]interface{})
+	assert.Equal(t, "Bearer abc", props["Headers"].(map[string]interface{})["Authorization"])
+	assert.Equal(t, RedactedValue, props["Buyer"].(map[string]interface{})["Name"])
+	assert.Equal(t, "boleto-api", props["Name"])
+}
+
+func TestRedacted_DoesNotChangeOriginalArgs(t *testing.T) {
+	inner := &recordingWriter{}
+	w := &Redacted{Writer: inner, redactor: NewRedactor("")}
+	props := LogEntry{"Password": "secret"}
+
+	w.Write(tracer.Entry{Message: "{Password}", Args: []interface{}{props}})
+
+	assert.Equal(t, "secret", props["Password"])
+	assert.Equal(t, RedactedValue, inner.entries[0].Args[0].(map[string]interface{})["Password"])
+}
+
+func TestStdoutWriter_WritesJSONLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewStdoutWriter(out, tracer.Informational, LogEntry{"Application": "boleto-api"})
+
+	w.Write(tracer.Entry{
+		Level:         tracer.Informational,
+		Message:       "Request {Operation}",
+		Args:          []interface{}{LogEntry{"Operation": "RegisterBoleto"}},
+		Time:          time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		TransactionId: "request-key",
+	})
+	w.Write(tracer.Entry{Level: tracer.Debug, Message: "ignored"})
+
+	var line map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "2021-01-02T03:04:05Z", line["Timestamp"])
+	assert.Equal(t, tracer.LevelNames[tracer.Informational], line["Level"])
+	assert.Equal(t, "Request RegisterBoleto", line["Message"])
+	assert.Equal(t, "Request {Operation}", line["MessageTemplate"])
+
+	props := line["Properties"].(map[string]interface{})
+	assert.Equal(t, "boleto-api", props["Application"])
+	assert.Equal(t, "request-key", props["RequestKey"])
+}
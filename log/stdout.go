@@ -0,0 +1,90 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/mralves/tracer"
+)
+
+var propertyPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+//StdoutWriter Escreve cada entrada de log como uma linha JSON, para execução local e coletores de stdout
+type StdoutWriter struct {
+	mu                sync.Mutex
+	out               io.Writer
+	minimumLevel      uint8
+	defaultProperties LogEntry
+}
+
+type stdoutEntry struct {
+	Timestamp       string   `json:"Timestamp"`
+	Level           string   `json:"Level"`
+	Message         string   `json:"Message"`
+	MessageTemplate string   `json:"MessageTemplate"`
+	Properties      LogEntry `json:"Properties"`
+}
+
+//NewStdoutWriter Cria um StdoutWriter que escreve em out as entradas até o nível minimumLevel
+func NewStdoutWriter(out io.Writer, minimumLevel uint8, defaultProperties LogEntry) *StdoutWriter {
+	return &StdoutWriter{
+		out:               out,
+		minimumLevel:      minimumLevel,
+		defaultProperties: defaultProperties,
+	}
+}
+
+func (w *StdoutWriter) Write(entry tracer.Entry) {
+	if entry.Level > w.minimumLevel {
+		return
+	}
+
+	props := LogEntry{}
+	for k, v := range w.defaultProperties {
+		props[k] = v
+	}
+	for _, arg := range entry.Args {
+		if m, ok := arg.(LogEntry); ok {
+			for k, v := range m {
+				props[k] = v
+			}
+		}
+	}
+	if entry.TransactionId != "" {
+		props["RequestKey"] = entry.TransactionId
+	}
+
+	line, err := json.Marshal(stdoutEntry{
+		Timestamp:       entry.Time.UTC().Format(time.RFC3339Nano),
+		Level:           tracer.LevelNames[entry.Level],
+		Message:         renderMessage(entry.Message, props),
+		MessageTemplate: entry.Message,
+		Properties:      props,
+	})
+	if err != nil {
+		line, _ = json.Marshal(stdoutEntry{
+			Timestamp:       entry.Time.UTC().Format(time.RFC3339Nano),
+			Level:           tracer.LevelNames[entry.Level],
+			Message:         fmt.Sprintf("could not serialize log entry: %v", err),
+			MessageTemplate: entry.Message,
+		})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.out.Write(append(line, '\n'))
+}
+
+// renderMessage substitui as propriedades {Nome} do template pelos seus valores
+func renderMessage(template string, props LogEntry) string {
+	return propertyPattern.ReplaceAllStringFunc(template, func(match string) string {
+		if v, ok := props[match[1:len(match)-1]]; ok {
+			return fmt.Sprint(v)
+		}
+		return match
+	})
+}
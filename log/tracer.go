@@ -2,9 +2,12 @@ package log
 
 import (
 	"fmt"
+	"os"
 
 	"regexp"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mundipagg/boleto-api/config"
@@ -31,14 +34,63 @@ func (s *Safe) Write(entry tracer.Entry) {
 	s.Writer.Write(entry)
 }
 
+// writerKeys são as configurações usadas na criação dos writers, que são recriados quando alguma delas muda
+var writerKeys = []string{
+	"SEQ_URL", "SEQ_API_KEY", "SEQ_ENABLED", "SPLUNK_SOURCE_TYPE", "SPLUNK_SOURCE_INDEX", "SPLUNK_ENABLED", "SPLUNK_ADDRESS",
+	"SPLUNK_KEY", "WAIT_SECONDS_RETENTATION_LOG", "LOG_STDOUT_ENABLED", "LOG_REDACTED_FIELDS", "ENVIRONMENT", "BUILD_VERSION",
+}
+
+var (
+	registerOnce sync.Once
+	current      atomic.Value
+
+	// remoteWriters guarda os writers do Seq e do Splunk pela configuração com que foram criados
+	// Esses writers não podem ser encerrados, pois mantêm goroutines e conexões próprias sem um Close,
+	// então o Reconfigure reaproveita o writer de uma configuração em vez de descartá-lo e criar outro
+	remoteWritersMu sync.Mutex
+	remoteWriters   = map[string]tracer.Writer{}
+)
+
+// switchWriter é o único writer registrado no tracer, que não permite remover writers
+// Ele repassa as entradas aos writers atuais, que podem ser trocados por Reconfigure
+type switchWriter struct{}
+
+func (switchWriter) Write(entry tracer.Entry) {
+	for _, writer := range current.Load().([]tracer.Writer) {
+		writer.Write(entry)
+	}
+}
+
 func configureTracer() {
-	var writers []tracer.Writer
 	tracer.DefaultContext.OverwriteChildren()
+	current.Store(buildWriters())
+	registerOnce.Do(func() {
+		tracer.RegisterWriter(switchWriter{})
+	})
+}
+
+//Reconfigure Recria os writers de log quando alguma das chaves alteradas é usada por eles
+//As entradas já enfileiradas nos writers anteriores continuam sendo enviadas por eles. Os writers do Seq e do Splunk
+//só são trocados quando a configuração deles muda, e cada configuração usa sempre o mesmo writer
+func Reconfigure(changed []string) bool {
+	for _, key := range changed {
+		for _, writerKey := range writerKeys {
+			if key == writerKey {
+				current.Store(buildWriters())
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func buildWriters() []tracer.Writer {
+	var writers []tracer.Writer
 
 	WaitTimeLog := toInt(config.Get().WaitSecondsRetentationLog, 1)
 
 	if config.Get().SeqEnabled == true {
-		writers = append(writers, &Safe{seq.New(seq.Config{
+		seqConfig := seq.Config{
 			Timeout:      3 * time.Second,
 			MinimumLevel: tracer.Debug,
 			DefaultProperties: LogEntry{
@@ -56,11 +108,12 @@ func configureTracer() {
 				BackOff:    time.Duration(WaitTimeLog) * time.Second,
 				Expiration: 5 * time.Second,
 			},
-		})})
+		}
+		writers = append(writers, remoteWriter(fmt.Sprintf("seq%+v", seqConfig), func() tracer.Writer { return seq.New(seqConfig) }))
 	}
 
 	if config.Get().SplunkEnabled == true {
-		writers = append(writers, &Safe{splunk.New(splunk.Config{
+		splunkConfig := splunk.Config{
 			Timeout:      3 * time.Second,
 			MinimumLevel: tracer.Debug,
 			ConfigLineLog: LogEntry{
@@ -90,12 +143,39 @@ func configureTracer() {
 				BackOff:    time.Duration(WaitTimeLog) * time.Second,
 				Expiration: 5 * time.Second,
 			},
-		})})
+		}
+		writers = append(writers, remoteWriter(fmt.Sprintf("splunk%+v", splunkConfig), func() tracer.Writer { return splunk.New(splunkConfig) }))
 	}
 
+	if config.Get().LogStdoutEnabled {
+		writers = append(writers, NewStdoutWriter(os.Stdout, tracer.Debug, LogEntry{
+			"Application":  config.Get().ApplicationName,
+			"Environment":  config.Get().Environment,
+			"Domain":       config.Get().SEQDomain,
+			"MachineName":  config.Get().MachineName,
+			"BuildVersion": config.Get().BuildVersion,
+		}))
+	}
+
+	redactor := NewRedactor(config.Get().LogRedactedFields)
+	safe := make([]tracer.Writer, 0, len(writers))
 	for _, writer := range writers {
-		tracer.RegisterWriter(writer)
+		safe = append(safe, &Safe{&Redacted{Writer: writer, redactor: redactor}})
+	}
+	return safe
+}
+
+// remoteWriter retorna o writer já criado com a mesma configuração ou cria um novo
+func remoteWriter(key string, create func() tracer.Writer) tracer.Writer {
+	remoteWritersMu.Lock()
+	defer remoteWritersMu.Unlock()
+
+	if writer, ok := remoteWriters[key]; ok {
+		return writer
 	}
+	writer := create()
+	remoteWriters[key] = writer
+	return writer
 }
 
 func toInt(str string, defaultValue ...int) int {
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mralves/tracer"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func unwrapWriter(w tracer.Writer) tracer.Writer {
+	return w.(*Safe).Writer.(*Redacted).Writer
+}
+
+func TestBuildWriters_ReusesSeqWriterWhileItsConfigurationIsTheSame(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("SEQ_ENABLED", "true")
+	os.Setenv("SEQ_URL", "http://seq-one:5341")
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+
+	first := buildWriters()
+	os.Setenv("LOG_REDACTED_FIELDS", "secret")
+	config.Install(true, false, true)
+	second := buildWriters()
+
+	assert.Equal(t, 1, len(first))
+	assert.Same(t, unwrapWriter(first[0]), unwrapWriter(second[0]), "o writer do Seq não deve ser recriado quando só o redactor muda")
+
+	os.Setenv("SEQ_URL", "http://seq-two:5341")
+	config.Install(true, false, true)
+	changed := buildWriters()
+	assert.NotSame(t, unwrapWriter(first[0]), unwrapWriter(changed[0]))
+
+	os.Setenv("SEQ_URL", "http://seq-one:5341")
+	config.Install(true, false, true)
+	restored := buildWriters()
+	assert.Same(t, unwrapWriter(first[0]), unwrapWriter(restored[0]), "voltar a uma configuração anterior reaproveita o writer dela")
+}
@@ -20,6 +20,7 @@ var (
 	disableLog   = flag.Bool("nolog", false, "-nolog disable seq log")
 	airPlaneMode = flag.Bool("airplane-mode", false, "-airplane-mode run api in dev, mock and nolog mode")
 	mockOnly     = flag.Bool("mockonly", false, "-mockonly run just mock service")
+	migrate      = flag.String("migrate", "", "-migrate encrypt-personal-data run a data migration and exit")
 )
 
 func init() {
@@ -52,6 +53,13 @@ func main() {
 			params.MockMode = *mockMode
 			env = strconv.FormatBool(params.DevMode)
 		}
+		if *migrate != "" {
+			if err := app.Migrate(params, *migrate); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
 		logo(env)
 		app.Run(params)
 
@@ -0,0 +1,36 @@
+package metrics
+
+// influxSink publica as métricas no InfluxDB mantendo as tags usadas pelos dashboards existentes
+type influxSink struct{}
+
+func (influxSink) RequestTime(method, route string, status int, seconds float64) {
+	PushTimingMetric("request-time", seconds)
+}
+
+func (influxSink) BankTime(tag, bank, operation string, seconds float64) {
+	PushTimingMetric(tag, seconds)
+}
+
+func (influxSink) BankStatus(bank string, status int) {
+	PushBusinessMetric(bank+"-status", status)
+}
+
+func (influxSink) BadRequest(bank string) {
+	PushBusinessMetric(bank+"-bad-request", 1)
+}
+
+func (influxSink) Fallback(path string) {
+	PushBusinessMetric("fallback-"+path, 1)
+}
+
+func (influxSink) LogDropped() {
+	PushBusinessMetric("log-dropped", 1)
+}
+
+func (influxSink) BreakerState(bank string, state int) {
+	PushBusinessMetric(bank+"-breaker-state", state)
+}
+
+func (influxSink) CertificateExpiry(name string, days float64) {
+	PushBusinessMetric(name+"-certificate-expiry-days", days)
+}
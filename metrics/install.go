@@ -1,12 +1,19 @@
 package metrics
 
 import (
+	"net/http"
+
+	"github.com/PMoneda/telemetry"
 	"github.com/PMoneda/telemetry/registry"
 	"github.com/mundipagg/boleto-api/config"
 )
 
-//Install Inicia telemetria
+var prometheusSink *PrometheusSink
+
+//Install Inicia telemetria nos destinos habilitados: InfluxDB (ENABLE_METRICS) e Prometheus (ENABLE_PROMETHEUS)
 func Install() {
+	ResetSinks()
+
 	if config.Get().EnableMetrics {
 		cnf := registry.Config{}
 		cnf.Host = config.Get().InfluxDBHost
@@ -14,5 +21,32 @@ func Install() {
 		InstallRuntime(cnf)
 		InstallTimingMetrics(cnf)
 		InstallBusinessMetrics(cnf)
+		AddSink(influxSink{})
+	}
+
+	if config.Get().EnablePrometheus {
+		prometheusSink = NewPrometheusSink()
+		AddSink(prometheusSink)
+	}
+}
+
+//Handler Retorna o handler do endpoint /metrics, que responde 404 quando o Prometheus está desabilitado
+func Handler() http.Handler {
+	if prometheusSink == nil {
+		return http.NotFoundHandler()
+	}
+	return prometheusSink.Handler()
+}
+
+//Flush Envia as métricas pendentes do InfluxDB. O Prometheus é lido pelo /metrics e não tem buffer
+func Flush() error {
+	for _, t := range []*telemetry.Telemetry{timing, business} {
+		if t == nil {
+			continue
+		}
+		if err := t.Flush(); err != nil {
+			return err
+		}
 	}
+	return nil
 }
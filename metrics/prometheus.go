@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "boleto_api"
+
+// PrometheusSink publica as métricas em um registry do Prometheus, exposto por Handler
+type PrometheusSink struct {
+	registry    *prometheus.Registry
+	requestTime *prometheus.HistogramVec
+	bankTime    *prometheus.HistogramVec
+	bankStatus  *prometheus.CounterVec
+	badRequest  *prometheus.CounterVec
+	fallback    *prometheus.CounterVec
+	logDropped  prometheus.Counter
+	breaker     *prometheus.GaugeVec
+	certificate *prometheus.GaugeVec
+}
+
+// NewPrometheusSink cria o sink do Prometheus com as métricas da aplicação e do runtime do Go
+func NewPrometheusSink() *PrometheusSink {
+	s := &PrometheusSink{
+		registry: prometheus.NewRegistry(),
+		requestTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the API requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		bankTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "bank_request_duration_seconds",
+			Help:      "Duration of the calls to the bank integrations.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+		}, []string{"bank", "operation"}),
+		bankStatus: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "register_responses_total",
+			Help:      "Boleto registration responses by bank and status code.",
+		}, []string{"bank", "status"}),
+		badRequest: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "register_bad_requests_total",
+			Help:      "Boleto registrations refused by invalid data, by bank.",
+		}, []string{"bank"}),
+		fallback: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fallback_total",
+			Help:      "Registered boletos persisted through a fallback path.",
+		}, []string{"path"}),
+		logDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "log_dropped_total",
+			Help:      "Log entries dropped because the log queue was full.",
+		}),
+		breaker: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bank_circuit_state",
+			Help:      "Circuit breaker state by bank: 0 closed, 1 half-open, 2 open.",
+		}, []string{"bank"}),
+		certificate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "certificate_expiry_days",
+			Help:      "Days until the certificate expires, negative when already expired.",
+		}, []string{"certificate"}),
+	}
+
+	s.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		s.requestTime,
+		s.bankTime,
+		s.bankStatus,
+		s.badRequest,
+		s.fallback,
+		s.logDropped,
+		s.breaker,
+		s.certificate,
+	)
+	return s
+}
+
+// Handler expõe as métricas no formato de exposição do Prometheus
+func (s *PrometheusSink) Handler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{})
+}
+
+func (s *PrometheusSink) RequestTime(method, route string, status int, seconds float64) {
+	s.requestTime.WithLabelValues(method, route, strconv.Itoa(status)).Observe(seconds)
+}
+
+func (s *PrometheusSink) BankTime(tag, bank, operation string, seconds float64) {
+	s.bankTime.WithLabelValues(bank, operation).Observe(seconds)
+}
+
+func (s *PrometheusSink) BankStatus(bank string, status int) {
+	s.bankStatus.WithLabelValues(bank, strconv.Itoa(status)).Inc()
+}
+
+func (s *PrometheusSink) BadRequest(bank string) {
+	s.badRequest.WithLabelValues(bank).Inc()
+}
+
+func (s *PrometheusSink) Fallback(path string) {
+	s.fallback.WithLabelValues(path).Inc()
+}
+
+func (s *PrometheusSink) LogDropped() {
+	s.logDropped.Inc()
+}
+
+func (s *PrometheusSink) BreakerState(bank string, state int) {
+	s.breaker.WithLabelValues(bank).Set(float64(state))
+}
+
+func (s *PrometheusSink) CertificateExpiry(name string, days float64) {
+	s.certificate.WithLabelValues(name).Set(days)
+}
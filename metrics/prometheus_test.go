@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusSink_ExposesPublishedMetrics(t *testing.T) {
+	sink := NewPrometheusSink()
+	ResetSinks()
+	AddSink(sink)
+	defer ResetSinks()
+
+	PushRequestTime("POST", "/v2/boleto/register", 200, 0.3)
+	PushBankTime("bb-register-boleto-time", "BancoDoBrasil", OperationRegister, 1.2)
+	PushBankStatus("BancoDoBrasil", 200)
+	PushBadRequest("Caixa")
+	PushFallback(FallbackQueue)
+	PushLogDropped()
+	PushBreakerState("Itau", 2)
+	PushCertificateExpiry("santander-ssl", 12.5)
+
+	body := scrape(t, sink)
+
+	assert.Contains(t, body, `boleto_api_request_duration_seconds_count{method="POST",route="/v2/boleto/register",status="200"} 1`)
+	assert.Contains(t, body, `boleto_api_bank_request_duration_seconds_count{bank="BancoDoBrasil",operation="register"} 1`)
+	assert.Contains(t, body, `boleto_api_register_responses_total{bank="BancoDoBrasil",status="200"} 1`)
+	assert.Contains(t, body, `boleto_api_register_bad_requests_total{bank="Caixa"} 1`)
+	assert.Contains(t, body, `boleto_api_fallback_total{path="queue"} 1`)
+	assert.Contains(t, body, "boleto_api_log_dropped_total 1")
+	assert.Contains(t, body, `boleto_api_bank_circuit_state{bank="Itau"} 2`)
+	assert.Contains(t, body, `boleto_api_certificate_expiry_days{certificate="santander-ssl"} 12.5`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestPush_WithoutSinks_DoesNothing(t *testing.T) {
+	ResetSinks()
+
+	assert.NotPanics(t, func() {
+		PushRequestTime("GET", "/boleto", 200, 0.1)
+		PushFallback(FallbackBlob)
+	})
+}
+
+func scrape(t *testing.T, sink *PrometheusSink) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	sink.Handler().ServeHTTP(w, req)
+
+	body, err := ioutil.ReadAll(w.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
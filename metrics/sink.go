@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"sync"
+)
+
+const (
+	// OperationRegister identifica a chamada de registro de boleto no banco
+	OperationRegister = "register"
+	// OperationToken identifica a chamada de obtenção de token ou ticket no banco
+	OperationToken = "token"
+
+	// FallbackMongoFailure conta os boletos que não foram gravados no Mongo
+	FallbackMongoFailure = "mongo_failure"
+	// FallbackQueue conta os boletos enviados para a fila de recuperação
+	FallbackQueue = "queue"
+	// FallbackBlob conta os boletos gravados no blob storage quando a fila falha
+	FallbackBlob = "blob"
+	// FallbackBlobFailure conta os boletos que não puderam ser gravados em nenhum fallback
+	FallbackBlobFailure = "blob_failure"
+	// FallbackEncryptionFailure conta os boletos que não foram para nenhum fallback porque os dados pessoais não puderam ser cifrados
+	FallbackEncryptionFailure = "encryption_failure"
+)
+
+// Sink recebe as métricas publicadas pela aplicação
+// Cada destino de telemetria (InfluxDB, Prometheus) implementa um Sink e todos os habilitados recebem as métricas
+type Sink interface {
+	// RequestTime registra o tempo total de uma requisição à API
+	RequestTime(method, route string, status int, seconds float64)
+	// BankTime registra o tempo de uma chamada ao banco. A tag é o nome da métrica no InfluxDB
+	BankTime(tag, bank, operation string, seconds float64)
+	// BankStatus registra o status code retornado pela API no registro de boleto de um banco
+	BankStatus(bank string, status int)
+	// BadRequest registra um registro de boleto recusado por dados inválidos
+	BadRequest(bank string)
+	// Fallback registra o uso de um caminho de contingência na gravação do boleto
+	Fallback(path string)
+	// LogDropped registra uma entrada de log descartada por fila cheia
+	LogDropped()
+	// BreakerState registra o estado do circuit breaker de um banco: 0 fechado, 1 meio aberto, 2 aberto
+	BreakerState(bank string, state int)
+	// CertificateExpiry registra quantos dias faltam para um certificado expirar, negativo quando já expirou
+	CertificateExpiry(name string, days float64)
+}
+
+var (
+	sinksMu sync.RWMutex
+	sinks   []Sink
+)
+
+// AddSink habilita um destino de métricas
+func AddSink(s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks = append(sinks, s)
+}
+
+// ResetSinks remove todos os destinos de métricas habilitados
+func ResetSinks() {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks = nil
+}
+
+func forEachSink(f func(Sink)) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, s := range sinks {
+		f(s)
+	}
+}
+
+// PushRequestTime publica o tempo de uma requisição à API
+func PushRequestTime(method, route string, status int, seconds float64) {
+	forEachSink(func(s Sink) { s.RequestTime(method, route, status, seconds) })
+}
+
+// PushBankTime publica o tempo de uma chamada ao banco
+func PushBankTime(tag, bank, operation string, seconds float64) {
+	forEachSink(func(s Sink) { s.BankTime(tag, bank, operation, seconds) })
+}
+
+// PushBankStatus publica o status code do registro de boleto de um banco
+func PushBankStatus(bank string, status int) {
+	forEachSink(func(s Sink) { s.BankStatus(bank, status) })
+}
+
+// PushBadRequest publica um registro de boleto recusado por dados inválidos
+func PushBadRequest(bank string) {
+	forEachSink(func(s Sink) { s.BadRequest(bank) })
+}
+
+// PushFallback publica o uso de um caminho de contingência na gravação do boleto
+func PushFallback(path string) {
+	forEachSink(func(s Sink) { s.Fallback(path) })
+}
+
+// PushLogDropped publica o descarte de uma entrada de log por fila cheia
+func PushLogDropped() {
+	forEachSink(func(s Sink) { s.LogDropped() })
+}
+
+// PushBreakerState publica o estado do circuit breaker de um banco
+func PushBreakerState(bank string, state int) {
+	forEachSink(func(s Sink) { s.BreakerState(bank, state) })
+}
+
+// PushCertificateExpiry publica quantos dias faltam para um certificado expirar
+func PushCertificateExpiry(name string, days float64) {
+	forEachSink(func(s Sink) { s.CertificateExpiry(name, days) })
+}
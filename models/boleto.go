@@ -1,6 +1,7 @@
 package models
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/mundipagg/boleto-api/util"
@@ -27,6 +28,8 @@ type BoletoRequest struct {
 	Buyer          Buyer           `json:"buyer"`
 	BankNumber     BankNumber      `json:"bankNumber"`
 	RequestKey     string          `json:"requestKey,omitempty"`
+	// BankCredentials são o certificado e as credenciais do usuário autenticado no banco, que não são gravados
+	BankCredentials BankCredentials `json:"-" bson:"-"`
 }
 
 // BoletoResponse entidade de saída para o boleto
@@ -63,11 +66,50 @@ type BoletoView struct {
 	Barcode       string             `json:"barcode,omitempty"`
 	Barcode64     string             `json:"barcode64,omitempty"`
 	Links         []Link             `json:"links,omitempty"`
+	Status        string             `json:"status,omitempty"`
+	ServiceUser   string             `json:"serviceUser,omitempty"`
+	// Signature é a assinatura do link público usado para abrir o boleto, repetida no link do PDF da página
+	Signature *LinkSignature `bson:"-" json:"-"`
+	// Encryption é a chave que cifra os dados pessoais do boleto guardado, ela própria cifrada pela chave mestra
+	Encryption *EncryptedKey `bson:"encryption,omitempty" json:"encryption,omitempty"`
+	// BuyerDocumentHash é o índice cego do documento do comprador, usado na busca quando ele está cifrado
+	BuyerDocumentHash string `bson:"buyerdocumenthash,omitempty" json:"buyerDocumentHash,omitempty"`
+	// PaymentDate é quando o boleto foi marcado como pago, o início do prazo de retenção dos boletos pagos depois do vencimento
+	PaymentDate *time.Time `bson:"paymentdate,omitempty" json:"paymentDate,omitempty"`
+	// ErasedAt é quando os dados pessoais do comprador foram eliminados
+	ErasedAt *time.Time `bson:"erasedat,omitempty" json:"erasedAt,omitempty"`
+}
+
+//EncryptedKey Chave de dados de um boleto, cifrada pela chave mestra KeyID
+type EncryptedKey struct {
+	KeyID string `json:"keyId"`
+	Key   string `json:"key"`
+}
+
+const (
+	// BoletoStatusRegistered boleto registrado com sucesso no banco
+	BoletoStatusRegistered = "Registered"
+	// BoletoStatusCancelled boleto baixado/cancelado
+	BoletoStatusCancelled = "Cancelled"
+	// BoletoStatusPaid boleto liquidado
+	BoletoStatusPaid = "Paid"
+)
+
+// IsValidBoletoStatus verifica se o status informado é um dos status conhecidos de boleto
+func IsValidBoletoStatus(status string) bool {
+	switch status {
+	case BoletoStatusRegistered, BoletoStatusCancelled, BoletoStatusPaid:
+		return true
+	default:
+		return false
+	}
 }
 
 // NewBoletoView cria um novo objeto view de boleto a partir de um boleto request, codigo de barras e linha digitavel
-func NewBoletoView(boleto BoletoRequest, response BoletoResponse, bankName string) BoletoView {
+// O serviceUser é o usuário autenticado que registrou o boleto e passa a ser o dono dele
+func NewBoletoView(boleto BoletoRequest, response BoletoResponse, bankName, serviceUser string) BoletoView {
 	boleto.Authentication = Authentication{}
+	boleto.BankCredentials = BankCredentials{}
 	uid, _ := uuid.NewUUID()
 	id := primitive.NewObjectID()
 	view := BoletoView{
@@ -81,6 +123,8 @@ func NewBoletoView(boleto BoletoRequest, response BoletoResponse, bankName strin
 		OurNumber:     response.OurNumber,
 		BankNumber:    boleto.BankNumber.GetBoletoBankNumberAndDigit(),
 		CreateDate:    time.Now(),
+		Status:        BoletoStatusRegistered,
+		ServiceUser:   serviceUser,
 	}
 	view.GeneratePublicKey()
 	view.Links = view.CreateLinks()
@@ -90,11 +134,20 @@ func NewBoletoView(boleto BoletoRequest, response BoletoResponse, bankName strin
 	return view
 }
 
+//IsOwnedBy verifica se o boleto foi registrado pelo usuário
+func (b BoletoView) IsOwnedBy(serviceUser string) bool {
+	return serviceUser != "" && b.ServiceUser == serviceUser
+}
+
 //EncodeURL tranforma o boleto view na forma que será escrito na url
+//Com LINK_SIGNING_KEYS configurado o link é assinado e expira em LINK_TTL_IN_HOURS
 func (b *BoletoView) EncodeURL(format string) string {
 	idBson := b.ID.Hex()
 	url := fmt.Sprintf("%s?fmt=%s&id=%s&pk=%s", config.Get().AppURL, format, idBson, b.PublicKey)
 
+	if signature := SignLink(idBson, b.PublicKey, time.Now()); signature != nil {
+		url += signature.Query()
+	}
 	return url
 }
 
@@ -118,6 +171,22 @@ func (b BoletoView) ToMinifyJSON() string {
 	return util.MinifyString(b.ToJSON(), "application/json")
 }
 
+//RotateKeys Troca a chave secreta do boleto, invalidando todos os links públicos emitidos, e gera os novos links
+func (b *BoletoView) RotateKeys() {
+	uid, _ := uuid.NewUUID()
+	b.SecretKey = uid.String()
+	b.GeneratePublicKey()
+
+	// links do banco, como o do BradescoShopFacil, não dependem da chave e são mantidos
+	links := b.CreateLinks()
+	for _, link := range b.Links {
+		if link.Rel != "html" && link.Rel != "pdf" {
+			links = append(links, link)
+		}
+	}
+	b.Links = links
+}
+
 //GeneratePublicKey Gera a chave pública criptografada para geração da URL do boleto
 func (b *BoletoView) GeneratePublicKey() {
 	s := b.SecretKey + b.CreateDate.String() + b.Barcode + b.Boleto.Buyer.Document.Number + strconv.FormatUint(b.Boleto.Title.AmountInCents, 10)
@@ -198,6 +267,12 @@ func BoletoErrorConector(e *flow.ExchangeMessage, u flow.URI, params ...interfac
 	b := "Erro interno"
 	switch t := e.GetBody().(type) {
 	case error:
+		if code, ok := util.HandshakeErrorCode(t); ok {
+			resp := GetBoletoResponseError(code, t.Error())
+			resp.StatusCode = http.StatusBadGateway
+			e.SetBody(resp)
+			return nil
+		}
 		b = t.Error()
 	case string:
 		b = t
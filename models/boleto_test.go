@@ -82,5 +82,5 @@ func arrangeBoletoView() BoletoView {
 	request := BoletoRequest{BankNumber: BancoDoBrasil}
 	response := BoletoResponse{BarCodeNumber: "123456789012345678901234567890", DigitableLine: "123467890123456790134567890", OurNumber: "1234567890"}
 
-	return NewBoletoView(request, response, "BancoDoBrasil")
+	return NewBoletoView(request, response, "BancoDoBrasil", "")
 }
@@ -1,15 +1,116 @@
 package models
 
 import (
+	"strconv"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
 )
 
 //Credentials Credenciais para requisição de Registro de Boleto
+//O Password guardado no banco é o hash bcrypt da senha do usuário
 type Credentials struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty"`
-	UserKey  string
-	Username string `bson:"username,omitempty"`
-	Password string `bson:"password,omitempty"`
+	UserKey  string             `bson:"-"`
+	Username string             `bson:"username,omitempty"`
+	Password string             `bson:"password,omitempty"`
+	// DailyRegistrationQuota limita os registros de boleto por dia do usuário, sobrescrevendo DAILY_REGISTRATION_QUOTA quando maior que zero
+	DailyRegistrationQuota int `bson:"dailyRegistrationQuota,omitempty"`
+	// RegistrationsPerMinute e RegistrationBurst limitam a taxa de registros do usuário, sobrescrevendo
+	// RATE_LIMIT_PER_MINUTE e RATE_LIMIT_BURST quando maiores que zero
+	RegistrationsPerMinute int       `bson:"registrationsPerMinute,omitempty"`
+	RegistrationBurst      int       `bson:"registrationBurst,omitempty"`
+	Disabled               bool      `bson:"disabled,omitempty"`
+	CreatedAt              time.Time `bson:"createdAt,omitempty"`
+	UpdatedAt              time.Time `bson:"updatedAt,omitempty"`
+	// Banks são o certificado e as credenciais próprias do usuário em cada banco, pelo número do banco
+	Banks map[string]BankCredentials `bson:"banks,omitempty"`
+	// APIKeys são as chaves de API do usuário, alternativas à senha
+	APIKeys []APIKey `bson:"apiKeys,omitempty"`
+	// Retention é a política de retenção própria dos boletos do usuário, que substitui RETENTION_DAYS e RETENTION_ACTION
+	Retention *RetentionPolicy `bson:"retention,omitempty"`
+	// Encryption é a chave de dados, cifrada pela chave mestra, dos client secrets dos bancos. Nulo quando eles estão em texto puro
+	Encryption *EncryptedKey `bson:"encryption,omitempty"`
+}
+
+//APIKey Chave de API de um usuário, com os escopos que ela permite. Só o hash SHA-256 da chave é guardado
+type APIKey struct {
+	ID        string    `bson:"id" json:"id"`
+	Hash      string    `bson:"hash" json:"-"`
+	Scopes    []string  `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+}
+
+//APIKeyRequest Requisição de criação de uma chave de API
+type APIKeyRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+//APIKeyView Chave de API criada. A Key só é retornada na criação
+type APIKeyView struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+//BankCredentials Certificado e credenciais de cliente de um usuário em um banco, para recebedores que registram com os próprios dados
+//Os campos vazios usam o certificado global da configuração e as credenciais enviadas no registro
+type BankCredentials struct {
+	// Certificate é o nome no store do certificado de cliente TLS. Key é o nome da chave, quando ela não está junto do certificado
+	Certificate string `bson:"certificate,omitempty" json:"certificate,omitempty"`
+	Key         string `bson:"key,omitempty" json:"key,omitempty"`
+	// SigningCertificate é o nome no store do certificado ICP que assina as requisições
+	SigningCertificate string `bson:"signingCertificate,omitempty" json:"signingCertificate,omitempty"`
+	ClientID           string `bson:"clientId,omitempty" json:"clientId,omitempty"`
+	ClientSecret       string `bson:"clientSecret,omitempty" json:"clientSecret,omitempty"`
+}
+
+//ClientCertificate Retorna os nomes do certificado de cliente e da chave do usuário, ou os informados quando ele não tem certificado próprio
+func (b BankCredentials) ClientCertificate(crt, key string) (string, string) {
+	if b.Certificate == "" {
+		return crt, key
+	}
+	if b.Key == "" {
+		return b.Certificate, b.Certificate
+	}
+	return b.Certificate, b.Key
+}
+
+//ForBank Retorna o certificado e as credenciais do usuário no banco
+func (c Credentials) ForBank(number BankNumber) BankCredentials {
+	return c.Banks[strconv.Itoa(int(number))]
+}
+
+//CredentialsRequest Requisição de criação de credenciais
+type CredentialsRequest struct {
+	Username               string `json:"username"`
+	DailyRegistrationQuota int    `json:"dailyRegistrationQuota,omitempty"`
+	RegistrationsPerMinute int    `json:"registrationsPerMinute,omitempty"`
+	RegistrationBurst      int    `json:"registrationBurst,omitempty"`
+	// Banks são o certificado e as credenciais próprias do usuário em cada banco, pelo número do banco
+	Banks map[string]BankCredentials `json:"banks,omitempty"`
+	// Retention é a política de retenção própria do usuário
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+}
+
+//CredentialsView Representação das credenciais retornada pela API de administração
+//O Password só é preenchido na criação e na rotação da senha
+type CredentialsView struct {
+	UserKey                string    `json:"userKey"`
+	Username               string    `json:"username"`
+	Password               string    `json:"password,omitempty"`
+	DailyRegistrationQuota int       `json:"dailyRegistrationQuota,omitempty"`
+	RegistrationsPerMinute int       `json:"registrationsPerMinute,omitempty"`
+	RegistrationBurst      int       `json:"registrationBurst,omitempty"`
+	Disabled               bool      `json:"disabled"`
+	CreatedAt              time.Time `json:"createdAt,omitempty"`
+	UpdatedAt              time.Time `json:"updatedAt,omitempty"`
+	// Banks são os certificados e credenciais do usuário em cada banco, sem o ClientSecret
+	Banks map[string]BankCredentials `json:"banks,omitempty"`
+	// APIKeys são as chaves de API do usuário, sem o hash
+	APIKeys []APIKey `json:"apiKeys,omitempty"`
+	// Retention é a política de retenção própria do usuário. Vazia usa a política padrão
+	Retention *RetentionPolicy `json:"retention,omitempty"`
 }
 
 //NewCredentials Cria uma instância de Credential
@@ -19,3 +120,50 @@ func NewCredentials(k, p string) *Credentials {
 		Password: p,
 	}
 }
+
+//SetPassword Guarda o hash bcrypt da senha
+func (c *Credentials) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	c.Password = string(hash)
+	return nil
+}
+
+//CheckPassword Verifica a senha contra o hash guardado
+func (c Credentials) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(c.Password), []byte(password)) == nil
+}
+
+//HasHashedPassword Verifica se a senha guardada já é um hash bcrypt
+func (c Credentials) HasHashedPassword() bool {
+	_, err := bcrypt.Cost([]byte(c.Password))
+	return err == nil
+}
+
+//ToView Cria a representação das credenciais sem a senha e sem os segredos dos bancos
+func (c Credentials) ToView() CredentialsView {
+	var banks map[string]BankCredentials
+	if len(c.Banks) > 0 {
+		banks = make(map[string]BankCredentials, len(c.Banks))
+		for bank, credentials := range c.Banks {
+			credentials.ClientSecret = ""
+			banks[bank] = credentials
+		}
+	}
+
+	return CredentialsView{
+		UserKey:                c.ID.Hex(),
+		Username:               c.Username,
+		DailyRegistrationQuota: c.DailyRegistrationQuota,
+		RegistrationsPerMinute: c.RegistrationsPerMinute,
+		RegistrationBurst:      c.RegistrationBurst,
+		Banks:                  banks,
+		APIKeys:                c.APIKeys,
+		Retention:              c.Retention,
+		Disabled:               c.Disabled,
+		CreatedAt:              c.CreatedAt,
+		UpdatedAt:              c.UpdatedAt,
+	}
+}
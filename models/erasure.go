@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnonymizedName substitui o nome do comprador nos boletos anonimizados
+const AnonymizedName = "ANONIMIZADO"
+
+//ErasureRequest Requisição de eliminação dos dados pessoais de um comprador, pela LGPD
+type ErasureRequest struct {
+	BuyerDocument string `json:"buyerDocument"`
+	// ServiceUser restringe a eliminação aos boletos de um usuário. Vazio elimina em todos
+	ServiceUser string `json:"serviceUser,omitempty"`
+	// Reason identifica a solicitação do titular, como o protocolo do atendimento
+	Reason string `json:"reason,omitempty"`
+}
+
+//ErasureRecord Registro auditável de uma eliminação de dados pessoais
+//O documento do comprador só é guardado como hash, para que o registro não mantenha o dado eliminado
+type ErasureRecord struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	DocumentHash string             `bson:"documenthash" json:"documentHash"`
+	ServiceUser  string             `bson:"serviceuser,omitempty" json:"serviceUser,omitempty"`
+	Reason       string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	RequestedBy  string             `bson:"requestedby" json:"requestedBy"`
+	// Boletos são os ids dos boletos anonimizados no banco
+	Boletos []string `bson:"boletos" json:"boletos"`
+	// CacheEntries e FallbackFiles são as entradas do Redis e os arquivos do fallback removidos ou anonimizados
+	CacheEntries  int       `bson:"cacheentries" json:"cacheEntries"`
+	FallbackFiles int       `bson:"fallbackfiles" json:"fallbackFiles"`
+	Errors        []string  `bson:"errors,omitempty" json:"errors,omitempty"`
+	Completed     bool      `bson:"completed" json:"completed"`
+	CreatedAt     time.Time `bson:"createdat" json:"createdAt"`
+	FinishedAt    time.Time `bson:"finishedat" json:"finishedAt"`
+}
+
+//AnonymizeBuyer Substitui os dados pessoais do comprador, mantendo só o tipo do documento
+func (b *BoletoView) AnonymizeBuyer(erasedAt time.Time) {
+	b.Boleto.Buyer = Buyer{
+		Name:     AnonymizedName,
+		Document: Document{Type: b.Boleto.Buyer.Document.Type},
+	}
+	b.BuyerDocumentHash = ""
+	b.ErasedAt = &erasedAt
+}
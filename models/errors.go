@@ -121,6 +121,24 @@ func (e FormatError) ErrorCode() string {
 	return e.Code
 }
 
+//ServiceUnavailableError interface para implementar Error
+type ServiceUnavailableError ErrorResponse
+
+//NewServiceUnavailableError cria um novo objeto ServiceUnavailableError a partir de um código e mensagem
+func NewServiceUnavailableError(code, msg string) ServiceUnavailableError {
+	return ServiceUnavailableError{Message: msg, Code: code}
+}
+
+//Error Retorna a mensagem do erro
+func (e ServiceUnavailableError) Error() string {
+	return e.Message
+}
+
+//ErrorCode Retorna um erro code
+func (e ServiceUnavailableError) ErrorCode() string {
+	return e.Code
+}
+
 //BadGatewayError interface para implementar Error
 type BadGatewayError ErrorResponse
 
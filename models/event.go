@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tipos dos eventos de auditoria dos boletos
+const (
+	// EventRegistration tentativa de registro, bem sucedida ou não
+	EventRegistration = "registration"
+	// EventRead leitura do boleto pelo link público ou pela API
+	EventRead = "read"
+	// EventLinksReissued emissão de novos links públicos
+	EventLinksReissued = "links.reissued"
+	// EventLinksRevoked revogação dos links públicos
+	EventLinksRevoked = "links.revoked"
+	// EventFallbackSaved envio do boleto para a fila ou para o fallback quando o banco falha
+	EventFallbackSaved = "fallback.saved"
+)
+
+// Resultados dos eventos de auditoria
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+//BoletoEvent Evento de auditoria de uma operação sobre um boleto. Os eventos nunca são alterados ou removidos
+//Tentativas de registro que falharam não têm boleto e são listadas pelo RequestKey em GET /v2/events
+type BoletoEvent struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	BoletoID    string             `bson:"boletoid,omitempty" json:"boletoId,omitempty"`
+	Type        string             `bson:"type" json:"type"`
+	Outcome     string             `bson:"outcome" json:"outcome"`
+	ServiceUser string             `bson:"serviceuser,omitempty" json:"serviceUser,omitempty"`
+	IPAddress   string             `bson:"ipaddress,omitempty" json:"ipAddress,omitempty"`
+	RequestKey  string             `bson:"requestkey,omitempty" json:"requestKey,omitempty"`
+	StatusCode  int                `bson:"statuscode,omitempty" json:"statusCode,omitempty"`
+	// Detail complementa o resultado, como o código do erro ou o destino do fallback
+	Detail    string    `bson:"detail,omitempty" json:"detail,omitempty"`
+	CreatedAt time.Time `bson:"createdat" json:"createdAt"`
+}
+
+//BoletoEvents Eventos de auditoria de um boleto, do mais antigo ao mais recente
+type BoletoEvents struct {
+	Events []BoletoEvent `json:"events"`
+}
+
+//OutcomeOf Retorna o resultado do evento de acordo com o sucesso da operação
+func OutcomeOf(success bool) string {
+	if success {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
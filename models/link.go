@@ -0,0 +1,137 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+)
+
+// defaultLinkTTL é a validade dos links públicos quando LINK_TTL_IN_HOURS não está definido
+const defaultLinkTTL = 30 * 24 * time.Hour
+
+var (
+	//ErrLinkExpired O link público do boleto expirou
+	ErrLinkExpired = errors.New("link expired")
+	//ErrLinkInvalidSignature A assinatura do link público não confere ou foi feita com uma chave desconhecida ou aposentada
+	ErrLinkInvalidSignature = errors.New("invalid link signature")
+	//ErrLinkUnsigned O link público não é assinado e o prazo de LINK_UNSIGNED_UNTIL para links sem assinatura acabou
+	ErrLinkUnsigned = errors.New("unsigned link")
+)
+
+//LinkSignature Assinatura HMAC de um link público de boleto, com a expiração e a chave usada
+type LinkSignature struct {
+	KeyID   string
+	Expires int64
+	Value   string
+}
+
+type linkKey struct {
+	id     string
+	secret []byte
+}
+
+//SignLink Assina o link público do boleto com a chave atual de LINK_SIGNING_KEYS
+//Retorna nil quando não há chave configurada, mantendo os links sem assinatura
+func SignLink(id, publicKey string, now time.Time) *LinkSignature {
+	keys := linkKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	expires := now.Add(linkTTL()).Unix()
+	return &LinkSignature{
+		KeyID:   keys[0].id,
+		Expires: expires,
+		Value:   linkMAC(keys[0].secret, id, publicKey, expires),
+	}
+}
+
+//VerifyLink Valida a assinatura e a expiração do link público do boleto
+//A chave atual sempre é aceita. As anteriores só durante LINK_ROTATION_WINDOW_IN_HOURS após LINK_KEY_ROTATED_AT
+//Links sem assinatura, emitidos antes das chaves, só são aceitos até LINK_UNSIGNED_UNTIL
+func VerifyLink(id, publicKey string, signature *LinkSignature, now time.Time) error {
+	if signature == nil {
+		if len(linkKeys()) == 0 || acceptsUnsigned(now) {
+			return nil
+		}
+		return ErrLinkUnsigned
+	}
+
+	for i, key := range linkKeys() {
+		if key.id != signature.KeyID {
+			continue
+		}
+		if i > 0 && !inRotationWindow(now) {
+			return ErrLinkInvalidSignature
+		}
+		if !hmac.Equal([]byte(linkMAC(key.secret, id, publicKey, signature.Expires)), []byte(strings.ToLower(signature.Value))) {
+			return ErrLinkInvalidSignature
+		}
+		if now.Unix() > signature.Expires {
+			return ErrLinkExpired
+		}
+		return nil
+	}
+	return ErrLinkInvalidSignature
+}
+
+//Query Retorna os parâmetros da assinatura para a URL do link
+func (s LinkSignature) Query() string {
+	return fmt.Sprintf("&exp=%d&kid=%s&sig=%s", s.Expires, s.KeyID, s.Value)
+}
+
+func linkMAC(secret []byte, id, publicKey string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "." + publicKey + "." + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// linkKeys lê LINK_SIGNING_KEYS, no formato kid=segredo separado por vírgula, com a chave atual primeiro
+func linkKeys() []linkKey {
+	keys := []linkKey{}
+	for _, entry := range strings.Split(config.Get().LinkSigningKeys, ",") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			continue
+		}
+		keys = append(keys, linkKey{id: kv[0], secret: []byte(kv[1])})
+	}
+	return keys
+}
+
+func linkTTL() time.Duration {
+	if hours := config.Get().LinkTTLInHours; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultLinkTTL
+}
+
+// inRotationWindow indica se as chaves anteriores ainda são aceitas. Sem LINK_KEY_ROTATED_AT elas são aceitas sempre
+func inRotationWindow(now time.Time) bool {
+	rotatedAt, err := time.Parse(time.RFC3339, config.Get().LinkKeyRotatedAt)
+	if err != nil {
+		return true
+	}
+	return now.Before(rotatedAt.Add(time.Duration(config.Get().LinkRotationWindowInHours) * time.Hour))
+}
+
+// acceptsUnsigned indica se os links sem assinatura ainda são aceitos. Sem LINK_UNSIGNED_UNTIL eles são recusados assim que há chaves
+func acceptsUnsigned(now time.Time) bool {
+	until, err := time.Parse(time.RFC3339, config.Get().LinkUnsignedUntil)
+	if err != nil {
+		return false
+	}
+	return now.Before(until)
+}
+
+//BoletoLinks Links públicos de um boleto reemitidos
+type BoletoLinks struct {
+	Links []Link `json:"links"`
+}
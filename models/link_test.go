@@ -0,0 +1,82 @@
+package models
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const linkTestID, linkTestPK = "60be988b3193b131b8061835", "ad29db446917b1453b1fcac6397fd1af87dcbfcda22095317c0531cfee9d01d5"
+
+func arrangeLinkKeys(t *testing.T, env map[string]string) {
+	os.Clearenv()
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+}
+
+func TestSignLink_WhenSigned_VerifiesUntilExpiration(t *testing.T) {
+	arrangeLinkKeys(t, map[string]string{"LINK_SIGNING_KEYS": "k2=new-secret,k1=old-secret", "LINK_TTL_IN_HOURS": "1"})
+	now := time.Now()
+
+	signature := SignLink(linkTestID, linkTestPK, now)
+
+	assert.Equal(t, "k2", signature.KeyID)
+	assert.Equal(t, now.Add(time.Hour).Unix(), signature.Expires)
+	assert.Nil(t, VerifyLink(linkTestID, linkTestPK, signature, now))
+	assert.Equal(t, ErrLinkExpired, VerifyLink(linkTestID, linkTestPK, signature, now.Add(2*time.Hour)))
+	assert.Equal(t, ErrLinkInvalidSignature, VerifyLink(linkTestID, "another-pk", signature, now))
+
+	tampered := *signature
+	tampered.Expires += 3600
+	assert.Equal(t, ErrLinkInvalidSignature, VerifyLink(linkTestID, linkTestPK, &tampered, now))
+}
+
+func TestVerifyLink_AcceptsPreviousKeyOnlyDuringRotationWindow(t *testing.T) {
+	arrangeLinkKeys(t, map[string]string{"LINK_SIGNING_KEYS": "k1=old-secret"})
+	now := time.Now()
+	signature := SignLink(linkTestID, linkTestPK, now)
+
+	arrangeLinkKeys(t, map[string]string{
+		"LINK_SIGNING_KEYS":             "k2=new-secret,k1=old-secret",
+		"LINK_KEY_ROTATED_AT":           now.Add(-time.Hour).Format(time.RFC3339),
+		"LINK_ROTATION_WINDOW_IN_HOURS": "24",
+	})
+	assert.Nil(t, VerifyLink(linkTestID, linkTestPK, signature, now))
+	assert.Equal(t, ErrLinkInvalidSignature, VerifyLink(linkTestID, linkTestPK, signature, now.Add(24*time.Hour)))
+
+	arrangeLinkKeys(t, map[string]string{"LINK_SIGNING_KEYS": "k2=new-secret"})
+	assert.Equal(t, ErrLinkInvalidSignature, VerifyLink(linkTestID, linkTestPK, signature, now))
+}
+
+func TestVerifyLink_UnsignedLinks(t *testing.T) {
+	arrangeLinkKeys(t, nil)
+	assert.Nil(t, VerifyLink(linkTestID, linkTestPK, nil, time.Now()), "sem chaves os links continuam sem assinatura")
+
+	now := time.Now()
+	arrangeLinkKeys(t, map[string]string{"LINK_SIGNING_KEYS": "k1=secret", "LINK_UNSIGNED_UNTIL": now.Add(24 * time.Hour).Format(time.RFC3339)})
+	assert.Nil(t, VerifyLink(linkTestID, linkTestPK, nil, now), "os links já enviados continuam válidos até a data de corte")
+	assert.Equal(t, ErrLinkUnsigned, VerifyLink(linkTestID, linkTestPK, nil, now.Add(25*time.Hour)))
+
+	arrangeLinkKeys(t, map[string]string{"LINK_SIGNING_KEYS": "k1=secret"})
+	assert.Equal(t, ErrLinkUnsigned, VerifyLink(linkTestID, linkTestPK, nil, time.Now()))
+}
+
+func TestRotateKeys_ChangesPublicKeyAndLinks(t *testing.T) {
+	arrangeLinkKeys(t, map[string]string{"APP_URL": "http://localhost:3000/boleto", "LINK_SIGNING_KEYS": "k1=secret"})
+	view := NewBoletoView(BoletoRequest{BankNumber: Caixa}, BoletoResponse{}, "", "")
+	previous := view.PublicKey
+
+	view.RotateKeys()
+
+	assert.NotEqual(t, previous, view.PublicKey)
+	assert.Equal(t, 2, len(view.Links))
+	assert.True(t, strings.Contains(view.Links[0].Href, "pk="+view.PublicKey+"&exp="))
+	assert.True(t, strings.Contains(view.Links[0].Href, "&kid=k1&sig="))
+}
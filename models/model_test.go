@@ -183,11 +183,15 @@ func TestNewBoletoView(t *testing.T) {
 		DigitableLine: expectedDigitableLine,
 	}
 
-	result := NewBoletoView(BoletoRequest{}, response, "BradescoShopFacil")
+	result := NewBoletoView(BoletoRequest{}, response, "BradescoShopFacil", "user")
 
 	assert.NotEmpty(t, result.UID)
 	assert.Equal(t, expectedBarCode, result.Barcode)
 	assert.Equal(t, expectedDigitableLine, result.DigitableLine)
+	assert.Equal(t, "user", result.ServiceUser)
+	assert.True(t, result.IsOwnedBy("user"))
+	assert.False(t, result.IsOwnedBy("other"))
+	assert.False(t, result.IsOwnedBy(""))
 }
 
 func TestNewErrorResponse(t *testing.T) {
@@ -2,6 +2,8 @@ package models
 
 import (
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +13,7 @@ type GetBoletoResult struct {
 	Id                                string
 	Format                            string
 	PublicKey                         string
+	Signature                         *LinkSignature
 	URI                               string
 	BoletoSource                      string
 	TotalElapsedTimeInMilliseconds    int64
@@ -25,6 +28,10 @@ func NewGetBoletoResult(c *gin.Context) *GetBoletoResult {
 	g.Id = c.Query("id")
 	g.Format = c.Query("fmt")
 	g.PublicKey = c.Query("pk")
+	if sig := c.Query("sig"); sig != "" {
+		expires, _ := strconv.ParseInt(c.Query("exp"), 10, 64)
+		g.Signature = &LinkSignature{KeyID: c.Query("kid"), Expires: expires, Value: sig}
+	}
 	g.URI = c.Request.RequestURI
 	g.BoletoSource = "none"
 	return g
@@ -43,6 +50,11 @@ func (g *GetBoletoResult) HasValidParameters() bool {
 	return HasValidPublicKey(g) && HasValidId(g)
 }
 
+//VerifySignature Valida a assinatura e a expiração do link usado para buscar o boleto
+func (g *GetBoletoResult) VerifySignature(now time.Time) error {
+	return VerifyLink(g.Id, g.PublicKey, g.Signature, now)
+}
+
 //SetErrorResponse Insere as informações de erro para resposta
 func (g *GetBoletoResult) SetErrorResponse(c *gin.Context, err ErrorResponse, statusCode int) {
 	g.ErrorResponse = BoletoResponse{
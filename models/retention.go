@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+)
+
+// Ações da política de retenção
+const (
+	// RetentionArchive move o boleto para a coleção de arquivo
+	RetentionArchive = "archive"
+	// RetentionPurge apaga o boleto
+	RetentionPurge = "purge"
+)
+
+//RetentionPolicy Política de retenção dos boletos de um usuário
+//Os boletos são arquivados ou apagados Days dias depois do vencimento ou do pagamento, o que for mais tarde
+type RetentionPolicy struct {
+	Days   int    `bson:"days" json:"days"`
+	Action string `bson:"action" json:"action"`
+}
+
+//DefaultRetentionPolicy Retorna a política de RETENTION_DAYS e RETENTION_ACTION, usada pelos usuários sem política própria
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{Days: config.Get().RetentionDays, Action: config.Get().RetentionAction}
+}
+
+//Enabled Indica se a política remove boletos. Sem dias configurados os boletos são mantidos para sempre
+func (p RetentionPolicy) Enabled() bool {
+	return p.Days > 0 && IsValidRetentionAction(p.Action)
+}
+
+//Cutoff Retorna a data antes da qual os boletos já passaram do prazo de retenção
+func (p RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.Days)
+}
+
+//Validate Verifica a política. Zero dias é válido e mantém os boletos para sempre
+func (p RetentionPolicy) Validate() error {
+	if p.Days < 0 {
+		return errors.New("retention days cannot be negative")
+	}
+	if p.Days > 0 && !IsValidRetentionAction(p.Action) {
+		return fmt.Errorf("retention action must be %s or %s", RetentionArchive, RetentionPurge)
+	}
+	return nil
+}
+
+//IsValidRetentionAction Verifica se a ação é uma das ações de retenção conhecidas
+func IsValidRetentionAction(action string) bool {
+	return action == RetentionArchive || action == RetentionPurge
+}
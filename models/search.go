@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// BoletoSummary representação resumida de um boleto retornada na busca de boletos
+type BoletoSummary struct {
+	ID                string     `json:"id"`
+	BankNumber        BankNumber `json:"bankNumber"`
+	OurNumber         string     `json:"ourNumber,omitempty"`
+	DocumentNumber    string     `json:"documentNumber,omitempty"`
+	DigitableLine     string     `json:"digitableLine,omitempty"`
+	AmountInCents     uint64     `json:"amountInCents"`
+	ExpireDate        string     `json:"expireDate,omitempty"`
+	CreateDate        time.Time  `json:"createDate"`
+	Status            string     `json:"status,omitempty"`
+	RecipientDocument string     `json:"recipientDocument,omitempty"`
+	BuyerDocument     string     `json:"buyerDocument,omitempty"`
+	Links             []Link     `json:"links,omitempty"`
+}
+
+// BoletoSearchResponse página de resultados da busca de boletos
+type BoletoSearchResponse struct {
+	Boletos    []BoletoSummary `json:"boletos"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// NewBoletoSummary cria o resumo de um boleto sem expor chaves e dados completos do pagador
+func NewBoletoSummary(b BoletoView) BoletoSummary {
+	return BoletoSummary{
+		ID:                b.ID.Hex(),
+		BankNumber:        b.BankID,
+		OurNumber:         b.OurNumber,
+		DocumentNumber:    b.Boleto.Title.DocumentNumber,
+		DigitableLine:     b.DigitableLine,
+		AmountInCents:     b.Boleto.Title.AmountInCents,
+		ExpireDate:        b.Boleto.Title.ExpireDate,
+		CreateDate:        b.CreateDate,
+		Status:            b.Status,
+		RecipientDocument: b.Boleto.Recipient.Document.Number,
+		BuyerDocument:     b.Boleto.Buyer.Document.Number,
+		Links:             b.Links,
+	}
+}
@@ -4,11 +4,13 @@ import "time"
 
 // Token represents a token used to access external resources
 // The field CreatedAt is used by Mongo to control token TTL
+// The field ExpiresAt comes from the expires_in informed by the bank and is zero when it was not informed
 type Token struct {
 	ClientID    string    `json:"clientid,omitempty"`
 	IssuerBank  string    `json:"issuerbank,omitempty"`
 	AccessToken string    `json:"accesstoken,omitempty"`
 	CreatedAt   time.Time `json:"createdat"`
+	ExpiresAt   time.Time `json:"expiresat,omitempty"`
 }
 
 //Token Creates a Token instance
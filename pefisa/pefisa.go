@@ -1,17 +1,22 @@
 package pefisa
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	. "github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tokenmanager"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
@@ -43,7 +48,7 @@ func (b bankPefisa) Log() *log.Log {
 	return b.log
 }
 
-func (b bankPefisa) GetToken(boleto *models.BoletoRequest) (string, error) {
+func (b bankPefisa) GetToken(ctx context.Context, boleto *models.BoletoRequest) (tokenmanager.Token, error) {
 
 	pipe := NewFlow()
 	url := config.Get().URLPefisaToken
@@ -51,14 +56,24 @@ func (b bankPefisa) GetToken(boleto *models.BoletoRequest) (string, error) {
 	pipe.From("message://?source=inline", boleto, getRequestToken(), tmpl.GetFuncMaps())
 	pipe.To("log://?type=request&url="+url, b.log)
 
-	duration := util.Duration(func() {
-		pipe.To(url, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutToken})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationToken)
+	tracing.InjectFlow(ctx, pipe)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationToken, b.log, pipe, func() {
+		duration = util.Duration(func() {
+			pipe.To(url, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutToken})
+		})
 	})
-	metrics.PushTimingMetric("pefisa-get-token-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return tokenmanager.Token{}, err
+	}
+	tracing.EndFlow(span, pipe)
+	metrics.PushBankTime("pefisa-get-token-boleto-time", b.GetBankNameIntegration(), metrics.OperationToken, duration.Seconds())
 	pipe.To("log://?type=response&url="+url, b.log)
 	ch := pipe.Choice()
 	ch.When(Header("status").IsEqualTo("200"))
-	ch.To("transform://?format=json", getTokenResponse(), `{{.access_token}}`, tmpl.GetFuncMaps())
+	ch.To("transform://?format=json", getTokenResponse(), `{{.access_token}}:::{{.expires_in}}`, tmpl.GetFuncMaps())
 
 	ch.When(Header("status").IsEqualTo("401"))
 	ch.To("transform://?format=json", getTokenErrorResponse(), `{{.error_description}}`, tmpl.GetFuncMaps())
@@ -68,16 +83,16 @@ func (b bankPefisa) GetToken(boleto *models.BoletoRequest) (string, error) {
 	ch.To("log://?type=request&url="+url, b.log).To("print://?msg=${body}").To("set://?prop=body", errors.New("integration error"))
 	switch t := pipe.GetBody().(type) {
 	case string:
-
-		return t, nil
+		parts := strings.Split(t, ":::")
+		return tokenmanager.NewToken(parts[0], parts[len(parts)-1]), nil
 	case error:
-		return "", t
+		return tokenmanager.Token{}, t
 	}
-	return "", nil
+	return tokenmanager.Token{}, nil
 
 }
 
-func (b bankPefisa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankPefisa) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	pefisaURL := config.Get().URLPefisaRegister
 
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType(boleto)
@@ -88,14 +103,24 @@ func (b bankPefisa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoR
 	var response string
 	var status int
 	var err error
-	duration := util.Duration(func() {
-		response, status, err = b.sendRequest(exec.GetBody().(string), boleto.Authentication.AuthorizationToken)
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	var duration time.Duration
+	callErr := resilience.Call(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, func() resilience.Attempt {
+		duration = util.Duration(func() {
+			response, status, err = b.sendRequest(ctx, exec.GetBody().(string), boleto.Authentication.AuthorizationToken)
+		})
+		return resilience.Attempt{Status: status, Err: err}
 	})
+	if resilience.Rejected(callErr) {
+		tracing.End(span, callErr)
+		return models.BoletoResponse{}, callErr
+	}
+	tracing.EndHTTP(span, status, err)
 	if err != nil {
 		return models.BoletoResponse{}, err
 	}
 
-	metrics.PushTimingMetric("pefisa-register-boleto-time", duration.Seconds())
+	metrics.PushBankTime("pefisa-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	exec.To("set://?prop=header", map[string]string{"status": strconv.Itoa(status)})
 	exec.To("set://?prop=body", response)
 	exec.To("log://?type=response&url="+pefisaURL, b.log)
@@ -132,19 +157,24 @@ func (b bankPefisa) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoR
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
 
-func (b bankPefisa) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankPefisa) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	if token, err := b.GetToken(boleto); err != nil {
+	// a cópia evita que a renovação do token em segundo plano leia o boleto alterado pelo registro
+	credentials := *boleto
+	clientID := tokenmanager.ClientKey(boleto.Authentication.Username, boleto.Authentication.Password)
+	token, err := tokenmanager.Get(ctx, b.GetBankNameIntegration(), clientID, b.log, func(ctx context.Context) (tokenmanager.Token, error) {
+		return b.GetToken(ctx, &credentials)
+	})
+	if err != nil {
 		return models.BoletoResponse{Errors: errs}, err
-	} else {
-		boleto.Authentication.AuthorizationToken = token
 	}
+	boleto.Authentication.AuthorizationToken = token
 
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 
 }
 
@@ -165,11 +195,11 @@ func (b bankPefisa) GetErrorsMap() map[string]int {
 	return nil
 }
 
-func (b bankPefisa) sendRequest(body string, token string) (string, int, error) {
+func (b bankPefisa) sendRequest(ctx context.Context, body string, token string) (string, int, error) {
 	serviceURL := config.Get().URLPefisaRegister
 
 	h := map[string]string{"Authorization": "Bearer " + token, "Content-Type": "application/json"}
-	return util.Post(serviceURL, body, config.Get().TimeoutRegister, h)
+	return util.Post(ctx, b.GetBankNameIntegration(), serviceURL, body, config.Get().TimeoutRegister, h)
 }
 
 func pefisaBoletoTypes() map[string]string {
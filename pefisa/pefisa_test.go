@@ -1,6 +1,7 @@
 package pefisa
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -26,7 +27,7 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	input := newStubBoletoRequestPefisa().Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -37,7 +38,7 @@ func TestProcessBoleto_WhenServiceRespondsFailed_ShouldHasFailedBoletoResponse(t
 	input := newStubBoletoRequestPefisa().WithAmountInCents(201).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoFailed(t, output)
 }
@@ -56,7 +57,7 @@ func TestTemplateResponse_WhenRequestHasTabCharacter_ShouldBeParsedSuccessful(t
 	input := newStubBoletoRequestPefisa().WithBuyerName("Usuario \tTeste").Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -54,7 +54,8 @@ const pefisaGetTokenRequest = `
 grant_type=client_credentials`
 
 const tokenResponse = `{	
-	"access_token": "{{access_token}}"
+	"access_token": "{{access_token}}",
+	"expires_in": "{{expires_in}}"
 }`
 
 const tokenErrorResponse = `{    
@@ -0,0 +1,197 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	//ErrBuyerDocumentRequired A eliminação precisa do documento do comprador
+	ErrBuyerDocumentRequired = errors.New("buyerDocument is required")
+	//ErrDocumentHashKeyRequired Os registros de eliminação precisam de ENCRYPTION_BLIND_INDEX_KEY para identificar o documento
+	ErrDocumentHashKeyRequired = errors.New("ENCRYPTION_BLIND_INDEX_KEY is required to record erasures")
+)
+
+//Cache Caches do Redis com o HTML e o JSON dos boletos
+type Cache interface {
+	DeleteBoletoHTML(ctx context.Context, id, pk string, lg *log.Log) (bool, error)
+	GetAllJSON(ctx context.Context, lg *log.Log) ([]string, error)
+	GetBoletoJSONByKey(ctx context.Context, key string, lg *log.Log) (models.BoletoView, error)
+	SetBoletoJSONByKey(ctx context.Context, key, b string, lg *log.Log) error
+}
+
+//FallbackStorage Armazenamento dos boletos que não puderam ser salvos no banco
+type FallbackStorage interface {
+	ListFallback(ctx context.Context) ([]string, error)
+	DownloadFallback(ctx context.Context, fileNamePrefix string) (string, error)
+	UploadAsJson(ctx context.Context, fileNamePrefix, payload string) (int64, error)
+}
+
+//Eraser Elimina os dados pessoais de um comprador do banco, dos caches e do fallback, registrando cada eliminação
+type Eraser struct {
+	boletos  db.BoletoRepository
+	records  db.ErasureRepository
+	cache    Cache
+	fallback func() (FallbackStorage, error)
+}
+
+//NewEraser Cria um Eraser. O fallback é obtido a cada eliminação, como no envio dos boletos para ele
+func NewEraser(boletos db.BoletoRepository, records db.ErasureRepository, cache Cache, fallback func() (FallbackStorage, error)) *Eraser {
+	return &Eraser{boletos: boletos, records: records, cache: cache, fallback: fallback}
+}
+
+//Erase Anonimiza o comprador em todos os boletos com o documento e guarda o registro da eliminação
+//As falhas em cada armazenamento não interrompem as demais e ficam no registro, que não é marcado como completo
+func (e *Eraser) Erase(ctx context.Context, request models.ErasureRequest, requestedBy string) (models.ErasureRecord, error) {
+	document := strings.TrimSpace(request.BuyerDocument)
+	if document == "" {
+		return models.ErasureRecord{}, ErrBuyerDocumentRequired
+	}
+	documentHash, err := DocumentHash(document)
+	if err != nil {
+		return models.ErasureRecord{}, err
+	}
+
+	lg := log.CreateLog()
+	lg.Operation = "EraseBuyerData"
+
+	now := time.Now()
+	record := models.ErasureRecord{
+		ID:           primitive.NewObjectID(),
+		DocumentHash: documentHash,
+		ServiceUser:  request.ServiceUser,
+		Reason:       request.Reason,
+		RequestedBy:  requestedBy,
+		Boletos:      []string{},
+		CreatedAt:    now,
+	}
+	fail := func(source string, err error) {
+		record.Errors = append(record.Errors, source+": "+err.Error())
+	}
+
+	erased, err := e.boletos.EraseBuyerData(ctx, request.ServiceUser, document, now)
+	if err != nil {
+		fail("repository", err)
+	}
+	for _, b := range erased {
+		record.Boletos = append(record.Boletos, b.ID.Hex())
+		if deleted, err := e.cache.DeleteBoletoHTML(ctx, b.ID.Hex(), b.PublicKey, lg); err != nil {
+			fail("cache", err)
+		} else if deleted {
+			record.CacheEntries++
+		}
+	}
+
+	if err := e.eraseCachedJSON(ctx, request.ServiceUser, document, now, &record, lg); err != nil {
+		fail("cache", err)
+	}
+	if err := e.eraseFallback(ctx, request.ServiceUser, document, now, &record); err != nil {
+		fail("fallback", err)
+	}
+
+	record.Completed = len(record.Errors) == 0
+	record.FinishedAt = time.Now()
+	if err := e.records.SaveErasure(ctx, record); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+//History Lista as eliminações do documento do comprador
+func (e *Eraser) History(ctx context.Context, buyerDocument string) ([]models.ErasureRecord, error) {
+	document := strings.TrimSpace(buyerDocument)
+	if document == "" {
+		return nil, ErrBuyerDocumentRequired
+	}
+	documentHash, err := DocumentHash(document)
+	if err != nil {
+		return nil, err
+	}
+	return e.records.FindErasures(ctx, documentHash)
+}
+
+//DocumentHash Identifica o documento nos registros de eliminação sem guardá-lo, com o índice cego da cifragem
+//Sem ENCRYPTION_BLIND_INDEX_KEY retorna ErrDocumentHashKeyRequired, já que um hash sem chave de um CPF é revertido por força bruta
+func DocumentHash(document string) (string, error) {
+	hash := encryption.BlindIndex(document)
+	if hash == "" {
+		return "", ErrDocumentHashKeyRequired
+	}
+	return hash, nil
+}
+
+// eraseCachedJSON anonimiza os boletos ainda não salvos no banco que estão no Redis
+func (e *Eraser) eraseCachedJSON(ctx context.Context, serviceUser, document string, now time.Time, record *models.ErasureRecord, lg *log.Log) error {
+	keys, err := e.cache.GetAllJSON(ctx, lg)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		view, err := e.cache.GetBoletoJSONByKey(ctx, key, lg)
+		if err != nil || !belongsToBuyer(ctx, view, serviceUser, document) {
+			continue
+		}
+
+		view.AnonymizeBuyer(now)
+		if err := e.cache.SetBoletoJSONByKey(ctx, key, view.ToMinifyJSON(), lg); err != nil {
+			return err
+		}
+		record.CacheEntries++
+	}
+	return nil
+}
+
+// eraseFallback anonimiza os boletos que estão no fallback, mantendo-os para a recuperação
+func (e *Eraser) eraseFallback(ctx context.Context, serviceUser, document string, now time.Time, record *models.ErasureRecord) error {
+	storage, err := e.fallback()
+	if err != nil {
+		return err
+	}
+
+	names, err := storage.ListFallback(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		payload, err := storage.DownloadFallback(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		view := models.BoletoView{}
+		if err := json.Unmarshal([]byte(payload), &view); err != nil || !belongsToBuyer(ctx, view, serviceUser, document) {
+			continue
+		}
+
+		view.AnonymizeBuyer(now)
+		if _, err := storage.UploadAsJson(ctx, name, view.ToMinifyJSON()); err != nil {
+			return err
+		}
+		record.FallbackFiles++
+	}
+	return nil
+}
+
+// belongsToBuyer compara o documento do comprador, decifrando o boleto quando necessário
+func belongsToBuyer(ctx context.Context, view models.BoletoView, serviceUser, document string) bool {
+	if serviceUser != "" && view.ServiceUser != serviceUser {
+		return false
+	}
+
+	plain := view
+	if err := encryption.DecryptBoleto(ctx, &plain); err != nil {
+		return view.BuyerDocumentHash != "" && view.BuyerDocumentHash == encryption.BlindIndex(document)
+	}
+	return plain.Boleto.Buyer.Document.Number == document
+}
@@ -0,0 +1,190 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/encryption"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const erasedDocument = "12345678909"
+
+type cacheStub struct {
+	html map[string]bool
+	json map[string]string
+}
+
+func (c *cacheStub) DeleteBoletoHTML(ctx context.Context, id, pk string, lg *log.Log) (bool, error) {
+	key := id + ":" + pk
+	cached := c.html[key]
+	delete(c.html, key)
+	return cached, nil
+}
+
+func (c *cacheStub) GetAllJSON(ctx context.Context, lg *log.Log) ([]string, error) {
+	keys := make([]string, 0, len(c.json))
+	for key := range c.json {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (c *cacheStub) GetBoletoJSONByKey(ctx context.Context, key string, lg *log.Log) (models.BoletoView, error) {
+	view := models.BoletoView{}
+	err := json.Unmarshal([]byte(c.json[key]), &view)
+	return view, err
+}
+
+func (c *cacheStub) SetBoletoJSONByKey(ctx context.Context, key, b string, lg *log.Log) error {
+	c.json[key] = b
+	return nil
+}
+
+type fallbackStub map[string]string
+
+func (f fallbackStub) ListFallback(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (f fallbackStub) DownloadFallback(ctx context.Context, fileNamePrefix string) (string, error) {
+	return f[fileNamePrefix], nil
+}
+
+func (f fallbackStub) UploadAsJson(ctx context.Context, fileNamePrefix, payload string) (int64, error) {
+	f[fileNamePrefix] = payload
+	return 0, nil
+}
+
+func newBuyerBoleto(user, document string) models.BoletoView {
+	request := models.BoletoRequest{BankNumber: models.Caixa}
+	request.Buyer = models.Buyer{
+		Name:     "Fulano de Tal",
+		Email:    "fulano@example.com",
+		Document: models.Document{Type: "CPF", Number: document},
+		Address:  models.Address{Street: "Rua Um", City: "Rio de Janeiro"},
+	}
+	request.Title.ExpireDateTime = time.Now().AddDate(0, 0, 10)
+	return models.NewBoletoView(request, models.BoletoResponse{}, "", user)
+}
+
+func arrangeEraser(t *testing.T, fallback func() (FallbackStorage, error)) (*Eraser, db.BoletoRepository, *cacheStub) {
+	arrangePrivacyConfig(t, map[string]string{
+		"ENCRYPTION_KEY_SOURCE":      "local",
+		"ENCRYPTION_KEYRING":         "k1=a2V5LW9uZS1lbmNyeXB0aW9uLWtleS0zMi1ieXRlcyE=",
+		"ENCRYPTION_BLIND_INDEX_KEY": "index-key",
+		"RETENTION_DAYS":             "30",
+		"RETENTION_ACTION":           models.RetentionArchive,
+	})
+	repository := db.NewMemoryRepository()
+	cache := &cacheStub{html: map[string]bool{}, json: map[string]string{}}
+	return NewEraser(repository, db.NewMemoryErasureRepository(), cache, fallback), repository, cache
+}
+
+func TestErase_AnonymizesBuyerEverywhere(t *testing.T) {
+	fallback := fallbackStub{}
+	eraser, repository, cache := arrangeEraser(t, func() (FallbackStorage, error) { return fallback, nil })
+
+	stored := newBuyerBoleto("user-a", erasedDocument)
+	repository.SaveBoleto(context.Background(), stored)
+	cache.html[stored.ID.Hex()+":"+stored.PublicKey] = true
+
+	archived := newBuyerBoleto("user-a", erasedDocument)
+	archived.Boleto.Title.ExpireDateTime = time.Now().AddDate(-1, 0, 0)
+	repository.SaveBoleto(context.Background(), archived)
+	ApplyRetention(context.Background(), repository.(db.RetentionRepository), nil, time.Now())
+
+	another := newBuyerBoleto("user-a", "98765432100")
+	repository.SaveBoleto(context.Background(), another)
+
+	cache.json["boleto:json:1:pk"] = newBuyerBoleto("user-a", erasedDocument).ToMinifyJSON()
+	cache.json["boleto:json:2:pk"] = another.ToMinifyJSON()
+	fallback["pending"] = newBuyerBoleto("user-a", erasedDocument).ToMinifyJSON()
+
+	record, err := eraser.Erase(context.Background(), models.ErasureRequest{BuyerDocument: erasedDocument, Reason: "protocolo 123"}, "admin")
+
+	assert.Nil(t, err)
+	assert.True(t, record.Completed)
+	assert.ElementsMatch(t, []string{stored.ID.Hex(), archived.ID.Hex()}, record.Boletos)
+	assert.Equal(t, 2, record.CacheEntries)
+	assert.Equal(t, 1, record.FallbackFiles)
+	assert.Equal(t, "admin", record.RequestedBy)
+	assert.Equal(t, encryption.BlindIndex(erasedDocument), record.DocumentHash)
+	assert.NotContains(t, record.DocumentHash, erasedDocument)
+
+	got, _, _ := repository.GetBoletoByID(context.Background(), stored.ID.Hex(), stored.PublicKey)
+	assert.Equal(t, models.Buyer{Name: models.AnonymizedName, Document: models.Document{Type: "CPF"}}, got.Boleto.Buyer)
+	assert.NotNil(t, got.ErasedAt)
+	untouched, _, _ := repository.GetBoletoByID(context.Background(), another.ID.Hex(), another.PublicKey)
+	assert.Equal(t, "Fulano de Tal", untouched.Boleto.Buyer.Name)
+
+	assert.Empty(t, cache.html)
+	assert.False(t, strings.Contains(cache.json["boleto:json:1:pk"], erasedDocument))
+	assert.True(t, strings.Contains(cache.json["boleto:json:2:pk"], "98765432100"))
+	assert.False(t, strings.Contains(fallback["pending"], erasedDocument))
+
+	history, err := eraser.History(context.Background(), erasedDocument)
+	assert.Nil(t, err)
+	assert.Equal(t, []models.ErasureRecord{record}, history)
+}
+
+func TestErase_WhenFallbackFails_RecordsIncompleteErasure(t *testing.T) {
+	eraser, repository, _ := arrangeEraser(t, func() (FallbackStorage, error) { return nil, errors.New("storage unavailable") })
+	stored := newBuyerBoleto("user-a", erasedDocument)
+	repository.SaveBoleto(context.Background(), stored)
+
+	record, err := eraser.Erase(context.Background(), models.ErasureRequest{BuyerDocument: erasedDocument}, "admin")
+
+	assert.Nil(t, err)
+	assert.False(t, record.Completed)
+	assert.Equal(t, []string{"fallback: storage unavailable"}, record.Errors)
+	assert.Equal(t, []string{stored.ID.Hex()}, record.Boletos)
+	history, _ := eraser.History(context.Background(), erasedDocument)
+	assert.Equal(t, 1, len(history))
+}
+
+func TestErase_OnlyServiceUserBoletos(t *testing.T) {
+	eraser, repository, _ := arrangeEraser(t, func() (FallbackStorage, error) { return fallbackStub{}, nil })
+	own := newBuyerBoleto("user-a", erasedDocument)
+	other := newBuyerBoleto("user-b", erasedDocument)
+	repository.SaveBoleto(context.Background(), own)
+	repository.SaveBoleto(context.Background(), other)
+
+	record, _ := eraser.Erase(context.Background(), models.ErasureRequest{BuyerDocument: erasedDocument, ServiceUser: "user-a"}, "admin")
+
+	assert.Equal(t, []string{own.ID.Hex()}, record.Boletos)
+	got, _, _ := repository.GetBoletoByID(context.Background(), other.ID.Hex(), other.PublicKey)
+	assert.Equal(t, erasedDocument, got.Boleto.Buyer.Document.Number)
+
+	_, err := eraser.Erase(context.Background(), models.ErasureRequest{BuyerDocument: " "}, "admin")
+	assert.Equal(t, ErrBuyerDocumentRequired, err)
+}
+
+func TestErase_WithoutBlindIndexKey_ErasesNothing(t *testing.T) {
+	eraser, repository, _ := arrangeEraser(t, func() (FallbackStorage, error) { return fallbackStub{}, nil })
+	stored := newBuyerBoleto("user-a", erasedDocument)
+	repository.SaveBoleto(context.Background(), stored)
+	arrangePrivacyConfig(t, map[string]string{
+		"ENCRYPTION_KEY_SOURCE": "local",
+		"ENCRYPTION_KEYRING":    "k1=a2V5LW9uZS1lbmNyeXB0aW9uLWtleS0zMi1ieXRlcyE=",
+	})
+
+	_, err := eraser.Erase(context.Background(), models.ErasureRequest{BuyerDocument: erasedDocument}, "admin")
+	assert.Equal(t, ErrDocumentHashKeyRequired, err)
+	_, err = eraser.History(context.Background(), erasedDocument)
+	assert.Equal(t, ErrDocumentHashKeyRequired, err)
+
+	got, _, _ := repository.GetBoletoByID(context.Background(), stored.ID.Hex(), stored.PublicKey)
+	assert.Equal(t, erasedDocument, got.Boleto.Buyer.Document.Number, "sem a chave o documento não é eliminado para não ficar sem registro")
+}
@@ -0,0 +1,93 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/usermanagement"
+)
+
+//ApplyRetention Arquiva ou apaga os boletos que passaram do prazo de retenção e retorna quantos foram removidos
+//Os usuários com política própria seguem ela, mesmo que mantenha os boletos para sempre, e os demais seguem a política padrão
+func ApplyRetention(ctx context.Context, repository db.RetentionRepository, credentials []models.Credentials, now time.Time) (int, error) {
+	custom := make([]string, 0)
+	removed := 0
+
+	for _, c := range credentials {
+		if c.Retention == nil {
+			continue
+		}
+		custom = append(custom, c.Username)
+		if !c.Retention.Enabled() {
+			continue
+		}
+
+		n, err := repository.ApplyRetention(ctx, db.RetentionRule{
+			ServiceUsers: []string{c.Username},
+			Before:       c.Retention.Cutoff(now),
+			Action:       c.Retention.Action,
+		})
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	policy := models.DefaultRetentionPolicy()
+	if !policy.Enabled() {
+		return removed, nil
+	}
+
+	n, err := repository.ApplyRetention(ctx, db.RetentionRule{
+		ServiceUsers: custom,
+		Except:       true,
+		Before:       policy.Cutoff(now),
+		Action:       policy.Action,
+	})
+	return removed + n, err
+}
+
+//RunRetention Aplica as políticas de retenção a cada intervalo até o contexto ser cancelado
+//Não faz nada quando o repositório de boletos não suporta retenção
+func RunRetention(ctx context.Context, repository db.BoletoRepository, interval time.Duration) {
+	retention, ok := repository.(db.RetentionRepository)
+	if !ok || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runRetention(ctx, retention)
+		}
+	}
+}
+
+func runRetention(ctx context.Context, repository db.RetentionRepository) {
+	l := log.CreateLog()
+	l.Operation = "ApplyRetention"
+
+	credentials, err := usermanagement.ListCredentials(ctx)
+	if err != nil {
+		l.ErrorWithBasic("Error listing credentials for the retention policies", "Error", err)
+		return
+	}
+
+	start := time.Now()
+	removed, err := ApplyRetention(ctx, repository, credentials, start)
+	if err != nil {
+		l.ErrorWithBasic("Error applying retention policies", "Error", err)
+	}
+	if removed > 0 {
+		props := map[string]interface{}{"Boletos": removed, "TotalElapsedTimeInMilliseconds": time.Since(start).Milliseconds()}
+		l.InfoWithBasic("Boletos past their retention period removed", "Information", props)
+	}
+}
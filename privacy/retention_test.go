@@ -0,0 +1,78 @@
+package privacy
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangePrivacyConfig(t *testing.T, env map[string]string) {
+	os.Clearenv()
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	config.Install(true, false, true)
+	t.Cleanup(os.Clearenv)
+}
+
+func saveRetentionBoleto(repository db.BoletoRepository, user string, expiredDaysAgo int, paidDaysAgo int) models.BoletoView {
+	view := models.NewBoletoView(models.BoletoRequest{BankNumber: models.Caixa}, models.BoletoResponse{}, "", user)
+	view.Boleto.Title.ExpireDateTime = time.Now().AddDate(0, 0, -expiredDaysAgo)
+	if paidDaysAgo >= 0 {
+		paid := time.Now().AddDate(0, 0, -paidDaysAgo)
+		view.PaymentDate = &paid
+	}
+	repository.SaveBoleto(context.Background(), view)
+	return view
+}
+
+func TestApplyRetention_FollowsEachServiceUserPolicy(t *testing.T) {
+	arrangePrivacyConfig(t, map[string]string{"RETENTION_DAYS": "30", "RETENTION_ACTION": models.RetentionArchive})
+	repository := db.NewMemoryRepository()
+	credentials := []models.Credentials{
+		{Username: "user-a", Retention: &models.RetentionPolicy{Days: 10, Action: models.RetentionPurge}},
+		{Username: "user-b", Retention: &models.RetentionPolicy{Days: 0}},
+		{Username: "user-c"},
+	}
+
+	removed := []models.BoletoView{
+		saveRetentionBoleto(repository, "user-a", 20, -1),
+		saveRetentionBoleto(repository, "user-c", 40, -1),
+		saveRetentionBoleto(repository, "", 40, 35),
+	}
+	kept := []models.BoletoView{
+		saveRetentionBoleto(repository, "user-a", 5, -1),
+		saveRetentionBoleto(repository, "user-b", 400, -1),
+		saveRetentionBoleto(repository, "user-c", 40, 5),
+	}
+
+	total, err := ApplyRetention(context.Background(), repository.(db.RetentionRepository), credentials, time.Now())
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(removed), total)
+	for _, b := range removed {
+		_, _, err := repository.GetBoletoByID(context.Background(), b.ID.Hex(), b.PublicKey)
+		assert.Equal(t, db.ErrBoletoNotFound, err)
+	}
+	for _, b := range kept {
+		_, _, err := repository.GetBoletoByID(context.Background(), b.ID.Hex(), b.PublicKey)
+		assert.Nil(t, err, "o boleto de %s vencido há %s deve ser mantido", b.ServiceUser, b.Boleto.Title.ExpireDateTime)
+	}
+}
+
+func TestApplyRetention_WithoutDefaultPolicy_KeepsBoletos(t *testing.T) {
+	arrangePrivacyConfig(t, nil)
+	repository := db.NewMemoryRepository()
+	saveRetentionBoleto(repository, "user-a", 4000, -1)
+
+	total, err := ApplyRetention(context.Background(), repository.(db.RetentionRepository), nil, time.Now())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, total)
+}
@@ -1,7 +1,12 @@
 package queue
 
 import (
+	"context"
+
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 //PublisherInterface Interface do Publicador
@@ -12,8 +17,17 @@ type PublisherInterface interface {
 	GetMessageToPublish() []byte
 }
 
-//WriteMessage Publica um messagem na fila
-func WriteMessage(queuePublisher PublisherInterface) bool {
+//WriteMessage Publica um messagem na fila, propagando o trace-context nos headers da mensagem
+func WriteMessage(ctx context.Context, queuePublisher PublisherInterface) (ok bool) {
+	ctx, span := tracing.Start(ctx, "amqp.publish",
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination", queuePublisher.GetExchangeName()))
+	defer func() {
+		if !ok {
+			span.SetStatus(codes.Error, "message not published")
+		}
+		span.End()
+	}()
 
 	var channel *amqp.Channel
 	var queue amqp.Queue
@@ -35,7 +49,7 @@ func WriteMessage(queuePublisher PublisherInterface) bool {
 	if exchangeDeclare(channel, queuePublisher.GetExchangeName(), "topic") &&
 		queueDeclare(channel, queue, queuePublisher.GetQueueName()) &&
 		queueBinding(channel, queuePublisher.GetQueueName(), queuePublisher.GetExchangeName(), queuePublisher.GetRoutingKey()) {
-		return writeMessage(channel, queuePublisher) == nil
+		return writeMessage(channel, queuePublisher, tracing.Headers(ctx)) == nil
 	}
 	return false
 }
@@ -16,6 +16,9 @@ import (
 
 const HEARTBEAT_DEFAULT = 10
 
+//QueueTrustPolicy Nome da política de confiança do RabbitMQ em TLS_TRUST, como em rabbitmq:ca=rabbitmq-ca
+const QueueTrustPolicy = "rabbitmq"
+
 func openChannel(conn *amqp.Connection, op string) (*amqp.Channel, error) {
 	var channel *amqp.Channel
 	var err error
@@ -73,16 +76,22 @@ func queueBinding(channel *amqp.Channel, queue, exchange, key string) bool {
 	return err == nil
 }
 
-func writeMessage(channel *amqp.Channel, p PublisherInterface) error {
+func writeMessage(channel *amqp.Channel, p PublisherInterface, headers map[string]string) error {
 	notifyConfirm := make(chan amqp.Confirmation)
 	channel.NotifyPublish(notifyConfirm)
 
+	table := amqp.Table{}
+	for k, v := range headers {
+		table[k] = v
+	}
+
 	err := channel.Publish(
 		p.GetExchangeName(),
 		p.GetRoutingKey(), // queue
 		false,             // mandatory
 		false,             // immediate
 		amqp.Publishing{
+			Headers:      table,
 			DeliveryMode: amqp.Persistent,
 			ContentType:  "text/plain, charset=UTF-8",
 			Body:         p.GetMessageToPublish(),
@@ -107,18 +116,34 @@ func openConnection() (*amqp.Connection, error) {
 		hb = HEARTBEAT_DEFAULT
 	}
 
+	tlsConfig, err := queueTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	conn, err := amqp.DialConfig(config.Get().ConnQueue, amqp.Config{
-		Heartbeat: time.Duration(hb) * time.Second,
-		TLSClientConfig: &tls.Config{
-			MinVersion:         util.GetTLSVersion(config.Get().QueueMinTLS),
-			MaxVersion:         util.GetTLSVersion(config.Get().QueueMaxTLS),
-			InsecureSkipVerify: config.Get().QueueByPassCertificate,
-		},
+		Heartbeat:       time.Duration(hb) * time.Second,
+		TLSClientConfig: tlsConfig,
 	})
 
 	return conn, err
 }
 
+//queueTLSConfig Monta a configuração TLS da conexão com o RabbitMQ pela política de confiança rabbitmq do TLS_TRUST,
+//como nas chamadas aos bancos. QUEUE_MIN_TLS e QUEUE_MAX_TLS continuam limitando as versões aceitas
+func queueTLSConfig() (*tls.Config, error) {
+	cfg, err := util.TLSConfig(QueueTrustPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if min := util.GetTLSVersion(config.Get().QueueMinTLS); min > cfg.MinVersion {
+		cfg.MinVersion = min
+	}
+	cfg.MaxVersion = util.GetTLSVersion(config.Get().QueueMaxTLS)
+	return cfg, nil
+}
+
 func closeConnection(conn *amqp.Connection, op string) {
 	if conn != nil {
 		err := conn.Close()
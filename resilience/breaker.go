@@ -0,0 +1,162 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+//State Estado do circuit breaker de um banco
+type State int
+
+const (
+	//Closed As chamadas ao banco são executadas normalmente
+	Closed State = iota
+	//HalfOpen Uma chamada de teste é executada para decidir se o circuito fecha
+	HalfOpen
+	//Open As chamadas ao banco são rejeitadas até o fim do tempo de abertura
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+//Settings Parâmetros do circuit breaker e do bulkhead de um banco
+type Settings struct {
+	// FailureThreshold é a quantidade de falhas consecutivas que abre o circuito
+	FailureThreshold int
+	// OpenTimeout é o tempo que o circuito fica aberto antes de permitir uma chamada de teste
+	OpenTimeout time.Duration
+	// MaxConcurrent é a quantidade máxima de chamadas simultâneas ao banco
+	MaxConcurrent int
+}
+
+//Breaker Circuit breaker e bulkhead das chamadas a um banco
+type Breaker struct {
+	bank     string
+	settings Settings
+	slots    chan struct{}
+	now      func() time.Time
+	onChange func(bank string, state State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+//NewBreaker Cria o circuit breaker de um banco. onChange é chamado a cada mudança de estado
+func NewBreaker(bank string, settings Settings, onChange func(bank string, state State)) *Breaker {
+	return &Breaker{
+		bank:     bank,
+		settings: settings,
+		slots:    make(chan struct{}, settings.MaxConcurrent),
+		now:      time.Now,
+		onChange: onChange,
+	}
+}
+
+//State Retorna o estado atual do circuito
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+//Execute Executa call quando o circuito permite e há vaga no bulkhead
+//O erro retornado por call indica uma falha do banco e alimenta o circuito. Chamadas rejeitadas retornam
+//ServiceUnavailableError sem executar call
+func (b *Breaker) Execute(call func() error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		b.release(probe)
+		return rejected(b.bank, "bulkhead is full")
+	}
+
+	err = call()
+	<-b.slots
+
+	b.record(probe, err == nil)
+	return err
+}
+
+func (b *Breaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case Open:
+		return false, rejected(b.bank, "circuit is open")
+	case HalfOpen:
+		if b.probing {
+			return false, rejected(b.bank, "circuit is half-open")
+		}
+		b.probing = true
+		b.setState(HalfOpen)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (b *Breaker) release(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) record(probe, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	if success {
+		b.failures = 0
+		b.setState(Closed)
+		return
+	}
+
+	b.failures++
+	if probe || b.failures >= b.settings.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(Open)
+	}
+}
+
+// currentState considera o circuito meio aberto quando o tempo de abertura já passou
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(b.bank, state)
+	}
+}
@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PMoneda/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+var errBank = errors.New("connection refused")
+
+func arrangeBreaker(clock *time.Time) (*Breaker, *[]State) {
+	changes := &[]State{}
+	b := NewBreaker("Itau", Settings{FailureThreshold: 2, OpenTimeout: time.Minute, MaxConcurrent: 1}, func(bank string, state State) {
+		*changes = append(*changes, state)
+	})
+	b.now = func() time.Time { return *clock }
+	return b, changes
+}
+
+func fail() error    { return errBank }
+func succeed() error { return nil }
+
+func TestBreaker_OpensAfterConsecutiveFailuresAndFailsFast(t *testing.T) {
+	clock := time.Now()
+	b, changes := arrangeBreaker(&clock)
+
+	assert.Equal(t, errBank, b.Execute(fail))
+	assert.Equal(t, Closed, b.State())
+	assert.Equal(t, errBank, b.Execute(fail))
+	assert.Equal(t, Open, b.State())
+
+	called := false
+	err := b.Execute(func() error { called = true; return nil })
+
+	assert.False(t, called)
+	assert.True(t, Rejected(err))
+	assert.Equal(t, UnavailableCode, err.(interface{ ErrorCode() string }).ErrorCode())
+	assert.Equal(t, []State{Open}, *changes)
+}
+
+func TestBreaker_SuccessResetsConsecutiveFailures(t *testing.T) {
+	clock := time.Now()
+	b, _ := arrangeBreaker(&clock)
+
+	b.Execute(fail)
+	b.Execute(succeed)
+	b.Execute(fail)
+
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_HalfOpenProbeClosesOrReopensCircuit(t *testing.T) {
+	clock := time.Now()
+	b, changes := arrangeBreaker(&clock)
+	b.Execute(fail)
+	b.Execute(fail)
+
+	clock = clock.Add(time.Minute)
+	assert.Equal(t, HalfOpen, b.State())
+	assert.Equal(t, errBank, b.Execute(fail))
+	assert.Equal(t, Open, b.State())
+
+	clock = clock.Add(time.Minute)
+	assert.Nil(t, b.Execute(succeed))
+	assert.Equal(t, Closed, b.State())
+	assert.Equal(t, []State{Open, HalfOpen, Open, HalfOpen, Closed}, *changes)
+}
+
+func TestBreaker_RejectsWhenBulkheadIsFull(t *testing.T) {
+	clock := time.Now()
+	b, _ := arrangeBreaker(&clock)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go b.Execute(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	err := b.Execute(succeed)
+	close(release)
+
+	assert.True(t, Rejected(err))
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestAttempt_CountsOnlyUnavailabilityAsFailure(t *testing.T) {
+	assert.Nil(t, Attempt{Status: http.StatusOK}.failure())
+	assert.Nil(t, Attempt{Status: http.StatusInternalServerError}.failure())
+	assert.NotNil(t, Attempt{Status: http.StatusGatewayTimeout}.failure())
+	assert.Equal(t, errBank, Attempt{Err: errBank}.failure())
+}
+
+func TestFlowAttempt_ReadsStatusAndTransportError(t *testing.T) {
+	f := flow.NewFlow()
+	f.SetHeader("status", "503")
+	assert.Equal(t, Attempt{Status: 503}, FlowAttempt(f))
+
+	f = flow.NewFlow()
+	f.To("set://?prop=body", errBank)
+	assert.Equal(t, Attempt{Err: errBank}, FlowAttempt(f))
+
+	f = flow.NewFlow()
+	f.SetHeader("status", "400")
+	assert.Nil(t, FlowAttempt(f).failure())
+}
@@ -0,0 +1,86 @@
+package resilience
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/metrics"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+const (
+	//UnavailableCode Código de erro das chamadas rejeitadas pelo circuit breaker ou pelo bulkhead
+	UnavailableCode = "MP503"
+
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultMaxConcurrent    = 50
+)
+
+var breakers = sync.Map{}
+
+//Get Retorna o circuit breaker do banco, criando-o com a configuração atual no primeiro uso
+func Get(bank string) *Breaker {
+	if b, ok := breakers.Load(bank); ok {
+		return b.(*Breaker)
+	}
+	b, _ := breakers.LoadOrStore(bank, NewBreaker(bank, settings(), publishState))
+	return b.(*Breaker)
+}
+
+//States Retorna o estado do circuito de cada banco já chamado
+func States() map[string]State {
+	states := make(map[string]State)
+	breakers.Range(func(key, value interface{}) bool {
+		states[key.(string)] = value.(*Breaker).State()
+		return true
+	})
+	return states
+}
+
+//Banks Retorna os bancos que já possuem circuit breaker, em ordem alfabética
+func Banks() []string {
+	banks := []string{}
+	breakers.Range(func(key, value interface{}) bool {
+		banks = append(banks, key.(string))
+		return true
+	})
+	sort.Strings(banks)
+	return banks
+}
+
+//Rejected Indica se o erro é de uma chamada rejeitada pelo circuit breaker ou pelo bulkhead
+func Rejected(err error) bool {
+	e, ok := err.(models.ServiceUnavailableError)
+	return ok && e.Code == UnavailableCode
+}
+
+func rejected(bank, reason string) error {
+	return models.NewServiceUnavailableError(UnavailableCode, fmt.Sprintf("%s unavailable: %s", bank, reason))
+}
+
+func publishState(bank string, state State) {
+	metrics.PushBreakerState(bank, int(state))
+}
+
+func settings() Settings {
+	s := Settings{
+		FailureThreshold: config.Get().BreakerFailureThreshold,
+		OpenTimeout:      time.Duration(config.Get().BreakerOpenTimeoutInSeconds) * time.Second,
+		MaxConcurrent:    config.Get().BulkheadMaxConcurrent,
+	}
+
+	if s.FailureThreshold <= 0 {
+		s.FailureThreshold = defaultFailureThreshold
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = defaultOpenTimeout
+	}
+	if s.MaxConcurrent <= 0 {
+		s.MaxConcurrent = defaultMaxConcurrent
+	}
+	return s
+}
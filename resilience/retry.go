@@ -0,0 +1,365 @@
+package resilience
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PMoneda/flow"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/metrics"
+)
+
+//RetryPolicy Política de retry das chamadas de uma operação em um banco
+type RetryPolicy struct {
+	// MaxAttempts é a quantidade máxima de tentativas, incluindo a primeira
+	MaxAttempts int
+	// Backoff é a espera antes da segunda tentativa, dobrada a cada nova tentativa
+	Backoff time.Duration
+	// MaxBackoff limita a espera entre tentativas
+	MaxBackoff time.Duration
+	// Statuses são os status HTTP que permitem uma nova tentativa
+	Statuses map[int]bool
+}
+
+//Attempt Resultado de uma tentativa de chamada ao banco
+//Status diferente de zero indica que o banco respondeu
+type Attempt struct {
+	Status int
+	Err    error
+}
+
+var (
+	defaultPolicies = map[string]RetryPolicy{
+		metrics.OperationToken: {
+			MaxAttempts: 3,
+			Backoff:     200 * time.Millisecond,
+			MaxBackoff:  2 * time.Second,
+			Statuses:    map[int]bool{429: true, 502: true, 503: true, 504: true},
+		},
+		metrics.OperationRegister: {
+			MaxAttempts: 2,
+			Backoff:     500 * time.Millisecond,
+			MaxBackoff:  2 * time.Second,
+			Statuses:    map[int]bool{},
+		},
+	}
+
+	// nonIdempotent são as operações que só são repetidas quando a conexão com o banco nem chegou a ser aberta
+	nonIdempotent = map[string]bool{metrics.OperationRegister: true}
+
+	policiesMu  sync.Mutex
+	policiesRaw string
+	policies    map[string]RetryPolicy
+)
+
+//Call Executa uma chamada ao banco protegida pelo circuit breaker, repetindo-a conforme a política da operação
+//Só são repetidas as falhas de conexão e os status configurados; operações não idempotentes, como o registro,
+//só são repetidas quando a requisição não chegou a ser enviada. Cada tentativa com falha é logada em lg
+func Call(ctx context.Context, bank, operation string, lg *log.Log, call func() Attempt) error {
+	policy := GetPolicy(bank, operation)
+	breaker := Get(bank)
+
+	for attempt := 1; ; attempt++ {
+		var result Attempt
+		err := breaker.Execute(func() error {
+			result = call()
+			return result.failure()
+		})
+		if Rejected(err) {
+			return err
+		}
+
+		retry := attempt < policy.MaxAttempts && policy.allows(operation, result)
+		if err == nil && !retry {
+			return nil
+		}
+
+		wait := policy.wait(attempt)
+		logAttempt(lg, bank, operation, attempt, policy.MaxAttempts, result, retry, wait)
+		if !retry {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+//CallFlow Executa com Call uma chamada feita pelo flow, reenviando a mesma mensagem a cada tentativa
+func CallFlow(ctx context.Context, bank, operation string, lg *log.Log, f *flow.Flow, send func()) error {
+	request := f.GetBody()
+	return Call(ctx, bank, operation, lg, func() Attempt {
+		f.SetBody(request)
+		f.SetHeader("status", "")
+		send()
+		return FlowAttempt(f)
+	})
+}
+
+//FlowAttempt Retorna o resultado de uma chamada feita pelo flow, a partir do status e do erro da mensagem
+func FlowAttempt(f *flow.Flow) Attempt {
+	status, _ := strconv.Atoi(f.GetHeader().Get("status"))
+	result := Attempt{Status: status}
+	if err, ok := f.GetBody().(error); ok {
+		result.Err = err
+	}
+	return result
+}
+
+// failure indica uma falha do banco: erros de transporte e os status de indisponibilidade 502, 503 e 504
+// Status 500 não conta como falha porque alguns bancos o usam para SOAP faults de validação
+func (a Attempt) failure() error {
+	if a.Err != nil {
+		return a.Err
+	}
+	switch a.Status {
+	case 502, 503, 504:
+		return fmt.Errorf("bank responded with status %d", a.Status)
+	}
+	return nil
+}
+
+func (p RetryPolicy) allows(operation string, result Attempt) bool {
+	if result.Status != 0 {
+		return !nonIdempotent[operation] && p.Statuses[result.Status]
+	}
+	if nonIdempotent[operation] {
+		return isNotSentError(result.Err)
+	}
+	return isConnectionError(result.Err)
+}
+
+// wait calcula o backoff exponencial da tentativa com jitter, entre metade e o total da espera
+func (p RetryPolicy) wait(attempt int) time.Duration {
+	backoff := p.Backoff << uint(attempt-1)
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isConnectionError identifica falhas de conexão, em que o banco não respondeu. Timeouts não são repetidos
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isNotSentError identifica as falhas em que a requisição certamente não chegou ao banco: a conexão não foi aberta
+// ou o handshake TLS falhou antes de qualquer escrita. Conexões encerradas depois da escrita não entram, porque o banco
+// pode ter processado a requisição
+func isNotSentError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+
+	var opErr *net.OpError
+	var tlsErr tls.RecordHeaderError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.As(err, &tlsErr)
+}
+
+func logAttempt(lg *log.Log, bank, operation string, attempt, maxAttempts int, result Attempt, retry bool, wait time.Duration) {
+	if lg == nil {
+		return
+	}
+
+	props := log.LogEntry{
+		"Bank":        bank,
+		"BankCall":    operation,
+		"Attempt":     attempt,
+		"MaxAttempts": maxAttempts,
+		"Status":      result.Status,
+		"WillRetry":   retry,
+	}
+	if result.Err != nil {
+		props["Error"] = result.Err.Error()
+	}
+	if retry {
+		props["BackoffInMilliseconds"] = wait.Milliseconds()
+	}
+
+	lg.Warn(props, fmt.Sprintf("Attempt %d of %d to %s %s failed", attempt, maxAttempts, operation, bank))
+}
+
+//GetPolicy Retorna a política de retry do banco e operação
+//RETRY_POLICIES sobrescreve as políticas padrão com entradas separadas por ponto e vírgula, no formato
+//[banco.]operação:attempts=3,backoff=200ms,maxBackoff=2s,statuses=429|503. Uma entrada de banco parte da política da operação
+func GetPolicy(bank, operation string) RetryPolicy {
+	configured := configuredPolicies()
+
+	if p, ok := configured[policyKey(bank, operation)]; ok {
+		return p
+	}
+	if p, ok := configured[policyKey("", operation)]; ok {
+		return p
+	}
+	return defaultPolicy(operation)
+}
+
+func defaultPolicy(operation string) RetryPolicy {
+	if p, ok := defaultPolicies[strings.ToLower(operation)]; ok {
+		return p
+	}
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+func configuredPolicies() map[string]RetryPolicy {
+	raw := config.Get().RetryPolicies
+
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	if policies == nil || raw != policiesRaw {
+		parsed, err := ParsePolicies(raw)
+		if err != nil {
+			l := log.CreateLog()
+			l.Operation = "ParseRetryPolicies"
+			l.ErrorWithBasic("Invalid entries in RETRY_POLICIES were ignored", "Error", err)
+		}
+		policies, policiesRaw = parsed, raw
+	}
+	return policies
+}
+
+//ParsePolicies Interpreta a configuração RETRY_POLICIES. Em caso de erro, as entradas válidas são mantidas
+func ParsePolicies(raw string) (map[string]RetryPolicy, error) {
+	parsed := make(map[string]RetryPolicy)
+	var errs []string
+
+	entries := strings.Split(raw, ";")
+	// as entradas de operação são aplicadas antes, para servirem de base às entradas de banco
+	for _, bankEntries := range []bool{false, true} {
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			parts := strings.SplitN(entry, ":", 2)
+			bank, operation := splitPolicyKey(parts[0])
+			if (bank != "") != bankEntries {
+				continue
+			}
+
+			policy, ok := parsed[policyKey("", operation)]
+			if !ok {
+				policy = defaultPolicy(operation)
+			}
+
+			var opts string
+			if len(parts) == 2 {
+				opts = parts[1]
+			}
+			p, err := applyOptions(policy, opts)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", entry, err))
+				continue
+			}
+			parsed[policyKey(bank, operation)] = p
+		}
+	}
+
+	if len(errs) > 0 {
+		return parsed, errors.New(strings.Join(errs, "; "))
+	}
+	return parsed, nil
+}
+
+func applyOptions(p RetryPolicy, opts string) (RetryPolicy, error) {
+	statuses := make(map[int]bool, len(p.Statuses))
+	for k, v := range p.Statuses {
+		statuses[k] = v
+	}
+	p.Statuses = statuses
+
+	for _, opt := range strings.Split(opts, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid option %q", opt)
+		}
+
+		var err error
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "attempts":
+			p.MaxAttempts, err = strconv.Atoi(kv[1])
+			if err == nil && p.MaxAttempts < 1 {
+				err = errors.New("attempts must be at least 1")
+			}
+		case "backoff":
+			p.Backoff, err = time.ParseDuration(kv[1])
+		case "maxbackoff":
+			p.MaxBackoff, err = time.ParseDuration(kv[1])
+		case "statuses":
+			p.Statuses = make(map[int]bool)
+			for _, s := range strings.Split(kv[1], "|") {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				status, e := strconv.Atoi(s)
+				if e != nil {
+					err = e
+					break
+				}
+				p.Statuses[status] = true
+			}
+		default:
+			err = fmt.Errorf("unknown option %q", kv[0])
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func splitPolicyKey(key string) (bank, operation string) {
+	key = strings.TrimSpace(key)
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+func policyKey(bank, operation string) string {
+	return strings.ToLower(bank) + "." + strings.ToLower(operation)
+}
@@ -0,0 +1,147 @@
+package resilience
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func arrangeRetry(t *testing.T, bank, raw string) {
+	t.Helper()
+	parsed, err := ParsePolicies(raw)
+	assert.Nil(t, err)
+
+	policiesMu.Lock()
+	policies, policiesRaw = parsed, config.Get().RetryPolicies
+	policiesMu.Unlock()
+	breakers.Delete(bank)
+
+	t.Cleanup(func() {
+		policiesMu.Lock()
+		policies = nil
+		policiesMu.Unlock()
+		breakers.Delete(bank)
+	})
+}
+
+func countCalls(results ...Attempt) (func() Attempt, *int) {
+	calls := new(int)
+	return func() Attempt {
+		r := results[*calls]
+		*calls++
+		return r
+	}, calls
+}
+
+func TestParsePolicies_BankEntryStartsFromOperationEntry(t *testing.T) {
+	parsed, err := ParsePolicies("token:attempts=4,backoff=1s; Itau.token:statuses=503 ;register:maxBackoff=3s")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, parsed[".token"].MaxAttempts)
+	assert.Equal(t, 4, parsed["itau.token"].MaxAttempts)
+	assert.Equal(t, time.Second, parsed["itau.token"].Backoff)
+	assert.Equal(t, map[int]bool{503: true}, parsed["itau.token"].Statuses)
+	assert.True(t, parsed[".token"].Statuses[429])
+	assert.Equal(t, 2, parsed[".register"].MaxAttempts)
+	assert.Equal(t, 3*time.Second, parsed[".register"].MaxBackoff)
+}
+
+func TestParsePolicies_KeepsValidEntriesOnError(t *testing.T) {
+	parsed, err := ParsePolicies("token:attempts=0;register:attempts=3;bb.token:foo=1")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, parsed[".register"].MaxAttempts)
+	_, ok := parsed[".token"]
+	assert.False(t, ok)
+	_, ok = parsed["bb.token"]
+	assert.False(t, ok)
+}
+
+func TestRetryPolicy_WaitIsBoundedAndJittered(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for i := 0; i < 50; i++ {
+		first := p.wait(1)
+		assert.True(t, first >= 50*time.Millisecond && first <= 100*time.Millisecond)
+		capped := p.wait(5)
+		assert.True(t, capped >= 150*time.Millisecond && capped <= 300*time.Millisecond)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.wait(1))
+}
+
+func TestCall_RetriesTokenOnConfiguredStatusAndConnectionError(t *testing.T) {
+	arrangeRetry(t, "RetryBank", "token:attempts=3,backoff=1ms,maxBackoff=1ms")
+	call, calls := countCalls(Attempt{Status: http.StatusTooManyRequests}, Attempt{Err: errRefused}, Attempt{Status: http.StatusOK})
+
+	err := Call(context.Background(), "RetryBank", metrics.OperationToken, nil, call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestCall_NeverRetriesRegisterAfterBankResponded(t *testing.T) {
+	arrangeRetry(t, "RetryBank", "register:attempts=3,backoff=1ms,maxBackoff=1ms,statuses=503")
+	call, calls := countCalls(Attempt{Status: http.StatusServiceUnavailable}, Attempt{Status: http.StatusOK})
+
+	err := Call(context.Background(), "RetryBank", metrics.OperationRegister, nil, call)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestCall_RetriesRegisterOnlyOnConnectionError(t *testing.T) {
+	arrangeRetry(t, "RetryBank", "register:attempts=3,backoff=1ms,maxBackoff=1ms")
+	call, calls := countCalls(Attempt{Err: errRefused}, Attempt{Err: timeoutError{}}, Attempt{Status: http.StatusOK})
+
+	err := Call(context.Background(), "RetryBank", metrics.OperationRegister, nil, call)
+
+	assert.Equal(t, timeoutError{}, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestCall_NeverRetriesRegisterWhenConnectionDropsAfterTheWrite(t *testing.T) {
+	arrangeRetry(t, "RetryBank", "register:attempts=3,backoff=1ms,maxBackoff=1ms")
+	for _, dropped := range []error{
+		io.EOF,
+		io.ErrUnexpectedEOF,
+		&url.Error{Op: "Post", URL: "https://bank", Err: io.EOF},
+		&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		&net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE},
+	} {
+		call, calls := countCalls(Attempt{Err: dropped}, Attempt{Status: http.StatusOK})
+
+		err := Call(context.Background(), "RetryBank", metrics.OperationRegister, nil, call)
+
+		assert.Equal(t, dropped, err)
+		assert.Equal(t, 1, *calls, dropped.Error())
+	}
+}
+
+func TestCall_StopsWhenContextIsDone(t *testing.T) {
+	arrangeRetry(t, "RetryBank", "token:attempts=3,backoff=1h,maxBackoff=1h")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	call, calls := countCalls(Attempt{Err: errRefused}, Attempt{Status: http.StatusOK})
+
+	err := Call(ctx, "RetryBank", metrics.OperationToken, nil, call)
+
+	assert.Equal(t, errRefused, err)
+	assert.Equal(t, 1, *calls)
+}
@@ -1,6 +1,7 @@
 package santander
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"sync"
 
 	"net/http"
+	"time"
 
 	. "github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/certificate"
@@ -15,16 +17,17 @@ import (
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
 
 var (
-	onceMap       = &sync.Once{}
-	onceTransport = &sync.Once{}
-	transportTLS  *http.Transport
-	m             map[string]string
+	onceMap    = &sync.Once{}
+	transports = &util.TLSTransports{}
+	m          map[string]string
 )
 
 type bankSantander struct {
@@ -34,22 +37,18 @@ type bankSantander struct {
 }
 
 //New Create a new Santander Integration Instance
-func New() (bankSantander, error) {
+//The recipient credentials select its own client certificate; without one the configured certificate is used
+func New(credentials models.BankCredentials) (bankSantander, error) {
 	var err error
 	b := bankSantander{
 		validate: models.NewValidator(),
 		log:      log.CreateLog(),
 	}
 
-	certificates := certificate.TLSCertificate{
-		Crt: config.Get().SantanderCertificateSSLName,
-		Key: config.Get().SantanderCertificateSSLName,
-	}
+	crt, key := credentials.ClientCertificate(config.Get().SantanderCertificateSSLName, config.Get().SantanderCertificateSSLName)
+	certificates := certificate.TLSCertificate{Crt: crt, Key: key}
 
-	onceTransport.Do(func() {
-		transportTLS, err = util.BuildTLSTransport(certificates)
-	})
-	b.transport = transportTLS
+	b.transport, err = transports.Get(b.GetBankNameIntegration(), certificates)
 
 	if err != nil || (b.transport == nil && !config.Get().MockMode) {
 		return bankSantander{}, fmt.Errorf("fail on load TLSTransport: %v", err)
@@ -70,7 +69,9 @@ func (b bankSantander) Log() *log.Log {
 	return b.log
 }
 
-func (b bankSantander) GetTicket(boleto *models.BoletoRequest) (string, error) {
+//GetTicket Solicita o ticket de registro do boleto
+//O ticket é gerado a partir dos dados do título e vale apenas para o seu registro, por isso não é guardado pelo tokenmanager
+func (b bankSantander) GetTicket(ctx context.Context, boleto *models.BoletoRequest) (string, error) {
 	boleto.Title.OurNumber = calculateOurNumber(boleto)
 	boleto.Title.BoletoType, boleto.Title.BoletoTypeCode = getBoletoType(boleto)
 	pipe := NewFlow()
@@ -78,10 +79,20 @@ func (b bankSantander) GetTicket(boleto *models.BoletoRequest) (string, error) {
 	tlsURL := strings.Replace(config.Get().URLTicketSantander, "https", "tls", 1)
 	pipe.From("message://?source=inline", boleto, getRequestTicket(), tmpl.GetFuncMaps())
 	pipe.To("log://?type=request&url="+url, b.log)
-	duration := util.Duration(func() {
-		pipe.To(tlsURL, b.transport, map[string]string{"timeout": config.Get().TimeoutToken})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationToken)
+	tracing.InjectFlow(ctx, pipe)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationToken, b.log, pipe, func() {
+		duration = util.Duration(func() {
+			pipe.To(tlsURL, b.transport, map[string]string{"timeout": config.Get().TimeoutToken})
+		})
 	})
-	metrics.PushTimingMetric("santander-get-ticket-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return "", err
+	}
+	tracing.EndFlow(span, pipe)
+	metrics.PushBankTime("santander-get-ticket-boleto-time", b.GetBankNameIntegration(), metrics.OperationToken, duration.Seconds())
 	pipe.To("log://?type=response&url="+url, b.log)
 	ch := pipe.Choice()
 	ch.When(Header("status").IsEqualTo("200"))
@@ -102,7 +113,7 @@ func (b bankSantander) GetTicket(boleto *models.BoletoRequest) (string, error) {
 	return "", nil
 }
 
-func (b bankSantander) RegisterBoleto(input *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankSantander) RegisterBoleto(ctx context.Context, input *models.BoletoRequest) (models.BoletoResponse, error) {
 	serviceURL := config.Get().URLRegisterBoletoSantander
 	fromResponse := getResponseSantander()
 	toAPI := getAPIResponseSantander()
@@ -111,10 +122,20 @@ func (b bankSantander) RegisterBoleto(input *models.BoletoRequest) (models.Bolet
 
 	exec := NewFlow().From("message://?source=inline", input, inputTemplate, tmpl.GetFuncMaps())
 	exec.To("log://?type=request&url="+serviceURL, b.log)
-	duration := util.Duration(func() {
-		exec.To(santanderURL, b.transport, map[string]string{"method": "POST", "insecureSkipVerify": "true", "timeout": config.Get().TimeoutRegister})
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	tracing.InjectFlow(ctx, exec)
+	var duration time.Duration
+	err := resilience.CallFlow(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, exec, func() {
+		duration = util.Duration(func() {
+			exec.To(santanderURL, b.transport, map[string]string{"method": "POST", "bank": b.GetBankNameIntegration(), "timeout": config.Get().TimeoutRegister})
+		})
 	})
-	metrics.PushTimingMetric("santander-register-boleto-time", duration.Seconds())
+	if resilience.Rejected(err) {
+		tracing.End(span, err)
+		return models.BoletoResponse{}, err
+	}
+	tracing.EndFlow(span, exec)
+	metrics.PushBankTime("santander-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 	exec.To("log://?type=response&url="+serviceURL, b.log)
 	ch := exec.Choice()
 	ch.When(Header("status").IsEqualTo("200"))
@@ -130,17 +151,17 @@ func (b bankSantander) RegisterBoleto(input *models.BoletoRequest) (models.Bolet
 	}
 	return models.BoletoResponse{}, models.NewInternalServerError("MP500", "Internal error")
 }
-func (b bankSantander) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankSantander) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
-	if ticket, err := b.GetTicket(boleto); err != nil {
+	if ticket, err := b.GetTicket(ctx, boleto); err != nil {
 		return models.BoletoResponse{Errors: errs}, err
 	} else {
 		boleto.Authentication.AuthorizationToken = ticket
 	}
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
 func (b bankSantander) ValidateBoleto(boleto *models.BoletoRequest) models.Errors {
@@ -1,6 +1,7 @@
 package santander
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/mock"
@@ -55,9 +56,9 @@ func TestProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBoleto
 	mock.StartMockService("9046")
 	input := new(models.BoletoRequest)
 	errConvert := util.FromJSON(baseMockJSON, input)
-	bank, _ := New()
+	bank, _ := New(models.BankCredentials{})
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	assert.Nil(t, errConvert)
 	test.AssertProcessBoletoWithSuccess(t, output)
@@ -1,19 +1,23 @@
 package stone
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mundipagg/boleto-api/config"
-	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/log"
-	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/metrics"
+	"github.com/mundipagg/boleto-api/resilience"
+	"github.com/mundipagg/boleto-api/tokenmanager"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 )
 
 var (
-	HttpClient         = &util.HTTPClient{}
+	HttpClient         = &util.HTTPClient{Bank: "Stone"}
 	mu                 sync.Mutex
 	AccessTokenPayload = map[string]string{
 		"client_id":             "",
@@ -24,11 +28,9 @@ var (
 )
 
 const (
-	issuerBank              = "stone"
-	BadRequestError         = "status code 400"
-	DocumentNotFound        = "mongo: no documents in result"
-	recoverTokenFailMessage = "token query into mongo has failed"
-	saveTokenFailMessage    = "token save into mongo has failed"
+	issuerBank      = "stone"
+	bankName        = "Stone"
+	BadRequestError = "status code 400"
 )
 
 type AuthResponse struct {
@@ -42,85 +44,58 @@ type AuthResponse struct {
 	Scope                 string `json:"scope"`
 }
 
-func authenticate(clientID string, log *log.Log) (string, error) {
-	tk, err := fetchTokenFromStorage(clientID)
-	if err != nil {
-		log.Error(err, recoverTokenFailMessage)
-	}
-
-	if tk != "" {
-		log.InfoWithParams("Token recovered from mongo", "Information", map[string]interface{}{"Content": tk})
-		return tk, nil
-	}
-
-	return authenticateAndSaveToken(clientID, log)
-}
-
-func authenticateAndSaveToken(clientID string, log *log.Log) (string, error) {
-	mu.Lock()
-	defer mu.Unlock()
-
-	tk, err := AuthenticationWithRetryOnBadRequest(log)
-	if err != nil {
-		return "", err
-	}
-
-	token := models.NewToken(clientID, issuerBank, tk)
-	if err := db.SaveToken(token); err != nil {
-		log.Error(err, saveTokenFailMessage)
-	}
-
-	return tk, nil
-}
-
-func fetchTokenFromStorage(clientID string) (string, error) {
-	token, err := db.GetTokenByClientIDAndIssuerBank(clientID, issuerBank)
-	if err != nil {
-		if err.Error() == DocumentNotFound {
-			return "", nil
-		}
-		return "", err
-	}
+func authenticate(ctx context.Context, clientID string, log *log.Log) (string, error) {
+	return tokenmanager.Get(ctx, issuerBank, clientID, log, func(ctx context.Context) (tk tokenmanager.Token, err error) {
+		ctx, span := tracing.StartBank(ctx, bankName, metrics.OperationToken)
+		defer func() { tracing.End(span, err) }()
 
-	return token.AccessToken, nil
+		// AccessTokenPayload é compartilhado entre os clientes
+		mu.Lock()
+		defer mu.Unlock()
+		return AuthenticationWithRetryOnBadRequest(ctx, log)
+	})
 }
 
 // AuthenticationWithRetryOnBadRequest encapsulates logic for retry access token request once again
 // in bad request status code. That's because duplicated jti returns this mencioned status code
-func AuthenticationWithRetryOnBadRequest(log *log.Log) (string, error) {
-	var tk string
+func AuthenticationWithRetryOnBadRequest(ctx context.Context, log *log.Log) (tokenmanager.Token, error) {
+	var tk tokenmanager.Token
 	var err error
 
-	if tk, err = doAuthentication(log); err != nil {
+	if tk, err = doAuthentication(ctx, log); err != nil {
 		if !strings.Contains(err.Error(), BadRequestError) {
-			return "", err
+			return tokenmanager.Token{}, err
 		}
-		return doAuthentication(log)
+		return doAuthentication(ctx, log)
 	}
 
 	return tk, nil
 }
 
-func doAuthentication(log *log.Log) (string, error) {
+func doAuthentication(ctx context.Context, log *log.Log) (tokenmanager.Token, error) {
 	jwt, err := generateJWT()
 	if err != nil {
-		return "", err
+		return tokenmanager.Token{}, err
 	}
 
 	AccessTokenPayload["client_assertion"] = jwt
 	AccessTokenPayload["client_id"] = config.Get().StoneClientID
 
-	resp, err := HttpClient.PostFormURLEncoded(config.Get().URLStoneToken, AccessTokenPayload, log)
+	var resp []byte
+	err = resilience.Call(ctx, bankName, metrics.OperationToken, log, func() resilience.Attempt {
+		resp, err = HttpClient.PostFormURLEncoded(ctx, config.Get().URLStoneToken, AccessTokenPayload, log)
+		return resilience.Attempt{Err: err}
+	})
 
 	if err != nil {
-		return "", err
+		return tokenmanager.Token{}, err
 	}
 
 	var r AuthResponse
 	err = json.Unmarshal(resp, &r)
 	if err != nil {
-		return "", err
+		return tokenmanager.Token{}, err
 	}
 
-	return r.AccessToken, nil
+	return tokenmanager.Token{AccessToken: r.AccessToken, ExpiresIn: time.Duration(r.AccessTokenExpiresAt) * time.Second}, nil
 }
@@ -4,6 +4,7 @@
 package stone
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mundipagg/boleto-api/certificate"
@@ -41,7 +42,7 @@ func Test_authenticate(t *testing.T) {
 	l := log.CreateLog()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := authenticate(tt.args.clientID, l)
+			got, err := authenticate(context.Background(), tt.args.clientID, l)
 			assert.Nil(t, err)
 			assert.False(t, (err != nil) != tt.wantErr)
 			assert.Equal(t, tt.want, got)
@@ -1,15 +1,19 @@
 package stone
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/PMoneda/flow"
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
 	"github.com/mundipagg/boleto-api/metrics"
 	"github.com/mundipagg/boleto-api/models"
+	"github.com/mundipagg/boleto-api/resilience"
 	"github.com/mundipagg/boleto-api/tmpl"
+	"github.com/mundipagg/boleto-api/tracing"
 	"github.com/mundipagg/boleto-api/util"
 	"github.com/mundipagg/boleto-api/validations"
 )
@@ -38,21 +42,24 @@ func New() bankStone {
 	return b
 }
 
-func (b bankStone) ProcessBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankStone) ProcessBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	errs := b.ValidateBoleto(boleto)
 	if len(errs) > 0 {
 		return models.BoletoResponse{Errors: errs}, nil
 	}
 
-	if accToken, err := authenticate(boleto.Authentication.AccessKey, b.log); err != nil {
+	if accToken, err := authenticate(ctx, boleto.Authentication.AccessKey, b.log); err != nil {
+		if code, ok := util.HandshakeErrorCode(err); ok {
+			return models.GetBoletoResponseError(code, err.Error()), nil
+		}
 		return models.GetBoletoResponseError("MP500", err.Error()), nil
 	} else {
 		boleto.Authentication.AuthorizationToken = accToken
 	}
-	return b.RegisterBoleto(boleto)
+	return b.RegisterBoleto(ctx, boleto)
 }
 
-func (b bankStone) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoResponse, error) {
+func (b bankStone) RegisterBoleto(ctx context.Context, boleto *models.BoletoRequest) (models.BoletoResponse, error) {
 	var response string
 	var header string
 	var status int
@@ -65,10 +72,20 @@ func (b bankStone) RegisterBoleto(boleto *models.BoletoRequest) (models.BoletoRe
 	head := hearders(boleto.Authentication.AuthorizationToken)
 	b.log.Request(body, stoneURL, head)
 
-	duration := util.Duration(func() {
-		response, header, status, err = util.PostReponseWithHeader(stoneURL, util.SanitizeBody(body), config.Get().TimeoutRegister, head)
+	ctx, span := tracing.StartBank(ctx, b.GetBankNameIntegration(), metrics.OperationRegister)
+	var duration time.Duration
+	callErr := resilience.Call(ctx, b.GetBankNameIntegration(), metrics.OperationRegister, b.log, func() resilience.Attempt {
+		duration = util.Duration(func() {
+			response, header, status, err = util.PostReponseWithHeader(ctx, b.GetBankNameIntegration(), stoneURL, util.SanitizeBody(body), config.Get().TimeoutRegister, head)
+		})
+		return resilience.Attempt{Status: status, Err: err}
 	})
-	metrics.PushTimingMetric("stone-register-boleto-time", duration.Seconds())
+	if resilience.Rejected(callErr) {
+		tracing.End(span, callErr)
+		return models.BoletoResponse{}, callErr
+	}
+	tracing.EndHTTP(span, status, err)
+	metrics.PushBankTime("stone-register-boleto-time", b.GetBankNameIntegration(), metrics.OperationRegister, duration.Seconds())
 
 	params := getLogResponseProperties(header)
 
@@ -86,6 +103,10 @@ func getLogResponseProperties(header string) map[string]interface{} {
 
 func mapStoneResponse(request *models.BoletoRequest, response string, status int, httpErr error) models.BoletoResponse {
 	f := flow.NewFlow().To("set://?prop=body", response)
+	if code, ok := util.HandshakeErrorCode(httpErr); ok {
+		return models.GetBoletoResponseError(code, httpErr.Error())
+	}
+
 	switch status {
 	case 0, 504:
 		var msg string
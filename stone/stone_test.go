@@ -4,6 +4,7 @@
 package stone
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -85,7 +86,7 @@ func Test_ProcessBoleto_WhenServiceRespondsSuccessfully_ShouldHasSuccessfulBolet
 	input := newStubBoletoRequestStone().WithAmountInCents(201).Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -96,7 +97,7 @@ func Test_ProcessBoleto_WhenServiceRespondsUnsuccessful_ShouldHasErrorResponse(t
 
 	for _, fact := range boletoResponseFailParameters {
 		request := fact.Input.(*models.BoletoRequest)
-		response, _ := bank.ProcessBoleto(request)
+		response, _ := bank.ProcessBoleto(context.Background(), request)
 
 		test.AssertProcessBoletoFailed(t, response)
 		assert.Equal(t, fact.Expected.(models.ErrorResponse).Code, response.Errors[0].Code)
@@ -168,7 +169,7 @@ func Test_bankStone_ProcessBoleto(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, _ := tt.b.ProcessBoleto(tt.args.request)
+			got, _ := tt.b.ProcessBoleto(context.Background(), tt.args.request)
 			assert.Greater(t, len(got.Errors), 0)
 			err := got.Errors[0]
 			assert.Equal(t, err.Code, "MP400")
@@ -183,7 +184,7 @@ func BenchmarkBankStoneProcessBoleto(b *testing.B) {
 	input := newStubBoletoRequestStone().WithAmountInCents(201).Build()
 	bank := New()
 
-	bank.ProcessBoleto(input)
+	bank.ProcessBoleto(context.Background(), input)
 }
 
 func TestTemplateResponse_WhenRequestHasSpecialCharacter_ShouldBeParsedSuccessful(t *testing.T) {
@@ -191,7 +192,7 @@ func TestTemplateResponse_WhenRequestHasSpecialCharacter_ShouldBeParsedSuccessfu
 	input := newStubBoletoRequestStone().WithAmountInCents(201).WithBuyerName("Nome do \tComprador (Cliente)").Build()
 	bank := New()
 
-	output, _ := bank.ProcessBoleto(input)
+	output, _ := bank.ProcessBoleto(context.Background(), input)
 
 	test.AssertProcessBoletoWithSuccess(t, output)
 }
@@ -0,0 +1,256 @@
+package tokenmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/lifecycle"
+	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+)
+
+const (
+	// refreshAfter é a fração da validade a partir da qual o token é renovado em segundo plano
+	refreshAfter = 0.75
+	// expireAfter é a fração da validade a partir da qual o token deixa de ser usado, margem para a latência das chamadas
+	expireAfter = 0.9
+	// refreshTimeout limita a renovação em segundo plano, que não tem o contexto de uma requisição
+	refreshTimeout = time.Minute
+
+	recoverTokenFailMessage = "token query into mongo has failed"
+	saveTokenFailMessage    = "token save into mongo has failed"
+)
+
+//Token Token de acesso emitido pelo banco
+//ExpiresIn zerado indica que o banco não informou a validade, que passa a ser TOKEN_SAFE_DURATION_IN_MINUTES
+type Token struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+}
+
+//Fetcher Solicita um novo token ao banco
+type Fetcher func(ctx context.Context) (Token, error)
+
+//Manager Gerenciador dos tokens de acesso aos bancos
+//Mantém os tokens em memória e no Store compartilhado entre as instâncias, renova apenas um token por vez para cada
+//banco e cliente e renova o token em segundo plano antes que ele expire
+type Manager struct {
+	store Store
+	now   func() time.Time
+	// background executa as renovações proativas. Sem ele é usado o grupo definido por Install
+	background *lifecycle.Group
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	token   models.Token
+	pending *refresh
+}
+
+// refresh é a renovação em andamento de um token, aguardada por todas as chamadas que precisam dele
+type refresh struct {
+	done  chan struct{}
+	token models.Token
+	err   error
+}
+
+var (
+	defaultManager     *Manager
+	defaultManagerOnce sync.Once
+
+	installedMu sync.Mutex
+	installed   *lifecycle.Group
+)
+
+//Install Define o grupo das rotinas em segundo plano onde as renovações proativas dos tokens são executadas
+//Assim o desligamento cancela e aguarda as renovações antes de fechar a conexão com o Mongo
+func Install(background *lifecycle.Group) {
+	installedMu.Lock()
+	defer installedMu.Unlock()
+	installed = background
+}
+
+//NewManager Cria um gerenciador de tokens que usa o store como armazenamento compartilhado
+func NewManager(store Store) *Manager {
+	return &Manager{
+		store:   store,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+//Get Retorna o token do banco para o cliente usando o gerenciador padrão, criado com o Store de TOKEN_STORE no primeiro uso
+func Get(ctx context.Context, bank, clientID string, lg *log.Log, fetch Fetcher) (string, error) {
+	defaultManagerOnce.Do(func() {
+		defaultManager = NewManager(NewStore())
+	})
+	return defaultManager.Get(ctx, bank, clientID, lg, fetch)
+}
+
+//ClientKey Identifica um cliente pelas suas credenciais, para que um token nunca seja usado com uma senha diferente
+//da que o obteve. A senha não é armazenada, apenas o seu hash
+func ClientKey(clientID, secret string) string {
+	sum := sha256.Sum256([]byte(clientID + ":" + secret))
+	return clientID + "#" + hex.EncodeToString(sum[:])
+}
+
+//Get Retorna o token do banco para o cliente
+//Um token válido é retornado da memória e, perto de expirar, é renovado em segundo plano. Sem token válido, a chamada
+//aguarda a renovação, que consulta o Store antes de chamar fetch
+func (m *Manager) Get(ctx context.Context, bank, clientID string, lg *log.Log, fetch Fetcher) (string, error) {
+	bank = strings.ToLower(bank)
+	key := bank + ":" + clientID
+
+	m.mu.Lock()
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{}
+		m.entries[key] = e
+	}
+
+	now := m.now()
+	if usable(e.token, now) {
+		token := e.token.AccessToken
+		if refreshDue(e.token, now) && e.pending == nil {
+			r := m.begin(e)
+			m.mu.Unlock()
+
+			m.inBackground(func(ctx context.Context) {
+				ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+				defer cancel()
+				m.refresh(ctx, e, r, bank, clientID, lg, fetch, true)
+			})
+			return token, nil
+		}
+		m.mu.Unlock()
+		return token, nil
+	}
+
+	r := e.pending
+	if r == nil {
+		r = m.begin(e)
+		m.mu.Unlock()
+		m.refresh(ctx, e, r, bank, clientID, lg, fetch, false)
+	} else {
+		m.mu.Unlock()
+	}
+
+	select {
+	case <-r.done:
+		return r.token.AccessToken, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// inBackground executa a renovação proativa no grupo das rotinas em segundo plano, quando definido
+func (m *Manager) inBackground(run func(ctx context.Context)) {
+	background := m.background
+	if background == nil {
+		installedMu.Lock()
+		background = installed
+		installedMu.Unlock()
+	}
+	if background == nil {
+		go run(context.Background())
+		return
+	}
+	background.Go(run)
+}
+
+// begin registra uma renovação em andamento. Deve ser chamado com m.mu travado
+func (m *Manager) begin(e *entry) *refresh {
+	r := &refresh{done: make(chan struct{})}
+	e.pending = r
+	return r
+}
+
+func (m *Manager) refresh(ctx context.Context, e *entry, r *refresh, bank, clientID string, lg *log.Log, fetch Fetcher, proactive bool) {
+	// uma renovação iniciada depois do desligamento não consulta o Store nem o banco
+	token, err := models.Token{}, ctx.Err()
+	if err == nil {
+		token, err = m.load(ctx, bank, clientID, lg, fetch, proactive)
+	}
+	if err != nil && proactive && lg != nil {
+		lg.Warn(err, "Proactive token refresh has failed, the current token is kept")
+	}
+
+	m.mu.Lock()
+	if err == nil {
+		e.token = token
+	}
+	e.pending = nil
+	r.token, r.err = token, err
+	m.mu.Unlock()
+
+	close(r.done)
+}
+
+// load usa o token do Store quando outra instância já o renovou e, caso contrário, solicita um novo token ao banco
+func (m *Manager) load(ctx context.Context, bank, clientID string, lg *log.Log, fetch Fetcher, proactive bool) (models.Token, error) {
+	stored, err := m.store.Get(bank, clientID)
+	if err != nil && lg != nil {
+		lg.Error(err, recoverTokenFailMessage)
+	}
+
+	now := m.now()
+	if err == nil && usable(stored, now) && !(proactive && refreshDue(stored, now)) {
+		return stored, nil
+	}
+
+	fetched, err := fetch(ctx)
+	if err != nil {
+		return models.Token{}, err
+	}
+
+	token := newToken(bank, clientID, fetched, now)
+	if err := m.store.Save(token); err != nil && lg != nil {
+		lg.Error(err, saveTokenFailMessage)
+	}
+	return token, nil
+}
+
+func newToken(bank, clientID string, fetched Token, now time.Time) models.Token {
+	token := models.NewToken(clientID, bank, fetched.AccessToken)
+	token.CreatedAt = now
+	if fetched.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(fetched.ExpiresIn)
+	}
+	return token
+}
+
+func usable(token models.Token, now time.Time) bool {
+	return token.AccessToken != "" && now.Before(after(token, expireAfter))
+}
+
+func refreshDue(token models.Token, now time.Time) bool {
+	return !now.Before(after(token, refreshAfter))
+}
+
+// after retorna o instante em que a fração informada da validade do token foi consumida
+func after(token models.Token, fraction float64) time.Time {
+	expiresAt := token.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = token.CreatedAt.Add(time.Duration(config.Get().TokenSafeDurationInMinutes) * time.Minute)
+	}
+	lifetime := expiresAt.Sub(token.CreatedAt)
+	return token.CreatedAt.Add(time.Duration(float64(lifetime) * fraction))
+}
+
+//NewToken Cria um Token a partir do access_token e do expires_in, em segundos, extraídos da resposta do banco
+//expires_in ausente ou inválido resulta em validade zerada
+func NewToken(accessToken, expiresIn string) Token {
+	seconds, err := strconv.Atoi(strings.TrimSpace(expiresIn))
+	if err != nil || seconds < 0 {
+		seconds = 0
+	}
+	return Token{AccessToken: accessToken, ExpiresIn: time.Duration(seconds) * time.Second}
+}
@@ -0,0 +1,211 @@
+package tokenmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/lifecycle"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	tokens map[string]models.Token
+	saves  int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{tokens: map[string]models.Token{}}
+}
+
+func (s *fakeStore) Get(bank, clientID string) (models.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[bank+":"+clientID], nil
+}
+
+func (s *fakeStore) Save(token models.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.IssuerBank+":"+token.ClientID] = token
+	s.saves++
+	return nil
+}
+
+func arrangeManager(store Store, clock *time.Time) *Manager {
+	m := NewManager(store)
+	m.now = func() time.Time { return *clock }
+	return m
+}
+
+func countingFetch(calls *int32, expiresIn time.Duration) Fetcher {
+	return func(ctx context.Context) (Token, error) {
+		n := atomic.AddInt32(calls, 1)
+		return Token{AccessToken: "token-" + string(rune('0'+n)), ExpiresIn: expiresIn}, nil
+	}
+}
+
+func TestGet_ReusesTokenUntilItExpires(t *testing.T) {
+	clock := time.Now()
+	store := newFakeStore()
+	m := arrangeManager(store, &clock)
+	var calls int32
+	fetch := countingFetch(&calls, time.Hour)
+
+	first, err := m.Get(context.Background(), "Itau", "client", nil, fetch)
+	assert.Nil(t, err)
+	second, _ := m.Get(context.Background(), "Itau", "client", nil, fetch)
+
+	assert.Equal(t, "token-1", first)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, clock.Add(time.Hour), store.tokens["itau:client"].ExpiresAt)
+
+	clock = clock.Add(55 * time.Minute)
+	third, _ := m.Get(context.Background(), "Itau", "client", nil, fetch)
+
+	assert.Equal(t, "token-2", third)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestGet_UsesTokenSavedByAnotherInstance(t *testing.T) {
+	clock := time.Now()
+	store := newFakeStore()
+	token := models.NewToken("client", "pefisa", "stored")
+	token.CreatedAt, token.ExpiresAt = clock, clock.Add(time.Hour)
+	store.Save(token)
+	var calls int32
+
+	got, err := arrangeManager(store, &clock).Get(context.Background(), "Pefisa", "client", nil, countingFetch(&calls, time.Hour))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "stored", got)
+	assert.Equal(t, int32(0), calls)
+}
+
+func TestGet_RefreshesOnlyOnceForConcurrentCalls(t *testing.T) {
+	clock := time.Now()
+	m := arrangeManager(newFakeStore(), &clock)
+	var calls int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (Token, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return Token{AccessToken: "shared", ExpiresIn: time.Hour}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = m.Get(context.Background(), "BancoDoBrasil", "client", nil, fetch)
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	for _, r := range results {
+		assert.Equal(t, "shared", r)
+	}
+}
+
+func TestGet_RefreshesProactivelyKeepingCurrentToken(t *testing.T) {
+	clock := time.Now()
+	m := arrangeManager(newFakeStore(), &clock)
+	var calls int32
+	refreshed := make(chan struct{})
+	fetch := func(ctx context.Context) (Token, error) {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			defer close(refreshed)
+			return Token{AccessToken: "new", ExpiresIn: time.Hour}, nil
+		}
+		return Token{AccessToken: "old", ExpiresIn: time.Hour}, nil
+	}
+	m.Get(context.Background(), "Itau", "client", nil, fetch)
+
+	clock = clock.Add(50 * time.Minute)
+	got, err := m.Get(context.Background(), "Itau", "client", nil, fetch)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", got)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("token was not refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		got, _ = m.Get(context.Background(), "Itau", "client", nil, fetch)
+		return got == "new"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestGet_ProactiveRefreshStopsWithTheBackgroundGroup(t *testing.T) {
+	clock := time.Now()
+	store := newFakeStore()
+	m := arrangeManager(store, &clock)
+	m.background = lifecycle.NewGroup()
+	var calls int32
+	started := make(chan struct{})
+	fetch := func(ctx context.Context) (Token, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return Token{AccessToken: "old", ExpiresIn: time.Hour}, nil
+		}
+		close(started)
+		<-ctx.Done()
+		return Token{}, ctx.Err()
+	}
+	m.Get(context.Background(), "Itau", "client", nil, fetch)
+
+	clock = clock.Add(50 * time.Minute)
+	got, _ := m.Get(context.Background(), "Itau", "client", nil, fetch)
+	assert.Equal(t, "old", got)
+	<-started
+
+	assert.Nil(t, m.background.Stop(context.Background()), "o desligamento cancela e aguarda a renovação")
+	assert.Equal(t, 1, store.saves)
+
+	got, _ = m.Get(context.Background(), "Itau", "client", nil, fetch)
+	assert.Equal(t, "old", got)
+	m.mu.Lock()
+	pending := m.entries["itau:client"].pending
+	m.mu.Unlock()
+	if pending != nil {
+		<-pending.done
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "depois do desligamento o banco não é chamado de novo")
+	assert.Equal(t, 1, store.saves)
+}
+
+func TestGet_ReturnsFetchErrorWithoutCaching(t *testing.T) {
+	clock := time.Now()
+	store := newFakeStore()
+	m := arrangeManager(store, &clock)
+	errBank := errors.New("401 Unauthorized")
+
+	_, err := m.Get(context.Background(), "Itau", "client", nil, func(ctx context.Context) (Token, error) {
+		return Token{}, errBank
+	})
+
+	assert.Equal(t, errBank, err)
+	assert.Equal(t, 0, store.saves)
+}
+
+func TestClientKey_DependsOnSecret(t *testing.T) {
+	assert.NotEqual(t, ClientKey("client", "secret"), ClientKey("client", "other"))
+	assert.NotContains(t, ClientKey("client", "secret"), "secret")
+}
+
+func TestNewToken_ParsesExpiresIn(t *testing.T) {
+	assert.Equal(t, Token{AccessToken: "tk", ExpiresIn: time.Hour}, NewToken("tk", "3600"))
+	assert.Equal(t, Token{AccessToken: "tk"}, NewToken("tk", "null"))
+}
@@ -0,0 +1,58 @@
+package tokenmanager
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//Store Armazenamento dos tokens compartilhado entre as instâncias da aplicação
+//Get retorna um token vazio, sem erro, quando não há token válido armazenado
+type Store interface {
+	Get(bank, clientID string) (models.Token, error)
+	Save(token models.Token) error
+}
+
+const (
+	// MongoStore compartilha os tokens entre as instâncias pela coleção MONGODB_TOKEN_COLLECTION
+	MongoStore = "mongo"
+	// MemoryStore mantém os tokens apenas na memória da instância, para testes e modo dev
+	MemoryStore = "memory"
+)
+
+//NewStore Cria o Store configurado em TOKEN_STORE. Mongo é o padrão
+func NewStore() Store {
+	if strings.ToLower(config.Get().TokenStore) == MemoryStore {
+		return memoryStore{}
+	}
+	return mongoStore{}
+}
+
+// memoryStore não compartilha os tokens, que ficam apenas no cache do Manager
+type memoryStore struct{}
+
+func (memoryStore) Get(bank, clientID string) (models.Token, error) {
+	return models.Token{}, nil
+}
+
+func (memoryStore) Save(token models.Token) error {
+	return nil
+}
+
+type mongoStore struct{}
+
+func (mongoStore) Get(bank, clientID string) (models.Token, error) {
+	token, err := db.GetTokenByClientIDAndIssuerBank(clientID, bank)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Token{}, nil
+	}
+	return token, err
+}
+
+func (mongoStore) Save(token models.Token) error {
+	return db.SaveToken(token)
+}
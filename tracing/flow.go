@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/PMoneda/flow"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//InjectFlow Propaga o trace-context nos headers da mensagem que o flow envia ao banco
+func InjectFlow(ctx context.Context, f *flow.Flow) {
+	for k, v := range Headers(ctx) {
+		f.SetHeader(k, v)
+	}
+}
+
+//EndFlow Encerra o span de uma chamada feita pelo flow, lendo o status HTTP do header da mensagem
+func EndFlow(span trace.Span, f *flow.Flow) {
+	status, _ := strconv.Atoi(f.GetHeader().Get("status"))
+
+	var err error
+	if e, ok := f.GetBody().(error); ok {
+		err = e
+	}
+	EndHTTP(span, status, err)
+}
@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mundipagg/boleto-api/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	//ExporterOTLP Exporta os spans via OTLP/HTTP para o coletor em TRACING_OTLP_ENDPOINT
+	ExporterOTLP = "otlp"
+	//ExporterStdout Escreve os spans no stdout, para execução local
+	ExporterStdout = "stdout"
+
+	tracerName = "github.com/mundipagg/boleto-api"
+
+	//AttrBankName Nome de integração do banco chamado
+	AttrBankName = attribute.Key("bank.name")
+	//AttrBankOperation Operação executada no banco (token ou registro)
+	AttrBankOperation = attribute.Key("bank.operation")
+)
+
+var provider *sdktrace.TracerProvider
+
+//Install Configura a propagação W3C trace-context e o exportador definido em TRACING_EXPORTER
+//Sem exportador configurado, ou em modo mock, os spans não são gravados, mas o trace-context continua sendo propagado
+func Install() error {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if config.Get().MockMode {
+		return nil
+	}
+
+	exporter, err := newExporter(config.Get().TracingExporter)
+	if err != nil || exporter == nil {
+		return err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(config.Get().ApplicationName),
+		semconv.ServiceVersionKey.String(config.Get().BuildVersion),
+		semconv.DeploymentEnvironmentKey.String(config.Get().Environment),
+		semconv.HostNameKey.String(config.Get().MachineName),
+	)
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+func newExporter(kind string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(kind) {
+	case "":
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if config.Get().TracingOTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Get().TracingOTLPEndpoint))
+		}
+		if config.Get().TracingOTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %s", kind)
+	}
+}
+
+//Shutdown Exporta os spans pendentes e encerra o exportador
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+//Start Inicia um span filho do span presente no contexto
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+//StartRequest Inicia o span raiz de uma requisição recebida, continuando o trace-context enviado pelo cliente
+func StartRequest(r *http.Request, route string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return otel.Tracer(tracerName).Start(ctx, r.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(r.Method),
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPTargetKey.String(r.URL.Path),
+		))
+}
+
+//StartBank Inicia o span de uma chamada a um banco
+func StartBank(ctx context.Context, bank, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "bank."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrBankName.String(bank), AttrBankOperation.String(operation)))
+}
+
+//End Registra o erro, quando houver, e encerra o span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+//EndHTTP Registra o status HTTP da resposta e encerra o span. Status 5xx marcam o span com erro
+func EndHTTP(span trace.Span, status int, err error) {
+	if status != 0 {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if err == nil && status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+	End(span, err)
+}
+
+//Inject Propaga o trace-context do contexto no mapa de headers de uma requisição de saída
+func Inject(ctx context.Context, header map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, mapCarrier(header))
+}
+
+//InjectRequest Propaga o trace-context do contexto da requisição nos seus headers
+func InjectRequest(r *http.Request) {
+	otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+//Headers Retorna os headers de trace-context do contexto
+func Headers(ctx context.Context) map[string]string {
+	header := make(map[string]string)
+	Inject(ctx, header)
+	return header
+}
+
+// mapCarrier adapta um mapa de headers para o propagador de trace-context
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c mapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c mapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PMoneda/flow"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+func arrangeRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+func TestStartBank_PropagatesTraceContextAndRecordsStatus(t *testing.T) {
+	recorder := arrangeRecorder()
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/boleto/register", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := StartRequest(req, "/v2/boleto/register")
+
+	ctx, span := StartBank(ctx, "BancoDoBrasil", "register")
+	header := Headers(ctx)
+	EndHTTP(span, http.StatusBadGateway, nil)
+	EndHTTP(root, http.StatusOK, nil)
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+
+	bankSpan := spans[0]
+	assert.Equal(t, "bank.register", bankSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", bankSpan.SpanContext().TraceID().String())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), bankSpan.Parent().SpanID())
+	assert.Contains(t, bankSpan.Attributes(), AttrBankName.String("BancoDoBrasil"))
+	assert.Contains(t, bankSpan.Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusBadGateway))
+	assert.Equal(t, codes.Error, bankSpan.Status().Code)
+
+	assert.Contains(t, header["traceparent"], bankSpan.SpanContext().SpanID().String())
+}
+
+func TestEndFlow_ReadsStatusAndErrorFromMessage(t *testing.T) {
+	recorder := arrangeRecorder()
+
+	ctx, span := StartBank(context.Background(), "Itau", "token")
+	f := flow.NewFlow()
+	InjectFlow(ctx, f)
+	assert.NotEmpty(t, f.GetHeader().Get("traceparent"))
+
+	f.SetHeader("status", "403")
+	f.To("set://?prop=body", errors.New("403 Forbidden"))
+	EndFlow(span, f)
+
+	spans := recorder.Ended()
+	assert.Equal(t, 1, len(spans))
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusForbidden))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "403 Forbidden", spans[0].Status().Description)
+}
@@ -7,13 +7,15 @@ import (
 
 //LoadMockUserCredentials Cria credenciais do mock
 func LoadMockUserCredentials() (string, string) {
+	const password = "pass"
 	c := models.Credentials{
 		ID:       primitive.NewObjectID(),
 		Username: "user",
-		Password: "pass",
 	}
+	c.SetPassword(password)
 	uk := c.ID.Hex()
 	c.UserKey = string(uk)
+	mockUsers.Store(c.UserKey, true)
 	addUser(c.UserKey, c)
-	return c.UserKey, c.Password
+	return c.UserKey, password
 }
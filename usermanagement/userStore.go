@@ -1,17 +1,69 @@
 package usermanagement
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mundipagg/boleto-api/db"
 	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var userCredentialStorage = sync.Map{}
+//ErrAPIKeyNotFound A chave de API não existe no usuário
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const (
+	generatedPasswordSize = 24
+	generatedAPIKeyIDSize = 8
+	// apiKeyPrefix identifica as chaves de API, que são aleatórias com o mesmo tamanho das senhas geradas
+	apiKeyPrefix = "bk_"
+)
+
+var (
+	userCredentialStorage = sync.Map{}
+	// verifiedPasswords guarda o digest da última senha validada de cada usuário, evitando um bcrypt por requisição
+	verifiedPasswords = sync.Map{}
+	// apiKeys indexa o usuário dono de cada chave de API pelo hash da chave
+	apiKeys = sync.Map{}
+	// mockUsers guarda as chaves dos usuários do mock, que não existem no banco e não são removidos na recarga
+	mockUsers = sync.Map{}
+
+	repositoryMu sync.RWMutex
+	repository   = db.NewMongoCredentialRepository()
+)
+
+//Install Define o repositório onde as credenciais são persistidas
+func Install(r db.CredentialRepository) {
+	repositoryMu.Lock()
+	defer repositoryMu.Unlock()
+	repository = r
+}
+
+func getRepository() db.CredentialRepository {
+	repositoryMu.RLock()
+	defer repositoryMu.RUnlock()
+	return repository
+}
 
 func addUser(key string, value interface{}) {
+	if previous, ok := userCredentialStorage.Load(key); ok {
+		removeAPIKeys(previous.(models.Credentials))
+	}
 	userCredentialStorage.Store(key, value)
+	for _, apiKey := range value.(models.Credentials).APIKeys {
+		apiKeys.Store(apiKey.Hash, key)
+	}
 }
 
 //GetUser Busca credenciais de um usuário
@@ -22,18 +74,454 @@ func GetUser(key string) (interface{}, bool) {
 	return nil, false
 }
 
+//Authenticate Valida a senha de um usuário ativo e retorna suas credenciais
+func Authenticate(key, password string) (models.Credentials, bool) {
+	u, ok := GetUser(key)
+	if !ok {
+		return models.Credentials{}, false
+	}
+
+	user := u.(models.Credentials)
+	if user.Disabled {
+		return models.Credentials{}, false
+	}
+
+	digest := passwordDigest(user.Password, password)
+	if v, ok := verifiedPasswords.Load(key); ok && subtle.ConstantTimeCompare(v.([]byte), digest) == 1 {
+		return user, true
+	}
+
+	if !user.CheckPassword(password) {
+		return models.Credentials{}, false
+	}
+
+	verifiedPasswords.Store(key, digest)
+	return user, true
+}
+
+//AuthenticateAPIKey Valida a chave de API de um usuário ativo e retorna suas credenciais e a chave usada
+func AuthenticateAPIKey(key string) (models.Credentials, models.APIKey, bool) {
+	hash := HashAPIKey(key)
+	userKey, ok := apiKeys.Load(hash)
+	if !ok {
+		return models.Credentials{}, models.APIKey{}, false
+	}
+
+	u, ok := GetUser(userKey.(string))
+	if !ok {
+		return models.Credentials{}, models.APIKey{}, false
+	}
+
+	user := u.(models.Credentials)
+	if user.Disabled {
+		return models.Credentials{}, models.APIKey{}, false
+	}
+	for _, apiKey := range user.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hash)) == 1 {
+			return user, apiKey, true
+		}
+	}
+	return models.Credentials{}, models.APIKey{}, false
+}
+
+//GetUserByUsername Busca as credenciais ativas de um usuário pelo nome
+func GetUserByUsername(username string) (models.Credentials, bool) {
+	var found models.Credentials
+	var ok bool
+	userCredentialStorage.Range(func(key, value interface{}) bool {
+		if user := value.(models.Credentials); user.Username == username && !user.Disabled {
+			found, ok = user, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+//HashAPIKey Retorna o hash SHA-256, em hexadecimal, guardado no lugar da chave de API
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+//EnsureIndexes Cria os índices do repositório de credenciais, como o que impede dois usuários com o mesmo nome
+func EnsureIndexes(ctx context.Context) error {
+	if indexed, ok := getRepository().(db.IndexedRepository); ok {
+		return indexed.EnsureIndexes(ctx)
+	}
+	return nil
+}
+
 //LoadUserCredentials Carrega credenciais salvas no banco de dados
+//Senhas ainda em texto puro são substituídas pelo seu hash. Usuários removidos ou desabilitados deixam de autenticar.
+//A carga é mesclada com a memória: credenciais alteradas pela API de administração depois da leitura não são sobrescritas nem removidas
 func LoadUserCredentials() {
 	log := log.CreateLog()
+	log.Operation = "LoadUserCredentials"
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.ConnectionTimeout)
+	defer cancel()
 
-	c, err := db.GetUserCredentials()
+	readAt := time.Now()
+	c, err := getRepository().GetCredentials(ctx)
 	if err != nil {
 		log.Error(err.Error(), fmt.Sprintf("Error in get user crendentials - %s", err.Error()))
 		return
 	}
 
+	loaded := make(map[string]bool, len(c))
 	for _, u := range c {
+		if !u.HasHashedPassword() {
+			if err := hashLegacyPassword(ctx, &u); err != nil {
+				log.Error(err.Error(), fmt.Sprintf("Error hashing password of user %s", u.Username))
+				continue
+			}
+		}
+
 		u.UserKey = u.ID.Hex()
+		loaded[u.UserKey] = true
+
+		if current, ok := GetUser(u.UserKey); ok && current.(models.Credentials).UpdatedAt.After(u.UpdatedAt) {
+			continue
+		}
+		if u.Disabled {
+			removeUser(u.UserKey)
+			continue
+		}
 		addUser(u.UserKey, u)
 	}
+
+	userCredentialStorage.Range(func(key, value interface{}) bool {
+		if _, mock := mockUsers.Load(key); mock || loaded[key.(string)] {
+			return true
+		}
+		if value.(models.Credentials).UpdatedAt.Before(readAt) {
+			removeUser(key.(string))
+		}
+		return true
+	})
+}
+
+//RefreshUserCredentials Recarrega as credenciais periodicamente até o contexto ser cancelado
+func RefreshUserCredentials(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			LoadUserCredentials()
+		}
+	}
+}
+
+//ListCredentials Lista todas as credenciais, inclusive as desabilitadas
+func ListCredentials(ctx context.Context) ([]models.Credentials, error) {
+	return getRepository().GetCredentials(ctx)
+}
+
+//EncryptStoredCredentials Grava novamente as credenciais com client secrets de bancos, que passam a ser guardados cifrados
+//Retorna a quantidade de credenciais gravadas
+func EncryptStoredCredentials(ctx context.Context) (int, error) {
+	credentials, err := getRepository().GetCredentials(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, c := range credentials {
+		if len(c.Banks) == 0 {
+			continue
+		}
+		if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+			return total, err
+		}
+		total++
+	}
+	return total, nil
+}
+
+//CreateCredentials Cria as credenciais de um novo usuário e retorna a senha gerada
+//Retorna db.ErrUsernameTaken quando o nome já é usado por outras credenciais, mesmo desabilitadas
+func CreateCredentials(ctx context.Context, request models.CredentialsRequest) (models.Credentials, string, error) {
+	if strings.TrimSpace(request.Username) == "" {
+		return models.Credentials{}, "", errors.New("username cannot be empty")
+	}
+	if request.DailyRegistrationQuota < 0 {
+		return models.Credentials{}, "", errors.New("dailyRegistrationQuota cannot be negative")
+	}
+	if request.RegistrationsPerMinute < 0 || request.RegistrationBurst < 0 {
+		return models.Credentials{}, "", errors.New("registrationsPerMinute and registrationBurst cannot be negative")
+	}
+	for bank := range request.Banks {
+		if err := validateBankNumber(bank); err != nil {
+			return models.Credentials{}, "", err
+		}
+	}
+	if err := validateRetentionPolicy(request.Retention); err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	now := time.Now()
+	c := models.Credentials{
+		ID:                     primitive.NewObjectID(),
+		Username:               request.Username,
+		DailyRegistrationQuota: request.DailyRegistrationQuota,
+		RegistrationsPerMinute: request.RegistrationsPerMinute,
+		RegistrationBurst:      request.RegistrationBurst,
+		Banks:                  request.Banks,
+		Retention:              request.Retention,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+	}
+	if err := c.SetPassword(password); err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	if err := getRepository().SaveCredentials(ctx, c); err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	c.UserKey = c.ID.Hex()
+	addUser(c.UserKey, c)
+	return c, password, nil
+}
+
+//RotatePassword Gera uma nova senha para o usuário, invalidando a anterior
+func RotatePassword(ctx context.Context, key string) (models.Credentials, string, error) {
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return models.Credentials{}, "", err
+	}
+	if err := c.SetPassword(password); err != nil {
+		return models.Credentials{}, "", err
+	}
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.Credentials{}, "", err
+	}
+
+	c.UserKey = c.ID.Hex()
+	removeUser(c.UserKey)
+	if !c.Disabled {
+		addUser(c.UserKey, c)
+	}
+	return c, password, nil
+}
+
+//DisableCredentials Desabilita o usuário, que deixa de autenticar imediatamente
+func DisableCredentials(ctx context.Context, key string) (models.Credentials, error) {
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.Credentials{}, err
+	}
+
+	c.Disabled = true
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.Credentials{}, err
+	}
+
+	removeUser(c.ID.Hex())
+	return c, nil
+}
+
+//SetBankCredentials Define o certificado e as credenciais próprias do usuário no banco. Credenciais vazias removem as do banco
+func SetBankCredentials(ctx context.Context, key, bank string, credentials models.BankCredentials) (models.Credentials, error) {
+	if err := validateBankNumber(bank); err != nil {
+		return models.Credentials{}, err
+	}
+
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.Credentials{}, err
+	}
+
+	banks := make(map[string]models.BankCredentials, len(c.Banks)+1)
+	for number, current := range c.Banks {
+		banks[number] = current
+	}
+	if credentials == (models.BankCredentials{}) {
+		delete(banks, bank)
+	} else {
+		banks[bank] = credentials
+	}
+	c.Banks = banks
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.Credentials{}, err
+	}
+
+	c.UserKey = c.ID.Hex()
+	if !c.Disabled {
+		addUser(c.UserKey, c)
+	}
+	return c, nil
+}
+
+//SetRetentionPolicy Define a política de retenção própria do usuário. Sem política o usuário volta a usar a padrão
+func SetRetentionPolicy(ctx context.Context, key string, policy *models.RetentionPolicy) (models.Credentials, error) {
+	if err := validateRetentionPolicy(policy); err != nil {
+		return models.Credentials{}, err
+	}
+
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.Credentials{}, err
+	}
+
+	c.Retention = policy
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.Credentials{}, err
+	}
+
+	c.UserKey = c.ID.Hex()
+	if !c.Disabled {
+		addUser(c.UserKey, c)
+	}
+	return c, nil
+}
+
+func validateRetentionPolicy(policy *models.RetentionPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	return policy.Validate()
+}
+
+func validateBankNumber(bank string) error {
+	if _, err := strconv.Atoi(bank); err != nil {
+		return fmt.Errorf("invalid bank number %q", bank)
+	}
+	return nil
+}
+
+//CreateAPIKey Cria uma chave de API para o usuário com os escopos informados e retorna a chave gerada, que não é guardada
+func CreateAPIKey(ctx context.Context, key string, scopes []string) (models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return models.APIKey{}, "", errors.New("scopes cannot be empty")
+	}
+
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	id, err := randomString(generatedAPIKeyIDSize)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret, err := generatePassword()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	generated := apiKeyPrefix + secret
+
+	apiKey := models.APIKey{ID: id, Hash: HashAPIKey(generated), Scopes: scopes, CreatedAt: time.Now()}
+	c.APIKeys = append(append([]models.APIKey{}, c.APIKeys...), apiKey)
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	c.UserKey = c.ID.Hex()
+	if !c.Disabled {
+		addUser(c.UserKey, c)
+	}
+	return apiKey, generated, nil
+}
+
+//RevokeAPIKey Remove a chave de API do usuário, que deixa de autenticar imediatamente
+func RevokeAPIKey(ctx context.Context, key, id string) (models.Credentials, error) {
+	c, err := getRepository().GetCredentialsByKey(ctx, key)
+	if err != nil {
+		return models.Credentials{}, err
+	}
+
+	remaining := []models.APIKey{}
+	for _, apiKey := range c.APIKeys {
+		if apiKey.ID != id {
+			remaining = append(remaining, apiKey)
+		}
+	}
+	if len(remaining) == len(c.APIKeys) {
+		return models.Credentials{}, ErrAPIKeyNotFound
+	}
+	c.APIKeys = remaining
+	c.UpdatedAt = time.Now()
+
+	if err := getRepository().UpdateCredentials(ctx, c); err != nil {
+		return models.Credentials{}, err
+	}
+
+	c.UserKey = c.ID.Hex()
+	if !c.Disabled {
+		addUser(c.UserKey, c)
+	}
+	return c, nil
+}
+
+func removeUser(key string) {
+	if previous, ok := userCredentialStorage.Load(key); ok {
+		removeAPIKeys(previous.(models.Credentials))
+	}
+	userCredentialStorage.Delete(key)
+	verifiedPasswords.Delete(key)
+}
+
+func removeAPIKeys(c models.Credentials) {
+	for _, apiKey := range c.APIKeys {
+		apiKeys.Delete(apiKey.Hash)
+	}
+}
+
+func hashLegacyPassword(ctx context.Context, c *models.Credentials) error {
+	if err := c.SetPassword(c.Password); err != nil {
+		return err
+	}
+	c.UpdatedAt = time.Now()
+	return getRepository().UpdateCredentials(ctx, *c)
+}
+
+func passwordDigest(hash, password string) []byte {
+	d := sha256.Sum256([]byte(hash + "\x00" + password))
+	return d[:]
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, generatedPasswordSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
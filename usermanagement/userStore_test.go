@@ -0,0 +1,177 @@
+package usermanagement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mundipagg/boleto-api/db"
+	"github.com/mundipagg/boleto-api/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthenticate_WithCreatedCredentials(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+
+	c, password, err := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "gandalf", DailyRegistrationQuota: 10})
+	assert.Nil(t, err)
+	assert.NotEqual(t, password, c.Password, "a senha deve ser guardada como hash")
+
+	user, ok := Authenticate(c.UserKey, password)
+	assert.True(t, ok)
+	assert.Equal(t, "gandalf", user.Username)
+	assert.Equal(t, 10, user.DailyRegistrationQuota)
+
+	_, ok = Authenticate(c.UserKey, password)
+	assert.True(t, ok, "a segunda autenticação usa a senha já verificada")
+
+	_, ok = Authenticate(c.UserKey, "invalid")
+	assert.False(t, ok)
+}
+
+func TestRotatePassword_InvalidatesPreviousPassword(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	c, oldPassword, _ := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "saruman"})
+	Authenticate(c.UserKey, oldPassword)
+
+	_, newPassword, err := RotatePassword(context.Background(), c.UserKey)
+	assert.Nil(t, err)
+
+	_, ok := Authenticate(c.UserKey, oldPassword)
+	assert.False(t, ok)
+	_, ok = Authenticate(c.UserKey, newPassword)
+	assert.True(t, ok)
+}
+
+func TestDisableCredentials_StopsAuthenticating(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	c, password, _ := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "radagast"})
+
+	_, err := DisableCredentials(context.Background(), c.UserKey)
+	assert.Nil(t, err)
+
+	_, ok := Authenticate(c.UserKey, password)
+	assert.False(t, ok)
+
+	LoadUserCredentials()
+	_, ok = Authenticate(c.UserKey, password)
+	assert.False(t, ok, "usuários desabilitados não devem voltar após a recarga")
+
+	_, err = DisableCredentials(context.Background(), primitive.NewObjectID().Hex())
+	assert.Equal(t, db.ErrCredentialsNotFound, err)
+}
+
+func TestLoadUserCredentials_HashesLegacyPasswords(t *testing.T) {
+	repository := db.NewMemoryCredentialRepository()
+	Install(repository)
+	legacy := models.Credentials{ID: primitive.NewObjectID(), Username: "frodo", Password: "ring"}
+	repository.SaveCredentials(context.Background(), legacy)
+
+	LoadUserCredentials()
+
+	stored, _ := repository.GetCredentialsByKey(context.Background(), legacy.ID.Hex())
+	assert.True(t, stored.HasHashedPassword())
+	_, ok := Authenticate(legacy.ID.Hex(), "ring")
+	assert.True(t, ok)
+}
+
+func TestLoadUserCredentials_RemovesDeletedUsers(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	deleted := models.Credentials{ID: primitive.NewObjectID(), Username: "boromir", UpdatedAt: time.Now().Add(-time.Hour)}
+	deleted.SetPassword("horn")
+	addUser(deleted.ID.Hex(), deleted)
+
+	LoadUserCredentials()
+
+	_, ok := Authenticate(deleted.ID.Hex(), "horn")
+	assert.False(t, ok)
+}
+
+func TestLoadUserCredentials_KeepsMockUsers(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	key, password := LoadMockUserCredentials()
+
+	LoadUserCredentials()
+
+	_, ok := Authenticate(key, password)
+	assert.True(t, ok)
+}
+
+func TestLoadUserCredentials_KeepsCredentialsChangedAfterTheRead(t *testing.T) {
+	repository := &staleCredentialRepository{CredentialRepository: db.NewMemoryCredentialRepository()}
+	Install(repository)
+	rotated, _, _ := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "aragorn"})
+
+	var created models.Credentials
+	var createdPassword, rotatedPassword string
+	repository.afterRead = func() {
+		created, createdPassword, _ = CreateCredentials(context.Background(), models.CredentialsRequest{Username: "legolas"})
+		_, rotatedPassword, _ = RotatePassword(context.Background(), rotated.UserKey)
+	}
+
+	LoadUserCredentials()
+
+	_, ok := Authenticate(created.UserKey, createdPassword)
+	assert.True(t, ok, "credenciais criadas depois da leitura não devem ser removidas")
+	_, ok = Authenticate(rotated.UserKey, rotatedPassword)
+	assert.True(t, ok, "a leitura anterior à troca de senha não deve sobrescrevê-la")
+}
+
+// staleCredentialRepository simula alterações feitas pela API de administração enquanto a recarga está em andamento
+type staleCredentialRepository struct {
+	db.CredentialRepository
+	afterRead func()
+}
+
+func (r *staleCredentialRepository) GetCredentials(ctx context.Context) ([]models.Credentials, error) {
+	c, err := r.CredentialRepository.GetCredentials(ctx)
+	if r.afterRead != nil {
+		r.afterRead()
+		r.afterRead = nil
+	}
+	return c, err
+}
+
+func TestSetBankCredentials_ReplacesAndRemovesBankCredentials(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	c, password, _ := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "radagast"})
+
+	_, err := SetBankCredentials(context.Background(), c.UserKey, "33", models.BankCredentials{Certificate: "radagast-santander"})
+	assert.Nil(t, err)
+	user, _ := Authenticate(c.UserKey, password)
+	assert.Equal(t, "radagast-santander", user.ForBank(models.Santander).Certificate)
+
+	_, err = SetBankCredentials(context.Background(), c.UserKey, "33", models.BankCredentials{})
+	assert.Nil(t, err)
+	user, _ = Authenticate(c.UserKey, password)
+	assert.Empty(t, user.Banks)
+
+	_, err = SetBankCredentials(context.Background(), c.UserKey, "santander", models.BankCredentials{Certificate: "x"})
+	assert.EqualError(t, err, `invalid bank number "santander"`)
+}
+
+func TestCreateAPIKey_AuthenticatesUntilRevoked(t *testing.T) {
+	Install(db.NewMemoryCredentialRepository())
+	c, _, _ := CreateCredentials(context.Background(), models.CredentialsRequest{Username: "radagast"})
+
+	apiKey, key, err := CreateAPIKey(context.Background(), c.UserKey, []string{"boleto:read"})
+	assert.Nil(t, err)
+	assert.Equal(t, HashAPIKey(key), apiKey.Hash, "só o hash da chave deve ser guardado")
+
+	user, used, ok := AuthenticateAPIKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, "radagast", user.Username)
+	assert.Equal(t, []string{"boleto:read"}, used.Scopes)
+
+	_, _, ok = AuthenticateAPIKey(key + "x")
+	assert.False(t, ok)
+
+	_, err = RevokeAPIKey(context.Background(), c.UserKey, apiKey.ID)
+	assert.Nil(t, err)
+	_, _, ok = AuthenticateAPIKey(key)
+	assert.False(t, ok)
+
+	_, err = RevokeAPIKey(context.Background(), c.UserKey, apiKey.ID)
+	assert.Equal(t, ErrAPIKeyNotFound, err)
+}
@@ -1,7 +1,12 @@
 package util
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/mundipagg/boleto-api/config"
 	"github.com/mundipagg/boleto-api/log"
@@ -63,6 +68,7 @@ func TlsConector(e *flow.ExchangeMessage, u flow.URI, params ...interface{}) err
 	if len(params) > 0 {
 
 		var timeout = params[1].(map[string]string)["timeout"]
+		var bank = params[1].(map[string]string)["bank"]
 
 		switch t := params[0].(type) {
 		case *http.Transport:
@@ -71,12 +77,13 @@ func TlsConector(e *flow.ExchangeMessage, u flow.URI, params ...interface{}) err
 			var status int
 			var err error
 
+			// o trace-context já foi copiado para os headers da mensagem por tracing.InjectFlow
 			if config.Get().MockMode {
 				url = strings.Replace(u.GetRaw(), "tls", "http", 1)
-				response, status, err = Post(url, b, timeout, e.GetHeaderMap())
+				response, status, err = Post(context.Background(), bank, url, b, timeout, e.GetHeaderMap())
 			} else {
 				url = strings.Replace(u.GetRaw(), "tls", "https", 1)
-				response, status, err = PostTLS(url, b, timeout, e.GetHeaderMap(), t)
+				response, status, err = PostTLS(context.Background(), bank, url, b, timeout, e.GetHeaderMap(), t)
 			}
 			if err != nil {
 				e.SetHeader("error", err.Error())
@@ -93,3 +100,73 @@ func TlsConector(e *flow.ExchangeMessage, u flow.URI, params ...interface{}) err
 	}
 	return nil
 }
+
+//HTTPSConector substitui o conector https do flow, que nunca verifica o certificado do servidor
+//Aceita as mesmas opções do flow (method, timeout, auth, username e password) e bank, que escolhe a política de confiança
+func HTTPSConector(e *flow.ExchangeMessage, u flow.URI, params ...interface{}) error {
+	opts := map[string]string{}
+	if len(params) > 0 {
+		if o, ok := params[0].(map[string]string); ok {
+			opts = o
+		}
+	}
+	method := opts["method"]
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	switch t := e.GetBody().(type) {
+	case string:
+		body = strings.NewReader(t)
+	default:
+		j, err := json.Marshal(t)
+		if err != nil {
+			e.SetHeader("error", err.Error())
+			e.SetBody(err)
+			return err
+		}
+		body = strings.NewReader(string(j))
+	}
+
+	fail := func(err error) error {
+		e.SetHeader("error", err.Error())
+		e.SetBody(err)
+		return err
+	}
+
+	req, err := http.NewRequest(method, u.GetRaw(), body)
+	if err != nil {
+		return fail(err)
+	}
+	if opts["auth"] == "basic" {
+		req.SetBasicAuth(opts["username"], opts["password"])
+	}
+	for k, v := range e.GetHeaderMap() {
+		req.Header.Add(k, v)
+	}
+
+	transport, err := BankTransport(opts["bank"])
+	if err != nil {
+		return fail(err)
+	}
+	client := &http.Client{Transport: transport, Timeout: GetDurationTimeoutRequest(opts["timeout"]) * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(handshakeError(opts["bank"], err))
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+
+	for k := range resp.Header {
+		e.SetHeader(k, resp.Header.Get(k))
+	}
+	e.SetHeader("status", fmt.Sprintf("%d", resp.StatusCode))
+	e.SetBody(string(data))
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/mundipagg/boleto-api/certificate"
 	"github.com/mundipagg/boleto-api/log"
+	"github.com/mundipagg/boleto-api/tracing"
 
 	s "github.com/fullsailor/pkcs7"
 	"github.com/mundipagg/boleto-api/config"
@@ -27,28 +28,28 @@ import (
 
 var defaultDialer = &net.Dialer{Timeout: 16 * time.Second, KeepAlive: 16 * time.Second}
 
-var (
-	client            *http.Client
-	onceDefaultClient = &sync.Once{}
-	onceTransport     = &sync.Once{}
-	icpCert           certificate.ICPCertificate
-	transport         *http.Transport
-)
-
 // HTTPInterface is an abstraction for HTTP client
 type HTTPInterface interface {
 	Post(url string, headers map[string]string, body interface{}) (*http.Response, error)
 }
 
 // HTTPClient is the struct for making requests
-type HTTPClient struct{}
+// Bank selects the TLS trust policy used to verify the server certificate
+type HTTPClient struct {
+	Bank string
+}
 
 // PostFormEncoded is a function for making requests using Post Http method with content-type application/x-www-form-urlencoded.
 //
-// It receives an endpoint, params and pointer for log and it creates a new Post request, returning []byte and a error.
-func (hc *HTTPClient) PostFormURLEncoded(endpoint string, params map[string]string, log *log.Log) ([]byte, error) {
+// It receives a context, an endpoint, params and pointer for log and it creates a new Post request, returning []byte and a error.
+func (hc *HTTPClient) PostFormURLEncoded(ctx context.Context, endpoint string, params map[string]string, log *log.Log) ([]byte, error) {
+	transport, err := BankTransport(hc.Bank)
+	if err != nil {
+		return []byte(""), err
+	}
 	client := &http.Client{
-		Timeout: time.Second * 10,
+		Transport: transport,
+		Timeout:   time.Second * 10,
 	}
 
 	uri, err := url.ParseRequestURI(endpoint)
@@ -61,7 +62,7 @@ func (hc *HTTPClient) PostFormURLEncoded(endpoint string, params map[string]stri
 		values.Set(k, v)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, uri.String(), strings.NewReader(values.Encode())) // URL-encoded payload
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri.String(), strings.NewReader(values.Encode())) // URL-encoded payload
 
 	if err != nil {
 		return []byte(""), err
@@ -75,11 +76,12 @@ func (hc *HTTPClient) PostFormURLEncoded(endpoint string, params map[string]stri
 	for k, v := range header {
 		req.Header.Add(k, v)
 	}
+	tracing.InjectRequest(req)
 
 	log.Request(params, endpoint, header)
 	resp, err := client.Do(req)
 	if err != nil {
-		return []byte(""), err
+		return []byte(""), handshakeError(hc.Bank, err)
 	}
 	defer resp.Body.Close()
 
@@ -93,46 +95,42 @@ func (hc *HTTPClient) PostFormURLEncoded(endpoint string, params map[string]stri
 	return respByte, err
 }
 
-// DefaultHTTPClient retorna um cliente http configurado para dar um skip na validação do certificado digital
-func DefaultHTTPClient() *http.Client {
-	onceDefaultClient.Do(func() {
-		client = &http.Client{
-			Transport: &http.Transport{
-				Dial:                defaultDialer.Dial,
-				TLSHandshakeTimeout: 16 * time.Second,
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
-		}
-	})
-	return client
+// bankHTTPClient retorna um cliente http que verifica o certificado do servidor conforme a política de confiança do banco
+func bankHTTPClient(bank string) (*http.Client, error) {
+	transport, err := BankTransport(bank)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
 }
 
 //Post faz um requisição POST para uma URL e retorna o response, status e erro
-func PostReponseWithHeader(url, body, timeout string, header map[string]string) (string, string, int, error) {
-	return doRequest("POST", url, body, timeout, header)
+func PostReponseWithHeader(ctx context.Context, bank, url, body, timeout string, header map[string]string) (string, string, int, error) {
+	return doRequest(ctx, bank, "POST", url, body, timeout, header)
 }
 
 //Post faz um requisição POST para uma URL e retorna o response, status e erro
-func Post(url, body, timeout string, header map[string]string) (string, int, error) {
-	resp, _, st, err := doRequest("POST", url, body, timeout, header)
+func Post(ctx context.Context, bank, url, body, timeout string, header map[string]string) (string, int, error) {
+	resp, _, st, err := doRequest(ctx, bank, "POST", url, body, timeout, header)
 	return resp, st, err
 }
 
 //PostWithHeader faz um requisição POST para uma URL e retorna o response, status e erro
-func PostWithHeader(url, body, timeout string, header map[string]string) (string, map[string]interface{}, int, error) {
-	resp, respHeader, st, err := doRequestWithHeaderObject("POST", url, body, timeout, header)
+func PostWithHeader(ctx context.Context, bank, url, body, timeout string, header map[string]string) (string, map[string]interface{}, int, error) {
+	resp, respHeader, st, err := doRequestWithHeaderObject(ctx, bank, "POST", url, body, timeout, header)
 	return resp, respHeader, st, err
 }
 
-func doRequest(method, url, body, timeout string, header map[string]string) (string, string, int, error) {
+func doRequest(ctx context.Context, bank, method, url, body, timeout string, header map[string]string) (string, string, int, error) {
 	t := GetDurationTimeoutRequest(timeout) * time.Second
 
-	ctx, cls := context.WithTimeout(context.Background(), t)
+	ctx, cls := context.WithTimeout(ctx, t)
 	defer cls()
 
-	client := DefaultHTTPClient()
+	client, err := bankHTTPClient(bank)
+	if err != nil {
+		return "", "", 0, err
+	}
 
 	message := strings.NewReader(body)
 
@@ -145,9 +143,10 @@ func doRequest(method, url, body, timeout string, header map[string]string) (str
 			req.Header.Add(k, v)
 		}
 	}
+	tracing.InjectRequest(req)
 	resp, errResp := client.Do(req)
 	if errResp != nil {
-		return "", "", 0, errResp
+		return "", "", 0, handshakeError(bank, errResp)
 	}
 	defer resp.Body.Close()
 	respHeader := fmt.Sprintf("%v", resp.Header)
@@ -160,13 +159,16 @@ func doRequest(method, url, body, timeout string, header map[string]string) (str
 	return sData, respHeader, resp.StatusCode, nil
 }
 
-func doRequestWithHeaderObject(method, url, body, timeout string, header map[string]string) (string, map[string]interface{}, int, error) {
+func doRequestWithHeaderObject(ctx context.Context, bank, method, url, body, timeout string, header map[string]string) (string, map[string]interface{}, int, error) {
 	t := GetDurationTimeoutRequest(timeout) * time.Second
 
-	ctx, cls := context.WithTimeout(context.Background(), t)
+	ctx, cls := context.WithTimeout(ctx, t)
 	defer cls()
 
-	client := DefaultHTTPClient()
+	client, err := bankHTTPClient(bank)
+	if err != nil {
+		return "", nil, 0, err
+	}
 
 	message := strings.NewReader(body)
 
@@ -178,10 +180,11 @@ func doRequestWithHeaderObject(method, url, body, timeout string, header map[str
 	for k, v := range header {
 		req.Header.Add(k, v)
 	}
+	tracing.InjectRequest(req)
 
 	resp, errResp := client.Do(req)
 	if errResp != nil {
-		return "", nil, 0, errResp
+		return "", nil, 0, handshakeError(bank, errResp)
 	}
 	defer resp.Body.Close()
 
@@ -204,7 +207,8 @@ func convertHeader(respHeader map[string][]string) map[string]interface{} {
 }
 
 // BuildTLSTransport creates a TLS Client Transport from crt, ca and key files
-func BuildTLSTransport(con certificate.TLSCertificate) (*http.Transport, error) {
+// The server certificate is verified according to the bank trust policy
+func BuildTLSTransport(bank string, con certificate.TLSCertificate) (*http.Transport, error) {
 
 	if config.Get().MockMode {
 		return nil, nil
@@ -226,16 +230,77 @@ func BuildTLSTransport(con certificate.TLSCertificate) (*http.Transport, error)
 		return nil, err
 	}
 
-	transport = &http.Transport{
+	tlsConfig, err := TLSConfig(bank)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	return &http.Transport{
 		Dial:                defaultDialer.Dial,
 		TLSHandshakeTimeout: 16 * time.Second,
-		TLSClientConfig: &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			InsecureSkipVerify: true,
-		},
+		TLSClientConfig:     tlsConfig,
+	}, nil
+}
+
+// TLSTransportCache keeps the client certificate transport of a bank, rebuilt when the certificates
+// in the store or the bank trust policy change. If the rebuild fails the previous transport is kept
+type TLSTransportCache struct {
+	mu        sync.Mutex
+	key       string
+	transport *http.Transport
+}
+
+// Get returns the current transport, rebuilding it when the certificates were rotated
+func (c *TLSTransportCache) Get(bank string, con certificate.TLSCertificate) (*http.Transport, error) {
+	if config.Get().MockMode {
+		return nil, nil
 	}
 
-	return transport, nil
+	if err := certificate.EnsureTLS(con.Crt, con.Key); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d:%d:%s", certificate.Version(con.Crt), certificate.Version(con.Key), trustKey(bank))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transport != nil && c.key == key {
+		return c.transport, nil
+	}
+
+	t, err := BuildTLSTransport(bank, con)
+	if err != nil {
+		if c.transport == nil {
+			return nil, err
+		}
+		// keep the working transport and try again only after the next rotation
+		c.key = key
+		l := log.CreateLog()
+		l.Operation = "RebuildTLSTransport"
+		l.BankName = bank
+		l.ErrorWithBasic("Error rebuilding TLS transport, keeping the current one", "Error", err)
+		return c.transport, nil
+	}
+
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	c.transport, c.key = t, key
+	return t, nil
+}
+
+// TLSTransports keeps one TLSTransportCache per certificate set, so each recipient with its own
+// client certificate gets its own transport
+type TLSTransports struct {
+	caches sync.Map
+}
+
+// Get returns the current transport of the certificate set
+func (t *TLSTransports) Get(bank string, con certificate.TLSCertificate) (*http.Transport, error) {
+	cache, _ := t.caches.LoadOrStore(con, &TLSTransportCache{})
+	return cache.(*TLSTransportCache).Get(bank, con)
 }
 
 func getCertificateByType(key interface{}) []byte {
@@ -247,16 +312,20 @@ func getCertificateByType(key interface{}) []byte {
 	}
 }
 
-//Sign request
-func SignRequest(request string) (string, error) {
+//Sign request with the ICP certificate stored with the name. An empty name uses CERTIFICATE_ICP_NAME
+func SignRequest(request, certificateName string) (string, error) {
+	if certificateName == "" {
+		certificateName = config.Get().CertificateICPName
+	} else if err := certificate.EnsureICP(certificateName); err != nil {
+		return "", err
+	}
 
-	if icpCert == (certificate.ICPCertificate{}) {
-		icp, err := certificate.GetCertificateFromStore(config.Get().CertificateICPName)
-		if err != nil {
-			return "", err
-		}
-		icpCert = icp.(certificate.ICPCertificate)
+	// o certificado é lido do store a cada assinatura para usar a versão renovada
+	icp, err := certificate.GetCertificateFromStore(certificateName)
+	if err != nil {
+		return "", err
 	}
+	icpCert := icp.(certificate.ICPCertificate)
 
 	signedData, err := s.NewSignedData([]byte(request))
 	if err != nil {
@@ -324,12 +393,12 @@ func parseChainCertificates() (*x509.Certificate, error) {
 	return cert, nil
 }
 
-func doRequestTLS(method, url, body, timeout string, header map[string]string, transport *http.Transport) (string, int, error) {
+func doRequestTLS(ctx context.Context, bank, method, url, body, timeout string, header map[string]string, transport *http.Transport) (string, int, error) {
 	tlsClient := &http.Client{}
 	tlsClient.Transport = transport
 	tlsClient.Timeout = GetDurationTimeoutRequest(timeout) * time.Second
 	b := strings.NewReader(body)
-	req, err := http.NewRequest(method, url, b)
+	req, err := http.NewRequestWithContext(ctx, method, url, b)
 	if err != nil {
 		return "", 0, err
 	}
@@ -339,9 +408,10 @@ func doRequestTLS(method, url, body, timeout string, header map[string]string, t
 			req.Header.Add(k, v)
 		}
 	}
+	tracing.InjectRequest(req)
 	resp, err := tlsClient.Do(req)
 	if err != nil {
-		return "", 0, err
+		return "", 0, handshakeError(bank, err)
 	}
 	defer resp.Body.Close()
 	// Dump response
@@ -353,12 +423,12 @@ func doRequestTLS(method, url, body, timeout string, header map[string]string, t
 	return sData, resp.StatusCode, nil
 }
 
-func doRequestTLSWithHeader(method, url, body, timeout string, header map[string]string, transport *http.Transport) (string, map[string]interface{}, int, error) {
+func doRequestTLSWithHeader(ctx context.Context, bank, method, url, body, timeout string, header map[string]string, transport *http.Transport) (string, map[string]interface{}, int, error) {
 	tlsClient := &http.Client{}
 	tlsClient.Transport = transport
 	tlsClient.Timeout = GetDurationTimeoutRequest(timeout) * time.Second
 	b := strings.NewReader(body)
-	req, err := http.NewRequest(method, url, b)
+	req, err := http.NewRequestWithContext(ctx, method, url, b)
 	if err != nil {
 		return "", nil, 0, err
 	}
@@ -366,10 +436,11 @@ func doRequestTLSWithHeader(method, url, body, timeout string, header map[string
 	for k, v := range header {
 		req.Header.Add(k, v)
 	}
+	tracing.InjectRequest(req)
 
 	resp, err := tlsClient.Do(req)
 	if err != nil {
-		return "", nil, 0, err
+		return "", nil, 0, handshakeError(bank, err)
 	}
 	respHeader := convertHeader(resp.Header)
 	defer resp.Body.Close()
@@ -382,12 +453,12 @@ func doRequestTLSWithHeader(method, url, body, timeout string, header map[string
 	return sData, respHeader, resp.StatusCode, nil
 }
 
-func PostTLS(url, body, timeout string, header map[string]string, transport *http.Transport) (string, int, error) {
-	return doRequestTLS("POST", url, body, timeout, header, transport)
+func PostTLS(ctx context.Context, bank, url, body, timeout string, header map[string]string, transport *http.Transport) (string, int, error) {
+	return doRequestTLS(ctx, bank, "POST", url, body, timeout, header, transport)
 }
 
-func PostTLSWithHeader(url, body, timeout string, header map[string]string, transport *http.Transport) (string, map[string]interface{}, int, error) {
-	return doRequestTLSWithHeader("POST", url, body, timeout, header, transport)
+func PostTLSWithHeader(ctx context.Context, bank, url, body, timeout string, header map[string]string, transport *http.Transport) (string, map[string]interface{}, int, error) {
+	return doRequestTLSWithHeader(ctx, bank, "POST", url, body, timeout, header, transport)
 }
 
 //HeaderToMap converte um http Header para um dicionário string -> string
@@ -2,8 +2,12 @@ package util
 
 import (
 	"net/http"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/mundipagg/boleto-api/certificate"
+	"github.com/mundipagg/boleto-api/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,3 +40,58 @@ func getHeader() http.Header {
 	header.Add("UserAgent", "PostmanRuntime/7.28.4")
 	return header
 }
+
+func TestTLSTransportCache_RebuildsOnRotationAndKeepsTransportOnInvalidCertificate(t *testing.T) {
+	os.Clearenv()
+	config.Install(false, false, true)
+	con := certificate.TLSCertificate{Crt: "cache-crt", Key: "cache-key"}
+	pair := certificate.GenerateTestCertificate(time.Now().AddDate(1, 0, 0))
+	certificate.SetCertificateOnStore(con.Crt, certificate.SSLCertificate{PemData: pair})
+	certificate.SetCertificateOnStore(con.Key, certificate.SSLCertificate{PemData: pair})
+	cache := &TLSTransportCache{}
+
+	first, err := cache.Get("Santander", con)
+	assert.Nil(t, err)
+	same, _ := cache.Get("Santander", con)
+	assert.True(t, first == same)
+
+	rotated := certificate.GenerateTestCertificate(time.Now().AddDate(2, 0, 0))
+	certificate.SetCertificateOnStore(con.Crt, certificate.SSLCertificate{PemData: rotated})
+	certificate.SetCertificateOnStore(con.Key, certificate.SSLCertificate{PemData: rotated})
+	second, err := cache.Get("Santander", con)
+	assert.Nil(t, err)
+	assert.False(t, first == second)
+
+	certificate.SetCertificateOnStore(con.Crt, certificate.SSLCertificate{PemData: []byte("invalid")})
+	kept, err := cache.Get("Santander", con)
+	assert.Nil(t, err)
+	assert.True(t, second == kept)
+}
+
+func TestTLSTransports_KeepsOneTransportPerCertificateSetAndLoadsMissingCertificates(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CERTIFICATE_SOURCE", "transports-test")
+	config.Install(false, false, true)
+	loaded := []string{}
+	pair := certificate.GenerateTestCertificate(time.Now().AddDate(1, 0, 0))
+	certificate.RegisterProvider("transports-test", func() certificate.Provider {
+		return certificate.ProviderFunc(func(names ...string) error {
+			for _, name := range names {
+				loaded = append(loaded, name)
+				certificate.SetCertificateOnStore(name, pair)
+			}
+			return nil
+		})
+	})
+	transports := &TLSTransports{}
+
+	global, err := transports.Get("Citibank", certificate.TLSCertificate{Crt: "merchant-a", Key: "merchant-a"})
+	assert.Nil(t, err)
+	merchant, err := transports.Get("Citibank", certificate.TLSCertificate{Crt: "merchant-b", Key: "merchant-b-key"})
+	assert.Nil(t, err)
+	again, _ := transports.Get("Citibank", certificate.TLSCertificate{Crt: "merchant-a", Key: "merchant-a"})
+
+	assert.False(t, global == merchant)
+	assert.True(t, global == again)
+	assert.Equal(t, []string{"merchant-a", "merchant-b", "merchant-b-key"}, loaded)
+}
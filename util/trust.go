@@ -0,0 +1,371 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mundipagg/boleto-api/certificate"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/mundipagg/boleto-api/log"
+)
+
+// Códigos das falhas de handshake TLS com os bancos
+const (
+	HandshakeUnknownAuthority   = "MPTLSUnknownAuthority"
+	HandshakeHostname           = "MPTLSHostname"
+	HandshakeInvalidCertificate = "MPTLSInvalidCertificate"
+	HandshakePinMismatch        = "MPTLSPinMismatch"
+	HandshakeProtocolVersion    = "MPTLSProtocolVersion"
+	HandshakeFailure            = "MPTLSHandshake"
+)
+
+var errPinMismatch = errors.New("no certificate in the server chain matches the pinned public keys")
+
+//TrustPolicy Política de confiança no certificado do servidor de um banco
+type TrustPolicy struct {
+	// Verify indica se a cadeia e o nome do certificado do servidor são verificados
+	Verify bool
+	// CA é o nome no store de certificados do bundle PEM de CAs confiáveis. Vazio usa as raízes do sistema
+	CA string
+	// Pins são os hashes SHA-256, em base64, das chaves públicas (SPKI) aceitas na cadeia do servidor
+	Pins []string
+	// MinVersion é a versão mínima do TLS
+	MinVersion uint16
+}
+
+var (
+	defaultTrustPolicy = TrustPolicy{Verify: true, MinVersion: tls.VersionTLS12}
+
+	trustMu          sync.Mutex
+	trustRaw         string
+	trustPolicies    map[string]TrustPolicy
+	trustedTransport = sync.Map{}
+)
+
+type bankTransport struct {
+	raw       string
+	transport *http.Transport
+}
+
+//HandshakeError Falha no handshake TLS com um banco, com o código que identifica o motivo
+type HandshakeError struct {
+	Code string
+	Bank string
+	Err  error
+}
+
+func (e HandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake with %s failed: %v", e.Bank, e.Err)
+}
+
+//ErrorCode Retorna o código da falha
+func (e HandshakeError) ErrorCode() string {
+	return e.Code
+}
+
+func (e HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+//IsHandshakeErrorCode Indica se o código é de uma falha de handshake TLS
+func IsHandshakeErrorCode(code string) bool {
+	return strings.HasPrefix(code, "MPTLS")
+}
+
+//HandshakeErrorCode Retorna o código da falha de handshake TLS contida no erro
+func HandshakeErrorCode(err error) (string, bool) {
+	var handshake HandshakeError
+	if errors.As(err, &handshake) {
+		return handshake.Code, true
+	}
+	return "", false
+}
+
+// handshakeError converte as falhas de handshake em HandshakeError. Os demais erros são retornados sem alteração
+func handshakeError(bank string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var handshake HandshakeError
+	if errors.As(err, &handshake) {
+		return handshake
+	}
+	if code := handshakeCode(err); code != "" {
+		return HandshakeError{Code: code, Bank: bank, Err: err}
+	}
+	return err
+}
+
+func handshakeCode(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var record tls.RecordHeaderError
+
+	switch {
+	case errors.Is(err, errPinMismatch):
+		return HandshakePinMismatch
+	case errors.As(err, &unknownAuthority):
+		return HandshakeUnknownAuthority
+	case errors.As(err, &hostname):
+		return HandshakeHostname
+	case errors.As(err, &invalid):
+		return HandshakeInvalidCertificate
+	case strings.Contains(err.Error(), "protocol version"):
+		return HandshakeProtocolVersion
+	case errors.As(err, &record), strings.Contains(err.Error(), "tls: "):
+		return HandshakeFailure
+	}
+	return ""
+}
+
+//GetTrustPolicy Retorna a política de confiança do banco
+//TLS_TRUST sobrescreve a política padrão, que verifica o certificado com as raízes do sistema e exige TLS 1.2,
+//com entradas separadas por ponto e vírgula no formato [banco:]verify=false,ca=nome,pins=hash1|hash2,minVersion=1.2
+//Uma entrada sem banco vale para todos e serve de base às entradas de banco
+func GetTrustPolicy(bank string) TrustPolicy {
+	policies := configuredTrustPolicies()
+	if p, ok := policies[strings.ToLower(bank)]; ok {
+		return p
+	}
+	if p, ok := policies[""]; ok {
+		return p
+	}
+	return defaultTrustPolicy
+}
+
+//TrustedCertificates Retorna os nomes dos bundles de CAs configurados em TLS_TRUST, que devem ser carregados no store
+func TrustedCertificates() []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, p := range configuredTrustPolicies() {
+		if p.CA != "" && !seen[p.CA] {
+			seen[p.CA] = true
+			names = append(names, p.CA)
+		}
+	}
+	return names
+}
+
+func configuredTrustPolicies() map[string]TrustPolicy {
+	raw := config.Get().TLSTrust
+
+	trustMu.Lock()
+	defer trustMu.Unlock()
+
+	if trustPolicies == nil || raw != trustRaw {
+		parsed, err := ParseTrustPolicies(raw)
+		if err != nil {
+			l := log.CreateLog()
+			l.Operation = "ParseTrustPolicies"
+			l.ErrorWithBasic("Invalid entries in TLS_TRUST were ignored", "Error", err)
+		}
+		trustPolicies, trustRaw = parsed, raw
+	}
+	return trustPolicies
+}
+
+//ParseTrustPolicies Interpreta a configuração TLS_TRUST. Em caso de erro, as entradas válidas são mantidas
+func ParseTrustPolicies(raw string) (map[string]TrustPolicy, error) {
+	parsed := make(map[string]TrustPolicy)
+	var errs []string
+
+	entries := strings.Split(raw, ";")
+	// a entrada sem banco é aplicada antes, para servir de base às entradas de banco
+	for _, bankEntries := range []bool{false, true} {
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			bank, opts := "", entry
+			if i := strings.Index(entry, ":"); i >= 0 && !strings.Contains(entry[:i], "=") {
+				bank, opts = strings.ToLower(strings.TrimSpace(entry[:i])), entry[i+1:]
+			}
+			if (bank != "") != bankEntries {
+				continue
+			}
+
+			policy, ok := parsed[""]
+			if !ok {
+				policy = defaultTrustPolicy
+			}
+			p, err := applyTrustOptions(policy, opts)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", entry, err))
+				continue
+			}
+			parsed[bank] = p
+		}
+	}
+
+	if len(errs) > 0 {
+		return parsed, errors.New(strings.Join(errs, "; "))
+	}
+	return parsed, nil
+}
+
+func applyTrustOptions(p TrustPolicy, opts string) (TrustPolicy, error) {
+	for _, opt := range strings.Split(opts, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid option %q", opt)
+		}
+		value := strings.TrimSpace(kv[1])
+
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "verify":
+			verify, err := strconv.ParseBool(value)
+			if err != nil {
+				return p, fmt.Errorf("invalid verify %q", value)
+			}
+			p.Verify = verify
+		case "ca":
+			p.CA = value
+		case "pins":
+			p.Pins = nil
+			for _, pin := range strings.Split(value, "|") {
+				pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+				if decoded, err := base64.StdEncoding.DecodeString(pin); err != nil || len(decoded) != sha256.Size {
+					return p, fmt.Errorf("invalid pin %q", pin)
+				}
+				p.Pins = append(p.Pins, pin)
+			}
+		case "minversion":
+			switch value {
+			case "1.0", "1.1", "1.2", "1.3":
+				p.MinVersion = GetTLSVersion(value)
+			default:
+				return p, fmt.Errorf("invalid minVersion %q", value)
+			}
+		default:
+			return p, fmt.Errorf("unknown option %q", kv[0])
+		}
+	}
+	return p, nil
+}
+
+//TLSConfig Monta a configuração TLS das chamadas ao banco conforme a política de confiança dele
+//A verificação só é desligada com verify=false; os pins, quando configurados, são conferidos mesmo assim
+func TLSConfig(bank string) (*tls.Config, error) {
+	policy := GetTrustPolicy(bank)
+
+	cfg := &tls.Config{
+		MinVersion:         policy.MinVersion,
+		InsecureSkipVerify: !policy.Verify,
+	}
+
+	if policy.CA != "" {
+		pool, err := loadCAPool(policy.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(policy.Pins) > 0 {
+		pins := make(map[string]bool, len(policy.Pins))
+		for _, pin := range policy.Pins {
+			pins[pin] = true
+		}
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPins(pins, rawCerts, verifiedChains)
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifyPins aceita o servidor quando algum certificado da cadeia tem a chave pública fixada
+// Com a verificação ligada só as cadeias verificadas são consideradas
+func verifyPins(pins map[string]bool, rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	var certs []*x509.Certificate
+	if len(verifiedChains) > 0 {
+		for _, chain := range verifiedChains {
+			certs = append(certs, chain...)
+		}
+	} else {
+		for _, raw := range rawCerts {
+			if cert, err := x509.ParseCertificate(raw); err == nil {
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	for _, cert := range certs {
+		if pins[SPKIHash(cert)] {
+			return nil
+		}
+	}
+	return errPinMismatch
+}
+
+//SPKIHash Retorna o hash SHA-256, em base64, da chave pública do certificado, no formato usado nos pins
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func loadCAPool(name string) (*x509.CertPool, error) {
+	stored, err := certificate.GetCertificateFromStore(name)
+	if err != nil {
+		return nil, fmt.Errorf("CA bundle %s: %v", name, err)
+	}
+
+	var pem []byte
+	switch v := stored.(type) {
+	case certificate.SSLCertificate:
+		pem = v.PemData
+	case []byte:
+		pem = v
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s has no PEM certificates", name)
+	}
+	return pool, nil
+}
+
+// trustKey identifica a política de confiança do banco e a versão do bundle de CAs usado por ela
+func trustKey(bank string) string {
+	return fmt.Sprintf("%d:%s", certificate.Version(GetTrustPolicy(bank).CA), config.Get().TLSTrust)
+}
+
+//BankTransport Retorna o transport das chamadas ao banco sem certificado de cliente, recriado quando a política de confiança
+//ou o bundle de CAs dela mudam
+func BankTransport(bank string) (*http.Transport, error) {
+	key := strings.ToLower(bank)
+	raw := trustKey(bank)
+
+	if t, ok := trustedTransport.Load(key); ok && t.(bankTransport).raw == raw {
+		return t.(bankTransport).transport, nil
+	}
+
+	cfg, err := TLSConfig(bank)
+	if err != nil {
+		return nil, err
+	}
+	t := &http.Transport{
+		Dial:                defaultDialer.Dial,
+		TLSHandshakeTimeout: 16 * time.Second,
+		TLSClientConfig:     cfg,
+	}
+	trustedTransport.Store(key, bankTransport{raw: raw, transport: t})
+	return t, nil
+}
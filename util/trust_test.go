@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PMoneda/flow"
+	"github.com/mundipagg/boleto-api/certificate"
+	"github.com/mundipagg/boleto-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func arrangeTrust(raw string) {
+	os.Clearenv()
+	os.Setenv("TLS_TRUST", raw)
+	config.Install(true, false, true)
+}
+
+func arrangeTLSServer(t *testing.T, maxVersion uint16) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = &tls.Config{MaxVersion: maxVersion}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func serverPin(srv *httptest.Server) string {
+	return SPKIHash(srv.Certificate())
+}
+
+func TestParseTrustPolicies_BankEntriesStartFromDefaultEntry(t *testing.T) {
+	pin := strings.Repeat("A", 43) + "="
+
+	parsed, err := ParseTrustPolicies("minVersion=1.3; Itau:ca=itau-ca,pins=sha256/" + pin + "; Caixa:verify=false; Citi:minVersion=2.0")
+
+	assert.EqualError(t, err, `Citi:minVersion=2.0: invalid minVersion "2.0"`)
+	assert.Equal(t, TrustPolicy{Verify: true, MinVersion: tls.VersionTLS13}, parsed[""])
+	assert.Equal(t, TrustPolicy{Verify: true, CA: "itau-ca", Pins: []string{pin}, MinVersion: tls.VersionTLS13}, parsed["itau"])
+	assert.Equal(t, TrustPolicy{Verify: false, MinVersion: tls.VersionTLS13}, parsed["caixa"])
+	assert.NotContains(t, parsed, "citi")
+}
+
+func TestGetTrustPolicy_VerifiesByDefault(t *testing.T) {
+	arrangeTrust("Caixa:verify=false")
+
+	assert.Equal(t, TrustPolicy{Verify: true, MinVersion: tls.VersionTLS12}, GetTrustPolicy("Itau"))
+	assert.Equal(t, TrustPolicy{Verify: false, MinVersion: tls.VersionTLS12}, GetTrustPolicy("Caixa"))
+}
+
+func TestPost_WhenServerIsNotTrusted_ReturnUnknownAuthorityCode(t *testing.T) {
+	srv := arrangeTLSServer(t, 0)
+	arrangeTrust("")
+
+	_, _, err := Post(context.Background(), "Itau", srv.URL, "", "5", nil)
+
+	assert.IsType(t, HandshakeError{}, err)
+	assert.Equal(t, HandshakeUnknownAuthority, err.(HandshakeError).Code)
+}
+
+func TestPost_WhenCAIsInStore_TrustServer(t *testing.T) {
+	srv := arrangeTLSServer(t, 0)
+	certificate.SetCertificateOnStore("test-ca", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	arrangeTrust("Itau:ca=test-ca")
+
+	body, status, err := Post(context.Background(), "Itau", srv.URL, "", "5", nil)
+	_, _, hostnameErr := Post(context.Background(), "Itau", strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), "", "5", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "ok", body)
+	code, _ := HandshakeErrorCode(hostnameErr)
+	assert.Equal(t, HandshakeHostname, code)
+	assert.Equal(t, []string{"test-ca"}, TrustedCertificates())
+}
+
+func TestPost_WhenPinDoesNotMatch_ReturnPinMismatchCode(t *testing.T) {
+	srv := arrangeTLSServer(t, 0)
+	arrangeTrust(fmt.Sprintf("verify=false; Itau:pins=%s; Caixa:pins=%s", strings.Repeat("A", 43)+"=", serverPin(srv)))
+
+	_, _, err := Post(context.Background(), "Itau", srv.URL, "", "5", nil)
+	_, status, pinnedErr := Post(context.Background(), "Caixa", srv.URL, "", "5", nil)
+
+	code, _ := HandshakeErrorCode(err)
+	assert.Equal(t, HandshakePinMismatch, code)
+	assert.Nil(t, pinnedErr)
+	assert.Equal(t, 200, status)
+}
+
+func TestPost_WhenServerDoesNotSupportMinVersion_ReturnProtocolVersionCode(t *testing.T) {
+	srv := arrangeTLSServer(t, tls.VersionTLS12)
+	arrangeTrust("Itau:verify=false,minVersion=1.3")
+
+	_, _, err := Post(context.Background(), "Itau", srv.URL, "", "5", nil)
+
+	code, _ := HandshakeErrorCode(err)
+	assert.Equal(t, HandshakeProtocolVersion, code)
+}
+
+func TestHTTPSConector_UsesBankTrustPolicy(t *testing.T) {
+	srv := arrangeTLSServer(t, 0)
+	arrangeTrust("Caixa:verify=false")
+	flow.RegisterConnector("https", HTTPSConector)
+
+	trusted := flow.NewFlow().SetBody("").To(srv.URL, map[string]string{"method": "POST", "bank": "Caixa", "timeout": "5"})
+	untrusted := flow.NewFlow().SetBody("").To(srv.URL, map[string]string{"method": "POST", "bank": "Itau", "timeout": "5"})
+
+	assert.Equal(t, "ok", trusted.GetBody())
+	assert.Equal(t, "200", trusted.GetHeader().Get("status"))
+	code, _ := HandshakeErrorCode(untrusted.GetBody().(error))
+	assert.Equal(t, HandshakeUnknownAuthority, code)
+}